@@ -0,0 +1,42 @@
+// Command report renders a vBRIEF document as a self-contained HTML page.
+//
+// Usage:
+//
+//	go run ./examples/report plan.vbrief.json > report.html
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/parser"
+	"github.com/visionik/vBRIEF/api/go/pkg/render"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: report <file.vbrief.json>")
+		os.Exit(2)
+	}
+
+	f, err := os.Open(os.Args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	doc, err := parser.NewAutoParser().Parse(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := render.DefaultOptions()
+	opts.HighlightCriticalPath = true
+	opts.HighlightReady = true
+	out, err := render.HTML(doc, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Stdout.Write(out)
+}
@@ -27,9 +27,9 @@ func main() {
 		fmt.Printf("errors.Is(ErrUnknownFormat)=%v\n", errors.Is(err, parser.ErrUnknownFormat))
 	}
 
-	// validator: extensions not supported
+	// validator: extensions without registered rules are not supported
 	v := validator.NewValidator()
-	if err := v.ValidateExtensions(doc, []string{"timestamps"}); err != nil {
+	if err := v.ValidateExtensions(doc, []string{"teleportation"}); err != nil {
 		fmt.Printf("validate extensions error: %v\n", err)
 		fmt.Printf("errors.Is(ErrExtensionsNotSupported)=%v\n", errors.Is(err, validator.ErrExtensionsNotSupported))
 	}
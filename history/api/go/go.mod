@@ -4,11 +4,13 @@ go 1.25.4
 
 replace github.com/tron-format/trongo => github.com/visionik/trongo v0.0.0-20251227045632-5400bcb8e3ef
 
-require github.com/tron-format/trongo v0.0.0-00010101000000-000000000000
+require (
+	github.com/stretchr/testify v1.11.1
+	github.com/tron-format/trongo v0.0.0-00010101000000-000000000000
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.11.1 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
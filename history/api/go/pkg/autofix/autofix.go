@@ -0,0 +1,124 @@
+// Package autofix repairs documents by applying the fixes that validation
+// and lint rules attach to their findings: a missing version, the legacy
+// "inProgress" status, lowercase narrative keys, duplicate IDs, dangling
+// edges, a missing items array and the like.
+//
+// Fixes run through updater.Transaction on a copy of the document, so a
+// document is only changed once every pass has been applied, and never in
+// dry-run mode. Each call reports what it fixed, what is left and a
+// unified diff of the change.
+package autofix
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/updater"
+	"github.com/visionik/vBRIEF/api/go/pkg/validator"
+)
+
+// maxPasses bounds the number of check-and-fix rounds. One fix can expose
+// another finding (renaming a duplicate parent changes its children's
+// IDs), so Fix re-checks until nothing fixable remains.
+const maxPasses = 5
+
+// Options configures Fix.
+type Options struct {
+	// DryRun computes the fixes and the diff without changing the
+	// document.
+	DryRun bool
+	// Registry supplies the rules and their fixes. Nil means
+	// validator.DefaultRegistry().
+	Registry *validator.Registry
+	// Config selects the rules to run. An empty Profile means
+	// validator.ProfileStrict, so every fixable rule runs.
+	Config validator.Config
+}
+
+// Result describes the outcome of Fix.
+type Result struct {
+	// Fixed lists the findings that were fixed, in the order they were
+	// fixed.
+	Fixed validator.ValidationErrors
+	// Remaining lists the findings, of any severity, left after fixing.
+	Remaining validator.ValidationErrors
+	// Failed joins the errors of fixes that could not be applied, such as
+	// validator.ErrFixConflict. Their findings stay in Remaining.
+	Failed error
+	// Diff is a unified diff of the document's indented JSON before and
+	// after fixing, or "" if nothing changed.
+	Diff string
+	// Document is the fixed document: the one passed to Fix, or a fixed
+	// copy in dry-run mode.
+	Document *core.Document
+}
+
+// Changed reports whether any fix changed the document.
+func (r *Result) Changed() bool {
+	return r.Diff != ""
+}
+
+// Fix applies every safe fix for doc's findings. Findings are fixed last
+// to first, so fixes that remove array elements do not shift the paths of
+// findings still to be fixed. A fix that fails does not stop the others.
+func Fix(doc *core.Document, opts Options) (*Result, error) {
+	if doc == nil {
+		return nil, updater.ErrNilDocument
+	}
+	reg := opts.Registry
+	if reg == nil {
+		reg = validator.DefaultRegistry()
+	}
+	cfg := opts.Config
+	if cfg.Profile == "" {
+		cfg.Profile = validator.ProfileStrict
+	}
+	v, err := validator.NewValidatorWithOptions(validator.Options{Registry: reg, Config: cfg})
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Document: doc.Clone()}
+	u := updater.NewUpdater(res.Document).WithValidator(v)
+	for pass := 0; pass < maxPasses; pass++ {
+		var fixed validator.ValidationErrors
+		err := u.Transaction(func(u *updater.Updater) error {
+			var fixable validator.ValidationErrors
+			findings := validator.Check(v, u.Document())
+			for i := len(findings) - 1; i >= 0; i-- {
+				if reg.Fixable(findings[i]) {
+					fixable = append(fixable, findings[i])
+				}
+			}
+			fixed, res.Failed = reg.Fix(u.Document(), fixable)
+			return nil
+		})
+		var invalid validator.ValidationErrors
+		if err != nil && !errors.As(err, &invalid) {
+			return nil, err
+		}
+		res.Fixed = append(res.Fixed, fixed...)
+		if len(fixed) == 0 {
+			break
+		}
+	}
+	res.Remaining = validator.Check(v, res.Document)
+
+	after, err := json.MarshalIndent(res.Document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	res.Diff = unified("original", "fixed", string(before), string(after))
+
+	if !opts.DryRun {
+		*doc = *res.Document
+		res.Document = doc
+	}
+	return res, nil
+}
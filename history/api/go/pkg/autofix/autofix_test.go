@@ -0,0 +1,249 @@
+package autofix
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/parser"
+	"github.com/visionik/vBRIEF/api/go/pkg/updater"
+	"github.com/visionik/vBRIEF/api/go/pkg/validator"
+)
+
+// agentJSON is the kind of slightly malformed plan agents produce.
+const agentJSON = `{
+  "vBRIEFInfo": {},
+  "plan": {
+    "title": "Release",
+    "status": "inProgress",
+    "narratives": {"proposal": "Ship it"},
+    "items": [
+      {"id": "build", "title": "Build", "status": "completed"},
+      {"id": "test", "title": "Test", "status": "inProgress", "narrative": {"result": "Green"}},
+      {"id": "test", "title": "Test again", "status": "pending"},
+      {"id": "ship", "title": "Ship", "status": "pending"}
+    ],
+    "edges": [
+      {"from": "build", "to": "test", "type": "blocks"},
+      {"from": "test", "to": "deploy", "type": "blocks"},
+      {"from": "ghost", "to": "ship", "type": "blocks"},
+      {"from": "test", "to": "ship", "type": "blocks"}
+    ]
+  }
+}`
+
+func parse(t *testing.T, s string) *core.Document {
+	t.Helper()
+	doc, err := parser.NewJSONParser().ParseString(s)
+	require.NoError(t, err)
+	return doc
+}
+
+func TestFix(t *testing.T) {
+	doc := parse(t, agentJSON)
+	require.Error(t, validator.NewValidator().Validate(doc))
+
+	res, err := Fix(doc, Options{})
+	require.NoError(t, err)
+	require.NoError(t, res.Failed)
+	assert.Same(t, doc, res.Document)
+	assert.True(t, res.Changed())
+
+	plan := doc.Plan
+	assert.Equal(t, core.SpecVersion, doc.Info.Version)
+	assert.Equal(t, core.PlanStatusRunning, plan.Status)
+	assert.Equal(t, core.PlanItemStatusRunning, plan.Items[1].Status)
+	assert.Equal(t, map[string]string{"Proposal": "Ship it"}, plan.Narratives)
+	assert.Equal(t, map[string]string{"Result": "Green"}, plan.Items[1].Narrative)
+	assert.Equal(t, []string{"build", "test", "test-2", "ship"},
+		[]string{plan.Items[0].ID, plan.Items[1].ID, plan.Items[2].ID, plan.Items[3].ID})
+	assert.Equal(t, []core.Edge{
+		{From: "build", To: "test", Type: core.EdgeBlocks},
+		{From: "test", To: "ship", Type: core.EdgeBlocks},
+	}, plan.Edges)
+
+	assert.NoError(t, validator.NewValidator().Validate(doc))
+	for _, f := range res.Remaining {
+		assert.NotEqual(t, "error", string(f.Severity), "%s %s", f.Code, f.Field)
+	}
+
+	codes := make(map[string]int)
+	for _, f := range res.Fixed {
+		codes[f.Code]++
+	}
+	assert.Equal(t, map[string]int{
+		validator.CodeVersionRequired:  1,
+		validator.CodeLegacyStatus:     2,
+		validator.CodeNarrativeKeyCase: 2,
+		validator.CodeDuplicateID:      1,
+		validator.CodeDanglingEdge:     2,
+	}, codes)
+
+	again, err := Fix(doc, Options{})
+	require.NoError(t, err)
+	assert.Empty(t, again.Fixed, "fixing is idempotent")
+	assert.False(t, again.Changed())
+}
+
+func TestFix_DryRun(t *testing.T) {
+	doc := parse(t, agentJSON)
+	before := doc.Clone()
+
+	res, err := Fix(doc, Options{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, before, doc, "dry run leaves the document alone")
+	assert.NotSame(t, doc, res.Document)
+	assert.Equal(t, core.SpecVersion, res.Document.Info.Version)
+
+	assert.True(t, strings.HasPrefix(res.Diff, "--- original\n+++ fixed\n@@ "), res.Diff)
+	for _, line := range []string{
+		`-    "version": ""`,
+		`+    "version": "0.5"`,
+		`-    "status": "inProgress",`,
+		`+    "status": "running",`,
+		`-      "proposal": "Ship it"`,
+		`+      "Proposal": "Ship it"`,
+		`+        "id": "test-2",`,
+		`-        "to": "deploy",`,
+	} {
+		assert.Contains(t, res.Diff, "\n"+line+"\n")
+	}
+}
+
+func TestFix_Cases(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   *core.Document
+		opts  Options
+		check func(t *testing.T, res *Result)
+	}{
+		{
+			name: "missing items array",
+			doc:  &core.Document{Info: core.Info{Version: "0.5"}, Plan: &core.Plan{Title: "P", Status: core.PlanStatusDraft}},
+			check: func(t *testing.T, res *Result) {
+				assert.NotNil(t, res.Document.Plan.Items)
+				assert.Contains(t, res.Diff, "+    \"items\": []\n")
+			},
+		},
+		{
+			name: "legacy todo status",
+			doc: &core.Document{Info: core.Info{Version: "0.5"}, TodoList: &core.TodoList{Items: []core.TodoItem{
+				{Title: "A", Status: core.StatusInProgress},
+			}}},
+			check: func(t *testing.T, res *Result) {
+				assert.Equal(t, core.StatusRunning, res.Document.TodoList.Items[0].Status)
+			},
+		},
+		{
+			name: "nested duplicates keep hierarchical IDs unique",
+			doc: &core.Document{Info: core.Info{Version: "0.5"}, Plan: &core.Plan{Title: "P", Status: core.PlanStatusDraft,
+				Items: []core.PlanItem{{ID: "a", Title: "A", Status: core.PlanItemStatusPending, SubItems: []core.PlanItem{
+					{ID: "x", Title: "X", Status: core.PlanItemStatusPending},
+					{ID: "x", Title: "Y", Status: core.PlanItemStatusPending},
+					{ID: "x-2", Title: "Z", Status: core.PlanItemStatusPending},
+					{ID: "x", Title: "W", Status: core.PlanItemStatusPending},
+				}}},
+			}},
+			check: func(t *testing.T, res *Result) {
+				var ids []string
+				for _, item := range res.Document.Plan.Items[0].SubItems {
+					ids = append(ids, item.ID)
+				}
+				assert.Equal(t, []string{"x", "x-3", "x-2", "x-4"}, ids)
+			},
+		},
+		{
+			name: "profile limits fixes",
+			doc: &core.Document{Plan: &core.Plan{Title: "P", Status: core.PlanStatusInProgress, Items: []core.PlanItem{},
+				Narratives: map[string]string{"proposal": "x"}}},
+			opts: Options{Config: validator.Config{Profile: validator.ProfileCore}},
+			check: func(t *testing.T, res *Result) {
+				assert.Equal(t, core.SpecVersion, res.Document.Info.Version)
+				assert.Equal(t, core.PlanStatusInProgress, res.Document.Plan.Status)
+				assert.Contains(t, res.Document.Plan.Narratives, "proposal")
+			},
+		},
+		{
+			name: "failed fixes are reported",
+			doc: &core.Document{Info: core.Info{Version: "0.5"}, Plan: &core.Plan{Title: "P", Status: core.PlanStatusDraft, Items: []core.PlanItem{},
+				Narratives: map[string]string{"Proposal": "A", "proposal": "B"}}},
+			check: func(t *testing.T, res *Result) {
+				assert.ErrorIs(t, res.Failed, validator.ErrFixConflict)
+				assert.False(t, res.Changed())
+				var codes []string
+				for _, f := range res.Remaining {
+					codes = append(codes, f.Code)
+				}
+				assert.Contains(t, codes, validator.CodeNarrativeKeyCase)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Fix(tt.doc, tt.opts)
+			require.NoError(t, err)
+			tt.check(t, res)
+		})
+	}
+}
+
+func TestFix_Errors(t *testing.T) {
+	_, err := Fix(nil, Options{})
+	assert.ErrorIs(t, err, updater.ErrNilDocument)
+
+	_, err = Fix(&core.Document{}, Options{Config: validator.Config{Profile: "nope"}})
+	assert.ErrorIs(t, err, validator.ErrUnknownProfile)
+}
+
+func TestUnified(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15"
+	tests := []struct {
+		name string
+		b    string
+		want string
+	}{
+		{"equal", a, ""},
+		{
+			name: "one change",
+			b:    strings.Replace(a, "\n8\n", "\neight\n", 1),
+			want: "--- a\n+++ b\n@@ -5,7 +5,7 @@\n 5\n 6\n 7\n-8\n+eight\n 9\n 10\n 11\n",
+		},
+		{
+			name: "nearby changes share a hunk",
+			b:    strings.Replace(strings.Replace(a, "\n2\n", "\n", 1), "\n8\n", "\n8\n8.5\n", 1),
+			want: "--- a\n+++ b\n@@ -1,11 +1,11 @@\n 1\n-2\n 3\n 4\n 5\n 6\n 7\n 8\n+8.5\n 9\n 10\n 11\n",
+		},
+		{
+			name: "distant changes split",
+			b:    strings.Replace(strings.Replace(a, "1\n", "0\n1\n", 1), "\n15", "", 1),
+			want: "--- a\n+++ b\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -12,4 +13,3 @@\n 12\n 13\n 14\n-15\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, unified("a", "b", a, tt.b))
+		})
+	}
+}
+
+func TestUnified_ManyChanges(t *testing.T) {
+	var a, b strings.Builder
+	for i := range maxEdits {
+		fmt.Fprintf(&a, "a%d\n", i)
+		fmt.Fprintf(&b, "b%d\n", i)
+	}
+	b.WriteString("same\n")
+	a.WriteString("same\n")
+
+	diff := unified("a", "b", a.String(), b.String())
+	n := maxEdits + 1
+	header := fmt.Sprintf("--- a\n+++ b\n@@ -1,%d +1,%d @@\n", n, n)
+	require.True(t, strings.HasPrefix(diff, header), diff[:40])
+	body := strings.TrimPrefix(diff, header)
+	assert.Equal(t, n, strings.Count(body, "-"), "past maxEdits the whole file is replaced")
+	assert.Equal(t, n, strings.Count(body, "+"))
+	assert.True(t, strings.HasSuffix(body, "+b999\n+same\n"))
+}
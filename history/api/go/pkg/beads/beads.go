@@ -0,0 +1,218 @@
+// Package beads converts between Beads issue trackers (.beads/issues.jsonl)
+// and vBRIEF plans, following the Beads interop extension.
+//
+// Issues map to plan items that carry the issue ID in beadsId. Beads
+// "parent-child" dependencies become subItems; other dependency types
+// become typed edges:
+//
+//	blocks           blocks
+//	related          informs
+//	discovered-from  discovered-from (custom edge type)
+//
+// Status and priority are normalised:
+//
+//	open → pending, in_progress → inProgress, blocked → blocked,
+//	closed → completed (or cancelled, when close_reason is "cancelled")
+//	priority 0 → critical, 1 → high, 2 → medium, 3-4 → low
+//
+// The plan's beadsSyncedAt records the newest issue update applied, so Sync
+// can apply only the issues that changed since the previous sync.
+package beads
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// ErrInvalidIssue is returned when an issue cannot be read or mapped.
+var ErrInvalidIssue = errors.New("beads: invalid issue")
+
+// Issue is one line of a Beads issues.jsonl file.
+type Issue struct {
+	ID                 string       `json:"id"`
+	Title              string       `json:"title"`
+	Description        string       `json:"description,omitempty"`
+	Design             string       `json:"design,omitempty"`
+	AcceptanceCriteria string       `json:"acceptance_criteria,omitempty"`
+	Notes              string       `json:"notes,omitempty"`
+	Status             string       `json:"status"`
+	Priority           int          `json:"priority"`
+	IssueType          string       `json:"issue_type,omitempty"`
+	Assignee           string       `json:"assignee,omitempty"`
+	Labels             []string     `json:"labels,omitempty"`
+	CreatedAt          time.Time    `json:"created_at"`
+	UpdatedAt          time.Time    `json:"updated_at"`
+	ClosedAt           *time.Time   `json:"closed_at,omitempty"`
+	CloseReason        string       `json:"close_reason,omitempty"`
+	Dependencies       []Dependency `json:"dependencies,omitempty"`
+}
+
+// Dependency records that IssueID depends on DependsOnID.
+type Dependency struct {
+	IssueID     string         `json:"issue_id"`
+	DependsOnID string         `json:"depends_on_id"`
+	Type        DependencyType `json:"type"`
+	CreatedAt   *time.Time     `json:"created_at,omitempty"`
+	CreatedBy   string         `json:"created_by,omitempty"`
+}
+
+// DependencyType is the kind of a Beads dependency.
+type DependencyType string
+
+const (
+	// DependsBlocks means the dependency must close before the issue can start.
+	DependsBlocks DependencyType = "blocks"
+	// DependsRelated links related issues.
+	DependsRelated DependencyType = "related"
+	// DependsParentChild makes the dependency the issue's parent.
+	DependsParentChild DependencyType = "parent-child"
+	// DependsDiscoveredFrom records the issue was found while working on the dependency.
+	DependsDiscoveredFrom DependencyType = "discovered-from"
+)
+
+// Beads status values.
+const (
+	StatusOpen       = "open"
+	StatusInProgress = "in_progress"
+	StatusBlocked    = "blocked"
+	StatusClosed     = "closed"
+
+	closeReasonCancelled = "cancelled"
+)
+
+// Narrative keys used for issue text fields.
+const (
+	NarrativeDescription        = "Overview"
+	NarrativeDesign             = "Design"
+	NarrativeAcceptanceCriteria = "AcceptanceCriteria"
+	NarrativeNotes              = "Notes"
+)
+
+// Metadata keys used for issue fields without a PlanItem equivalent.
+const (
+	MetadataIssueType = "issueType"
+	MetadataAssignee  = "assignee"
+)
+
+// ReadIssues reads issues from JSONL, one issue per line.
+func ReadIssues(r io.Reader) ([]Issue, error) {
+	var issues []Issue
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var issue Issue
+		if err := json.Unmarshal([]byte(line), &issue); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidIssue, lineNo, err)
+		}
+		if issue.ID == "" {
+			return nil, fmt.Errorf("%w: line %d: missing id", ErrInvalidIssue, lineNo)
+		}
+		issues = append(issues, issue)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// WriteIssues writes issues as JSONL, one issue per line.
+func WriteIssues(w io.Writer, issues []Issue) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, issue := range issues {
+		if err := enc.Encode(issue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func statusFromBeads(issue Issue) core.PlanItemStatus {
+	switch issue.Status {
+	case StatusInProgress:
+		return core.PlanItemStatusRunning
+	case StatusBlocked:
+		return core.PlanItemStatusBlocked
+	case StatusClosed:
+		if strings.EqualFold(issue.CloseReason, closeReasonCancelled) {
+			return core.PlanItemStatusCancelled
+		}
+		return core.PlanItemStatusCompleted
+	default:
+		return core.PlanItemStatusPending
+	}
+}
+
+func statusToBeads(s core.PlanItemStatus) (status, closeReason string) {
+	switch s {
+	case core.PlanItemStatusRunning, core.PlanItemStatusInProgress:
+		return StatusInProgress, ""
+	case core.PlanItemStatusBlocked:
+		return StatusBlocked, ""
+	case core.PlanItemStatusCompleted:
+		return StatusClosed, ""
+	case core.PlanItemStatusCancelled:
+		return StatusClosed, closeReasonCancelled
+	default:
+		return StatusOpen, ""
+	}
+}
+
+func priorityFromBeads(p int) core.Priority {
+	switch p {
+	case 0:
+		return core.PriorityCritical
+	case 1:
+		return core.PriorityHigh
+	case 2:
+		return core.PriorityMedium
+	default:
+		return core.PriorityLow
+	}
+}
+
+func priorityToBeads(p core.Priority) int {
+	switch p {
+	case core.PriorityCritical:
+		return 0
+	case core.PriorityHigh:
+		return 1
+	case core.PriorityLow:
+		return 3
+	default:
+		return 2
+	}
+}
+
+func edgeTypeFromBeads(t DependencyType) core.EdgeType {
+	switch t {
+	case DependsBlocks:
+		return core.EdgeBlocks
+	case DependsRelated:
+		return core.EdgeInforms
+	default:
+		return core.EdgeType(t)
+	}
+}
+
+func edgeTypeToBeads(t core.EdgeType) DependencyType {
+	switch t {
+	case core.EdgeBlocks:
+		return DependsBlocks
+	case core.EdgeInforms:
+		return DependsRelated
+	default:
+		return DependencyType(t)
+	}
+}
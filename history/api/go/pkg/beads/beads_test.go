@@ -0,0 +1,285 @@
+package beads
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// issuesJSONL is shaped like a .beads/issues.jsonl export: an epic with two
+// hierarchical child issues, a blocking dependency between the children and
+// a standalone bug discovered while working on one of them.
+const issuesJSONL = `{"id":"bd-a1","title":"Auth epic","description":"Replace session auth","status":"in_progress","priority":1,"issue_type":"epic","created_at":"2025-12-01T10:00:00Z","updated_at":"2025-12-02T10:00:00Z"}
+{"id":"bd-a1.1","title":"Token service","status":"closed","priority":0,"issue_type":"task","assignee":"ana","labels":["backend"],"created_at":"2025-12-01T11:00:00Z","updated_at":"2025-12-03T09:00:00Z","closed_at":"2025-12-03T09:00:00Z","dependencies":[{"issue_id":"bd-a1.1","depends_on_id":"bd-a1","type":"parent-child"}]}
+
+{"id":"bd-a1.2","title":"Login flow","acceptance_criteria":"Users can sign in","status":"open","priority":2,"created_at":"2025-12-01T12:00:00Z","updated_at":"2025-12-01T12:00:00Z","dependencies":[{"issue_id":"bd-a1.2","depends_on_id":"bd-a1","type":"parent-child"},{"issue_id":"bd-a1.2","depends_on_id":"bd-a1.1","type":"blocks"}]}
+{"id":"bd-f9","title":"Clock skew bug","status":"closed","close_reason":"cancelled","priority":3,"created_at":"2025-12-02T08:00:00Z","updated_at":"2025-12-02T08:00:00Z","closed_at":"2025-12-02T08:00:00Z","dependencies":[{"issue_id":"bd-f9","depends_on_id":"bd-a1.1","type":"discovered-from"}]}
+`
+
+func ts(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func readFixture(t *testing.T) []Issue {
+	t.Helper()
+	issues, err := ReadIssues(strings.NewReader(issuesJSONL))
+	require.NoError(t, err)
+	return issues
+}
+
+func TestReadIssues(t *testing.T) {
+	issues := readFixture(t)
+	require.Len(t, issues, 4)
+	assert.Equal(t, "bd-a1.1", issues[1].ID)
+	assert.Equal(t, 0, issues[1].Priority)
+	assert.Equal(t, []Dependency{{IssueID: "bd-a1.1", DependsOnID: "bd-a1", Type: DependsParentChild}}, issues[1].Dependencies)
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := ReadIssues(strings.NewReader("{\"id\":\"a\"}\nnot json\n"))
+		require.ErrorIs(t, err, ErrInvalidIssue)
+		assert.Contains(t, err.Error(), "line 2")
+
+		_, err = ReadIssues(strings.NewReader(`{"title":"x"}`))
+		require.ErrorIs(t, err, ErrInvalidIssue)
+		assert.Contains(t, err.Error(), "missing id")
+	})
+}
+
+func TestImport(t *testing.T) {
+	doc, err := Import(readFixture(t), "")
+	require.NoError(t, err)
+
+	plan := doc.Plan
+	assert.Equal(t, core.SpecVersion, doc.Info.Version)
+	assert.Equal(t, "Beads issues", plan.Title)
+	require.NotNil(t, plan.BeadsSyncedAt)
+	assert.Equal(t, ts("2025-12-03T09:00:00Z"), *plan.BeadsSyncedAt)
+
+	require.Len(t, plan.Items, 2)
+	epic := plan.Items[0]
+	assert.Equal(t, "bd-a1", epic.ID)
+	assert.Equal(t, "bd-a1", epic.BeadsID)
+	assert.Equal(t, core.PlanItemStatusRunning, epic.Status)
+	assert.Equal(t, core.PriorityHigh, epic.Priority)
+	assert.Equal(t, map[string]string{NarrativeDescription: "Replace session auth"}, epic.Narrative)
+	assert.Equal(t, map[string]interface{}{MetadataIssueType: "epic"}, epic.Metadata)
+
+	require.Len(t, epic.SubItems, 2)
+	token := epic.SubItems[0]
+	assert.Equal(t, "1", token.ID, "hierarchical Beads IDs keep their last segment")
+	assert.Equal(t, core.PlanItemStatusCompleted, token.Status)
+	assert.Equal(t, core.PriorityCritical, token.Priority)
+	assert.Equal(t, []string{"backend"}, token.Tags)
+	assert.Equal(t, "ana", token.Metadata[MetadataAssignee])
+	require.NotNil(t, token.Completed)
+	assert.Equal(t, ts("2025-12-03T09:00:00Z"), *token.Completed)
+
+	login := epic.SubItems[1]
+	assert.Equal(t, core.PriorityMedium, login.Priority)
+	assert.Equal(t, "Users can sign in", login.Narrative[NarrativeAcceptanceCriteria])
+
+	bug := plan.Items[1]
+	assert.Equal(t, core.PlanItemStatusCancelled, bug.Status)
+	assert.Equal(t, core.PriorityLow, bug.Priority)
+
+	assert.Equal(t, []core.Edge{
+		{From: "bd-a1.1", To: "bd-a1.2", Type: core.EdgeBlocks},
+		{From: "bd-a1.1", To: "bd-f9", Type: core.EdgeType("discovered-from")},
+	}, plan.Edges)
+}
+
+func TestSync_Incremental(t *testing.T) {
+	issues := readFixture(t)
+	doc, err := Import(issues, "Auth")
+	require.NoError(t, err)
+	plan := doc.Plan
+
+	// Local planning additions that Beads does not own.
+	plan.Items[0].Narrative["Risks"] = "Token leakage"
+	plan.Items[0].SubItems[1].Metadata = map[string]interface{}{"estimate": "2d"}
+	plan.Items = append(plan.Items, core.PlanItem{ID: "docs", Title: "Write docs", Status: core.PlanItemStatusPending})
+	plan.Edges = append(plan.Edges, core.Edge{From: "bd-a1.2", To: "docs", Type: core.EdgeInforms})
+
+	t.Run("nothing changed", func(t *testing.T) {
+		report, err := Sync(plan, issues)
+		require.NoError(t, err)
+		assert.Empty(t, report.Added)
+		assert.Empty(t, report.Updated)
+		assert.Len(t, report.Unchanged, 4)
+	})
+
+	// The login issue is started, unparented and loses its blocker; a new
+	// issue is filed under the epic.
+	issues[2].Status = StatusInProgress
+	issues[2].UpdatedAt = ts("2025-12-04T08:00:00Z")
+	issues[2].Dependencies = nil
+	issues = append(issues, Issue{
+		ID: "bd-a1.3", Title: "Logout", Status: StatusOpen, Priority: 2,
+		CreatedAt: ts("2025-12-04T09:00:00Z"), UpdatedAt: ts("2025-12-04T09:00:00Z"),
+		Dependencies: []Dependency{
+			{IssueID: "bd-a1.3", DependsOnID: "bd-a1", Type: DependsParentChild},
+			{IssueID: "bd-a1.3", DependsOnID: "bd-a1.2", Type: DependsRelated},
+		},
+	})
+
+	report, err := Sync(plan, issues)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bd-a1.3"}, report.Added)
+	assert.Equal(t, []string{"bd-a1.2"}, report.Updated)
+	assert.Equal(t, []string{"bd-a1", "bd-a1.1", "bd-f9"}, report.Unchanged)
+	assert.Equal(t, ts("2025-12-04T09:00:00Z"), *plan.BeadsSyncedAt)
+
+	require.Len(t, plan.Items, 4)
+	epic := plan.Items[0]
+	assert.Equal(t, "Token leakage", epic.Narrative["Risks"], "unchanged items are untouched")
+	require.Len(t, epic.SubItems, 2)
+	assert.Equal(t, "1", epic.SubItems[0].ID)
+	assert.Equal(t, "3", epic.SubItems[1].ID)
+
+	login := plan.Items[3]
+	assert.Equal(t, "2", login.ID, "moved items keep their local ID")
+	assert.Equal(t, core.PlanItemStatusRunning, login.Status)
+	assert.Equal(t, map[string]interface{}{"estimate": "2d"}, login.Metadata, "fields Beads does not own survive")
+
+	assert.ElementsMatch(t, []core.Edge{
+		{From: "bd-a1.1", To: "bd-f9", Type: core.EdgeType("discovered-from")},
+		{From: "2", To: "docs", Type: core.EdgeInforms},
+		{From: "2", To: "bd-a1.3", Type: core.EdgeInforms},
+	}, plan.Edges, "edges follow moved items and the dropped blocker is removed")
+}
+
+func TestSync_NewIssueBeforeWatermark(t *testing.T) {
+	issues := readFixture(t)
+	doc, err := Import(issues, "Auth")
+	require.NoError(t, err)
+	plan := doc.Plan
+	synced := *plan.BeadsSyncedAt
+
+	// Filed on another clone before the last sync and merged in since.
+	issues = append(issues, Issue{
+		ID: "bd-c3", Title: "Audit log", Status: StatusOpen, Priority: 2,
+		CreatedAt: ts("2025-11-01T09:00:00Z"), UpdatedAt: ts("2025-11-01T09:00:00Z"),
+	})
+	require.True(t, issues[len(issues)-1].UpdatedAt.Before(synced))
+
+	report, err := Sync(plan, issues)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bd-c3"}, report.Added)
+	assert.Empty(t, report.Updated)
+	assert.NotContains(t, report.Unchanged, "bd-c3")
+	assert.Equal(t, synced, *plan.BeadsSyncedAt, "an older issue does not move the watermark back")
+
+	require.Len(t, plan.Items, 3)
+	assert.Equal(t, "Audit log", plan.Items[2].Title)
+	assert.Equal(t, "bd-c3", plan.Items[2].BeadsID)
+}
+
+func TestSync_Errors(t *testing.T) {
+	_, err := Sync(nil, nil)
+	assert.ErrorIs(t, err, core.ErrNoPlan)
+
+	_, err = Sync(&core.Plan{}, []Issue{{ID: "a"}, {ID: "a"}})
+	require.ErrorIs(t, err, ErrInvalidIssue)
+	assert.Contains(t, err.Error(), `duplicate id "a"`)
+}
+
+func TestExport(t *testing.T) {
+	now := ts("2025-12-10T00:00:00Z")
+	plan := &core.Plan{
+		Title:  "Release",
+		Status: core.PlanStatusInProgress,
+		Items: []core.PlanItem{
+			{ID: "api", Title: "API", Status: core.PlanItemStatusInProgress, Priority: core.PriorityHigh, BeadsID: "bd-77",
+				Narrative: map[string]string{NarrativeDescription: "Public API"},
+				SubItems: []core.PlanItem{
+					{ID: "auth", Title: "Auth", Status: core.PlanItemStatusCancelled, Tags: []string{"security"}},
+					{Title: "Untracked note", Status: core.PlanItemStatusPending},
+				}},
+			{ID: "docs", Title: "Docs", Status: core.PlanItemStatusCompleted, Completed: &now,
+				Metadata: map[string]interface{}{MetadataAssignee: "li"}},
+		},
+		Edges: []core.Edge{
+			{From: "api.auth", To: "docs", Type: core.EdgeBlocks},
+			{From: "api", To: "docs", Type: core.EdgeSuggests},
+		},
+	}
+
+	issues, err := Export(plan, ExportOptions{Now: now})
+	require.NoError(t, err)
+	require.Len(t, issues, 4)
+
+	assert.Equal(t, Issue{
+		ID: "bd-77", Title: "API", Description: "Public API", Status: StatusInProgress, Priority: 1,
+		CreatedAt: now, UpdatedAt: now,
+	}, issues[0])
+
+	auth := issues[1]
+	assert.Equal(t, "bd-api.auth", auth.ID)
+	assert.Equal(t, StatusClosed, auth.Status)
+	assert.Equal(t, "cancelled", auth.CloseReason)
+	assert.Equal(t, []string{"security"}, auth.Labels)
+	assert.Equal(t, []Dependency{{IssueID: "bd-api.auth", DependsOnID: "bd-77", Type: DependsParentChild}}, auth.Dependencies)
+
+	assert.Equal(t, "bd-item-1", issues[2].ID)
+
+	docs := issues[3]
+	assert.Equal(t, "li", docs.Assignee)
+	require.NotNil(t, docs.ClosedAt)
+	assert.Equal(t, now, *docs.ClosedAt)
+	assert.Equal(t, []Dependency{
+		{IssueID: "bd-docs", DependsOnID: "bd-api.auth", Type: DependsBlocks},
+		{IssueID: "bd-docs", DependsOnID: "bd-77", Type: DependencyType("suggests")},
+	}, docs.Dependencies)
+
+	t.Run("changed only", func(t *testing.T) {
+		synced := ts("2025-12-05T00:00:00Z")
+		old, recent := ts("2025-12-01T00:00:00Z"), ts("2025-12-06T00:00:00Z")
+		p := &core.Plan{BeadsSyncedAt: &synced, Items: []core.PlanItem{
+			{ID: "old", Title: "Old", Status: core.PlanItemStatusPending, Updated: &old},
+			{ID: "new", Title: "New", Status: core.PlanItemStatusPending, Updated: &recent},
+		}}
+		issues, err := Export(p, ExportOptions{Prefix: "x-", ChangedOnly: true})
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		assert.Equal(t, "x-new", issues[0].ID)
+	})
+
+	t.Run("no plan", func(t *testing.T) {
+		_, err := Export(nil, ExportOptions{})
+		assert.ErrorIs(t, err, core.ErrNoPlan)
+	})
+}
+
+func TestRoundTrip(t *testing.T) {
+	issues := readFixture(t)
+	doc, err := Import(issues, "")
+	require.NoError(t, err)
+
+	exported, err := Export(doc.Plan, ExportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, issues, exported)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteIssues(&buf, exported))
+	again, err := ReadIssues(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, issues, again)
+}
+
+func TestStatusToBeads(t *testing.T) {
+	for _, s := range []core.PlanItemStatus{core.PlanItemStatusRunning, core.PlanItemStatusInProgress} {
+		status, reason := statusToBeads(s)
+		assert.Equal(t, StatusInProgress, status, s)
+		assert.Empty(t, reason)
+	}
+	assert.Equal(t, core.PlanItemStatusRunning, statusFromBeads(Issue{Status: StatusInProgress}))
+}
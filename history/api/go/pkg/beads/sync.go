@@ -0,0 +1,468 @@
+package beads
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+// SyncReport lists, by Beads ID, what a Sync did to the plan.
+type SyncReport struct {
+	// Added are issues that had no item and were added to the plan.
+	Added []string
+	// Updated are issues whose existing item was rewritten.
+	Updated []string
+	// Unchanged are issues not updated since the plan's beadsSyncedAt.
+	Unchanged []string
+}
+
+// Import builds a Plan document titled title from Beads issues.
+func Import(issues []Issue, title string) (*core.Document, error) {
+	if title == "" {
+		title = "Beads issues"
+	}
+	plan := &core.Plan{
+		Title:      title,
+		Status:     core.PlanStatusDraft,
+		Narratives: make(map[string]string),
+	}
+	if _, err := Sync(plan, issues); err != nil {
+		return nil, err
+	}
+	return &core.Document{
+		Info: core.Info{Version: core.SpecVersion},
+		Plan: plan,
+	}, nil
+}
+
+// node is a mutable plan item used while restructuring the plan. Edges
+// refer to nodes rather than IDs so they survive items moving.
+type node struct {
+	item     core.PlanItem
+	parent   *node
+	children []*node
+}
+
+type nodeEdge struct {
+	from, to *node
+	typ      core.EdgeType
+}
+
+// Sync applies Beads issues to plan in place.
+//
+// Issues are matched to items by beadsId; unmatched issues are added, even
+// if they predate plan.BeadsSyncedAt. Matched issues are only applied when
+// updated after plan.BeadsSyncedAt (always, if it is unset). Applying an
+// issue rewrites the item fields Beads owns (title, status, priority,
+// tags, timestamps, the Overview, Design, AcceptanceCriteria and Notes
+// narratives, and the assignee and issueType metadata), moves it under its
+// parent-child dependency, and replaces the edges into it that come from
+// other Beads items. Everything else on the item is left alone.
+// BeadsSyncedAt is advanced to the newest issue update.
+func Sync(plan *core.Plan, issues []Issue) (SyncReport, error) {
+	var report SyncReport
+	if plan == nil {
+		return report, core.ErrNoPlan
+	}
+	seen := make(map[string]bool, len(issues))
+	for _, issue := range issues {
+		if issue.ID == "" {
+			return report, fmt.Errorf("%w: missing id", ErrInvalidIssue)
+		}
+		if seen[issue.ID] {
+			return report, fmt.Errorf("%w: duplicate id %q", ErrInvalidIssue, issue.ID)
+		}
+		seen[issue.ID] = true
+	}
+
+	roots, edges, kept := load(plan)
+	byBeadsID := make(map[string]*node)
+	var walk func(ns []*node)
+	walk = func(ns []*node) {
+		for _, n := range ns {
+			if id := n.item.BeadsID; id != "" && byBeadsID[id] == nil {
+				byBeadsID[id] = n
+			}
+			walk(n.children)
+		}
+	}
+	walk(roots)
+
+	var changed []Issue
+	watermark := plan.BeadsSyncedAt
+	for _, issue := range issues {
+		// The watermark only skips issues the plan already has; one merged
+		// in from another clone may be older and still be new here.
+		if plan.BeadsSyncedAt != nil && byBeadsID[issue.ID] != nil && !issue.UpdatedAt.After(*plan.BeadsSyncedAt) {
+			report.Unchanged = append(report.Unchanged, issue.ID)
+			continue
+		}
+		changed = append(changed, issue)
+		if watermark == nil || issue.UpdatedAt.After(*watermark) {
+			t := issue.UpdatedAt
+			watermark = &t
+		}
+	}
+
+	// Create or update items first so parents and dependency targets
+	// resolve regardless of issue order.
+	fresh := make(map[*node]bool)
+	for _, issue := range changed {
+		n := byBeadsID[issue.ID]
+		if n == nil {
+			n = &node{}
+			byBeadsID[issue.ID] = n
+			fresh[n] = true
+			report.Added = append(report.Added, issue.ID)
+		} else {
+			report.Updated = append(report.Updated, issue.ID)
+		}
+		applyIssue(&n.item, issue)
+	}
+
+	for _, issue := range changed {
+		n := byBeadsID[issue.ID]
+		var parent *node
+		for _, dep := range issue.Dependencies {
+			if dep.Type == DependsParentChild {
+				if p := byBeadsID[dep.DependsOnID]; p != nil && !isAncestor(n, p) {
+					parent = p
+				}
+				break
+			}
+		}
+		if fresh[n] || parent != n.parent {
+			roots = detach(roots, n)
+			if fresh[n] {
+				n.item.ID = localID(issue.ID, parent)
+			}
+			roots = attach(roots, parent, n)
+		}
+	}
+
+	for _, issue := range changed {
+		n := byBeadsID[issue.ID]
+		filtered := edges[:0]
+		for _, e := range edges {
+			if e.to != n || e.from.item.BeadsID == "" {
+				filtered = append(filtered, e)
+			}
+		}
+		edges = filtered
+		for _, dep := range issue.Dependencies {
+			if dep.Type == DependsParentChild {
+				continue
+			}
+			if from := byBeadsID[dep.DependsOnID]; from != nil && from != n {
+				edges = append(edges, nodeEdge{from: from, to: n, typ: edgeTypeFromBeads(dep.Type)})
+			}
+		}
+	}
+
+	store(plan, roots, edges, kept)
+	plan.BeadsSyncedAt = watermark
+	return report, nil
+}
+
+// load converts the plan into a node tree. Edges that resolve are returned
+// as node edges; dangling edges are returned unchanged.
+func load(plan *core.Plan) ([]*node, []nodeEdge, []core.Edge) {
+	g := graph.New(plan)
+	nodes := make(map[*graph.Node]*node)
+	var roots []*node
+	for _, gn := range g.Nodes() {
+		n := &node{item: *gn.Item}
+		n.item.SubItems = nil
+		nodes[gn] = n
+		if gn.Parent == nil {
+			roots = append(roots, n)
+		} else {
+			p := nodes[gn.Parent]
+			n.parent = p
+			p.children = append(p.children, n)
+		}
+	}
+	var edges []nodeEdge
+	for _, e := range g.Edges() {
+		edges = append(edges, nodeEdge{from: nodes[g.Node(e.From)], to: nodes[g.Node(e.To)], typ: e.Type})
+	}
+	return roots, edges, g.Dangling()
+}
+
+// store writes the node tree and edges back into the plan. Edges whose
+// endpoints are no longer addressable are dropped.
+func store(plan *core.Plan, roots []*node, edges []nodeEdge, kept []core.Edge) {
+	ids := make(map[*node]string)
+	var build func(ns []*node, parentID string, addressable bool) []core.PlanItem
+	build = func(ns []*node, parentID string, addressable bool) []core.PlanItem {
+		var items []core.PlanItem
+		for _, n := range ns {
+			item := n.item
+			id := ""
+			if addressable && item.ID != "" {
+				id = graph.JoinID(parentID, item.ID)
+				ids[n] = id
+			}
+			item.SubItems = build(n.children, id, id != "")
+			items = append(items, item)
+		}
+		return items
+	}
+	plan.Items = build(roots, "", true)
+
+	out := append([]core.Edge(nil), kept...)
+	for _, e := range edges {
+		from, to := ids[e.from], ids[e.to]
+		if from != "" && to != "" {
+			out = append(out, core.Edge{From: from, To: to, Type: e.typ})
+		}
+	}
+	plan.Edges = out
+}
+
+func isAncestor(n, of *node) bool {
+	for p := of; p != nil; p = p.parent {
+		if p == n {
+			return true
+		}
+	}
+	return false
+}
+
+func detach(roots []*node, n *node) []*node {
+	remove := func(ns []*node) []*node {
+		for i, c := range ns {
+			if c == n {
+				return append(ns[:i:i], ns[i+1:]...)
+			}
+		}
+		return ns
+	}
+	if n.parent != nil {
+		n.parent.children = remove(n.parent.children)
+		n.parent = nil
+		return roots
+	}
+	return remove(roots)
+}
+
+// attach appends n to parent's children (or the roots), renaming n if its
+// local ID collides with a sibling's.
+func attach(roots []*node, parent *node, n *node) []*node {
+	siblings := roots
+	if parent != nil {
+		siblings = parent.children
+	}
+	if n.item.ID != "" {
+		taken := make(map[string]bool, len(siblings))
+		for _, s := range siblings {
+			taken[s.item.ID] = true
+		}
+		base := n.item.ID
+		for i := 2; taken[n.item.ID]; i++ {
+			n.item.ID = fmt.Sprintf("%s-%d", base, i)
+		}
+	}
+	n.parent = parent
+	if parent == nil {
+		return append(roots, n)
+	}
+	parent.children = append(parent.children, n)
+	return roots
+}
+
+// localID derives an item ID from a Beads ID. Hierarchical Beads IDs such
+// as "bd-a1b2.1" under "bd-a1b2" keep only their last segment; otherwise
+// dots, which separate vBRIEF ID segments, become dashes.
+func localID(beadsID string, parent *node) string {
+	if parent != nil && parent.item.BeadsID != "" {
+		if rest, ok := strings.CutPrefix(beadsID, parent.item.BeadsID+"."); ok && rest != "" {
+			return strings.ReplaceAll(rest, ".", "-")
+		}
+	}
+	return strings.ReplaceAll(beadsID, ".", "-")
+}
+
+// applyIssue overwrites the fields of item that Beads owns.
+func applyIssue(item *core.PlanItem, issue Issue) {
+	item.BeadsID = issue.ID
+	item.Title = issue.Title
+	item.Status = statusFromBeads(issue)
+	item.Priority = priorityFromBeads(issue.Priority)
+	item.Tags = append([]string(nil), issue.Labels...)
+	item.Created = timePtr(issue.CreatedAt)
+	item.Updated = timePtr(issue.UpdatedAt)
+	item.Completed = nil
+	if issue.ClosedAt != nil {
+		t := *issue.ClosedAt
+		item.Completed = &t
+	}
+
+	for key, text := range map[string]string{
+		NarrativeDescription:        issue.Description,
+		NarrativeDesign:             issue.Design,
+		NarrativeAcceptanceCriteria: issue.AcceptanceCriteria,
+		NarrativeNotes:              issue.Notes,
+	} {
+		item.Narrative = setOrDelete(item.Narrative, key, text)
+	}
+	item.Metadata = setOrDeleteValue(item.Metadata, MetadataAssignee, issue.Assignee)
+	item.Metadata = setOrDeleteValue(item.Metadata, MetadataIssueType, issue.IssueType)
+}
+
+func setOrDelete(m map[string]string, key, value string) map[string]string {
+	if value == "" {
+		delete(m, key)
+		if len(m) == 0 {
+			return nil
+		}
+		return m
+	}
+	if m == nil {
+		m = make(map[string]string)
+	}
+	m[key] = value
+	return m
+}
+
+func setOrDeleteValue(m map[string]interface{}, key, value string) map[string]interface{} {
+	if value == "" {
+		delete(m, key)
+		if len(m) == 0 {
+			return nil
+		}
+		return m
+	}
+	if m == nil {
+		m = make(map[string]interface{})
+	}
+	m[key] = value
+	return m
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	// Prefix is prepended to generated IDs for items without a beadsId.
+	// Defaults to "bd-".
+	Prefix string
+	// ChangedOnly exports only items updated after the plan's
+	// beadsSyncedAt. Items without an updated timestamp are always exported.
+	ChangedOnly bool
+	// Now stamps issues that have no created or updated time. Defaults to
+	// the current time.
+	Now time.Time
+}
+
+// Export converts a plan's items to Beads issues in document order.
+//
+// Items keep their beadsId; other items get Prefix plus their hierarchical
+// ID (or "item-N" if they have none). Sub-items get a parent-child
+// dependency on their parent and edges become dependencies of their target.
+func Export(plan *core.Plan, opts ExportOptions) ([]Issue, error) {
+	if plan == nil {
+		return nil, core.ErrNoPlan
+	}
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = "bd-"
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+
+	g := graph.New(plan)
+	nodes := g.Nodes()
+	issueIDs := make(map[*graph.Node]string, len(nodes))
+	used := make(map[string]bool, len(nodes))
+	unnamed := 0
+	for _, n := range nodes {
+		id := n.Item.BeadsID
+		switch {
+		case id != "":
+		case n.ID != "":
+			id = prefix + n.ID
+		default:
+			unnamed++
+			id = fmt.Sprintf("%sitem-%d", prefix, unnamed)
+		}
+		if used[id] {
+			return nil, fmt.Errorf("%w: duplicate id %q at %s", ErrInvalidIssue, id, n.Path)
+		}
+		used[id] = true
+		issueIDs[n] = id
+	}
+
+	var issues []Issue
+	for _, n := range nodes {
+		item := n.Item
+		if opts.ChangedOnly && plan.BeadsSyncedAt != nil && item.Updated != nil && !item.Updated.After(*plan.BeadsSyncedAt) {
+			continue
+		}
+		issue := issueFromItem(item, now)
+		issue.ID = issueIDs[n]
+		if n.Parent != nil {
+			issue.Dependencies = append(issue.Dependencies, Dependency{
+				IssueID:     issue.ID,
+				DependsOnID: issueIDs[n.Parent],
+				Type:        DependsParentChild,
+			})
+		}
+		if n.ID != "" && g.Node(n.ID) == n {
+			for _, e := range g.Incoming(n.ID) {
+				issue.Dependencies = append(issue.Dependencies, Dependency{
+					IssueID:     issue.ID,
+					DependsOnID: issueIDs[g.Node(e.From)],
+					Type:        edgeTypeToBeads(e.Type),
+				})
+			}
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+func issueFromItem(item *core.PlanItem, now time.Time) Issue {
+	issue := Issue{
+		Title:              item.Title,
+		Description:        item.Narrative[NarrativeDescription],
+		Design:             item.Narrative[NarrativeDesign],
+		AcceptanceCriteria: item.Narrative[NarrativeAcceptanceCriteria],
+		Notes:              item.Narrative[NarrativeNotes],
+		Priority:           priorityToBeads(item.Priority),
+		Labels:             append([]string(nil), item.Tags...),
+	}
+	issue.Status, issue.CloseReason = statusToBeads(item.Status)
+	if s, ok := item.Metadata[MetadataAssignee].(string); ok {
+		issue.Assignee = s
+	}
+	if s, ok := item.Metadata[MetadataIssueType].(string); ok {
+		issue.IssueType = s
+	}
+	issue.CreatedAt = now
+	if item.Created != nil {
+		issue.CreatedAt = *item.Created
+	}
+	issue.UpdatedAt = issue.CreatedAt
+	if item.Updated != nil {
+		issue.UpdatedAt = *item.Updated
+	}
+	if issue.Status == StatusClosed {
+		closed := issue.UpdatedAt
+		if item.Completed != nil {
+			closed = *item.Completed
+		}
+		issue.ClosedAt = &closed
+	}
+	return issue
+}
@@ -0,0 +1,474 @@
+// Package budget estimates how many tokens a document costs in a prompt
+// and reduces it to fit a context budget.
+//
+// Fit applies progressive disclosure in a fixed order, checking the size
+// after every stage and stopping as soon as the document fits:
+//
+//  1. StepDropCompleted removes completed items whose sub-items are all
+//     finished, with their edges.
+//  2. StepCollapseSubItems replaces sub-items with a count, deepest level
+//     first. The count is kept in the item's metadata under
+//     MetadataCollapsed, and edges to hidden items move to the collapsed
+//     item.
+//  3. StepTruncateNarratives shortens plan and item narratives, in stages.
+//  4. StepOmitMetadata removes document and item metadata, keeping the
+//     collapsed counts.
+//
+// Tokens are counted by a pluggable Tokenizer; the default is an offline
+// byte-pair approximation.
+package budget
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+	"github.com/visionik/vBRIEF/api/go/pkg/tronenc"
+)
+
+// ErrOverBudget is returned by Fit when every step has been applied and the
+// document is still larger than the budget.
+var ErrOverBudget = errors.New("budget: document does not fit")
+
+// MetadataCollapsed is the item metadata key holding the number of
+// sub-items hidden by StepCollapseSubItems.
+const MetadataCollapsed = "collapsedSubItems"
+
+// Format is the serialisation whose tokens are counted.
+type Format string
+
+const (
+	// FormatJSON counts compact JSON.
+	FormatJSON Format = "json"
+	// FormatTRON counts TRON from package tronenc.
+	FormatTRON Format = "tron"
+)
+
+// Step is a reduction applied by Fit.
+type Step string
+
+const (
+	// StepDropCompleted removes finished work.
+	StepDropCompleted Step = "dropCompleted"
+	// StepCollapseSubItems replaces sub-items with counts.
+	StepCollapseSubItems Step = "collapseSubItems"
+	// StepTruncateNarratives shortens narratives.
+	StepTruncateNarratives Step = "truncateNarratives"
+	// StepOmitMetadata removes metadata.
+	StepOmitMetadata Step = "omitMetadata"
+)
+
+// DefaultSteps is the order Fit applies reductions in.
+var DefaultSteps = []Step{StepDropCompleted, StepCollapseSubItems, StepTruncateNarratives, StepOmitMetadata}
+
+// DefaultNarrativeLimits are the lengths, in runes, StepTruncateNarratives
+// cuts narratives to, one stage each.
+var DefaultNarrativeLimits = []int{400, 160, 60}
+
+// Counts holds a document's size in each format.
+type Counts struct {
+	JSON int
+	TRON int
+}
+
+// Count returns the tokens a document costs as compact JSON and as TRON.
+// A nil tokenizer selects NewApproxTokenizer.
+func Count(doc *core.Document, tok Tokenizer) (Counts, error) {
+	tok = orDefault(tok)
+	j, err := render(doc, FormatJSON, tok)
+	if err != nil {
+		return Counts{}, err
+	}
+	t, err := render(doc, FormatTRON, tok)
+	if err != nil {
+		return Counts{}, err
+	}
+	return Counts{JSON: tok.Count(string(j)), TRON: tok.Count(string(t))}, nil
+}
+
+// Options configures Fit.
+type Options struct {
+	// Tokenizer counts tokens. Defaults to NewApproxTokenizer.
+	Tokenizer Tokenizer
+	// Format is the serialisation that must fit. Defaults to FormatJSON.
+	Format Format
+	// Steps are the reductions to try, in order. Defaults to DefaultSteps.
+	Steps []Step
+	// NarrativeLimits are the stages of StepTruncateNarratives, longest
+	// first. Defaults to DefaultNarrativeLimits.
+	NarrativeLimits []int
+}
+
+// Result is a document reduced by Fit.
+type Result struct {
+	// Document is the reduced copy; the input is never modified.
+	Document *core.Document
+	// Data is Document in the requested format.
+	Data []byte
+	// Tokens is the size of Data.
+	Tokens int
+	// Applied lists the steps that changed the document, in order.
+	Applied []Step
+	// Dropped is the number of items removed by StepDropCompleted,
+	// including their sub-items.
+	Dropped int
+	// Collapsed is the number of sub-items hidden by
+	// StepCollapseSubItems.
+	Collapsed int
+}
+
+// Fit returns a copy of doc reduced until it costs at most limit tokens in
+// the chosen format. When the steps run out first, Fit returns the most
+// reduced document together with an error wrapping ErrOverBudget.
+func Fit(doc *core.Document, limit int, opts Options) (*Result, error) {
+	if doc == nil {
+		return nil, errors.New("budget: nil document")
+	}
+	opts.Tokenizer = orDefault(opts.Tokenizer)
+	if opts.Format == "" {
+		opts.Format = FormatJSON
+	}
+	if opts.Format != FormatJSON && opts.Format != FormatTRON {
+		return nil, fmt.Errorf("budget: unknown format %q", opts.Format)
+	}
+	steps := opts.Steps
+	if steps == nil {
+		steps = DefaultSteps
+	}
+	limits := opts.NarrativeLimits
+	if limits == nil {
+		limits = DefaultNarrativeLimits
+	}
+
+	copyDoc, err := clone(doc)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Document: copyDoc}
+	if err := res.measure(opts); err != nil || res.Tokens <= limit {
+		return res, err
+	}
+
+	for _, step := range steps {
+		var stages []func() bool
+		switch step {
+		case StepDropCompleted:
+			stages = []func() bool{func() bool {
+				n := dropCompleted(res.Document)
+				res.Dropped += n
+				return n > 0
+			}}
+		case StepCollapseSubItems:
+			if p := res.Document.Plan; p != nil {
+				for depth := maxParentDepth(p.Items, 0); depth >= 0; depth-- {
+					depth := depth
+					stages = append(stages, func() bool {
+						n := collapse(p, depth)
+						res.Collapsed += n
+						return n > 0
+					})
+				}
+			}
+		case StepTruncateNarratives:
+			for _, max := range limits {
+				max := max
+				stages = append(stages, func() bool { return truncateNarratives(res.Document, max) })
+			}
+		case StepOmitMetadata:
+			stages = []func() bool{func() bool { return omitMetadata(res.Document) }}
+		default:
+			return nil, fmt.Errorf("budget: unknown step %q", step)
+		}
+
+		applied := false
+		for _, stage := range stages {
+			if !stage() {
+				continue
+			}
+			if !applied {
+				res.Applied = append(res.Applied, step)
+				applied = true
+			}
+			if err := res.measure(opts); err != nil {
+				return nil, err
+			}
+			if res.Tokens <= limit {
+				return res, nil
+			}
+		}
+	}
+	return res, fmt.Errorf("%w: %d tokens, budget %d", ErrOverBudget, res.Tokens, limit)
+}
+
+func (r *Result) measure(opts Options) error {
+	data, err := render(r.Document, opts.Format, opts.Tokenizer)
+	if err != nil {
+		return err
+	}
+	r.Data = data
+	r.Tokens = opts.Tokenizer.Count(string(data))
+	return nil
+}
+
+func orDefault(tok Tokenizer) Tokenizer {
+	if tok == nil {
+		return NewApproxTokenizer()
+	}
+	return tok
+}
+
+func render(doc *core.Document, format Format, tok Tokenizer) ([]byte, error) {
+	if format == FormatTRON {
+		return tronenc.Encode(doc, tronenc.Options{CountTokens: tok.Count})
+	}
+	return json.Marshal(doc)
+}
+
+func clone(doc *core.Document) (*core.Document, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var out core.Document
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// finished reports whether an item and all its sub-items are completed or
+// cancelled, with the item itself completed.
+func finished(item *core.PlanItem) bool {
+	if item.Status != core.PlanItemStatusCompleted {
+		return false
+	}
+	for i := range item.SubItems {
+		s := &item.SubItems[i]
+		if s.Status != core.PlanItemStatusCancelled && !finished(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// dropCompleted removes finished items and returns how many items went.
+func dropCompleted(doc *core.Document) int {
+	dropped := 0
+	if t := doc.TodoList; t != nil {
+		kept := t.Items[:0]
+		for _, it := range t.Items {
+			if it.Status == core.StatusCompleted {
+				dropped++
+				continue
+			}
+			kept = append(kept, it)
+		}
+		t.Items = kept
+	}
+	p := doc.Plan
+	if p == nil {
+		return dropped
+	}
+
+	drop := make(map[*core.PlanItem]bool)
+	removed := make(map[string]bool)
+	for _, n := range graph.New(p).Nodes() {
+		if n.Parent != nil && drop[n.Parent.Item] {
+			drop[n.Item] = true
+		} else if !finished(n.Item) {
+			continue
+		}
+		drop[n.Item] = true
+		dropped++
+		if n.ID != "" {
+			removed[n.ID] = true
+		}
+	}
+	if dropped == 0 {
+		return 0
+	}
+	var prune func(items []core.PlanItem) []core.PlanItem
+	prune = func(items []core.PlanItem) []core.PlanItem {
+		var kept []core.PlanItem
+		for i := range items {
+			if drop[&items[i]] {
+				continue
+			}
+			items[i].SubItems = prune(items[i].SubItems)
+			kept = append(kept, items[i])
+		}
+		return kept
+	}
+	p.Items = prune(p.Items)
+	p.Edges = remapEdges(p.Edges, func(id string) (string, bool) {
+		return id, !removed[id]
+	})
+	return dropped
+}
+
+// maxParentDepth returns the deepest level, counting top-level items as 0,
+// at which an item has sub-items, or -1 when none has.
+func maxParentDepth(items []core.PlanItem, depth int) int {
+	deepest := -1
+	for i := range items {
+		if len(items[i].SubItems) == 0 {
+			continue
+		}
+		if depth > deepest {
+			deepest = depth
+		}
+		if d := maxParentDepth(items[i].SubItems, depth+1); d > deepest {
+			deepest = d
+		}
+	}
+	return deepest
+}
+
+// collapse hides the sub-items of every item at depth and returns how many
+// items were hidden.
+func collapse(p *core.Plan, depth int) int {
+	hidden := 0
+	// owner maps the ID of each hidden item to the collapsed item that
+	// takes its edges, or to "" when that item has no ID.
+	owner := make(map[string]string)
+	// hide returns the count recorded on the collapsed item, which
+	// includes items collapsed into its descendants by an earlier pass.
+	var hide func(n *graph.Node, target string) int
+	hide = func(n *graph.Node, target string) int {
+		count := 0
+		for _, c := range n.Children {
+			hidden++
+			prev, _ := c.Item.Metadata[MetadataCollapsed].(float64)
+			count += 1 + int(prev) + hide(c, target)
+			if c.ID != "" {
+				owner[c.ID] = target
+			}
+		}
+		return count
+	}
+	for _, n := range graph.New(p).Nodes() {
+		if len(n.Children) == 0 || nodeDepth(n) != depth {
+			continue
+		}
+		count := hide(n, n.ID)
+		it := n.Item
+		if it.Metadata == nil {
+			it.Metadata = make(map[string]interface{})
+		}
+		prev, _ := it.Metadata[MetadataCollapsed].(float64)
+		it.Metadata[MetadataCollapsed] = prev + float64(count)
+	}
+	if hidden == 0 {
+		return 0
+	}
+	// Clear sub-items only now: the graph's nodes point into them.
+	var clear func(items []core.PlanItem, d int)
+	clear = func(items []core.PlanItem, d int) {
+		for i := range items {
+			if d == depth {
+				items[i].SubItems = nil
+				continue
+			}
+			clear(items[i].SubItems, d+1)
+		}
+	}
+	clear(p.Items, 0)
+	p.Edges = remapEdges(p.Edges, func(id string) (string, bool) {
+		if o, ok := owner[id]; ok {
+			return o, o != ""
+		}
+		return id, true
+	})
+	return hidden
+}
+
+func nodeDepth(n *graph.Node) int {
+	d := 0
+	for p := n.Parent; p != nil; p = p.Parent {
+		d++
+	}
+	return d
+}
+
+// remapEdges rewrites edge endpoints, dropping edges whose endpoint is
+// gone and the self-loops and duplicates remapping creates.
+func remapEdges(edges []core.Edge, remap func(id string) (string, bool)) []core.Edge {
+	var out []core.Edge
+	seen := make(map[core.Edge]bool)
+	for _, e := range edges {
+		from, ok1 := remap(e.From)
+		to, ok2 := remap(e.To)
+		if !ok1 || !ok2 {
+			continue
+		}
+		changed := from != e.From || to != e.To
+		e.From, e.To = from, to
+		if (changed && from == to) || seen[e] {
+			continue
+		}
+		seen[e] = true
+		out = append(out, e)
+	}
+	return out
+}
+
+// truncateNarratives cuts every narrative to max runes and reports whether
+// any was cut.
+func truncateNarratives(doc *core.Document, max int) bool {
+	if max < 1 {
+		return false
+	}
+	cut := false
+	shorten := func(m map[string]string) {
+		for k, v := range m {
+			if r := []rune(v); len(r) > max {
+				m[k] = string(r[:max-1]) + "…"
+				cut = true
+			}
+		}
+	}
+	if p := doc.Plan; p != nil {
+		shorten(p.Narratives)
+		var walk func(items []core.PlanItem)
+		walk = func(items []core.PlanItem) {
+			for i := range items {
+				shorten(items[i].Narrative)
+				walk(items[i].SubItems)
+			}
+		}
+		walk(p.Items)
+	}
+	return cut
+}
+
+// omitMetadata removes metadata other than the collapsed counts and
+// reports whether anything was removed.
+func omitMetadata(doc *core.Document) bool {
+	removed := false
+	if doc.Info.Metadata != nil {
+		doc.Info.Metadata = nil
+		removed = true
+	}
+	if p := doc.Plan; p != nil {
+		var walk func(items []core.PlanItem)
+		walk = func(items []core.PlanItem) {
+			for i := range items {
+				it := &items[i]
+				for k := range it.Metadata {
+					if k != MetadataCollapsed {
+						delete(it.Metadata, k)
+						removed = true
+					}
+				}
+				if len(it.Metadata) == 0 {
+					it.Metadata = nil
+				}
+				walk(it.SubItems)
+			}
+		}
+		walk(p.Items)
+	}
+	return removed
+}
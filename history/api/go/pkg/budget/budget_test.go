@@ -0,0 +1,165 @@
+package budget
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/tronenc"
+)
+
+func sampleDoc() *core.Document {
+	long := strings.Repeat("Lots of background on why this matters. ", 20)
+	return &core.Document{
+		Info: core.Info{Version: core.SpecVersion, Metadata: map[string]interface{}{"source": "jira"}},
+		Plan: &core.Plan{
+			Title:      "Release",
+			Status:     core.PlanStatusInProgress,
+			Narratives: map[string]string{"Overview": long},
+			Items: []core.PlanItem{
+				{ID: "done", Title: "Kickoff", Status: core.PlanItemStatusCompleted, SubItems: []core.PlanItem{
+					{ID: "a", Title: "Agenda", Status: core.PlanItemStatusCompleted},
+					{ID: "b", Title: "Invites", Status: core.PlanItemStatusCancelled},
+				}},
+				{ID: "api", Title: "Build API", Status: core.PlanItemStatusInProgress,
+					Narrative: map[string]string{"Overview": long},
+					Metadata:  map[string]interface{}{"jiraKey": "SHOP-2"},
+					SubItems: []core.PlanItem{
+						{ID: "schema", Title: "Schema", Status: core.PlanItemStatusCompleted},
+						{ID: "handlers", Title: "Handlers", Status: core.PlanItemStatusPending, SubItems: []core.PlanItem{
+							{ID: "auth", Title: "Auth", Status: core.PlanItemStatusPending},
+							{ID: "orders", Title: "Orders", Status: core.PlanItemStatusPending},
+						}},
+					}},
+				{ID: "docs", Title: "Write docs", Status: core.PlanItemStatusPending},
+			},
+			Edges: []core.Edge{
+				{From: "done", To: "api", Type: core.EdgeBlocks},
+				{From: "api.handlers.auth", To: "docs", Type: core.EdgeBlocks},
+				{From: "api.handlers.orders", To: "docs", Type: core.EdgeBlocks},
+				{From: "api.schema", To: "api.handlers", Type: core.EdgeBlocks},
+			},
+		},
+	}
+}
+
+func size(t *testing.T, doc *core.Document) int {
+	t.Helper()
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	return NewApproxTokenizer().Count(string(data))
+}
+
+func TestCount(t *testing.T) {
+	counts, err := Count(sampleDoc(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, size(t, sampleDoc()), counts.JSON)
+	assert.Greater(t, counts.JSON, counts.TRON)
+
+	counts, err = Count(sampleDoc(), TokenizerFunc(func(s string) int { return len(s) }))
+	require.NoError(t, err)
+	data, _ := json.Marshal(sampleDoc())
+	assert.Equal(t, len(data), counts.JSON)
+}
+
+func TestFit(t *testing.T) {
+	full := size(t, sampleDoc())
+
+	t.Run("already fits", func(t *testing.T) {
+		res, err := Fit(sampleDoc(), full, Options{})
+		require.NoError(t, err)
+		assert.Empty(t, res.Applied)
+		assert.Equal(t, full, res.Tokens)
+	})
+
+	t.Run("drops completed work first", func(t *testing.T) {
+		doc := sampleDoc()
+		res, err := Fit(doc, full-1, Options{})
+		require.NoError(t, err)
+		assert.Equal(t, []Step{StepDropCompleted}, res.Applied)
+		assert.Equal(t, 4, res.Dropped, "kickoff with its two sub-items, and the schema")
+		plan := res.Document.Plan
+		require.Len(t, plan.Items, 2)
+		assert.Equal(t, "api", plan.Items[0].ID)
+		assert.Len(t, plan.Items[0].SubItems, 1)
+		assert.Equal(t, []core.Edge{
+			{From: "api.handlers.auth", To: "docs", Type: core.EdgeBlocks},
+			{From: "api.handlers.orders", To: "docs", Type: core.EdgeBlocks},
+		}, plan.Edges)
+		assert.Len(t, doc.Plan.Items, 3, "the input is not modified")
+	})
+
+	t.Run("collapses deepest sub-items next", func(t *testing.T) {
+		dropped, err := Fit(sampleDoc(), full-1, Options{})
+		require.NoError(t, err)
+
+		res, err := Fit(sampleDoc(), dropped.Tokens-1, Options{})
+		require.NoError(t, err)
+		assert.Equal(t, []Step{StepDropCompleted, StepCollapseSubItems}, res.Applied)
+		assert.Equal(t, 2, res.Collapsed)
+		handlers := res.Document.Plan.Items[0].SubItems[0]
+		assert.Nil(t, handlers.SubItems)
+		assert.Equal(t, 2.0, handlers.Metadata[MetadataCollapsed])
+		assert.Equal(t, []core.Edge{{From: "api.handlers", To: "docs", Type: core.EdgeBlocks}}, res.Document.Plan.Edges,
+			"edges move to the collapsed item and duplicates merge")
+	})
+
+	t.Run("applies every step in order", func(t *testing.T) {
+		res, err := Fit(sampleDoc(), 1, Options{})
+		require.ErrorIs(t, err, ErrOverBudget)
+		require.NotNil(t, res)
+		assert.Equal(t, DefaultSteps, res.Applied)
+		assert.Equal(t, 3, res.Collapsed)
+
+		plan := res.Document.Plan
+		assert.Equal(t, 60, len([]rune(plan.Narratives["Overview"])))
+		assert.True(t, strings.HasSuffix(plan.Narratives["Overview"], "…"))
+		assert.Equal(t, map[string]interface{}{MetadataCollapsed: 3.0}, plan.Items[0].Metadata,
+			"collapsed counts survive omitting metadata")
+		assert.Nil(t, res.Document.Info.Metadata)
+		assert.Equal(t, size(t, res.Document), res.Tokens)
+	})
+
+	t.Run("custom steps, limits and format", func(t *testing.T) {
+		res, err := Fit(sampleDoc(), 1, Options{
+			Format:          FormatTRON,
+			Steps:           []Step{StepTruncateNarratives},
+			NarrativeLimits: []int{20},
+		})
+		require.ErrorIs(t, err, ErrOverBudget)
+		assert.Equal(t, []Step{StepTruncateNarratives}, res.Applied)
+		assert.Equal(t, 20, len([]rune(res.Document.Plan.Narratives["Overview"])))
+		assert.Len(t, res.Document.Plan.Items, 3)
+		assert.True(t, strings.HasPrefix(string(res.Data), "class "), "TRON declares its classes first")
+	})
+
+	t.Run("todo lists", func(t *testing.T) {
+		doc := &core.Document{Info: core.Info{Version: core.SpecVersion}, TodoList: &core.TodoList{Items: []core.TodoItem{
+			{Title: "a", Status: core.StatusCompleted},
+			{Title: "b", Status: core.StatusPending},
+		}}}
+		res, err := Fit(doc, size(t, doc)-1, Options{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, res.Dropped)
+		assert.Len(t, res.Document.TodoList.Items, 1)
+	})
+}
+
+func TestFit_Errors(t *testing.T) {
+	_, err := Fit(nil, 10, Options{})
+	assert.Error(t, err)
+	_, err = Fit(sampleDoc(), 1, Options{Format: "xml"})
+	assert.ErrorContains(t, err, `unknown format "xml"`)
+	_, err = Fit(sampleDoc(), 1, Options{Steps: []Step{"shrink"}})
+	assert.ErrorContains(t, err, `unknown step "shrink"`)
+}
+
+func TestApproxTokenizer(t *testing.T) {
+	tok := NewApproxTokenizer()
+	for _, s := range []string{"", "hello world", "internationalization", `{"title":"A"}`, "日本語"} {
+		assert.Equal(t, tronenc.EstimateTokens(s), tok.Count(s), s)
+	}
+}
@@ -0,0 +1,34 @@
+package budget
+
+import "github.com/visionik/vBRIEF/api/go/pkg/tronenc"
+
+// Tokenizer counts the tokens a model would see for a text.
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// TokenizerFunc adapts a function to the Tokenizer interface, so a real
+// tokenizer binding can be plugged in without a wrapper type.
+type TokenizerFunc func(text string) int
+
+// Count calls f(text).
+func (f TokenizerFunc) Count(text string) int {
+	return f(text)
+}
+
+// approxTokenizer approximates a byte-pair tokenizer offline.
+type approxTokenizer struct{}
+
+// NewApproxTokenizer returns the default tokenizer, an offline
+// approximation of byte-pair encodings such as cl100k that counts with
+// tronenc.EstimateTokens, so budgets and TRON reports agree. That is
+// close enough to plan a context budget, not to fill one to the last
+// token; plug in a real tokenizer for that.
+func NewApproxTokenizer() Tokenizer {
+	return approxTokenizer{}
+}
+
+// Count returns the approximate token count of text.
+func (approxTokenizer) Count(text string) int {
+	return tronenc.EstimateTokens(text)
+}
@@ -25,6 +25,7 @@ func NewPlanWithStatus(version, title string, status core.PlanStatus) *PlanBuild
 				Title:      title,
 				Status:     status,
 				Narratives: make(map[string]string),
+				Items:      []core.PlanItem{},
 			},
 		},
 	}
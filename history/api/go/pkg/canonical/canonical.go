@@ -0,0 +1,348 @@
+// Package canonical writes vBRIEF documents in a canonical form, so that
+// equal documents serialise to equal bytes regardless of how they were
+// built, parsed or last written.
+//
+// The canonical form follows RFC 8785 (JCS) for scalars and adds fixed key
+// ordering:
+//
+//   - known fields appear in the order the vBRIEF schema lists them, and
+//     fields the schema does not know follow in declaration order;
+//   - map keys (narratives, metadata) are sorted by UTF-16 code units;
+//   - timestamps are written in UTC as RFC 3339 with trailing fractional
+//     zeros removed;
+//   - numbers use the shortest ECMAScript form, and strings escape only
+//     quotes, backslashes and control characters;
+//   - unset required collections are written as [] or {} rather than null.
+//
+// Timestamps held as strings in metadata are left as they are. JSON writes
+// compact JCS-style output unless Options.Indent is set; TRON writes one
+// top-level key per line and declares no classes, so the output does not
+// depend on tronenc's token estimates.
+package canonical
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// ErrInvalidDocument is returned when a document has no canonical form,
+// such as a nil document or a metadata value that is NaN.
+var ErrInvalidDocument = errors.New("canonical: invalid document")
+
+// DigestPrefix names the hash algorithm in digests returned by Digest.
+const DigestPrefix = "sha256:"
+
+// Options configures JSON and TRON.
+type Options struct {
+	// Indent is written once per nesting level. Empty selects compact
+	// output.
+	Indent string
+}
+
+// JSON writes a document as canonical JSON.
+func JSON(doc *core.Document, opts Options) ([]byte, error) {
+	root, err := build(doc)
+	if err != nil {
+		return nil, err
+	}
+	w := &writer{indent: opts.Indent}
+	w.value(root, 0)
+	if opts.Indent != "" {
+		w.b.WriteByte('\n')
+	}
+	return []byte(w.b.String()), nil
+}
+
+// TRON writes a document as canonical TRON.
+func TRON(doc *core.Document, opts Options) ([]byte, error) {
+	root, err := build(doc)
+	if err != nil {
+		return nil, err
+	}
+	w := &writer{indent: opts.Indent, tron: true}
+	for i, k := range root.keys {
+		w.key(k)
+		w.value(root.vals[i], 0)
+		w.b.WriteByte('\n')
+	}
+	return []byte(w.b.String()), nil
+}
+
+// Digest returns the SHA-256 of a document's compact canonical JSON as
+// "sha256:" followed by lowercase hex. Documents with the same content
+// have the same digest however their maps, timestamps or fields were
+// ordered and zoned.
+func Digest(doc *core.Document) (string, error) {
+	data, err := JSON(doc, Options{})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return DigestPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+// specOrder lists the fields of each type in the order the vBRIEF schema
+// declares them.
+var specOrder = map[reflect.Type][]string{
+	reflect.TypeOf(core.Document{}): {"vBRIEFInfo", "todoList", "plan"},
+	reflect.TypeOf(core.Info{}):     {"version", "author", "description", "metadata", "created", "updated", "timezone"},
+	reflect.TypeOf(core.Plan{}): {"id", "uid", "title", "status", "items", "narratives", "edges", "tags", "metadata",
+		"created", "updated", "author", "reviewers", "uris", "references", "timezone", "agent", "lastModifiedBy",
+		"changeLog", "sequence", "fork"},
+	reflect.TypeOf(core.PlanItem{}): {"id", "uid", "title", "status", "narrative", "subItems", "planRef", "tags",
+		"metadata", "created", "updated", "completed", "priority", "dueDate", "startDate", "endDate",
+		"percentComplete", "participants", "location", "uris", "recurrence", "reminders", "classification",
+		"relatedComments", "timezone", "sequence", "lastModifiedBy", "lockedBy"},
+	reflect.TypeOf(core.Edge{}):           {"from", "to", "type"},
+	reflect.TypeOf(core.Participant{}):    {"id", "name", "email", "role", "status"},
+	reflect.TypeOf(core.RecurrenceRule{}): {"frequency", "interval", "until", "count", "byDay", "byMonth", "byMonthDay"},
+	reflect.TypeOf(core.Reminder{}):       {"trigger", "action", "description"},
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	numberType    = reflect.TypeOf(json.Number(""))
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// value is a JSON value with object keys in canonical order.
+type value struct {
+	// raw is the serialised form of a scalar.
+	raw      string
+	keys     []string
+	vals     []*value
+	elems    []*value
+	isObject bool
+	isArray  bool
+}
+
+func build(doc *core.Document) (*value, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("%w: nil document", ErrInvalidDocument)
+	}
+	return fromReflect(reflect.ValueOf(doc))
+}
+
+func fromReflect(v reflect.Value) (*value, error) {
+	if !v.IsValid() {
+		return &value{raw: "null"}, nil
+	}
+	t := v.Type()
+	switch {
+	case t == timeType:
+		return &value{raw: quote(v.Interface().(time.Time).UTC().Format(time.RFC3339Nano))}, nil
+	case t == numberType:
+		return number(v.String())
+	case t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface && t.Implements(marshalerType):
+		return fromMarshaler(v)
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return &value{raw: "null"}, nil
+		}
+		return fromReflect(v.Elem())
+	case reflect.Struct:
+		return fromStruct(v)
+	case reflect.Map:
+		return fromMap(v)
+	case reflect.Slice, reflect.Array:
+		// Nil slices and maps are written empty, so that building a
+		// document and parsing one agree.
+		out := &value{isArray: true}
+		for i := 0; i < v.Len(); i++ {
+			e, err := fromReflect(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			out.elems = append(out.elems, e)
+		}
+		return out, nil
+	case reflect.String:
+		return &value{raw: quote(v.String())}, nil
+	case reflect.Bool:
+		return &value{raw: strconv.FormatBool(v.Bool())}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &value{raw: strconv.FormatInt(v.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &value{raw: strconv.FormatUint(v.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		raw, err := formatFloat(v.Float())
+		if err != nil {
+			return nil, err
+		}
+		return &value{raw: raw}, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported type %s", ErrInvalidDocument, t)
+}
+
+// fromMarshaler canonicalises a value with its own JSON encoding.
+func fromMarshaler(v reflect.Value) (*value, error) {
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	var generic interface{}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	if err := dec.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	return fromReflect(reflect.ValueOf(generic))
+}
+
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+func fromStruct(v reflect.Value) (*value, error) {
+	out := &value{isObject: true}
+	for _, f := range fields(v.Type()) {
+		fv := v.Field(f.index)
+		if f.omitEmpty && isEmpty(fv) {
+			continue
+		}
+		val, err := fromReflect(fv)
+		if err != nil {
+			return nil, err
+		}
+		out.keys = append(out.keys, f.name)
+		out.vals = append(out.vals, val)
+	}
+	return out, nil
+}
+
+// fields returns a struct's JSON fields, those the schema knows first in
+// schema order.
+func fields(t reflect.Type) []field {
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		out = append(out, field{name: name, index: i, omitEmpty: strings.Contains(","+opts+",", ",omitempty,")})
+	}
+	rank := make(map[string]int)
+	for i, name := range specOrder[t] {
+		rank[name] = i + 1
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		ri, rj := rank[out[i].name], rank[out[j].name]
+		switch {
+		case ri == 0:
+			return false
+		case rj == 0:
+			return true
+		}
+		return ri < rj
+	})
+	return out
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+func fromMap(v reflect.Value) (*value, error) {
+	if v.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("%w: map key type %s", ErrInvalidDocument, v.Type().Key())
+	}
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Slice(keys, func(i, j int) bool { return lessUTF16(keys[i], keys[j]) })
+
+	out := &value{isObject: true, keys: keys}
+	for _, k := range keys {
+		val, err := fromReflect(v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())))
+		if err != nil {
+			return nil, err
+		}
+		out.vals = append(out.vals, val)
+	}
+	return out, nil
+}
+
+// lessUTF16 orders strings by their UTF-16 code units, as RFC 8785
+// requires.
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
+
+func number(s string) (*value, error) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return &value{raw: strconv.FormatInt(i, 10)}, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: number %q", ErrInvalidDocument, s)
+	}
+	raw, err := formatFloat(f)
+	if err != nil {
+		return nil, err
+	}
+	return &value{raw: raw}, nil
+}
+
+// formatFloat writes f in the shortest form that round-trips, using
+// ECMAScript's choice between decimal and exponent notation.
+func formatFloat(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("%w: number %v", ErrInvalidDocument, f)
+	}
+	if f == 0 {
+		// Negative zero is written as 0.
+		return "0", nil
+	}
+	// encoding/json formats floats as ECMAScript does.
+	data, err := json.Marshal(f)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	return string(data), nil
+}
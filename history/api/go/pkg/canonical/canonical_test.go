@@ -0,0 +1,148 @@
+package canonical
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/parser"
+)
+
+func samplePlan() *core.Document {
+	due := time.Date(2025, 3, 1, 17, 30, 0, 500000000, time.FixedZone("CEST", 2*3600))
+	done := time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC)
+	return &core.Document{
+		Info: core.Info{Version: "0.5", Metadata: map[string]interface{}{"b": 1.0, "a": []interface{}{"x", 0.1}}},
+		Plan: &core.Plan{
+			Title:      "Release",
+			Status:     core.PlanStatusDraft,
+			Narratives: map[string]string{"Risks": "none", "Overview": "ship it"},
+			Items: []core.PlanItem{{
+				ID:        "api",
+				Title:     "Build API",
+				Status:    core.PlanItemStatusCompleted,
+				Priority:  core.PriorityHigh,
+				DueDate:   &due,
+				Completed: &done,
+			}},
+		},
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := JSON(samplePlan(), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, `{"vBRIEFInfo":{"version":"0.5","metadata":{"a":["x",0.1],"b":1}},`+
+		`"plan":{"title":"Release","status":"draft",`+
+		`"items":[{"id":"api","title":"Build API","status":"completed","completed":"2025-02-01T09:00:00Z","priority":"high","dueDate":"2025-03-01T15:30:00.5Z"}],`+
+		`"narratives":{"Overview":"ship it","Risks":"none"}}}`, string(data),
+		"schema order for fields, sorted maps, UTC timestamps")
+
+	t.Run("indents stably", func(t *testing.T) {
+		data, err := JSON(&core.Document{Info: core.Info{Version: "0.5"}, Plan: &core.Plan{Title: "P", Status: core.PlanStatusDraft}}, Options{Indent: "  "})
+		require.NoError(t, err)
+		assert.Equal(t, "{\n  \"vBRIEFInfo\": {\n    \"version\": \"0.5\"\n  },\n  \"plan\": {\n    \"title\": \"P\",\n    \"status\": \"draft\",\n    \"items\": [],\n    \"narratives\": {}\n  }\n}\n", string(data))
+	})
+}
+
+func TestJSON_Scalars(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"integral float", 100.0, `100`},
+		{"fraction", 0.1, `0.1`},
+		{"negative zero", math.Copysign(0, -1), `0`},
+		{"large", 1e21, `1e+21`},
+		{"small", 1e-7, `1e-7`},
+		{"json number", json.Number("2.50"), `2.5`},
+		{"integer", 42, `42`},
+		{"escapes", "a\"b\\c\n\t\x01", `"a\"b\\c\n\t\u0001"`},
+		{"no HTML or separator escapes", "<&>\u2028", "\"<&>\u2028\""},
+		{"invalid UTF-8", "a\xffb", "\"a�b\""},
+		{"nil", nil, `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &core.Document{Info: core.Info{Version: "0.5", Metadata: map[string]interface{}{"v": tt.value}}}
+			data, err := JSON(doc, Options{})
+			require.NoError(t, err)
+			assert.Equal(t, `{"vBRIEFInfo":{"version":"0.5","metadata":{"v":`+tt.want+`}}}`, string(data))
+		})
+	}
+}
+
+func TestJSON_SortsKeysByUTF16(t *testing.T) {
+	// U+1F600 is a surrogate pair (D83D DE00) and sorts before U+FB01 in
+	// UTF-16, although its UTF-8 encoding sorts after.
+	doc := &core.Document{Info: core.Info{Version: "0.5", Metadata: map[string]interface{}{
+		"ﬁ": 1.0, "\U0001F600": 2.0, "b": 3.0, "B": 4.0,
+	}}}
+	data, err := JSON(doc, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "{\"vBRIEFInfo\":{\"version\":\"0.5\",\"metadata\":{\"B\":4,\"b\":3,\"\U0001F600\":2,\"ﬁ\":1}}}", string(data))
+}
+
+func TestJSON_Errors(t *testing.T) {
+	_, err := JSON(nil, Options{})
+	assert.ErrorIs(t, err, ErrInvalidDocument)
+
+	_, err = JSON(&core.Document{Info: core.Info{Version: "0.5", Metadata: map[string]interface{}{"v": math.NaN()}}}, Options{})
+	assert.ErrorIs(t, err, ErrInvalidDocument)
+}
+
+func TestTRON(t *testing.T) {
+	data, err := TRON(samplePlan(), Options{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "vBRIEFInfo:{version:\"0.5\",metadata:{a:[\"x\",0.1],b:1}}\nplan:{title:\"Release\","))
+
+	for _, indent := range []string{"", "  "} {
+		data, err := TRON(samplePlan(), Options{Indent: indent})
+		require.NoError(t, err)
+		doc, err := parser.NewTRONParser().ParseBytes(data)
+		require.NoError(t, err, "indent %q", indent)
+
+		want, err := JSON(samplePlan(), Options{})
+		require.NoError(t, err)
+		got, err := JSON(doc, Options{})
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got), "indent %q", indent)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	want, err := Digest(samplePlan())
+	require.NoError(t, err)
+	assert.Regexp(t, `^sha256:[0-9a-f]{64}$`, want)
+
+	// The same content, parsed from JSON written with Go's field order
+	// and the due date in UTC.
+	data, err := json.Marshal(samplePlan())
+	require.NoError(t, err)
+	doc, err := parser.NewJSONParser().ParseBytes(data)
+	require.NoError(t, err)
+	due := doc.Plan.Items[0].DueDate.UTC()
+	doc.Plan.Items[0].DueDate = &due
+	got, err := Digest(doc)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	doc.Plan.Title = "Release 2"
+	got, err = Digest(doc)
+	require.NoError(t, err)
+	assert.NotEqual(t, want, got)
+
+	t.Run("unset and empty narratives agree", func(t *testing.T) {
+		a, err := Digest(&core.Document{Info: core.Info{Version: "0.5"}, Plan: &core.Plan{Title: "P", Status: core.PlanStatusDraft}})
+		require.NoError(t, err)
+		b, err := Digest(&core.Document{Info: core.Info{Version: "0.5"}, Plan: &core.Plan{Title: "P", Status: core.PlanStatusDraft, Narratives: map[string]string{}}})
+		require.NoError(t, err)
+		assert.Equal(t, a, b)
+	})
+}
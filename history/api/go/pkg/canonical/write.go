@@ -0,0 +1,113 @@
+package canonical
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/tronenc"
+)
+
+// writer serialises a value tree as JSON, or as TRON when tron is set.
+type writer struct {
+	b      strings.Builder
+	indent string
+	tron   bool
+}
+
+func (w *writer) value(v *value, depth int) {
+	switch {
+	case v.isObject:
+		if len(v.keys) == 0 {
+			w.b.WriteString("{}")
+			return
+		}
+		w.b.WriteByte('{')
+		for i, k := range v.keys {
+			if i > 0 {
+				w.b.WriteByte(',')
+			}
+			w.newline(depth + 1)
+			w.key(k)
+			w.value(v.vals[i], depth+1)
+		}
+		w.newline(depth)
+		w.b.WriteByte('}')
+	case v.isArray:
+		if len(v.elems) == 0 {
+			w.b.WriteString("[]")
+			return
+		}
+		w.b.WriteByte('[')
+		for i, e := range v.elems {
+			if i > 0 {
+				w.b.WriteByte(',')
+			}
+			w.newline(depth + 1)
+			w.value(e, depth+1)
+		}
+		w.newline(depth)
+		w.b.WriteByte(']')
+	default:
+		w.b.WriteString(v.raw)
+	}
+}
+
+func (w *writer) newline(depth int) {
+	if w.indent == "" {
+		return
+	}
+	w.b.WriteByte('\n')
+	w.b.WriteString(strings.Repeat(w.indent, depth))
+}
+
+// key writes an object key and its colon. TRON keys that are identifiers
+// are written unquoted.
+func (w *writer) key(k string) {
+	if w.tron && tronenc.IsIdentifier(k) {
+		w.b.WriteString(k)
+	} else {
+		w.b.WriteString(quote(k))
+	}
+	w.b.WriteByte(':')
+	if w.indent != "" {
+		w.b.WriteByte(' ')
+	}
+}
+
+const hexDigits = "0123456789abcdef"
+
+// quote writes a string as RFC 8785 does: only quotes, backslashes and
+// control characters are escaped, using the short forms where JSON has
+// them. Invalid UTF-8 is replaced with U+FFFD.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch {
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\b':
+			b.WriteString(`\b`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20:
+			b.WriteString(`\u00`)
+			b.WriteByte(hexDigits[r>>4])
+			b.WriteByte(hexDigits[r&0xf])
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,403 @@
+// Package compose combines and splits plans along their planRefs.
+//
+// Flatten inlines the plans an item's planRef names as that item's
+// sub-items, producing one self-contained document. Because sub-items
+// take their parent's ID as a prefix, an inlined item "child" under the
+// referencing item "ref" is addressed as "ref.child", and edges copied
+// from the inlined plan are rewritten to match. Extract does the reverse:
+// it moves an item's sub-items into a plan of their own and leaves a
+// planRef behind. Both validate their results.
+package compose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+	"github.com/visionik/vBRIEF/api/go/pkg/resolver"
+	"github.com/visionik/vBRIEF/api/go/pkg/validator"
+)
+
+var (
+	// ErrNotFound is returned when Extract is given an unknown ID.
+	ErrNotFound = errors.New("item not found")
+	// ErrIDConflict is returned when an inlined item's ID is already used
+	// by a sibling.
+	ErrIDConflict = errors.New("id conflict")
+	// ErrInvalidResult is returned, wrapping the validation errors, when a
+	// result would not validate.
+	ErrInvalidResult = errors.New("result is not valid")
+	// ErrNoBase is returned when Extract needs to point an extracted
+	// "#id" planRef back at the source document but has no Base.
+	ErrNoBase = errors.New("no base URI")
+)
+
+// ProvenanceKey is the metadata key under which Flatten records where
+// inlined items came from, and Extract where a plan was split from.
+const ProvenanceKey = "provenance"
+
+// FlattenOptions configures Flatten.
+type FlattenOptions struct {
+	// Base is the document's URI, e.g. "file://plans/programme.json";
+	// relative refs resolve against it.
+	Base string
+	// Resolver loads referenced plans. Nil means resolver.NewResolver().
+	Resolver *resolver.Resolver
+}
+
+// ExtractOptions configures Extract.
+type ExtractOptions struct {
+	// Path is where the extracted plan will be saved, relative to the
+	// source document; the planRef left behind points there. It defaults
+	// to the item's local ID followed by ".vbrief.json".
+	Path string
+	// Base is the source document's URI, recorded in the extracted plan's
+	// provenance. Extracted "#id" planRefs to items that stay behind are
+	// rewritten to "Base#id".
+	Base string
+}
+
+// validate checks a result with the core and identifier rules, so that
+// IDs stay unique and edges resolve.
+func validate(doc *core.Document) error {
+	v, err := validator.NewValidatorWithOptions(validator.Options{
+		Config: validator.Config{Extensions: []string{validator.ExtIdentifiers}},
+	})
+	if err != nil {
+		return err
+	}
+	if err := v.Validate(doc); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidResult, err)
+	}
+	return nil
+}
+
+// flattener holds the state of one Flatten call.
+type flattener struct {
+	ctx      context.Context
+	resolver *resolver.Resolver
+	// ids maps "URI#sourceID" to the hierarchical ID in the result of the
+	// first placement of that source item.
+	ids map[string]string
+	// edges are edges of inlined plans, still in their source's terms.
+	edges []sourceEdge
+	// localRefs are same-document refs made inside inlined plans.
+	localRefs []localRef
+}
+
+// scope is one placement of a document, or part of one, in the result.
+// A document inlined twice has two scopes, and edges and "#id" refs made
+// inside it resolve within their own scope.
+type scope struct {
+	uri string
+	// ids maps source IDs to IDs in the result.
+	ids map[string]string
+}
+
+type sourceEdge struct {
+	scope *scope
+	edge  core.Edge
+}
+
+type localRef struct {
+	scope *scope
+	item  *core.PlanItem
+}
+
+// Flatten returns a copy of doc in which every planRef to another
+// document is replaced by the plan or item it names, inlined as sub-items
+// of the referencing item, recursively. A ref to a whole plan inlines the
+// plan's items; a ref to an item inlines that item. The referencing item
+// loses its planRef and gains metadata[ProvenanceKey] recording the ref
+// and its resolved target. Edges of inlined plans whose endpoints were
+// both inlined are copied with rewritten IDs; cross-document edge
+// endpoints ("file.json#item") that now name inlined items are rewritten
+// too; if a document was inlined more than once they name its first
+// placement. Same-document "#id" refs in doc are left as they are. doc is
+// not changed.
+func Flatten(ctx context.Context, doc *core.Document, opts FlattenOptions) (*core.Document, error) {
+	if doc == nil || doc.Plan == nil {
+		return nil, core.ErrNoPlan
+	}
+	r := opts.Resolver
+	if r == nil {
+		r = resolver.NewResolver()
+	}
+	out := doc.Clone()
+	f := &flattener{ctx: ctx, resolver: r, ids: make(map[string]string)}
+	root := &scope{uri: opts.Base, ids: make(map[string]string)}
+	if err := f.expand(out.Plan.Items, "", "", true, root, nil); err != nil {
+		return nil, err
+	}
+
+	for _, ref := range f.localRefs {
+		fragment := strings.TrimPrefix(ref.item.PlanRef, "#")
+		if id, ok := ref.scope.ids[fragment]; ok {
+			ref.item.PlanRef = "#" + id
+		} else {
+			ref.item.PlanRef = ref.scope.uri + "#" + fragment
+		}
+	}
+	for i, e := range out.Plan.Edges {
+		out.Plan.Edges[i].From = f.rewrite(root, e.From, e.From)
+		out.Plan.Edges[i].To = f.rewrite(root, e.To, e.To)
+	}
+	for _, se := range f.edges {
+		from := f.rewrite(se.scope, se.edge.From, "")
+		to := f.rewrite(se.scope, se.edge.To, "")
+		if from != "" && to != "" {
+			out.Plan.Edges = append(out.Plan.Edges, core.Edge{From: from, To: to, Type: se.edge.Type})
+		}
+	}
+
+	if err := validate(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// expand flattens items in place. parent is their parent's ID in the
+// result and srcParent its ID in their source document; items under a
+// parent that is not addressable have no IDs of their own. stack holds
+// the targets being inlined, for cycle detection.
+func (f *flattener) expand(items []core.PlanItem, parent, srcParent string, addressable bool, sc *scope, stack []string) error {
+	for i := range items {
+		item := &items[i]
+		var id, srcID string
+		if addressable && item.ID != "" {
+			id, srcID = graph.JoinID(parent, item.ID), graph.JoinID(srcParent, item.ID)
+			sc.ids[srcID] = id
+			if _, ok := f.ids[sc.uri+"#"+srcID]; !ok {
+				f.ids[sc.uri+"#"+srcID] = id
+			}
+		}
+
+		if err := f.expand(item.SubItems, id, srcID, id != "", sc, stack); err != nil {
+			return err
+		}
+
+		ref := item.PlanRef
+		if ref == "" {
+			continue
+		}
+		if strings.HasPrefix(ref, "#") {
+			if len(stack) > 0 {
+				f.localRefs = append(f.localRefs, localRef{scope: sc, item: item})
+			}
+			continue
+		}
+
+		t, err := f.resolver.Resolve(f.ctx, sc.uri, ref)
+		if err != nil {
+			return err
+		}
+		for _, s := range stack {
+			if s == t.String() {
+				return fmt.Errorf("%w: %s -> %s", resolver.ErrCycle, strings.Join(stack, " -> "), t)
+			}
+		}
+
+		var inlined []core.PlanItem
+		var srcPrefix string
+		if src := t.Document.Clone(); t.Item == nil {
+			inlined = src.Plan.Items
+		} else {
+			inlined = []core.PlanItem{*graph.New(src.Plan).Node(t.Fragment).Item}
+			srcPrefix, _ = graph.SplitID(t.Fragment)
+		}
+		inner := &scope{uri: t.URI, ids: make(map[string]string)}
+		for _, e := range t.Document.Plan.Edges {
+			f.edges = append(f.edges, sourceEdge{scope: inner, edge: e})
+		}
+		for _, in := range inlined {
+			for _, sib := range item.SubItems {
+				if in.ID != "" && in.ID == sib.ID {
+					return fmt.Errorf("%w: %s already has a sub-item %q", ErrIDConflict, id, in.ID)
+				}
+			}
+		}
+
+		provenance := map[string]interface{}{"planRef": ref, "source": t.String()}
+		if t.Item == nil {
+			provenance["title"] = t.Document.Plan.Title
+		}
+		if item.Metadata == nil {
+			item.Metadata = make(map[string]interface{})
+		}
+		item.Metadata[ProvenanceKey] = provenance
+		item.PlanRef = ""
+
+		n := len(item.SubItems)
+		item.SubItems = append(item.SubItems, inlined...)
+		if err := f.expand(item.SubItems[n:], id, srcPrefix, id != "", inner, append(stack, t.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewrite maps an edge endpoint written in scope sc to an ID in the
+// result. Endpoints that name no placed item map to fallback.
+func (f *flattener) rewrite(sc *scope, end, fallback string) string {
+	if !strings.Contains(end, "#") {
+		if id, ok := sc.ids[end]; ok {
+			return id
+		}
+		return fallback
+	}
+	ref := end
+	if !strings.Contains(end, "://") && !strings.HasPrefix(end, "#") {
+		ref = "file://" + end
+	}
+	target, fragment, err := resolver.Normalize(sc.uri, ref)
+	if err != nil {
+		return fallback
+	}
+	if target == sc.uri {
+		if id, ok := sc.ids[fragment]; ok {
+			return id
+		}
+	} else if id, ok := f.ids[target+"#"+fragment]; ok {
+		return id
+	}
+	return fallback
+}
+
+// Extract splits the item with hierarchical ID id out of doc. It returns
+// a copy of doc in which the item keeps its place, narrative and
+// metadata but its sub-items are replaced by a planRef to
+// opts.Path, and the extracted plan, which has the item's title,
+// narrative and sub-items. The plan's status follows the item's, and its
+// Proposal narrative defaults to the item's title.
+//
+// Edges between extracted items move to the new plan with the item's ID
+// prefix removed. Edges between an extracted item and one that stays
+// are kept in doc, with the extracted endpoint written as "path#id", and
+// "#id" planRefs are rewritten the same way. An extracted "#id" planRef to
+// an item that stays becomes "Base#id", or fails with ErrNoBase if Base
+// is empty. Other planRefs in the extracted items are copied unchanged,
+// so relative file:// refs only stay correct if Path is in the source
+// document's directory. doc is not changed.
+func Extract(doc *core.Document, id string, opts ExtractOptions) (rest, extracted *core.Document, err error) {
+	if doc == nil || doc.Plan == nil {
+		return nil, nil, core.ErrNoPlan
+	}
+	rest = doc.Clone()
+	n := graph.New(rest.Plan).Node(id)
+	if n == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	item := n.Item
+	file := opts.Path
+	if file == "" {
+		file = item.ID + ".vbrief.json"
+	}
+	file = path.Clean(file)
+
+	narratives := make(map[string]string, len(item.Narrative)+1)
+	for k, v := range item.Narrative {
+		narratives[k] = v
+	}
+	if narratives["Proposal"] == "" && narratives["proposal"] == "" {
+		narratives["Proposal"] = item.Title
+	}
+	items := item.SubItems
+	if items == nil {
+		items = []core.PlanItem{}
+	}
+	extracted = &core.Document{
+		Info: core.Info{
+			Version: doc.Info.Version,
+			Metadata: map[string]interface{}{
+				ProvenanceKey: map[string]interface{}{"extractedFrom": opts.Base, "id": id},
+			},
+		},
+		Plan: &core.Plan{
+			Title:      item.Title,
+			Status:     planStatus(item.Status),
+			Narratives: narratives,
+			Items:      items,
+		},
+	}
+
+	prefix := id + "."
+	var kept []core.Edge
+	for _, e := range rest.Plan.Edges {
+		from, fromIn := strings.CutPrefix(e.From, prefix)
+		to, toIn := strings.CutPrefix(e.To, prefix)
+		switch {
+		case fromIn && toIn:
+			extracted.Plan.Edges = append(extracted.Plan.Edges, core.Edge{From: from, To: to, Type: e.Type})
+		case fromIn:
+			kept = append(kept, core.Edge{From: file + "#" + from, To: e.To, Type: e.Type})
+		case toIn:
+			kept = append(kept, core.Edge{From: e.From, To: file + "#" + to, Type: e.Type})
+		default:
+			kept = append(kept, e)
+		}
+	}
+	rest.Plan.Edges = kept
+	item.SubItems = nil
+	item.PlanRef = "file://" + file
+	if err := rewriteExtractedRefs(extracted.Plan.Items, prefix, opts.Base); err != nil {
+		return nil, nil, err
+	}
+	rewriteRefs(rest.Plan.Items, "#"+prefix, "file://"+file+"#")
+
+	if err := validate(rest); err != nil {
+		return nil, nil, err
+	}
+	if err := validate(extracted); err != nil {
+		return nil, nil, err
+	}
+	return rest, extracted, nil
+}
+
+// rewriteRefs replaces the prefix old with new in planRefs under items.
+func rewriteRefs(items []core.PlanItem, old, new string) {
+	for i := range items {
+		if ref, ok := strings.CutPrefix(items[i].PlanRef, old); ok {
+			items[i].PlanRef = new + ref
+		}
+		rewriteRefs(items[i].SubItems, old, new)
+	}
+}
+
+// rewriteExtractedRefs rewrites the "#id" planRefs under extracted items:
+// refs inside the extracted subtree lose its ID prefix, and the rest point
+// back at the source document base.
+func rewriteExtractedRefs(items []core.PlanItem, prefix, base string) error {
+	for i := range items {
+		if ref, ok := strings.CutPrefix(items[i].PlanRef, "#"); ok {
+			if inner, ok := strings.CutPrefix(ref, prefix); ok {
+				items[i].PlanRef = "#" + inner
+			} else if base == "" {
+				return fmt.Errorf("%w: %s refers to %q outside the extracted item", ErrNoBase, items[i].ID, items[i].PlanRef)
+			} else {
+				items[i].PlanRef = base + "#" + ref
+			}
+		}
+		if err := rewriteExtractedRefs(items[i].SubItems, prefix, base); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// planStatus maps an item's status to the closest plan status.
+func planStatus(s core.PlanItemStatus) core.PlanStatus {
+	switch s {
+	case core.PlanItemStatusRunning, core.PlanItemStatusInProgress:
+		return core.PlanStatusRunning
+	case core.PlanItemStatusCompleted:
+		return core.PlanStatusCompleted
+	case core.PlanItemStatusCancelled:
+		return core.PlanStatusCancelled
+	default:
+		return core.PlanStatusDraft
+	}
+}
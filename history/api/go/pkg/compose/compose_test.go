@@ -0,0 +1,253 @@
+package compose
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+	"github.com/visionik/vBRIEF/api/go/pkg/resolver"
+)
+
+const base = "file://plans/programme.vbrief.json"
+
+// doc returns a draft plan document with the core fields the result
+// validation requires.
+func doc(title string, items []core.PlanItem, edges ...core.Edge) *core.Document {
+	if items == nil {
+		items = []core.PlanItem{}
+	}
+	return &core.Document{
+		Info: core.Info{Version: "0.5"},
+		Plan: &core.Plan{
+			Title: title, Status: core.PlanStatusDraft,
+			Narratives: map[string]string{"Proposal": title},
+			Items:      items, Edges: edges,
+		},
+	}
+}
+
+// task returns a pending item named after its ID.
+func task(id string, sub ...core.PlanItem) core.PlanItem {
+	return core.PlanItem{ID: id, Title: id, Status: core.PlanItemStatusPending, SubItems: sub}
+}
+
+// ref returns a task that links to planRef.
+func ref(id, planRef string, sub ...core.PlanItem) core.PlanItem {
+	it := task(id, sub...)
+	it.PlanRef = planRef
+	return it
+}
+
+func blocks(from, to string) core.Edge {
+	return core.Edge{From: from, To: to, Type: core.EdgeBlocks}
+}
+
+// split returns a root plan whose work lives in two other files, and a
+// resolver that loads them. The auth plan is referenced twice, once whole
+// from api and once for its login item from the root:
+//
+//	plans/programme.vbrief.json  api => api, login => auth#login, launch
+//	plans/api.vbrief.json        setup{db}, endpoints => #setup, auth => auth
+//	plans/auth.vbrief.json       login{form}, tokens
+//
+// launch is blocked by api and auth#login.form, endpoints by setup and
+// tokens by login.
+func split() (*core.Document, *resolver.Resolver) {
+	root := doc("Programme", []core.PlanItem{
+		ref("api", "file://api.vbrief.json"),
+		ref("login", "file://auth.vbrief.json#login"),
+		task("launch"),
+	},
+		blocks("api", "launch"),
+		blocks("auth.vbrief.json#login.form", "launch"),
+	)
+	loader := resolver.NewMemoryLoader(map[string]*core.Document{
+		"file://plans/api.vbrief.json": doc("API", []core.PlanItem{
+			task("setup", task("db")),
+			ref("endpoints", "#setup"),
+			ref("auth", "file://auth.vbrief.json"),
+		}, blocks("setup", "endpoints")),
+		"file://plans/auth.vbrief.json": doc("Auth", []core.PlanItem{
+			task("login", task("form")),
+			task("tokens"),
+		}, blocks("login", "tokens")),
+	})
+	return root, resolver.NewResolverWithOptions(resolver.Options{
+		Loaders: map[string]resolver.Loader{"file": loader},
+	})
+}
+
+func ids(d *core.Document) []string {
+	var out []string
+	for _, n := range graph.New(d.Plan).Nodes() {
+		out = append(out, n.ID)
+	}
+	return out
+}
+
+func TestFlatten(t *testing.T) {
+	root, r := split()
+	out, err := Flatten(context.Background(), root, FlattenOptions{Base: base, Resolver: r})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"api", "api.setup", "api.setup.db", "api.endpoints", "api.auth", "api.auth.login", "api.auth.login.form", "api.auth.tokens",
+		"login", "login.login", "login.login.form",
+		"launch",
+	}, ids(out))
+	// auth is inlined twice; the cross-document endpoint names its first
+	// placement, and each placement keeps only its own edges.
+	assert.Equal(t, []core.Edge{
+		blocks("api", "launch"),
+		blocks("api.auth.login.form", "launch"),
+		blocks("api.setup", "api.endpoints"),
+		blocks("api.auth.login", "api.auth.tokens"),
+	}, out.Plan.Edges)
+
+	g := graph.New(out.Plan)
+	assert.Equal(t, "#api.setup", g.Node("api.endpoints").Item.PlanRef)
+	api := g.Node("api").Item
+	assert.Empty(t, api.PlanRef)
+	assert.Equal(t, map[string]interface{}{
+		"planRef": "file://api.vbrief.json",
+		"source":  "file://plans/api.vbrief.json",
+		"title":   "API",
+	}, api.Metadata[ProvenanceKey])
+	assert.Equal(t, map[string]interface{}{
+		"planRef": "file://auth.vbrief.json#login",
+		"source":  "file://plans/auth.vbrief.json#login",
+	}, g.Node("login").Item.Metadata[ProvenanceKey])
+
+	// The input is untouched.
+	assert.Equal(t, "file://api.vbrief.json", root.Plan.Items[0].PlanRef)
+	assert.Empty(t, root.Plan.Items[0].SubItems)
+}
+
+func TestFlatten_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		docs map[string]*core.Document
+		root *core.Document
+		want error
+	}{
+		{
+			name: "cycle",
+			docs: map[string]*core.Document{
+				"file://plans/a.vbrief.json": doc("A", []core.PlanItem{ref("x", "file://b.vbrief.json")}),
+				"file://plans/b.vbrief.json": doc("B", []core.PlanItem{ref("y", "file://a.vbrief.json")}),
+			},
+			root: doc("Programme", []core.PlanItem{ref("a", "file://a.vbrief.json")}),
+			want: resolver.ErrCycle,
+		},
+		{
+			name: "id conflict",
+			docs: map[string]*core.Document{
+				"file://plans/a.vbrief.json": doc("A", []core.PlanItem{task("x")}),
+			},
+			root: doc("Programme", []core.PlanItem{ref("a", "file://a.vbrief.json", task("x"))}),
+			want: ErrIDConflict,
+		},
+		{
+			name: "missing",
+			root: doc("Programme", []core.PlanItem{ref("a", "file://a.vbrief.json")}),
+			want: resolver.ErrNotFound,
+		},
+		{
+			name: "no plan",
+			root: &core.Document{},
+			want: core.ErrNoPlan,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := resolver.NewResolverWithOptions(resolver.Options{
+				Loaders: map[string]resolver.Loader{"file": resolver.NewMemoryLoader(tt.docs)},
+			})
+			_, err := Flatten(context.Background(), tt.root, FlattenOptions{Base: base, Resolver: r})
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	src := doc("Programme", []core.PlanItem{
+		task("api", task("setup", task("db")), ref("endpoints", "#api.setup")),
+		task("docs"),
+		ref("launch", "#api.endpoints"),
+	},
+		blocks("api.setup", "api.endpoints"),
+		blocks("api.endpoints", "launch"),
+		blocks("docs", "api.setup"),
+		blocks("docs", "launch"),
+	)
+	src.Plan.Items[0].Status = core.PlanItemStatusRunning
+	src.Plan.Items[0].Narrative = map[string]string{"Action": "Build the API"}
+
+	rest, extracted, err := Extract(src, "api", ExtractOptions{Base: base})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"api", "docs", "launch"}, ids(rest))
+	assert.Equal(t, "file://api.vbrief.json", rest.Plan.Items[0].PlanRef)
+	assert.Equal(t, map[string]string{"Action": "Build the API"}, rest.Plan.Items[0].Narrative)
+	assert.Equal(t, "file://api.vbrief.json#endpoints", rest.Plan.Items[2].PlanRef)
+	assert.Equal(t, []core.Edge{
+		blocks("api.vbrief.json#endpoints", "launch"),
+		blocks("docs", "api.vbrief.json#setup"),
+		blocks("docs", "launch"),
+	}, rest.Plan.Edges)
+
+	assert.Equal(t, "api", extracted.Plan.Title)
+	assert.Equal(t, core.PlanStatusRunning, extracted.Plan.Status)
+	assert.Equal(t, map[string]string{"Action": "Build the API", "Proposal": "api"}, extracted.Plan.Narratives)
+	assert.Equal(t, []string{"setup", "setup.db", "endpoints"}, ids(extracted))
+	assert.Equal(t, "#setup", extracted.Plan.Items[1].PlanRef)
+	assert.Equal(t, []core.Edge{blocks("setup", "endpoints")}, extracted.Plan.Edges)
+	assert.Equal(t, map[string]interface{}{"extractedFrom": base, "id": "api"}, extracted.Info.Metadata[ProvenanceKey])
+
+	// The input is untouched.
+	assert.Len(t, src.Plan.Items[0].SubItems, 2)
+
+	_, _, err = Extract(src, "nope", ExtractOptions{})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestExtract_OutsideRefs(t *testing.T) {
+	src := doc("Programme", []core.PlanItem{
+		task("api", task("setup"), ref("spec", "#docs"), ref("self", "#api")),
+		task("docs", task("intro")),
+	})
+
+	_, extracted, err := Extract(src, "api", ExtractOptions{Base: base})
+	require.NoError(t, err)
+	assert.Equal(t, base+"#docs", extracted.Plan.Items[1].PlanRef, "refs to items left behind point back at the source")
+	assert.Equal(t, base+"#api", extracted.Plan.Items[2].PlanRef)
+
+	_, _, err = Extract(src, "api", ExtractOptions{})
+	assert.ErrorIs(t, err, ErrNoBase)
+	assert.ErrorContains(t, err, `spec refers to "#docs"`)
+}
+
+func TestExtractThenFlatten(t *testing.T) {
+	src := doc("Programme", []core.PlanItem{
+		task("api", task("setup"), task("endpoints")),
+		task("launch"),
+	},
+		blocks("api.setup", "api.endpoints"),
+		blocks("api.endpoints", "launch"),
+	)
+	rest, extracted, err := Extract(src, "api", ExtractOptions{Path: "api/api.vbrief.json"})
+	require.NoError(t, err)
+
+	r := resolver.NewResolverWithOptions(resolver.Options{
+		Loaders: map[string]resolver.Loader{"file": resolver.NewMemoryLoader(map[string]*core.Document{
+			"file://plans/api/api.vbrief.json": extracted,
+		})},
+	})
+	out, err := Flatten(context.Background(), rest, FlattenOptions{Base: base, Resolver: r})
+	require.NoError(t, err)
+	assert.Equal(t, ids(src), ids(out))
+	assert.ElementsMatch(t, src.Plan.Edges, out.Plan.Edges)
+}
@@ -2,13 +2,22 @@
 package convert
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 
-	"github.com/tron-format/trongo/pkg/tron"
+	"github.com/visionik/vBRIEF/api/go/pkg/beads"
+	"github.com/visionik/vBRIEF/api/go/pkg/canonical"
 	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/github"
+	"github.com/visionik/vBRIEF/api/go/pkg/ical"
+	"github.com/visionik/vBRIEF/api/go/pkg/render"
+	"github.com/visionik/vBRIEF/api/go/pkg/table"
+	"github.com/visionik/vBRIEF/api/go/pkg/todotxt"
+	"github.com/visionik/vBRIEF/api/go/pkg/tronenc"
+	"github.com/visionik/vBRIEF/api/go/pkg/yaml"
 )
 
 var (
@@ -24,6 +33,32 @@ const (
 	FormatJSON Format = "json"
 	// FormatTRON represents TRON format.
 	FormatTRON Format = "tron"
+	// FormatDOT represents a Graphviz DOT rendering of a plan's DAG.
+	FormatDOT Format = "dot"
+	// FormatMermaid represents a Mermaid flowchart of a plan's DAG.
+	FormatMermaid Format = "mermaid"
+	// FormatHTML represents a self-contained HTML report of a plan.
+	FormatHTML Format = "html"
+	// FormatICal represents an iCalendar (RFC 5545) export of a plan.
+	FormatICal Format = "ical"
+	// FormatCSV represents a comma-separated table of plan items.
+	FormatCSV Format = "csv"
+	// FormatTSV represents a tab-separated table of plan items.
+	FormatTSV Format = "tsv"
+	// FormatTodoTxt represents a todo.txt task list.
+	FormatTodoTxt Format = "todotxt"
+	// FormatBeads represents a Beads issues.jsonl file.
+	FormatBeads Format = "beads"
+	// FormatGitHub represents GitHub REST requests that mirror a plan.
+	FormatGitHub Format = "github"
+	// FormatYAML represents plain YAML.
+	FormatYAML Format = "yaml"
+	// FormatTROY represents TROY, YAML with tagged class instances.
+	FormatTROY Format = "troy"
+	// FormatCanonicalJSON represents compact canonical (JCS-style) JSON.
+	FormatCanonicalJSON Format = "canonical-json"
+	// FormatCanonicalTRON represents canonical TRON without classes.
+	FormatCanonicalTRON Format = "canonical-tron"
 )
 
 // Converter handles format conversion for documents.
@@ -48,7 +83,33 @@ func (c *converter) Convert(doc *core.Document, format Format) ([]byte, error) {
 	case FormatJSON:
 		return json.Marshal(doc)
 	case FormatTRON:
-		return tron.Marshal(doc)
+		return tronenc.Encode(doc, tronenc.Options{})
+	case FormatDOT:
+		return render.DOT(planOf(doc), render.DefaultOptions())
+	case FormatMermaid:
+		return render.Mermaid(planOf(doc), render.DefaultOptions())
+	case FormatHTML:
+		return render.HTML(doc, render.DefaultOptions())
+	case FormatICal:
+		return ical.Encode(doc, ical.Options{})
+	case FormatCSV:
+		return table.Encode(doc, table.Options{Comma: ','})
+	case FormatTSV:
+		return table.Encode(doc, table.Options{Comma: '\t'})
+	case FormatTodoTxt:
+		return todotxt.Encode(doc)
+	case FormatBeads:
+		return encodeBeads(doc)
+	case FormatGitHub:
+		return encodeGitHub(doc)
+	case FormatYAML:
+		return yaml.Encode(doc, yaml.Options{})
+	case FormatTROY:
+		return yaml.Encode(doc, yaml.Options{TROY: true})
+	case FormatCanonicalJSON:
+		return canonical.JSON(doc, canonical.Options{})
+	case FormatCanonicalTRON:
+		return canonical.TRON(doc, canonical.Options{})
 	default:
 		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
 	}
@@ -86,12 +147,123 @@ func ToJSONIndent(doc *core.Document, prefix, indent string) ([]byte, error) {
 	return json.MarshalIndent(doc, prefix, indent)
 }
 
-// ToTRON converts a document to TRON bytes.
+// ToTRON converts a document to TRON bytes, declaring classes for the
+// document's repeated object shapes. Use tronenc.EncodeWithReport to tune
+// the classes or see the tokens they save.
 func ToTRON(doc *core.Document) ([]byte, error) {
 	return Convert(doc, FormatTRON)
 }
 
-// ToTRONIndent converts a document to indented TRON bytes.
+// ToTRONIndent converts a document to indented TRON bytes. It declares
+// the same classes as ToTRON.
 func ToTRONIndent(doc *core.Document, prefix, indent string) ([]byte, error) {
-	return tron.MarshalIndent(doc, prefix, indent)
+	return tronenc.Encode(doc, tronenc.Options{Prefix: prefix, Indent: indent})
+}
+
+// ToCanonicalJSON converts a document to compact canonical JSON, which is
+// byte-for-byte stable for equal documents. Use canonical.JSON to indent it.
+func ToCanonicalJSON(doc *core.Document) ([]byte, error) {
+	return Convert(doc, FormatCanonicalJSON)
+}
+
+// ToCanonicalTRON converts a document to canonical TRON. Use canonical.TRON
+// to indent it.
+func ToCanonicalTRON(doc *core.Document) ([]byte, error) {
+	return Convert(doc, FormatCanonicalTRON)
+}
+
+// ToYAML converts a document to plain YAML. Use yaml.Encode to restore
+// comments read with yaml.DecodeWithComments.
+func ToYAML(doc *core.Document) ([]byte, error) {
+	return Convert(doc, FormatYAML)
+}
+
+// ToTROY converts a document to TROY, writing plan items, todo items,
+// edges, reminders and participants positionally.
+func ToTROY(doc *core.Document) ([]byte, error) {
+	return Convert(doc, FormatTROY)
+}
+
+// ToDOT renders a document's plan as a Graphviz DOT digraph.
+func ToDOT(doc *core.Document) ([]byte, error) {
+	return Convert(doc, FormatDOT)
+}
+
+// ToMermaid renders a document's plan as a Mermaid flowchart.
+func ToMermaid(doc *core.Document) ([]byte, error) {
+	return Convert(doc, FormatMermaid)
+}
+
+// ToHTML renders a document's plan as a self-contained HTML report.
+func ToHTML(doc *core.Document) ([]byte, error) {
+	return Convert(doc, FormatHTML)
+}
+
+// ToICal exports a document's plan as an iCalendar VCALENDAR.
+func ToICal(doc *core.Document) ([]byte, error) {
+	return Convert(doc, FormatICal)
+}
+
+// ToCSV exports a document's plan items as a comma-separated table using
+// table.DefaultColumns. Use table.Encode to choose columns.
+func ToCSV(doc *core.Document) ([]byte, error) {
+	return Convert(doc, FormatCSV)
+}
+
+// ToTSV exports a document's plan items as a tab-separated table using
+// table.DefaultColumns. Use table.Encode to choose columns.
+func ToTSV(doc *core.Document) ([]byte, error) {
+	return Convert(doc, FormatTSV)
+}
+
+// ToTodoTxt exports a document's plan items as todo.txt lines.
+func ToTodoTxt(doc *core.Document) ([]byte, error) {
+	return Convert(doc, FormatTodoTxt)
+}
+
+// ToBeads exports a document's plan items as Beads issues.jsonl lines.
+func ToBeads(doc *core.Document) ([]byte, error) {
+	return Convert(doc, FormatBeads)
+}
+
+// planOf returns doc's plan, or nil if doc is nil, so that renderers
+// report core.ErrNoPlan.
+func planOf(doc *core.Document) *core.Plan {
+	if doc == nil {
+		return nil
+	}
+	return doc.Plan
+}
+
+func encodeBeads(doc *core.Document) ([]byte, error) {
+	if doc == nil || doc.Plan == nil {
+		return nil, core.ErrNoPlan
+	}
+	issues, err := beads.Export(doc.Plan, beads.ExportOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := beads.WriteIssues(&buf, issues); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ToGitHub exports a document's plan as an indented JSON array of GitHub
+// REST requests for `gh api {owner}/{repo}`. Use github.Export to set the
+// repository and the next issue and milestone numbers.
+func ToGitHub(doc *core.Document) ([]byte, error) {
+	return Convert(doc, FormatGitHub)
+}
+
+func encodeGitHub(doc *core.Document) ([]byte, error) {
+	if doc == nil || doc.Plan == nil {
+		return nil, core.ErrNoPlan
+	}
+	requests, err := github.Export(doc.Plan, github.Options{})
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(requests, "", "  ")
 }
@@ -3,6 +3,7 @@ package convert
 import (
 	"bytes"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -165,6 +166,21 @@ func TestToTRONIndent(t *testing.T) {
 
 		require.NoError(t, err)
 		require.NotEmpty(t, data)
+		assert.Contains(t, string(data), "\n  ")
+	})
+
+	t.Run("declares the same classes as ToTRON", func(t *testing.T) {
+		for _, title := range []string{"Task 2", "Task 3", "Task 4", "Task 5", "Task 6"} {
+			doc.TodoList.Items = append(doc.TodoList.Items, core.TodoItem{Title: title, Status: core.StatusPending})
+		}
+		compact, err := ToTRON(doc)
+		require.NoError(t, err)
+		indented, err := ToTRONIndent(doc, "", "  ")
+		require.NoError(t, err)
+
+		header := func(b []byte) string { return strings.SplitN(string(b), "\n\n", 2)[0] }
+		assert.Contains(t, header(compact), "class TodoItem")
+		assert.Equal(t, header(compact), header(indented))
 	})
 }
 
@@ -224,3 +240,124 @@ func TestConverter_Plan(t *testing.T) {
 		require.NotEmpty(t, data)
 	})
 }
+
+func TestConverter_Diagrams(t *testing.T) {
+	doc := &core.Document{
+		Info: core.Info{Version: "0.5"},
+		Plan: &core.Plan{
+			Title:  "DAG",
+			Status: core.PlanStatusDraft,
+			Items: []core.PlanItem{
+				{ID: "a", Title: "A", Status: core.PlanItemStatusPending},
+				{ID: "b", Title: "B", Status: core.PlanItemStatusPending},
+			},
+			Edges: []core.Edge{{From: "a", To: "b", Type: core.EdgeBlocks}},
+		},
+	}
+
+	t.Run("converts to DOT", func(t *testing.T) {
+		data, err := ToDOT(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"a" -> "b"`)
+	})
+
+	t.Run("converts to Mermaid", func(t *testing.T) {
+		data, err := ToMermaid(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "n0 --> n1")
+	})
+
+	t.Run("converts to HTML", func(t *testing.T) {
+		data, err := ToHTML(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "<h1>DAG</h1>")
+	})
+
+	t.Run("converts to iCalendar", func(t *testing.T) {
+		data, err := ToICal(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "BEGIN:VTODO\r\nUID:a@dag.vbrief\r\n")
+	})
+
+	t.Run("converts to CSV and TSV", func(t *testing.T) {
+		data, err := ToCSV(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "b,B,pending,,,,,,,a\n")
+
+		data, err = ToTSV(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "b\tB\tpending\t\t\t\t\t\t\ta\n")
+	})
+
+	t.Run("converts to todo.txt", func(t *testing.T) {
+		data, err := ToTodoTxt(doc)
+		require.NoError(t, err)
+		assert.Equal(t, "A\nB\n", string(data))
+	})
+
+	t.Run("converts to Beads", func(t *testing.T) {
+		data, err := ToBeads(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"dependencies":[{"issue_id":"bd-b","depends_on_id":"bd-a","type":"blocks"}]`)
+	})
+
+	t.Run("converts to GitHub requests", func(t *testing.T) {
+		data, err := ToGitHub(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"body": "Blocked by #1"`)
+	})
+
+	t.Run("converts to YAML and TROY", func(t *testing.T) {
+		data, err := ToYAML(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "    - id: b\n      title: B\n")
+
+		data, err = ToTROY(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "    - !PlanItem [b, B, pending]\n")
+		assert.Contains(t, string(data), "    - !Edge [a, b, blocks]\n")
+	})
+
+	t.Run("converts to canonical JSON and TRON", func(t *testing.T) {
+		data, err := ToCanonicalJSON(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"plan":{"title":"DAG","status":"draft","items":[{"id":"a",`)
+
+		data, err = ToCanonicalTRON(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `edges:[{from:"a",to:"b",type:"blocks"}]`)
+	})
+
+	t.Run("requires a plan", func(t *testing.T) {
+		_, err := Convert(&core.Document{Info: core.Info{Version: "0.5"}}, FormatDOT)
+		assert.True(t, errors.Is(err, core.ErrNoPlan))
+
+		for _, format := range []Format{FormatDOT, FormatMermaid} {
+			_, err := Convert(nil, format)
+			assert.ErrorIs(t, err, core.ErrNoPlan, format)
+		}
+	})
+}
+
+func TestPlanWithoutItems(t *testing.T) {
+	doc := &core.Document{
+		Info: core.Info{Version: "0.5"},
+		Plan: &core.Plan{Title: "P", Status: core.PlanStatusDraft, Narratives: map[string]string{"Proposal": "p"}},
+	}
+	for _, f := range []Format{FormatJSON, FormatTRON, FormatCanonicalJSON, FormatCanonicalTRON, FormatYAML} {
+		t.Run(string(f), func(t *testing.T) {
+			data, err := Convert(doc, f)
+			require.NoError(t, err)
+			assert.NotContains(t, string(data), "null", "a plan built without items never emits null")
+		})
+	}
+
+	indented, err := ToTRONIndent(doc, "", "  ")
+	require.NoError(t, err)
+	assert.NotContains(t, string(indented), "null")
+
+	doc.Plan.Items = []core.PlanItem{}
+	data, err := ToJSON(doc)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"items":[]`, "an empty items array is kept")
+}
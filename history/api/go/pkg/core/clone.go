@@ -0,0 +1,71 @@
+package core
+
+import "reflect"
+
+// Clone returns a deep copy of the document. Nil and empty slices and maps
+// stay distinct, so the copy serialises exactly as the original does.
+func (d *Document) Clone() *Document {
+	if d == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(d)).Interface().(*Document)
+}
+
+// Clone returns a deep copy of the item and its sub-items.
+func (i *PlanItem) Clone() *PlanItem {
+	if i == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(i)).Interface().(*PlanItem)
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(deepCopy(v.Elem()))
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return out
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(deepCopy(v.Elem()))
+		return out
+	case reflect.Struct:
+		// Copy the whole value first so that unexported fields, such as
+		// time.Time's, carry over; then replace the exported ones.
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if out.Field(i).CanSet() {
+				out.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return out
+	default:
+		return v
+	}
+}
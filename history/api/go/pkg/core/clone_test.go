@@ -0,0 +1,69 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocument_Clone(t *testing.T) {
+	due := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	pct := 50.0
+	doc := &Document{
+		Info: Info{Version: "0.5", Metadata: map[string]interface{}{"tags": []interface{}{"a"}}},
+		Plan: &Plan{
+			Title:      "P",
+			Status:     PlanStatusRunning,
+			Narratives: map[string]string{"Proposal": "x"},
+			Items: []PlanItem{{
+				ID: "a", Title: "A", Status: PlanItemStatusPending,
+				SubItems:        []PlanItem{},
+				DueDate:         &due,
+				PercentComplete: &pct,
+				Metadata:        map[string]interface{}{"nested": map[string]interface{}{"n": 1.0}},
+			}},
+			Edges: []Edge{{From: "a", To: "a", Type: EdgeBlocks}},
+		},
+	}
+
+	c := doc.Clone()
+	require.Equal(t, doc, c)
+	assert.NotNil(t, c.Plan.Items[0].SubItems, "empty slices stay empty")
+	assert.Nil(t, c.TodoList)
+
+	c.Plan.Narratives["Proposal"] = "y"
+	c.Plan.Items[0].Title = "B"
+	*c.Plan.Items[0].DueDate = due.Add(time.Hour)
+	*c.Plan.Items[0].PercentComplete = 75
+	c.Plan.Items[0].Metadata["nested"].(map[string]interface{})["n"] = 2.0
+	c.Info.Metadata["tags"].([]interface{})[0] = "b"
+	c.Plan.Edges[0].To = "b"
+
+	assert.Equal(t, "x", doc.Plan.Narratives["Proposal"])
+	assert.Equal(t, "A", doc.Plan.Items[0].Title)
+	assert.Equal(t, due, *doc.Plan.Items[0].DueDate)
+	assert.Equal(t, 50.0, *doc.Plan.Items[0].PercentComplete)
+	assert.Equal(t, 1.0, doc.Plan.Items[0].Metadata["nested"].(map[string]interface{})["n"])
+	assert.Equal(t, "a", doc.Info.Metadata["tags"].([]interface{})[0])
+	assert.Equal(t, "a", doc.Plan.Edges[0].To)
+
+	assert.Nil(t, (*Document)(nil).Clone())
+}
+
+func TestPlanItem_Clone(t *testing.T) {
+	item := &PlanItem{
+		ID: "a", Title: "A", Status: PlanItemStatusPending,
+		SubItems: []PlanItem{{ID: "b", Title: "B", Status: PlanItemStatusPending}},
+		Metadata: map[string]interface{}{"runner": "shell"},
+	}
+	c := item.Clone()
+	require.Equal(t, item, c)
+
+	c.SubItems[0].Title = "C"
+	c.Metadata["runner"] = "http"
+	assert.Equal(t, "B", item.SubItems[0].Title)
+	assert.Equal(t, "shell", item.Metadata["runner"])
+	assert.Nil(t, (*PlanItem)(nil).Clone())
+}
@@ -4,8 +4,14 @@ package core
 import (
 	"errors"
 	"fmt"
+	"math"
+	"time"
 )
 
+// SpecVersion is the vBRIEF specification version written by importers that
+// construct documents from foreign formats.
+const SpecVersion = "0.5"
+
 // Common errors for document operations.
 var (
 	// ErrInvalidIndex is returned when an index is out of bounds.
@@ -51,7 +57,9 @@ type ItemStatus string
 const (
 	// StatusPending indicates the item has not been started.
 	StatusPending ItemStatus = "pending"
-	// StatusInProgress indicates the item is currently being worked on.
+	// StatusRunning indicates the item is currently being worked on.
+	StatusRunning ItemStatus = "running"
+	// StatusInProgress is the pre-0.5 name for StatusRunning.
 	StatusInProgress ItemStatus = "inProgress"
 	// StatusCompleted indicates the item has been finished.
 	StatusCompleted ItemStatus = "completed"
@@ -64,7 +72,7 @@ const (
 // IsValid returns true if the ItemStatus is a valid value.
 func (s ItemStatus) IsValid() bool {
 	switch s {
-	case StatusPending, StatusInProgress, StatusCompleted, StatusBlocked, StatusCancelled:
+	case StatusPending, StatusRunning, StatusInProgress, StatusCompleted, StatusBlocked, StatusCancelled:
 		return true
 	default:
 		return false
@@ -76,7 +84,49 @@ type Plan struct {
 	Title      string            `json:"title" tron:"title"`
 	Status     PlanStatus        `json:"status" tron:"status"`
 	Narratives map[string]string `json:"narratives" tron:"narratives"`
-	Items      []PlanItem        `json:"items,omitempty" tron:"items,omitempty"`
+	// Items is left out when nil, never written as null; an empty,
+	// non-nil slice is written as [] in JSON.
+	Items []PlanItem `json:"items,omitzero" tron:"items,omitempty"`
+	Edges []Edge     `json:"edges,omitempty" tron:"edges,omitempty"`
+
+	// Beads interop extension.
+	BeadsProject  string     `json:"beadsProject,omitempty" tron:"beadsProject,omitempty"`
+	BeadsSyncedAt *time.Time `json:"beadsSyncedAt,omitempty" tron:"beadsSyncedAt,omitempty"`
+}
+
+// Edge is a typed, directed relationship between two plan items.
+//
+// From and To reference items by hierarchical ID: the IDs of an item's
+// ancestors joined with dots, followed by its own ID (e.g. "setup.auth").
+type Edge struct {
+	From string   `json:"from" tron:"from"`
+	To   string   `json:"to" tron:"to"`
+	Type EdgeType `json:"type" tron:"type"`
+}
+
+// EdgeType represents the kind of relationship an Edge expresses.
+// Custom types are allowed; consumers should ignore types they do not know.
+type EdgeType string
+
+const (
+	// EdgeBlocks indicates the target cannot start until the source completes.
+	EdgeBlocks EdgeType = "blocks"
+	// EdgeInforms indicates the target benefits from the source's context.
+	EdgeInforms EdgeType = "informs"
+	// EdgeInvalidates indicates source completion makes the target unnecessary.
+	EdgeInvalidates EdgeType = "invalidates"
+	// EdgeSuggests is a weak recommendation with no hard dependency.
+	EdgeSuggests EdgeType = "suggests"
+)
+
+// IsCore returns true if the EdgeType is one of the core edge types.
+func (t EdgeType) IsCore() bool {
+	switch t {
+	case EdgeBlocks, EdgeInforms, EdgeInvalidates, EdgeSuggests:
+		return true
+	default:
+		return false
+	}
 }
 
 // PlanStatus represents the status of a plan.
@@ -89,7 +139,9 @@ const (
 	PlanStatusProposed PlanStatus = "proposed"
 	// PlanStatusApproved indicates the plan has been approved.
 	PlanStatusApproved PlanStatus = "approved"
-	// PlanStatusInProgress indicates the plan is being executed.
+	// PlanStatusRunning indicates the plan is being executed.
+	PlanStatusRunning PlanStatus = "running"
+	// PlanStatusInProgress is the pre-0.5 name for PlanStatusRunning.
 	PlanStatusInProgress PlanStatus = "inProgress"
 	// PlanStatusCompleted indicates the plan has been completed.
 	PlanStatusCompleted PlanStatus = "completed"
@@ -101,7 +153,7 @@ const (
 func (s PlanStatus) IsValid() bool {
 	switch s {
 	case PlanStatusDraft, PlanStatusProposed, PlanStatusApproved,
-		PlanStatusInProgress, PlanStatusCompleted, PlanStatusCancelled:
+		PlanStatusRunning, PlanStatusInProgress, PlanStatusCompleted, PlanStatusCancelled:
 		return true
 	default:
 		return false
@@ -109,9 +161,190 @@ func (s PlanStatus) IsValid() bool {
 }
 
 // PlanItem represents a stage of work within a plan.
+//
+// Items may be nested via SubItems; nested items share the same shape as
+// top-level items.
 type PlanItem struct {
-	Title  string          `json:"title" tron:"title"`
-	Status PlanItemStatus `json:"status" tron:"status"`
+	ID              string                 `json:"id,omitempty" tron:"id,omitempty"`
+	UID             string                 `json:"uid,omitempty" tron:"uid,omitempty"`
+	Title           string                 `json:"title" tron:"title"`
+	Status          PlanItemStatus         `json:"status" tron:"status"`
+	Narrative       map[string]string      `json:"narrative,omitempty" tron:"narrative,omitempty"`
+	SubItems        []PlanItem             `json:"subItems,omitempty" tron:"subItems,omitempty"`
+	PlanRef         string                 `json:"planRef,omitempty" tron:"planRef,omitempty"`
+	Tags            []string               `json:"tags,omitempty" tron:"tags,omitempty"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty" tron:"metadata,omitempty"`
+	Created         *time.Time             `json:"created,omitempty" tron:"created,omitempty"`
+	Updated         *time.Time             `json:"updated,omitempty" tron:"updated,omitempty"`
+	Priority        Priority               `json:"priority,omitempty" tron:"priority,omitempty"`
+	DueDate         *time.Time             `json:"dueDate,omitempty" tron:"dueDate,omitempty"`
+	Completed       *time.Time             `json:"completed,omitempty" tron:"completed,omitempty"`
+	StartDate       *time.Time             `json:"startDate,omitempty" tron:"startDate,omitempty"`
+	EndDate         *time.Time             `json:"endDate,omitempty" tron:"endDate,omitempty"`
+	PercentComplete *float64               `json:"percentComplete,omitempty" tron:"percentComplete,omitempty"`
+	Recurrence      *RecurrenceRule        `json:"recurrence,omitempty" tron:"recurrence,omitempty"`
+	Reminders       []Reminder             `json:"reminders,omitempty" tron:"reminders,omitempty"`
+	Participants    []Participant          `json:"participants,omitempty" tron:"participants,omitempty"`
+
+	// Beads interop extension.
+	BeadsID      string        `json:"beadsId,omitempty" tron:"beadsId,omitempty"`
+	BeadsMetrics *BeadsMetrics `json:"beadsMetrics,omitempty" tron:"beadsMetrics,omitempty"`
+}
+
+// BeadsMetrics holds graph metrics computed for a Beads issue by
+// beads_viewer.
+type BeadsMetrics struct {
+	PageRank     float64 `json:"pageRank,omitempty" tron:"pageRank,omitempty"`
+	Betweenness  float64 `json:"betweenness,omitempty" tron:"betweenness,omitempty"`
+	ImpactScore  float64 `json:"impactScore,omitempty" tron:"impactScore,omitempty"`
+	IsBottleneck bool    `json:"isBottleneck,omitempty" tron:"isBottleneck,omitempty"`
+	InCycle      bool    `json:"inCycle,omitempty" tron:"inCycle,omitempty"`
+	CycleID      string  `json:"cycleId,omitempty" tron:"cycleId,omitempty"`
+}
+
+// Priority represents the relative importance of a plan item.
+type Priority string
+
+const (
+	// PriorityLow indicates the item can wait.
+	PriorityLow Priority = "low"
+	// PriorityMedium indicates normal importance.
+	PriorityMedium Priority = "medium"
+	// PriorityHigh indicates the item should be done soon.
+	PriorityHigh Priority = "high"
+	// PriorityCritical indicates the item must be done first.
+	PriorityCritical Priority = "critical"
+)
+
+// IsValid returns true if the Priority is a valid value.
+func (p Priority) IsValid() bool {
+	switch p {
+	case PriorityLow, PriorityMedium, PriorityHigh, PriorityCritical:
+		return true
+	default:
+		return false
+	}
+}
+
+// RecurrenceRule describes how an item repeats. It mirrors the subset of the
+// iCalendar RRULE that vBRIEF supports.
+type RecurrenceRule struct {
+	Frequency  Frequency  `json:"frequency" tron:"frequency"`
+	Interval   int        `json:"interval,omitempty" tron:"interval,omitempty"`
+	Until      *time.Time `json:"until,omitempty" tron:"until,omitempty"`
+	Count      int        `json:"count,omitempty" tron:"count,omitempty"`
+	ByDay      []string   `json:"byDay,omitempty" tron:"byDay,omitempty"`
+	ByMonth    []int      `json:"byMonth,omitempty" tron:"byMonth,omitempty"`
+	ByMonthDay []int      `json:"byMonthDay,omitempty" tron:"byMonthDay,omitempty"`
+}
+
+// Frequency is the base repetition period of a RecurrenceRule.
+type Frequency string
+
+const (
+	// FrequencyDaily repeats every day.
+	FrequencyDaily Frequency = "daily"
+	// FrequencyWeekly repeats every week.
+	FrequencyWeekly Frequency = "weekly"
+	// FrequencyMonthly repeats every month.
+	FrequencyMonthly Frequency = "monthly"
+	// FrequencyYearly repeats every year.
+	FrequencyYearly Frequency = "yearly"
+)
+
+// IsValid returns true if the Frequency is a valid value.
+func (f Frequency) IsValid() bool {
+	switch f {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+		return true
+	default:
+		return false
+	}
+}
+
+// Reminder is a notification attached to a plan item.
+//
+// Trigger is either an ISO 8601 duration relative to the item's due date
+// (e.g. "-PT15M") or an RFC 3339 timestamp.
+type Reminder struct {
+	Trigger     string         `json:"trigger" tron:"trigger"`
+	Action      ReminderAction `json:"action" tron:"action"`
+	Description string         `json:"description,omitempty" tron:"description,omitempty"`
+}
+
+// ReminderAction is how a Reminder is delivered.
+type ReminderAction string
+
+const (
+	// ReminderDisplay shows a notification.
+	ReminderDisplay ReminderAction = "display"
+	// ReminderEmail sends an email.
+	ReminderEmail ReminderAction = "email"
+	// ReminderWebhook calls a webhook.
+	ReminderWebhook ReminderAction = "webhook"
+	// ReminderAudio plays a sound.
+	ReminderAudio ReminderAction = "audio"
+)
+
+// Participant is a person involved with a plan item.
+type Participant struct {
+	ID    string          `json:"id" tron:"id"`
+	Name  string          `json:"name,omitempty" tron:"name,omitempty"`
+	Email string          `json:"email,omitempty" tron:"email,omitempty"`
+	Role  ParticipantRole `json:"role" tron:"role"`
+}
+
+// ParticipantRole is a Participant's relationship to an item.
+type ParticipantRole string
+
+const (
+	// RoleOwner is accountable for the item.
+	RoleOwner ParticipantRole = "owner"
+	// RoleAssignee does the work.
+	RoleAssignee ParticipantRole = "assignee"
+	// RoleReviewer reviews the work.
+	RoleReviewer ParticipantRole = "reviewer"
+	// RoleObserver follows the item.
+	RoleObserver ParticipantRole = "observer"
+	// RoleContributor helps with the work.
+	RoleContributor ParticipantRole = "contributor"
+)
+
+// IsValid returns true if the ParticipantRole is a valid value.
+func (r ParticipantRole) IsValid() bool {
+	switch r {
+	case RoleOwner, RoleAssignee, RoleReviewer, RoleObserver, RoleContributor:
+		return true
+	default:
+		return false
+	}
+}
+
+// Progress returns the item's completion percentage in the range 0-100.
+//
+// An explicit PercentComplete takes precedence. Otherwise items with
+// sub-items report the mean progress of their non-cancelled children, and
+// leaf items report 100 when completed and 0 otherwise.
+func (i PlanItem) Progress() float64 {
+	if i.PercentComplete != nil {
+		return math.Max(0, math.Min(100, *i.PercentComplete))
+	}
+	var sum float64
+	var n int
+	for _, sub := range i.SubItems {
+		if sub.Status == PlanItemStatusCancelled {
+			continue
+		}
+		sum += sub.Progress()
+		n++
+	}
+	if n > 0 {
+		return sum / float64(n)
+	}
+	if i.Status == PlanItemStatusCompleted {
+		return 100
+	}
+	return 0
 }
 
 // PlanItemStatus represents the status of a plan item.
@@ -120,7 +353,9 @@ type PlanItemStatus string
 const (
 	// PlanItemStatusPending indicates the plan item has not been started.
 	PlanItemStatusPending PlanItemStatus = "pending"
-	// PlanItemStatusInProgress indicates the plan item is currently active.
+	// PlanItemStatusRunning indicates the plan item is currently active.
+	PlanItemStatusRunning PlanItemStatus = "running"
+	// PlanItemStatusInProgress is the pre-0.5 name for PlanItemStatusRunning.
 	PlanItemStatusInProgress PlanItemStatus = "inProgress"
 	// PlanItemStatusCompleted indicates the plan item has been finished.
 	PlanItemStatusCompleted PlanItemStatus = "completed"
@@ -133,7 +368,7 @@ const (
 // IsValid returns true if the PlanItemStatus is a valid value.
 func (s PlanItemStatus) IsValid() bool {
 	switch s {
-	case PlanItemStatusPending, PlanItemStatusInProgress, PlanItemStatusCompleted,
+	case PlanItemStatusPending, PlanItemStatusRunning, PlanItemStatusInProgress, PlanItemStatusCompleted,
 		PlanItemStatusBlocked, PlanItemStatusCancelled:
 		return true
 	default:
@@ -141,7 +376,6 @@ func (s PlanItemStatus) IsValid() bool {
 	}
 }
 
-
 // TodoList mutation methods
 
 // AddItem adds an item to the TodoList.
@@ -13,6 +13,7 @@ func TestItemStatus_IsValid(t *testing.T) {
 		want   bool
 	}{
 		{"pending is valid", StatusPending, true},
+		{"running is valid", StatusRunning, true},
 		{"inProgress is valid", StatusInProgress, true},
 		{"completed is valid", StatusCompleted, true},
 		{"blocked is valid", StatusBlocked, true},
@@ -38,6 +39,7 @@ func TestPlanStatus_IsValid(t *testing.T) {
 		{"draft is valid", PlanStatusDraft, true},
 		{"proposed is valid", PlanStatusProposed, true},
 		{"approved is valid", PlanStatusApproved, true},
+		{"running is valid", PlanStatusRunning, true},
 		{"inProgress is valid", PlanStatusInProgress, true},
 		{"completed is valid", PlanStatusCompleted, true},
 		{"cancelled is valid", PlanStatusCancelled, true},
@@ -60,6 +62,7 @@ func TestPlanItemStatus_IsValid(t *testing.T) {
 		want   bool
 	}{
 		{"pending is valid", PlanItemStatusPending, true},
+		{"running is valid", PlanItemStatusRunning, true},
 		{"inProgress is valid", PlanItemStatusInProgress, true},
 		{"completed is valid", PlanItemStatusCompleted, true},
 		{"blocked is valid", PlanItemStatusBlocked, true},
@@ -76,6 +79,64 @@ func TestPlanItemStatus_IsValid(t *testing.T) {
 	}
 }
 
+func TestFrequency_IsValid(t *testing.T) {
+	tests := []struct {
+		name string
+		freq Frequency
+		want bool
+	}{
+		{"daily is valid", FrequencyDaily, true},
+		{"yearly is valid", FrequencyYearly, true},
+		{"empty string is invalid", Frequency(""), false},
+		{"hourly is invalid", Frequency("hourly"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.freq.IsValid())
+		})
+	}
+}
+
+func TestParticipantRole_IsValid(t *testing.T) {
+	tests := []struct {
+		name string
+		role ParticipantRole
+		want bool
+	}{
+		{"owner is valid", RoleOwner, true},
+		{"contributor is valid", RoleContributor, true},
+		{"empty string is invalid", ParticipantRole(""), false},
+		{"random string is invalid", ParticipantRole("boss"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.role.IsValid())
+		})
+	}
+}
+
+func TestEdgeType_IsCore(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  EdgeType
+		want bool
+	}{
+		{"blocks is core", EdgeBlocks, true},
+		{"informs is core", EdgeInforms, true},
+		{"invalidates is core", EdgeInvalidates, true},
+		{"suggests is core", EdgeSuggests, true},
+		{"custom type is not core", EdgeType("duplicates"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.typ.IsCore())
+		})
+	}
+}
+
 func TestDocument_Structure(t *testing.T) {
 	t.Run("document with TodoList", func(t *testing.T) {
 		doc := Document{
@@ -109,3 +170,33 @@ func TestDocument_Structure(t *testing.T) {
 		assert.Equal(t, "Test Plan", doc.Plan.Title)
 	})
 }
+
+func TestPlanItem_Progress(t *testing.T) {
+	pct := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name     string
+		item     PlanItem
+		expected float64
+	}{
+		{"pending leaf", PlanItem{Status: PlanItemStatusPending}, 0},
+		{"completed leaf", PlanItem{Status: PlanItemStatusCompleted}, 100},
+		{"explicit percent wins", PlanItem{Status: PlanItemStatusCompleted, PercentComplete: pct(40)}, 40},
+		{"explicit percent is clamped", PlanItem{PercentComplete: pct(150)}, 100},
+		{"mean of children", PlanItem{SubItems: []PlanItem{
+			{Status: PlanItemStatusCompleted},
+			{Status: PlanItemStatusPending},
+			{Status: PlanItemStatusInProgress, PercentComplete: pct(50)},
+			{Status: PlanItemStatusCancelled},
+		}}, 50},
+		{"all children cancelled", PlanItem{Status: PlanItemStatusCompleted, SubItems: []PlanItem{
+			{Status: PlanItemStatusCancelled},
+		}}, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, tt.item.Progress(), 0.001)
+		})
+	}
+}
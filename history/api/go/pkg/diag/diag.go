@@ -0,0 +1,205 @@
+// Package diag describes problems found in vBRIEF documents, where they
+// are in the source, and how to render them for people and tools.
+//
+// A Diagnostic carries a stable rule code such as "VB0014", a severity,
+// the document path it concerns (e.g. "plan.items[3].status"), an
+// optional source span and an optional suggested fix. Text renders
+// diagnostics compiler-style and SARIF renders them for code scanning and
+// CI annotations.
+package diag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Severity is how serious a diagnostic is.
+type Severity string
+
+const (
+	// SeverityError marks a violation of a MUST rule.
+	SeverityError Severity = "error"
+	// SeverityWarning marks a violation of a SHOULD rule.
+	SeverityWarning Severity = "warning"
+	// SeverityInfo marks an observation that needs no action.
+	SeverityInfo Severity = "info"
+)
+
+// Position is a location in a source file.
+type Position struct {
+	// Offset is the 0-based byte offset.
+	Offset int `json:"offset"`
+	// Line is the 1-based line number.
+	Line int `json:"line"`
+	// Column is the 1-based column, counted in Unicode code points.
+	Column int `json:"column"`
+}
+
+// String returns "line:column".
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span is the source range of a value, from Start up to but not including
+// End.
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Fix is a suggested change that resolves a diagnostic.
+type Fix struct {
+	// Description tells the reader what to change.
+	Description string `json:"description"`
+	// Replacement, if non-empty, is the source text that replaces the
+	// diagnostic's span.
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// Rule describes a check that produces diagnostics.
+type Rule struct {
+	// Code is the stable identifier, e.g. "VB0014".
+	Code string `json:"code"`
+	// Name is a short kebab-case name, e.g. "invalid-status".
+	Name string `json:"name"`
+	// Description explains what the rule checks.
+	Description string `json:"description"`
+	// Severity is the severity the rule reports at by default.
+	Severity Severity `json:"severity"`
+}
+
+// Diagnostic is a single problem found in a document.
+type Diagnostic struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	// Path is the document path, e.g. "plan.items[3].title", or
+	// "document" for the document as a whole.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	// Span is where the problem is in the source, if known.
+	Span *Span `json:"span,omitempty"`
+	Fix  *Fix  `json:"fix,omitempty"`
+}
+
+// String returns the diagnostic on one line, without a file name.
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.Span != nil {
+		b.WriteString(d.Span.Start.String() + ": ")
+	}
+	fmt.Fprintf(&b, "%s[%s]: %s", d.Severity, d.Code, d.Message)
+	if d.Path != "" {
+		fmt.Fprintf(&b, " (%s)", d.Path)
+	}
+	return b.String()
+}
+
+// Sort orders diagnostics by source position, then by path and code.
+// Diagnostics without a span sort last.
+func Sort(diags []Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i], diags[j]
+		switch {
+		case a.Span != nil && b.Span != nil && a.Span.Start.Offset != b.Span.Start.Offset:
+			return a.Span.Start.Offset < b.Span.Start.Offset
+		case (a.Span == nil) != (b.Span == nil):
+			return a.Span != nil
+		case a.Path != b.Path:
+			return a.Path < b.Path
+		}
+		return a.Code < b.Code
+	})
+}
+
+// HasErrors reports whether any diagnostic is an error.
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// RootPath is the path of the document as a whole.
+const RootPath = "document"
+
+// SourceMap maps document paths to the spans of their values. The whole
+// document is recorded under RootPath.
+type SourceMap map[string]Span
+
+// Lookup returns the span recorded for path or, when the value is absent
+// from the source, for its nearest ancestor.
+func (m SourceMap) Lookup(path string) (Span, bool) {
+	for {
+		if s, ok := m[path]; ok {
+			return s, true
+		}
+		if path == RootPath || path == "" {
+			return Span{}, false
+		}
+		path = parentPath(path)
+	}
+}
+
+// parentPath strips the last ".key" or "[i]" from a path.
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "]") {
+		if i := strings.LastIndexByte(path, '['); i > 0 {
+			return path[:i]
+		}
+	}
+	if i := strings.LastIndexByte(path, '.'); i > 0 {
+		return path[:i]
+	}
+	return RootPath
+}
+
+// Lines converts byte offsets in a source to positions.
+type Lines struct {
+	src    []byte
+	starts []int
+}
+
+// NewLines indexes the line starts of src.
+func NewLines(src []byte) *Lines {
+	l := &Lines{src: src, starts: []int{0}}
+	for i, b := range src {
+		if b == '\n' {
+			l.starts = append(l.starts, i+1)
+		}
+	}
+	return l
+}
+
+// Position returns the position of a byte offset, clamped to the source.
+func (l *Lines) Position(offset int) Position {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(l.src) {
+		offset = len(l.src)
+	}
+	line := sort.Search(len(l.starts), func(i int) bool { return l.starts[i] > offset }) - 1
+	col := utf8.RuneCount(l.src[l.starts[line]:offset]) + 1
+	return Position{Offset: offset, Line: line + 1, Column: col}
+}
+
+// Span returns the span between two byte offsets.
+func (l *Lines) Span(start, end int) Span {
+	return Span{Start: l.Position(start), End: l.Position(end)}
+}
+
+// Line returns the text of a 1-based line without its line ending.
+func (l *Lines) Line(n int) string {
+	if n < 1 || n > len(l.starts) {
+		return ""
+	}
+	end := len(l.src)
+	if n < len(l.starts) {
+		end = l.starts[n] - 1
+	}
+	return strings.TrimSuffix(string(l.src[l.starts[n-1]:end]), "\r")
+}
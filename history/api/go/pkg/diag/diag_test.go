@@ -0,0 +1,196 @@
+package diag
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const source = "{\n  \"plan\": {\n    \"title\": \"Café\",\n    \"status\": \"inProgres\"\n  }\n}\n"
+
+// cafeEnd is the offset just past "é", which is two bytes.
+var cafeEnd = strings.Index(source, "é") + len("é")
+
+func TestLines(t *testing.T) {
+	lines := NewLines([]byte(source))
+
+	tests := []struct {
+		name   string
+		offset int
+		want   Position
+	}{
+		{"start", 0, Position{Offset: 0, Line: 1, Column: 1}},
+		{"second line", 4, Position{Offset: 4, Line: 2, Column: 3}},
+		{"counts runes", cafeEnd, Position{Offset: cafeEnd, Line: 3, Column: 19}},
+		{"clamps negative", -3, Position{Offset: 0, Line: 1, Column: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, lines.Position(tt.offset))
+		})
+	}
+
+	assert.Equal(t, `    "status": "inProgres"`, lines.Line(4))
+	assert.Equal(t, "", lines.Line(99))
+}
+
+func TestSourceMap_Lookup(t *testing.T) {
+	title := Span{Start: Position{Line: 3}}
+	items := Span{Start: Position{Line: 5}}
+	sm := SourceMap{
+		RootPath:     {Start: Position{Line: 1}},
+		"plan.title": title,
+		"plan.items": items,
+	}
+
+	tests := []struct {
+		path string
+		want Span
+	}{
+		{"plan.title", title},
+		{"plan.items[2].status", items},
+		{"plan.narratives.Proposal", sm[RootPath]},
+		{"document", sm[RootPath]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := sm.Lookup(tt.path)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, ok := SourceMap{}.Lookup("plan.title")
+	assert.False(t, ok)
+}
+
+func statusDiagnostic() Diagnostic {
+	lines := NewLines([]byte(source))
+	start := strings.Index(source, `"inProgres"`)
+	span := lines.Span(start, start+len(`"inProgres"`))
+	return Diagnostic{
+		Code:     "VB0014",
+		Severity: SeverityError,
+		Path:     "plan.status",
+		Message:  "invalid status: inProgres",
+		Span:     &span,
+		Fix:      &Fix{Description: `change status to "inProgress"`, Replacement: `"inProgress"`},
+	}
+}
+
+func TestSort(t *testing.T) {
+	d := statusDiagnostic()
+	diags := []Diagnostic{
+		{Code: "VB0013", Path: "plan.title"},
+		d,
+		{Code: "VB0010", Path: "plan.title"},
+	}
+	Sort(diags)
+	assert.Equal(t, "VB0014", diags[0].Code)
+	assert.Equal(t, "VB0010", diags[1].Code)
+	assert.Equal(t, "VB0013", diags[2].Code)
+	assert.True(t, HasErrors(diags[:1]))
+	assert.False(t, HasErrors([]Diagnostic{{Severity: SeverityWarning}}))
+}
+
+func TestText(t *testing.T) {
+	diags := []Diagnostic{
+		statusDiagnostic(),
+		{Code: "VB0101", Severity: SeverityWarning, Path: "plan.narratives", Message: "narrative keys should be TitleCase"},
+	}
+
+	t.Run("with source", func(t *testing.T) {
+		got := Text(diags, TextOptions{File: "plan.json", Source: []byte(source)})
+		assert.Equal(t, `plan.json:4:15: error[VB0014]: invalid status: inProgres (plan.status)
+    4 |     "status": "inProgres"
+      |               ^~~~~~~~~~~
+      = fix: change status to "inProgress"
+plan.json: warning[VB0101]: narrative keys should be TitleCase (plan.narratives)
+1 error, 1 warning
+`, string(got))
+	})
+
+	t.Run("without source", func(t *testing.T) {
+		got := Text(diags[:1], TextOptions{})
+		assert.Equal(t, `4:15: error[VB0014]: invalid status: inProgres (plan.status)
+      = fix: change status to "inProgress"
+1 error
+`, string(got))
+	})
+
+	t.Run("no diagnostics", func(t *testing.T) {
+		assert.Empty(t, Text(nil, TextOptions{}))
+	})
+}
+
+func TestSARIF(t *testing.T) {
+	diags := []Diagnostic{
+		statusDiagnostic(),
+		{Code: "VB0013", Severity: SeverityError, Path: "plan.title", Message: "title is required",
+			Fix: &Fix{Description: "add a title"}},
+	}
+	rules := []Rule{
+		{Code: "VB0013", Name: "title-required", Severity: SeverityError},
+		{Code: "VB0014", Name: "invalid-status", Severity: SeverityError},
+	}
+	out, err := SARIF(diags, SARIFOptions{ToolVersion: "1.0.0", URI: "plan.json", Rules: rules})
+	require.NoError(t, err)
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region *struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+				Fixes      []json.RawMessage `json:"fixes"`
+				Properties map[string]string `json:"properties"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(out, &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, "vbrief", run.Tool.Driver.Name)
+	assert.Len(t, run.Tool.Driver.Rules, 2)
+	require.Len(t, run.Results, 2)
+
+	status := run.Results[0]
+	assert.Equal(t, "VB0014", status.RuleID)
+	assert.Equal(t, 1, status.RuleIndex)
+	assert.Equal(t, "error", status.Level)
+	require.Len(t, status.Locations, 1)
+	assert.Equal(t, "plan.json", status.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	require.NotNil(t, status.Locations[0].PhysicalLocation.Region)
+	assert.Equal(t, 4, status.Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Equal(t, 15, status.Locations[0].PhysicalLocation.Region.StartColumn)
+	assert.Len(t, status.Fixes, 1)
+
+	title := run.Results[1]
+	assert.Nil(t, title.Locations[0].PhysicalLocation.Region)
+	assert.Empty(t, title.Fixes)
+	assert.Equal(t, "add a title", title.Properties["suggestedFix"])
+}
@@ -0,0 +1,209 @@
+package diag
+
+import "encoding/json"
+
+// SARIFSchema is the JSON schema URI written into SARIF logs.
+const SARIFSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// SARIFOptions configures SARIF.
+type SARIFOptions struct {
+	// ToolName names the analysis tool. Defaults to "vbrief".
+	ToolName string
+	// ToolVersion is the tool's version, if known.
+	ToolVersion string
+	// URI is the analysed file, relative to the repository root.
+	URI string
+	// Rules describe the codes that may appear. Rules with results are
+	// linked by index; others are listed for completeness.
+	Rules []Rule
+}
+
+// SARIF renders diagnostics as a SARIF 2.1.0 log with a single run, for
+// code scanning and CI annotations. Columns are Unicode code points.
+// Fixes with a replacement and a span become SARIF fixes; other fixes are
+// kept in the result's properties.
+func SARIF(diags []Diagnostic, opts SARIFOptions) ([]byte, error) {
+	if opts.ToolName == "" {
+		opts.ToolName = "vbrief"
+	}
+
+	rules := make([]sarifRule, 0, len(opts.Rules))
+	index := make(map[string]int, len(opts.Rules))
+	for _, r := range opts.Rules {
+		index[r.Code] = len(rules)
+		rules = append(rules, sarifRule{
+			ID:                   r.Code,
+			Name:                 r.Name,
+			ShortDescription:     &sarifMessage{Text: r.Description},
+			DefaultConfiguration: &sarifConfig{Level: level(r.Severity)},
+		})
+	}
+
+	results := make([]sarifResult, 0, len(diags))
+	for _, d := range diags {
+		res := sarifResult{
+			RuleID:  d.Code,
+			Level:   level(d.Severity),
+			Message: sarifMessage{Text: d.Message},
+		}
+		if i, ok := index[d.Code]; ok {
+			res.RuleIndex = &i
+		}
+		loc := sarifLocation{}
+		if d.Path != "" {
+			loc.LogicalLocations = []sarifLogical{{FullyQualifiedName: d.Path}}
+		}
+		if opts.URI != "" || d.Span != nil {
+			loc.PhysicalLocation = &sarifPhysical{ArtifactLocation: sarifArtifact{URI: opts.URI}}
+			if d.Span != nil {
+				loc.PhysicalLocation.Region = region(*d.Span)
+			}
+		}
+		if loc.PhysicalLocation != nil || loc.LogicalLocations != nil {
+			res.Locations = []sarifLocation{loc}
+		}
+		if d.Fix != nil {
+			if d.Fix.Replacement != "" && d.Span != nil {
+				res.Fixes = []sarifFix{{
+					Description: sarifMessage{Text: d.Fix.Description},
+					ArtifactChanges: []sarifChange{{
+						ArtifactLocation: sarifArtifact{URI: opts.URI},
+						Replacements: []sarifReplacement{{
+							DeletedRegion:   region(*d.Span),
+							InsertedContent: &sarifContent{Text: d.Fix.Replacement},
+						}},
+					}},
+				}}
+			} else {
+				res.Properties = map[string]string{"suggestedFix": d.Fix.Description}
+			}
+		}
+		results = append(results, res)
+	}
+
+	log := sarifLog{
+		Schema:  SARIFSchema,
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:       sarifTool{Driver: sarifDriver{Name: opts.ToolName, Version: opts.ToolVersion, Rules: rules}},
+			ColumnKind: "unicodeCodePoints",
+			Results:    results,
+		}},
+	}
+	return json.MarshalIndent(log, "", "  ")
+}
+
+func level(s Severity) string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func region(s Span) *sarifRegion {
+	return &sarifRegion{
+		StartLine:   s.Start.Line,
+		StartColumn: s.Start.Column,
+		EndLine:     s.End.Line,
+		EndColumn:   s.End.Column,
+		ByteOffset:  s.Start.Offset,
+		ByteLength:  s.End.Offset - s.Start.Offset,
+	}
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version,omitempty"`
+	Rules   []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID                   string        `json:"id"`
+	Name                 string        `json:"name,omitempty"`
+	ShortDescription     *sarifMessage `json:"shortDescription,omitempty"`
+	DefaultConfiguration *sarifConfig  `json:"defaultConfiguration,omitempty"`
+}
+
+type sarifConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	RuleIndex  *int              `json:"ruleIndex,omitempty"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations,omitempty"`
+	Fixes      []sarifFix        `json:"fixes,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysical `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogical `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysical struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           *sarifRegion  `json:"region,omitempty"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri,omitempty"`
+}
+
+type sarifLogical struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+	ByteOffset  int `json:"byteOffset"`
+	ByteLength  int `json:"byteLength"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage  `json:"description"`
+	ArtifactChanges []sarifChange `json:"artifactChanges"`
+}
+
+type sarifChange struct {
+	ArtifactLocation sarifArtifact      `json:"artifactLocation"`
+	Replacements     []sarifReplacement `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   *sarifRegion  `json:"deletedRegion"`
+	InsertedContent *sarifContent `json:"insertedContent,omitempty"`
+}
+
+type sarifContent struct {
+	Text string `json:"text"`
+}
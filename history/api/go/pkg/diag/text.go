@@ -0,0 +1,106 @@
+package diag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TextOptions configures Text.
+type TextOptions struct {
+	// File names the source at the start of each location.
+	File string
+	// Source, when set, adds the offending line with a marker under the
+	// span.
+	Source []byte
+}
+
+// Text renders diagnostics compiler-style, one per line, followed by a
+// count:
+//
+//	plan.json:12:17: error[VB0014]: invalid status: inProgres (plan.items[3].status)
+//	   12 |       "status": "inProgres"
+//	      |                 ^~~~~~~~~~~
+//	      = fix: change status to "inProgress"
+//	1 error
+func Text(diags []Diagnostic, opts TextOptions) []byte {
+	var lines *Lines
+	if opts.Source != nil {
+		lines = NewLines(opts.Source)
+	}
+	var b strings.Builder
+	counts := make(map[Severity]int)
+	for _, d := range diags {
+		counts[d.Severity]++
+		loc := opts.File
+		if d.Span != nil {
+			if loc != "" {
+				loc += ":"
+			}
+			loc += d.Span.Start.String()
+		}
+		if loc != "" {
+			b.WriteString(loc + ": ")
+		}
+		fmt.Fprintf(&b, "%s[%s]: %s", d.Severity, d.Code, d.Message)
+		if d.Path != "" {
+			fmt.Fprintf(&b, " (%s)", d.Path)
+		}
+		b.WriteString("\n")
+
+		if lines != nil && d.Span != nil {
+			excerpt(&b, lines, *d.Span)
+		}
+		if d.Fix != nil {
+			fmt.Fprintf(&b, "      = fix: %s\n", d.Fix.Description)
+		}
+	}
+	if len(diags) > 0 {
+		var parts []string
+		for _, c := range []struct {
+			severity       Severity
+			singular, many string
+		}{
+			{SeverityError, "error", "errors"},
+			{SeverityWarning, "warning", "warnings"},
+			{SeverityInfo, "info", "info"},
+		} {
+			switch n := counts[c.severity]; n {
+			case 0:
+			case 1:
+				parts = append(parts, "1 "+c.singular)
+			default:
+				parts = append(parts, fmt.Sprintf("%d %s", n, c.many))
+			}
+		}
+		b.WriteString(strings.Join(parts, ", ") + "\n")
+	}
+	return []byte(b.String())
+}
+
+// excerpt writes the span's first line with a marker under the span.
+func excerpt(b *strings.Builder, lines *Lines, span Span) {
+	text := lines.Line(span.Start.Line)
+	runes := []rune(text)
+	start := span.Start.Column - 1
+	if start > len(runes) {
+		start = len(runes)
+	}
+	width := 1
+	if span.End.Line == span.Start.Line && span.End.Column > span.Start.Column {
+		width = span.End.Column - span.Start.Column
+	} else if len(runes) > start {
+		width = len(runes) - start
+	}
+
+	// Keep tabs so that the marker lines up with the source.
+	var pad strings.Builder
+	for _, r := range runes[:start] {
+		if r == '\t' {
+			pad.WriteRune('\t')
+		} else {
+			pad.WriteRune(' ')
+		}
+	}
+	fmt.Fprintf(b, "%5d | %s\n", span.Start.Line, text)
+	fmt.Fprintf(b, "      | %s^%s\n", pad.String(), strings.Repeat("~", width-1))
+}
@@ -0,0 +1,94 @@
+package exec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/parser"
+)
+
+// ErrCheckpoint is returned when execution state cannot be recorded or
+// saved. The run stops, as it could not be resumed from where it is.
+var ErrCheckpoint = errors.New("checkpoint failed")
+
+// Checkpointer saves the document during a run, after every status
+// change, so that a crashed run can be resumed.
+type Checkpointer interface {
+	Checkpoint(doc *core.Document) error
+}
+
+// CheckpointFunc adapts a function to the Checkpointer interface.
+type CheckpointFunc func(doc *core.Document) error
+
+// Checkpoint calls f.
+func (f CheckpointFunc) Checkpoint(doc *core.Document) error {
+	return f(doc)
+}
+
+// FileCheckpointer saves the document as indented JSON to a file. Each
+// save writes a temporary file in the same directory and renames it over
+// the original, so the file always holds a complete document.
+type FileCheckpointer struct {
+	path string
+}
+
+// NewFileCheckpointer creates a checkpointer writing to path.
+func NewFileCheckpointer(path string) *FileCheckpointer {
+	return &FileCheckpointer{path: path}
+}
+
+// Path returns the file the checkpointer writes.
+func (c *FileCheckpointer) Path() string {
+	return c.path
+}
+
+// Checkpoint writes doc to the file atomically. An existing file keeps its
+// permissions.
+func (c *FileCheckpointer) Checkpoint(doc *core.Document) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCheckpoint, err)
+	}
+	data = append(data, '\n')
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(c.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), "."+filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCheckpoint, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%w: %w", ErrCheckpoint, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%w: %w", ErrCheckpoint, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCheckpoint, err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("%w: %w", ErrCheckpoint, err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("%w: %w", ErrCheckpoint, err)
+	}
+	return nil
+}
+
+// Load reads the saved document, for Resume.
+func (c *FileCheckpointer) Load() (*core.Document, error) {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, err
+	}
+	return parser.NewAutoParser().ParseBytes(data)
+}
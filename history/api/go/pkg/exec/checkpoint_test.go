@@ -0,0 +1,153 @@
+package exec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+var epoch = time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func clock() func() time.Time {
+	now := epoch
+	return func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+}
+
+func echo() *Registry {
+	reg := NewRegistry()
+	reg.Register("echo", RunnerFunc(func(_ context.Context, step Step) (map[string]interface{}, error) {
+		return map[string]interface{}{"id": step.ID, "attempt": step.Attempt}, nil
+	}))
+	return reg
+}
+
+func stateOf(t *testing.T, doc *core.Document, id string) State {
+	t.Helper()
+	s, ok := StateOf(graph.New(doc.Plan).Node(id).Item)
+	require.True(t, ok, id)
+	return s
+}
+
+func TestRun_RecordsState(t *testing.T) {
+	doc := plan([]core.Edge{edge("a", "b", core.EdgeBlocks)}, item("a"), item("b"))
+	e := NewExecutorWithOptions(echo(), Options{Workers: 1, DefaultRunner: "echo", Now: clock()})
+	_, err := e.Run(context.Background(), doc)
+	require.NoError(t, err)
+
+	start, end := epoch.Add(3*time.Minute), epoch.Add(4*time.Minute)
+	assert.Equal(t, State{
+		Attempts:   1,
+		StartedAt:  &start,
+		FinishedAt: &end,
+		Output:     map[string]interface{}{"id": "b", "attempt": 1.0},
+	}, stateOf(t, doc, "b"))
+	assert.IsType(t, map[string]interface{}{}, doc.Plan.Items[0].Metadata[StateKey], "state is stored as plain JSON values")
+}
+
+// crashAt returns a checkpointer keeping a copy of the document as it was
+// saved when id started, as if the process had died then.
+func crashAt(id string, saved **core.Document) Checkpointer {
+	return CheckpointFunc(func(doc *core.Document) error {
+		if *saved == nil && graph.New(doc.Plan).Node(id).Item.Status == core.PlanItemStatusRunning {
+			*saved = doc.Clone()
+		}
+		return nil
+	})
+}
+
+func TestResume(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      InterruptedPolicy
+		completed   []string
+		failed      []string
+		buildStatus core.PlanItemStatus
+		attempts    int
+	}{
+		{"retry", InterruptedRetry, []string{"build", "package.linux", "package.mac", "deploy"}, nil, core.PlanItemStatusCompleted, 2},
+		{"fail", InterruptedFail, nil, []string{"build"}, core.PlanItemStatusBlocked, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved *core.Document
+			first := NewExecutorWithOptions(echo(), Options{Workers: 1, DefaultRunner: "echo", Checkpoint: crashAt("build", &saved)})
+			_, err := first.Run(context.Background(), pipeline())
+			require.NoError(t, err)
+			require.NotNil(t, saved)
+
+			e := NewExecutorWithOptions(echo(), Options{Workers: 1, DefaultRunner: "echo", Interrupted: tt.policy})
+			report, err := e.Resume(context.Background(), saved)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"build"}, report.Interrupted)
+			assert.Equal(t, tt.completed, report.Completed)
+			assert.Equal(t, tt.failed, report.Failed)
+			assert.Equal(t, tt.buildStatus, status(t, saved, "build"))
+
+			s := stateOf(t, saved, "build")
+			assert.Equal(t, tt.attempts, s.Attempts)
+			assert.Equal(t, map[string]interface{}{"id": "lint", "attempt": 1.0}, stateOf(t, saved, "lint").Output,
+				"finished items are not run again")
+			if tt.policy == InterruptedFail {
+				assert.ErrorIs(t, report.Errors["build"], ErrInterrupted)
+				assert.Equal(t, "interrupted", s.Error)
+				assert.Equal(t, []string{"package.linux", "package.mac", "deploy"}, report.Pending)
+			}
+		})
+	}
+}
+
+func TestFileCheckpointer(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pipeline.vbrief.json")
+	require.NoError(t, os.WriteFile(path, []byte("{}"), 0o600))
+	cp := NewFileCheckpointer(path)
+	assert.Equal(t, path, cp.Path())
+
+	doc := pipeline()
+	e := NewExecutorWithOptions(echo(), Options{DefaultRunner: "echo", Checkpoint: cp})
+	_, err := e.Run(context.Background(), doc)
+	require.NoError(t, err)
+
+	loaded, err := cp.Load()
+	require.NoError(t, err)
+	assert.Equal(t, core.PlanStatusCompleted, loaded.Plan.Status)
+	assert.Equal(t, core.PlanItemStatusCompleted, status(t, loaded, "deploy"))
+	assert.Equal(t, 1, stateOf(t, loaded, "deploy").Attempts)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+
+	err = NewFileCheckpointer(filepath.Join(dir, "missing", "x.json")).Checkpoint(doc)
+	assert.ErrorIs(t, err, ErrCheckpoint)
+}
+
+func TestRun_CheckpointFailure(t *testing.T) {
+	doc := pipeline()
+	calls := 0
+	cp := CheckpointFunc(func(*core.Document) error {
+		calls++
+		if calls == 3 {
+			return ErrCheckpoint
+		}
+		return nil
+	})
+	report, err := NewExecutorWithOptions(echo(), Options{Workers: 1, DefaultRunner: "echo", Checkpoint: cp}).Run(context.Background(), doc)
+	assert.ErrorIs(t, err, ErrCheckpoint)
+	// The third save is test starting; it is left running, for Resume.
+	assert.Equal(t, []string{"lint"}, report.Completed)
+	assert.Equal(t, core.PlanItemStatusRunning, status(t, doc, "test"))
+}
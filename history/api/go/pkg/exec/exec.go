@@ -0,0 +1,843 @@
+// Package exec runs a plan. Each ready item is handed to the Runner named
+// by its metadata[RunnerKey], up to a worker limit at a time, and the
+// item statuses are updated through an updater.Updater as work starts,
+// succeeds or fails:
+//
+//   - pending leaf items whose blockers are all settled are started and
+//     become running; their pending ancestors become running too;
+//   - an item whose runner succeeds becomes completed, and the targets of
+//     its invalidates edges, if still pending, become cancelled with their
+//     sub-items, so their work is skipped;
+//   - an item whose runner fails is retried if its Policy allows, and
+//     otherwise handled by the policy's FailureAction; by default it
+//     becomes blocked, and the items it blocks are never started;
+//   - a container becomes completed once all its sub-items are completed
+//     or cancelled, or cancelled if they all are.
+//
+// A blocker is settled when it is completed or cancelled, or when it has
+// sub-items and they all are; a skipped item does not hold back the items
+// it blocks, but an item replaced by a fallback is settled only once the
+// fallback is. Items that cannot be addressed, because they or an
+// ancestor have no ID, are never run.
+//
+// Each item's attempts, timestamps, output and error are recorded in its
+// metadata[StateKey] in the same transaction as its status, and an
+// Options.Checkpoint saves the document after every change. Resume
+// continues from such a document after a crash. Outcomes are also
+// written to the item's narrative: a line per attempt under Observation,
+// and the final outcome under Result, so a finished run reads as a
+// retrospective.
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+	"github.com/visionik/vBRIEF/api/go/pkg/updater"
+	"github.com/visionik/vBRIEF/api/go/pkg/validator"
+)
+
+var (
+	// ErrNoRunner is an item's error when no runner is registered under
+	// the name its metadata gives, or it names none.
+	ErrNoRunner = errors.New("no runner")
+)
+
+// Narrative keys written by a run.
+const (
+	NarrativeObservation = "Observation"
+	NarrativeResult      = "Result"
+)
+
+// Options configures an Executor.
+type Options struct {
+	// Workers is the number of items run at once. Zero or less means
+	// runtime.GOMAXPROCS(0).
+	Workers int
+	// DefaultRunner names the runner for items without metadata[RunnerKey].
+	// If empty, such items fail with ErrNoRunner.
+	DefaultRunner string
+	// Policy applies to items without metadata[PolicyKey], and supplies
+	// the fields an item's policy leaves out.
+	Policy Policy
+	// Validator checks the document before the run and after every status
+	// change. Nil means validator.NewValidator().
+	Validator validator.Validator
+	// OnEvent, if set, is called after every status change. Calls come
+	// from one goroutine, while no status is changing, so the document
+	// may be read.
+	OnEvent func(Event)
+	// Checkpoint, if set, saves the document after every status change.
+	// If it fails, the run stops with its error.
+	Checkpoint Checkpointer
+	// Interrupted says what Resume does with items left running. The
+	// default is InterruptedRetry.
+	Interrupted InterruptedPolicy
+	// Now returns the time recorded in item states. Nil means time.Now.
+	Now func() time.Time
+}
+
+// InterruptedPolicy says what Resume does with items that a previous run
+// left running, because it crashed while they ran.
+type InterruptedPolicy string
+
+const (
+	// InterruptedRetry returns interrupted items to pending, so they run
+	// again.
+	InterruptedRetry InterruptedPolicy = "retry"
+	// InterruptedFail fails interrupted items for good, applying their
+	// policy's FailureAction, for work that is not safe to repeat.
+	InterruptedFail InterruptedPolicy = "fail"
+)
+
+// ErrInterrupted is the error recorded for items a previous run left
+// running.
+var ErrInterrupted = errors.New("interrupted")
+
+// Event reports an item's status change.
+type Event struct {
+	// ID is the item's hierarchical ID.
+	ID     string
+	Status core.PlanItemStatus
+	// Output is the runner's output, for items that finished running.
+	Output map[string]interface{}
+	// Err is the runner's error, for items that failed.
+	Err error
+}
+
+// Report summarises a run. Item lists are in the order things happened.
+type Report struct {
+	// Completed lists items whose runners succeeded.
+	Completed []string
+	// Failed lists items that failed their last attempt, including those
+	// whose failure a fallback or suggested items made up for; Errors
+	// holds why.
+	Failed []string
+	// Retried lists items once for every failed attempt that was retried.
+	Retried []string
+	// Skipped lists items cancelled because their work was not needed: by
+	// invalidates edges, or as fallbacks or suggested items of an item
+	// that did not fail.
+	Skipped []string
+	// Cancelled lists items cancelled by an OnFailureCancelSubtree policy.
+	Cancelled []string
+	// Interrupted lists items whose runners were stopped by cancellation,
+	// and, for Resume, items a previous run left running. Under
+	// InterruptedRetry they are pending again.
+	Interrupted []string
+	// Pending lists the leaf items still pending when the run ended, in
+	// document order, such as those blocked by failed items.
+	Pending []string
+	// Outputs holds each finished item's runner output.
+	Outputs map[string]map[string]interface{}
+	// Errors holds each failed item's error.
+	Errors map[string]error
+}
+
+// Err returns the failed items' errors joined, or nil.
+func (r *Report) Err() error {
+	errs := make([]error, 0, len(r.Failed))
+	for _, id := range r.Failed {
+		errs = append(errs, r.Errors[id])
+	}
+	return errors.Join(errs...)
+}
+
+// Executor runs plans.
+type Executor struct {
+	registry *Registry
+	opts     Options
+}
+
+// NewExecutor creates an executor using the runners in registry.
+func NewExecutor(registry *Registry) *Executor {
+	return NewExecutorWithOptions(registry, Options{})
+}
+
+// NewExecutorWithOptions creates an executor with custom options.
+func NewExecutorWithOptions(registry *Registry, opts Options) *Executor {
+	if registry == nil {
+		registry = NewRegistry()
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+	if opts.Validator == nil {
+		opts.Validator = validator.NewValidator()
+	}
+	if opts.Interrupted == "" {
+		opts.Interrupted = InterruptedRetry
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Executor{registry: registry, opts: opts}
+}
+
+// Run runs doc's plan until no item is ready, none is running and none is
+// waiting to be retried, and reports what happened. doc is updated in
+// place, and must not be changed by others during the run.
+//
+// Failed items do not make Run return an error; see Report.Err. Run
+// returns an error if the document is invalid before or after a status
+// change, in which case running items are cancelled first, or ctx's
+// error if it was cancelled. In both cases the partial report is
+// returned too. It returns ErrInvalidPolicy, before running anything, if
+// an item's policy is invalid.
+//
+// Items already running are left alone; use Resume to continue a run that
+// did not finish.
+func (e *Executor) Run(ctx context.Context, doc *core.Document) (*Report, error) {
+	r, err := e.newRun(doc)
+	if err != nil {
+		return nil, err
+	}
+	return r.report, r.loop(ctx)
+}
+
+// Resume continues a run from a document saved by an earlier one, such
+// as a checkpoint. Leaf items still running were interrupted; they are
+// handled according to Options.Interrupted and listed in
+// Report.Interrupted, and containers left running stay so until their
+// sub-items settle. Then the run continues as Run does: the ready set is
+// rebuilt from the statuses, and attempt counts and retry times carry on
+// from the recorded states.
+func (e *Executor) Resume(ctx context.Context, doc *core.Document) (*Report, error) {
+	r, err := e.newRun(doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.recover(); err != nil {
+		return r.report, err
+	}
+	return r.report, r.loop(ctx)
+}
+
+func (e *Executor) newRun(doc *core.Document) (*run, error) {
+	if doc == nil || doc.Plan == nil {
+		return nil, core.ErrNoPlan
+	}
+	if err := e.opts.Validator.Validate(doc); err != nil {
+		return nil, err
+	}
+	r := &run{
+		e:           e,
+		doc:         doc,
+		g:           graph.New(doc.Plan),
+		u:           updater.NewUpdater(doc).WithValidator(e.opts.Validator),
+		policies:    make(map[*graph.Node]Policy),
+		contingents: make(map[*graph.Node][]*graph.Node),
+		heldBy:      make(map[*graph.Node][]*graph.Node),
+		standsIn:    make(map[*graph.Node][]*graph.Node),
+		report: &Report{
+			Outputs: make(map[string]map[string]interface{}),
+			Errors:  make(map[string]error),
+		},
+	}
+	if err := r.loadPolicies(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// run holds the state of one Run. Only the goroutine running loop reads
+// or writes the document.
+type run struct {
+	e        *Executor
+	doc      *core.Document
+	g        *graph.Graph
+	u        *updater.Updater
+	policies map[*graph.Node]Policy
+	// contingents maps an item to the items held back until it fails: its
+	// fallback, or the targets of its suggests edges. heldBy is the
+	// reverse, and standsIn maps a fallback to the items it replaces.
+	contingents, heldBy, standsIn map[*graph.Node][]*graph.Node
+	report                        *Report
+}
+
+// result is a runner's outcome.
+type result struct {
+	node   *graph.Node
+	output map[string]interface{}
+	err    error
+}
+
+// loadPolicies reads every addressable item's policy and links fallbacks
+// and suggested items to the items they are held for.
+func (r *run) loadPolicies() error {
+	for _, n := range r.g.Nodes() {
+		if n.ID == "" {
+			continue
+		}
+		p, err := PolicyOf(n.Item, r.e.opts.Policy)
+		if err != nil {
+			return fmt.Errorf("%s: %w", n.ID, err)
+		}
+		r.policies[n] = p
+		switch p.OnFailure {
+		case OnFailureFallback:
+			f := r.g.Node(p.Fallback)
+			if f == nil || f == n {
+				return fmt.Errorf("%w: %s: fallback %q is not another item", ErrInvalidPolicy, n.ID, p.Fallback)
+			}
+			r.contingents[n] = append(r.contingents[n], f)
+			r.standsIn[f] = append(r.standsIn[f], n)
+		case OnFailureSuggests:
+			for _, e := range r.g.Outgoing(n.ID) {
+				if e.Type == core.EdgeSuggests {
+					r.contingents[n] = append(r.contingents[n], r.g.Node(e.To))
+				}
+			}
+		}
+		for _, c := range r.contingents[n] {
+			r.heldBy[c] = append(r.heldBy[c], n)
+		}
+	}
+	for n := range r.policies {
+		seen := map[*graph.Node]bool{}
+		for cur := n; cur != nil; cur = r.fallback(cur) {
+			if seen[cur] {
+				return fmt.Errorf("%w: %s: fallbacks form a cycle", ErrInvalidPolicy, n.ID)
+			}
+			seen[cur] = true
+		}
+	}
+	return nil
+}
+
+// fallback returns the item that replaces n if it fails, or nil.
+func (r *run) fallback(n *graph.Node) *graph.Node {
+	if p := r.policies[n]; p.OnFailure == OnFailureFallback {
+		return r.g.Node(p.Fallback)
+	}
+	return nil
+}
+
+func (r *run) loop(parent context.Context) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	results := make(chan result)
+	running := 0
+	var runErr error
+	fail := func(err error) {
+		if runErr == nil {
+			runErr = err
+			cancel()
+		}
+	}
+	for {
+		var wake <-chan time.Time
+		var timer *time.Timer
+		if runErr == nil && ctx.Err() == nil {
+			for _, n := range r.ready() {
+				if running >= r.e.opts.Workers {
+					break
+				}
+				if err := r.start(n); err != nil {
+					fail(err)
+					break
+				}
+				step := Step{ID: n.ID, Item: n.Item.Clone(), Attempt: r.state(n).Attempts}
+				running++
+				go func() { results <- r.call(ctx, n, step) }()
+			}
+			if at, ok := r.nextRetry(); ok && runErr == nil {
+				timer = time.NewTimer(at.Sub(r.e.opts.Now()))
+				wake = timer.C
+			}
+		}
+		if running == 0 && wake == nil {
+			break
+		}
+		var done <-chan struct{}
+		if wake != nil {
+			done = ctx.Done()
+		}
+		select {
+		case res := <-results:
+			running--
+			if err := r.finish(ctx, res); err != nil {
+				fail(err)
+			}
+		case <-wake:
+		case <-done:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+
+	for _, n := range r.g.Nodes() {
+		if n.ID != "" && n.IsLeaf() && n.Item.Status == core.PlanItemStatusPending {
+			r.report.Pending = append(r.report.Pending, n.ID)
+		}
+	}
+	if runErr != nil {
+		return runErr
+	}
+	if err := parent.Err(); err != nil {
+		return err
+	}
+	return r.finishPlan()
+}
+
+// ready returns the pending, addressable leaf items whose blockers are
+// all settled, that are not held back and not waiting to be retried, in
+// document order.
+func (r *run) ready() []*graph.Node {
+	var now time.Time
+	var out []*graph.Node
+	for _, n := range r.g.Nodes() {
+		if n.ID == "" || !n.IsLeaf() || n.Item.Status != core.PlanItemStatusPending || r.held(n) {
+			continue
+		}
+		if s := r.state(n); s.RetryAt != nil {
+			if now.IsZero() {
+				now = r.e.opts.Now()
+			}
+			if s.RetryAt.After(now) {
+				continue
+			}
+		}
+		unblocked := true
+		for _, b := range r.g.Blockers(n) {
+			if !r.settled(b) {
+				unblocked = false
+				break
+			}
+		}
+		if unblocked {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// nextRetry returns the earliest future time a pending item may be
+// retried.
+func (r *run) nextRetry() (time.Time, bool) {
+	var now, next time.Time
+	for _, n := range r.g.Nodes() {
+		if n.ID == "" || n.Item.Status != core.PlanItemStatusPending {
+			continue
+		}
+		s := r.state(n)
+		if s.RetryAt == nil {
+			continue
+		}
+		if now.IsZero() {
+			now = r.e.opts.Now()
+		}
+		if s.RetryAt.After(now) && (next.IsZero() || s.RetryAt.Before(next)) {
+			next = *s.RetryAt
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// held reports whether n, or an ancestor, is a fallback or suggested item
+// whose reason to run, an item failing, has not arisen.
+func (r *run) held(n *graph.Node) bool {
+	for m := n; m != nil; m = m.Parent {
+		for _, p := range r.heldBy[m] {
+			if !r.state(p).Failed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// settled reports whether an item needs no more work.
+func (r *run) settled(n *graph.Node) bool {
+	switch n.Item.Status {
+	case core.PlanItemStatusCompleted:
+		return true
+	case core.PlanItemStatusCancelled:
+		if f := r.fallback(n); f != nil && r.state(n).Failed {
+			return r.settled(f)
+		}
+		return true
+	}
+	if n.IsLeaf() {
+		return false
+	}
+	for _, c := range n.Children {
+		if !r.settled(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// call runs one step, turning a missing runner, a timeout or a panic into
+// an error.
+func (r *run) call(ctx context.Context, n *graph.Node, step Step) (res result) {
+	res.node = n
+	defer func() {
+		if p := recover(); p != nil {
+			res.err = fmt.Errorf("%s: runner panicked: %v", n.ID, p)
+		}
+	}()
+	name, _ := step.Item.Metadata[RunnerKey].(string)
+	if name == "" {
+		name = r.e.opts.DefaultRunner
+	}
+	runner, ok := r.e.registry.Lookup(name)
+	if !ok {
+		res.err = fmt.Errorf("%w: %q for %s", ErrNoRunner, name, n.ID)
+		return res
+	}
+	timeout := r.policies[n].Timeout
+	if timeout <= 0 {
+		res.output, res.err = runner.Run(ctx, step)
+		return res
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	res.output, res.err = runner.Run(attemptCtx, step)
+	if res.err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		res.err = fmt.Errorf("%w: %s after %s", ErrTimeout, n.ID, timeout)
+	}
+	return res
+}
+
+// recover applies the interrupted policy to leaf items left running.
+func (r *run) recover() error {
+	var interrupted []*graph.Node
+	for _, n := range r.g.Nodes() {
+		if n.ID != "" && n.IsLeaf() && n.Item.Status == core.PlanItemStatusRunning {
+			interrupted = append(interrupted, n)
+		}
+	}
+	if len(interrupted) == 0 {
+		return nil
+	}
+	now := r.e.opts.Now()
+	return r.update(nil, func(set setFunc) error {
+		for _, n := range interrupted {
+			r.report.Interrupted = append(r.report.Interrupted, n.ID)
+			s := r.state(n)
+			s.FinishedAt = &now
+			s.Error = ErrInterrupted.Error()
+			observe(n, fmt.Sprintf("Attempt %d was interrupted.", s.Attempts))
+			if r.e.opts.Interrupted == InterruptedFail {
+				if err := r.fail(n, s, fmt.Errorf("%w: %s", ErrInterrupted, n.ID), set); err != nil {
+					return err
+				}
+				continue
+			}
+			set(n, core.PlanItemStatusPending)
+			if err := setState(n.Item, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// setFunc changes an item's status within an update.
+type setFunc func(*graph.Node, core.PlanItemStatus)
+
+// state returns an item's recorded execution state.
+func (r *run) state(n *graph.Node) State {
+	s, _ := StateOf(n.Item)
+	return s
+}
+
+// start marks an item and its pending ancestors running.
+func (r *run) start(n *graph.Node) error {
+	now := r.e.opts.Now()
+	return r.update(nil, func(set setFunc) error {
+		if r.doc.Plan.Status != core.PlanStatusRunning {
+			r.doc.Plan.Status = core.PlanStatusRunning
+		}
+		for a := n.Parent; a != nil; a = a.Parent {
+			if a.Item.Status == core.PlanItemStatusPending {
+				set(a, core.PlanItemStatusRunning)
+			}
+		}
+		set(n, core.PlanItemStatusRunning)
+		return setState(n.Item, State{Attempts: r.state(n).Attempts + 1, StartedAt: &now})
+	})
+}
+
+// finish records a runner's outcome.
+func (r *run) finish(ctx context.Context, res result) error {
+	n := res.node
+	now := r.e.opts.Now()
+	s := r.state(n)
+	s.FinishedAt = &now
+	s.Output = res.output
+	if res.err != nil {
+		s.Error = res.err.Error()
+	}
+	took := ""
+	if s.StartedAt != nil {
+		took = " after " + now.Sub(*s.StartedAt).String()
+	}
+	if res.output != nil {
+		r.report.Outputs[n.ID] = res.output
+	}
+
+	switch {
+	case res.err != nil && ctx.Err() != nil:
+		r.report.Interrupted = append(r.report.Interrupted, n.ID)
+		return r.update(&res, func(set setFunc) error {
+			set(n, core.PlanItemStatusPending)
+			observe(n, fmt.Sprintf("Attempt %d was interrupted%s: %v.", s.Attempts, took, res.err))
+			return setState(n.Item, s)
+		})
+	case res.err != nil:
+		p := r.policies[n]
+		if s.Attempts < max(p.MaxAttempts, 1) {
+			delay := p.delay(s.Attempts)
+			retryAt := now.Add(delay)
+			s.RetryAt = &retryAt
+			r.report.Retried = append(r.report.Retried, n.ID)
+			return r.update(&res, func(set setFunc) error {
+				set(n, core.PlanItemStatusPending)
+				observe(n, fmt.Sprintf("Attempt %d failed%s: %v. Retrying in %s.", s.Attempts, took, res.err, delay))
+				return setState(n.Item, s)
+			})
+		}
+		return r.update(&res, func(set setFunc) error {
+			observe(n, fmt.Sprintf("Attempt %d failed%s: %v.", s.Attempts, took, res.err))
+			return r.fail(n, s, res.err, set)
+		})
+	default:
+		r.report.Completed = append(r.report.Completed, n.ID)
+		return r.update(&res, func(set setFunc) error {
+			set(n, core.PlanItemStatusCompleted)
+			observe(n, fmt.Sprintf("Attempt %d succeeded%s.", s.Attempts, took))
+			conclude(n, fmt.Sprintf("Completed on attempt %d.", s.Attempts))
+			if err := setState(n.Item, s); err != nil {
+				return err
+			}
+			r.settle(n, set)
+			return nil
+		})
+	}
+}
+
+// fail handles an item that has failed for good, according to its
+// policy's FailureAction. s is its state, which fail records.
+func (r *run) fail(n *graph.Node, s State, err error, set setFunc) error {
+	r.report.Failed = append(r.report.Failed, n.ID)
+	r.report.Errors[n.ID] = err
+	s.Failed = true
+	s.RetryAt = nil
+	if err := setState(n.Item, s); err != nil {
+		return err
+	}
+	result := fmt.Sprintf("Failed after %s: %v", plural(s.Attempts, "attempt"), err)
+
+	switch p := r.policies[n]; p.OnFailure {
+	case OnFailureCancelSubtree:
+		set(n, core.PlanItemStatusBlocked)
+		if ids := r.cancelDependents(n, set); len(ids) > 0 {
+			result += "; cancelled " + strings.Join(ids, ", ")
+		}
+	case OnFailureSuggests:
+		set(n, core.PlanItemStatusCancelled)
+		var ids []string
+		for _, c := range r.contingents[n] {
+			ids = append(ids, c.ID)
+		}
+		if len(ids) > 0 {
+			result += "; continuing with " + strings.Join(ids, ", ")
+		}
+		r.settle(n, set)
+	case OnFailureFallback:
+		set(n, core.PlanItemStatusCancelled)
+		result += "; falling back to " + p.Fallback
+		r.settle(n, set)
+	default:
+		set(n, core.PlanItemStatusBlocked)
+	}
+	conclude(n, result+".")
+	return nil
+}
+
+// settle follows up on an item that has become completed or cancelled.
+// It skips the targets of a completed item's invalidates edges, and the
+// contingents of an item that did not fail, and settles containers whose
+// sub-items, or whose items' fallbacks, are now all settled.
+func (r *run) settle(n *graph.Node, set setFunc) {
+	if n.Item.Status == core.PlanItemStatusCompleted && n.ID != "" {
+		for _, e := range r.g.Outgoing(n.ID) {
+			if e.Type == core.EdgeInvalidates {
+				r.skip(r.g.Node(e.To), "Skipped: invalidated by "+n.ID+".", set)
+			}
+		}
+	}
+	if !r.state(n).Failed {
+		for _, c := range r.contingents[n] {
+			r.skip(c, "Skipped: not needed, as "+n.ID+" did not fail.", set)
+		}
+	}
+	for _, p := range r.standsIn[n] {
+		r.rollup(p.Parent, set)
+	}
+	r.rollup(n.Parent, set)
+}
+
+// rollup settles a container once all its sub-items are settled: it
+// becomes completed, or cancelled if none of its sub-items, or of their
+// fallbacks, completed.
+func (r *run) rollup(p *graph.Node, set setFunc) {
+	if p == nil || p.Item.Status == core.PlanItemStatusCompleted || p.Item.Status == core.PlanItemStatusCancelled {
+		return
+	}
+	status := core.PlanItemStatusCancelled
+	for _, c := range p.Children {
+		if !r.settled(c) {
+			return
+		}
+		if c.Item.Status == core.PlanItemStatusCompleted || r.fallback(c) != nil && r.state(c).Failed {
+			status = core.PlanItemStatusCompleted
+		}
+	}
+	set(p, status)
+	r.settle(p, set)
+}
+
+// skip cancels a pending item and its pending sub-items, giving reason as
+// their Result.
+func (r *run) skip(n *graph.Node, reason string, set setFunc) {
+	if n.Item.Status != core.PlanItemStatusPending {
+		return
+	}
+	for _, m := range r.cancel(n, reason, set) {
+		r.report.Skipped = append(r.report.Skipped, m.ID)
+	}
+	r.settle(n, set)
+}
+
+// cancel cancels a pending item and its pending sub-items, returning
+// them.
+func (r *run) cancel(n *graph.Node, reason string, set setFunc) []*graph.Node {
+	if n.Item.Status != core.PlanItemStatusPending {
+		return nil
+	}
+	set(n, core.PlanItemStatusCancelled)
+	conclude(n, reason)
+	out := []*graph.Node{n}
+	for _, c := range n.Children {
+		out = append(out, r.cancel(c, reason, set)...)
+	}
+	return out
+}
+
+// cancelDependents cancels the pending items that depend on n through
+// blocks edges, transitively, with their sub-items, and returns their
+// IDs.
+func (r *run) cancelDependents(n *graph.Node, set setFunc) []string {
+	reason := "Cancelled: depends on " + n.ID + ", which failed."
+	var ids []string
+	seen := map[*graph.Node]bool{n: true}
+	queue := []*graph.Node{n}
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		for _, e := range r.g.Outgoing(m.ID) {
+			t := r.g.Node(e.To)
+			if e.Type != core.EdgeBlocks || seen[t] {
+				continue
+			}
+			seen[t] = true
+			cancelled := r.cancel(t, reason, set)
+			for _, c := range cancelled {
+				ids = append(ids, c.ID)
+				r.report.Cancelled = append(r.report.Cancelled, c.ID)
+				seen[c] = true
+			}
+			if len(cancelled) > 0 {
+				r.settle(t, set)
+			}
+			queue = append(queue, t)
+			queue = append(queue, cancelled...)
+		}
+	}
+	return ids
+}
+
+// observe appends a line to an item's Observation narrative.
+func observe(n *graph.Node, line string) {
+	if n.Item.Narrative == nil {
+		n.Item.Narrative = make(map[string]string)
+	}
+	if prev := n.Item.Narrative[NarrativeObservation]; prev != "" {
+		line = prev + "\n" + line
+	}
+	n.Item.Narrative[NarrativeObservation] = line
+}
+
+// conclude sets an item's Result narrative.
+func conclude(n *graph.Node, result string) {
+	if n.Item.Narrative == nil {
+		n.Item.Narrative = make(map[string]string)
+	}
+	n.Item.Narrative[NarrativeResult] = result
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// update applies changes in one validated transaction, saves a
+// checkpoint, then reports each status change. res, if set, supplies the
+// output and error for its item's event.
+func (r *run) update(res *result, fn func(set setFunc) error) error {
+	var changed []*graph.Node
+	err := r.u.Transaction(func(*updater.Updater) error {
+		return fn(func(n *graph.Node, status core.PlanItemStatus) {
+			if n.Item.Status != status {
+				n.Item.Status = status
+				changed = append(changed, n)
+			}
+		})
+	})
+	if err == nil && r.e.opts.Checkpoint != nil {
+		err = r.e.opts.Checkpoint.Checkpoint(r.doc)
+	}
+	if r.e.opts.OnEvent != nil {
+		for _, n := range changed {
+			ev := Event{ID: n.ID, Status: n.Item.Status}
+			if res != nil && res.node == n {
+				ev.Output, ev.Err = res.output, res.err
+			}
+			r.e.opts.OnEvent(ev)
+		}
+	}
+	return err
+}
+
+// finishPlan marks the plan completed once every item is settled.
+func (r *run) finishPlan() error {
+	if len(r.g.Roots()) == 0 {
+		return nil
+	}
+	for _, n := range r.g.Roots() {
+		if !r.settled(n) {
+			return nil
+		}
+	}
+	if r.doc.Plan.Status == core.PlanStatusCompleted {
+		return nil
+	}
+	return r.update(nil, func(setFunc) error {
+		r.doc.Plan.Status = core.PlanStatusCompleted
+		return nil
+	})
+}
@@ -0,0 +1,273 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+	"github.com/visionik/vBRIEF/api/go/pkg/validator"
+)
+
+func plan(edges []core.Edge, items ...core.PlanItem) *core.Document {
+	return &core.Document{
+		Info: core.Info{Version: "0.5"},
+		Plan: &core.Plan{
+			Title: "Pipeline", Status: core.PlanStatusApproved,
+			Narratives: map[string]string{"Proposal": "Build and ship."},
+			Items:      items, Edges: edges,
+		},
+	}
+}
+
+func item(id string, sub ...core.PlanItem) core.PlanItem {
+	return core.PlanItem{ID: id, Title: id, Status: core.PlanItemStatusPending, SubItems: sub}
+}
+
+func edge(from, to string, typ core.EdgeType) core.Edge {
+	return core.Edge{From: from, To: to, Type: typ}
+}
+
+func status(t *testing.T, doc *core.Document, id string) core.PlanItemStatus {
+	t.Helper()
+	n := graph.New(doc.Plan).Node(id)
+	require.NotNil(t, n, id)
+	return n.Item.Status
+}
+
+// recorder is a runner that records the order steps ran in and how many
+// ran at once, failing the IDs in fail.
+type recorder struct {
+	mu      sync.Mutex
+	order   []string
+	active  atomic.Int32
+	maxSeen atomic.Int32
+	fail    map[string]bool
+}
+
+func (r *recorder) Run(_ context.Context, step Step) (map[string]interface{}, error) {
+	n := r.active.Add(1)
+	defer r.active.Add(-1)
+	for {
+		m := r.maxSeen.Load()
+		if n <= m || r.maxSeen.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	r.mu.Lock()
+	r.order = append(r.order, step.ID)
+	r.mu.Unlock()
+	if r.fail[step.ID] {
+		return nil, errors.New("boom")
+	}
+	return map[string]interface{}{"id": step.ID}, nil
+}
+
+func (r *recorder) index(id string) int {
+	for i, s := range r.order {
+		if s == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// pipeline is a build pipeline:
+//
+//	lint, test -> build -> package{linux, mac} -> deploy
+func pipeline() *core.Document {
+	return plan([]core.Edge{
+		edge("lint", "build", core.EdgeBlocks),
+		edge("test", "build", core.EdgeBlocks),
+		edge("build", "package", core.EdgeBlocks),
+		edge("package", "deploy", core.EdgeBlocks),
+	},
+		item("lint"), item("test"), item("build"),
+		item("package", item("linux"), item("mac")),
+		item("deploy"),
+	)
+}
+
+func TestRun(t *testing.T) {
+	doc := pipeline()
+	rec := &recorder{}
+	reg := NewRegistry()
+	reg.Register("rec", rec)
+	var events []string
+	e := NewExecutorWithOptions(reg, Options{
+		Workers:       2,
+		DefaultRunner: "rec",
+		OnEvent:       func(ev Event) { events = append(events, ev.ID+" "+string(ev.Status)) },
+	})
+
+	report, err := e.Run(context.Background(), doc)
+	require.NoError(t, err)
+	require.NoError(t, report.Err())
+
+	assert.ElementsMatch(t, []string{"lint", "test", "build", "package.linux", "package.mac", "deploy"}, report.Completed)
+	assert.Less(t, rec.index("lint"), rec.index("build"))
+	assert.Less(t, rec.index("test"), rec.index("build"))
+	assert.Less(t, rec.index("build"), rec.index("package.linux"))
+	assert.Less(t, rec.index("package.mac"), rec.index("deploy"))
+	assert.Equal(t, int32(2), rec.maxSeen.Load())
+	assert.Equal(t, map[string]interface{}{"id": "deploy"}, report.Outputs["deploy"])
+
+	for _, id := range []string{"lint", "package", "package.mac", "deploy"} {
+		assert.Equal(t, core.PlanItemStatusCompleted, status(t, doc, id), id)
+	}
+	assert.Equal(t, core.PlanStatusCompleted, doc.Plan.Status)
+	assert.Contains(t, events, "package running")
+	assert.Contains(t, events, "package completed")
+}
+
+func TestRun_Workers(t *testing.T) {
+	doc := plan(nil, item("a"), item("b"), item("c"), item("d"))
+	rec := &recorder{}
+	reg := NewRegistry()
+	reg.Register("rec", rec)
+
+	_, err := NewExecutorWithOptions(reg, Options{Workers: 1, DefaultRunner: "rec"}).Run(context.Background(), doc)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, rec.order)
+	assert.Equal(t, int32(1), rec.maxSeen.Load())
+}
+
+func TestRun_Invalidates(t *testing.T) {
+	// A cache hit makes the full build unnecessary; publish still runs.
+	doc := plan([]core.Edge{
+		edge("cache", "full", core.EdgeInvalidates),
+		edge("cache", "full", core.EdgeBlocks),
+		edge("full", "publish", core.EdgeBlocks),
+	},
+		item("cache"), item("full", item("compile"), item("link")), item("publish"),
+	)
+	rec := &recorder{}
+	reg := NewRegistry()
+	reg.Register("rec", rec)
+
+	report, err := NewExecutorWithOptions(reg, Options{DefaultRunner: "rec"}).Run(context.Background(), doc)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cache", "publish"}, rec.order)
+	assert.Equal(t, []string{"full", "full.compile", "full.link"}, report.Skipped)
+	assert.Equal(t, core.PlanItemStatusCancelled, status(t, doc, "full.link"))
+	assert.Equal(t, core.PlanStatusCompleted, doc.Plan.Status)
+}
+
+func TestRun_Failure(t *testing.T) {
+	doc := pipeline()
+	doc.Plan.Items[1].Metadata = map[string]interface{}{RunnerKey: "missing"}
+	rec := &recorder{fail: map[string]bool{"lint": true}}
+	reg := NewRegistry()
+	reg.Register("rec", rec)
+
+	report, err := NewExecutorWithOptions(reg, Options{DefaultRunner: "rec"}).Run(context.Background(), doc)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lint", "test"}, report.Failed)
+	assert.ErrorIs(t, report.Errors["test"], ErrNoRunner)
+	assert.ErrorContains(t, report.Err(), "boom")
+	assert.Equal(t, []string{"build", "package.linux", "package.mac", "deploy"}, report.Pending)
+	assert.Equal(t, core.PlanItemStatusBlocked, status(t, doc, "lint"))
+	assert.Equal(t, core.PlanStatusRunning, doc.Plan.Status)
+}
+
+func TestRun_Cancel(t *testing.T) {
+	doc := pipeline()
+	ctx, cancel := context.WithCancel(context.Background())
+	reg := NewRegistry()
+	reg.Register("wait", RunnerFunc(func(ctx context.Context, step Step) (map[string]interface{}, error) {
+		if step.ID == "test" {
+			cancel()
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return nil, nil
+	}))
+
+	report, err := NewExecutorWithOptions(reg, Options{Workers: 1, DefaultRunner: "wait"}).Run(ctx, doc)
+	assert.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, report)
+	assert.Equal(t, []string{"lint"}, report.Completed)
+	assert.Equal(t, []string{"test"}, report.Interrupted)
+	assert.Equal(t, core.PlanItemStatusPending, status(t, doc, "test"))
+	assert.Empty(t, report.Failed)
+}
+
+// failingAfter rejects documents once the plan's first item is completed.
+type failingAfter struct {
+	validator.Validator
+}
+
+func (v failingAfter) Validate(doc *core.Document) error {
+	if doc.Plan.Items[0].Status == core.PlanItemStatusCompleted {
+		return validator.ValidationErrors{{Field: "plan.items[0]", Message: "rejected"}}
+	}
+	return v.Validator.Validate(doc)
+}
+
+func TestRun_InvalidTransition(t *testing.T) {
+	doc := plan(nil, item("a"), item("b"))
+	reg := NewRegistry()
+	reg.Register("noop", RunnerFunc(func(context.Context, Step) (map[string]interface{}, error) { return nil, nil }))
+	e := NewExecutorWithOptions(reg, Options{Workers: 1, DefaultRunner: "noop", Validator: failingAfter{validator.NewValidator()}})
+
+	report, err := e.Run(context.Background(), doc)
+	assert.ErrorContains(t, err, "rejected")
+	assert.Equal(t, []string{"b"}, report.Pending)
+
+	_, err = NewExecutor(reg).Run(context.Background(), &core.Document{})
+	assert.ErrorIs(t, err, core.ErrNoPlan)
+}
+
+func TestRun_Panic(t *testing.T) {
+	doc := plan(nil, item("a"))
+	reg := NewRegistry()
+	reg.Register("panic", RunnerFunc(func(context.Context, Step) (map[string]interface{}, error) { panic("oops") }))
+
+	report, err := NewExecutorWithOptions(reg, Options{DefaultRunner: "panic"}).Run(context.Background(), doc)
+	require.NoError(t, err)
+	assert.EqualError(t, report.Errors["a"], "a: runner panicked: oops")
+}
+
+func TestShell(t *testing.T) {
+	doc := plan(nil,
+		core.PlanItem{ID: "ok", Title: "ok", Status: core.PlanItemStatusPending,
+			Metadata: map[string]interface{}{RunnerKey: "shell", CommandKey: "echo hello"}},
+		core.PlanItem{ID: "bad", Title: "bad", Status: core.PlanItemStatusPending,
+			Metadata: map[string]interface{}{RunnerKey: "shell", CommandKey: "echo oops >&2; exit 3"}},
+	)
+	reg := NewRegistry()
+	reg.Register("shell", Shell)
+	assert.Equal(t, []string{"shell"}, reg.Names())
+
+	report, err := NewExecutor(reg).Run(context.Background(), doc)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", report.Outputs["ok"]["stdout"])
+	assert.Equal(t, "oops\n", report.Outputs["bad"]["stderr"])
+	assert.ErrorContains(t, report.Errors["bad"], "exit status 3")
+}
+
+func TestShell_Cancel(t *testing.T) {
+	// The shell forks sleep, which would hold stdout open for 3s if only
+	// the shell were killed.
+	doc := plan(nil, core.PlanItem{ID: "slow", Title: "slow", Status: core.PlanItemStatusPending,
+		Metadata: map[string]interface{}{RunnerKey: "shell", CommandKey: "sleep 3; echo hi"}})
+	reg := NewRegistry()
+	reg.Register("shell", Shell)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	report, err := NewExecutor(reg).Run(ctx, doc)
+	elapsed := time.Since(start)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, elapsed, 2*time.Second)
+	assert.Empty(t, report.Outputs["slow"]["stdout"])
+}
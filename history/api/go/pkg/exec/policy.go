@@ -0,0 +1,169 @@
+package exec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// PolicyKey is the item metadata key holding the item's Policy, e.g.
+//
+//	"metadata": {"policy": {"maxAttempts": 3, "backoff": "2s", "timeout": "1m", "onFailure": "fallback", "fallback": "cached"}}
+const PolicyKey = "policy"
+
+var (
+	// ErrInvalidPolicy is returned by Run and Resume when an item's policy
+	// cannot be read or names an unknown item.
+	ErrInvalidPolicy = errors.New("invalid policy")
+	// ErrTimeout is an item's error when an attempt exceeds the policy's
+	// timeout.
+	ErrTimeout = errors.New("timed out")
+)
+
+// FailureAction says what happens when an item has failed its last
+// attempt.
+type FailureAction string
+
+const (
+	// OnFailureBlock marks the item blocked; the items it blocks wait, and
+	// are left pending. This is the default.
+	OnFailureBlock FailureAction = "block"
+	// OnFailureCancelSubtree marks the item blocked and cancels every
+	// pending item that depends on it through blocks edges, transitively,
+	// with their sub-items, so the rest of the plan can finish.
+	OnFailureCancelSubtree FailureAction = "cancelSubtree"
+	// OnFailureSuggests cancels the item, so the items it blocks go ahead,
+	// and runs the targets of its suggests edges. Those targets are held
+	// back until the item fails, and skipped if it completes.
+	OnFailureSuggests FailureAction = "suggests"
+	// OnFailureFallback cancels the item and runs Policy.Fallback in its
+	// place: the items it blocks wait for the fallback instead. The
+	// fallback is held back until the item fails, and skipped if it
+	// completes.
+	OnFailureFallback FailureAction = "fallback"
+)
+
+// Policy controls how an item is retried and what its failure means.
+type Policy struct {
+	// MaxAttempts is the number of times the item is started before it
+	// fails for good. Zero means 1.
+	MaxAttempts int
+	// Backoff is the delay before the second attempt.
+	Backoff time.Duration
+	// BackoffFactor multiplies the delay for each later attempt. Zero
+	// means 2.
+	BackoffFactor float64
+	// MaxBackoff caps the delay, if positive.
+	MaxBackoff time.Duration
+	// Timeout limits each attempt, if positive. The attempt's context is
+	// cancelled when it expires and the attempt fails with ErrTimeout.
+	Timeout time.Duration
+	// OnFailure is the action taken after the last attempt fails. Empty
+	// means OnFailureBlock.
+	OnFailure FailureAction
+	// Fallback is the hierarchical ID of the item run instead, for
+	// OnFailureFallback.
+	Fallback string
+}
+
+// delay returns how long to wait before the attempt after attempt.
+func (p Policy) delay(attempt int) time.Duration {
+	factor := p.BackoffFactor
+	if factor == 0 {
+		factor = 2
+	}
+	// Clamp before converting: a large attempt overflows time.Duration.
+	limit := time.Duration(math.MaxInt64)
+	if p.MaxBackoff > 0 {
+		limit = p.MaxBackoff
+	}
+	d := float64(p.Backoff) * math.Pow(factor, float64(attempt-1))
+	if d >= float64(limit) {
+		return limit
+	}
+	return time.Duration(d)
+}
+
+// policyJSON is a policy as written in metadata, with durations as Go
+// duration strings such as "1m30s".
+type policyJSON struct {
+	MaxAttempts   *int           `json:"maxAttempts"`
+	Backoff       *string        `json:"backoff"`
+	BackoffFactor *float64       `json:"backoffFactor"`
+	MaxBackoff    *string        `json:"maxBackoff"`
+	Timeout       *string        `json:"timeout"`
+	OnFailure     *FailureAction `json:"onFailure"`
+	Fallback      *string        `json:"fallback"`
+}
+
+// PolicyOf returns the policy in item's metadata[PolicyKey], with fields
+// it leaves out taken from def.
+func PolicyOf(item *core.PlanItem, def Policy) (Policy, error) {
+	p := def
+	raw, ok := item.Metadata[PolicyKey]
+	if !ok {
+		return p, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return p, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+	var pj policyJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return p, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+
+	if pj.MaxAttempts != nil {
+		p.MaxAttempts = *pj.MaxAttempts
+	}
+	if pj.BackoffFactor != nil {
+		p.BackoffFactor = *pj.BackoffFactor
+	}
+	if pj.OnFailure != nil {
+		p.OnFailure = *pj.OnFailure
+	}
+	if pj.Fallback != nil {
+		p.Fallback = *pj.Fallback
+	}
+	for _, d := range []struct {
+		name string
+		in   *string
+		out  *time.Duration
+	}{
+		{"backoff", pj.Backoff, &p.Backoff},
+		{"maxBackoff", pj.MaxBackoff, &p.MaxBackoff},
+		{"timeout", pj.Timeout, &p.Timeout},
+	} {
+		if d.in == nil {
+			continue
+		}
+		v, err := time.ParseDuration(*d.in)
+		if err != nil {
+			return p, fmt.Errorf("%w: %s: %w", ErrInvalidPolicy, d.name, err)
+		}
+		*d.out = v
+	}
+
+	switch {
+	case p.MaxAttempts < 0:
+		return p, fmt.Errorf("%w: maxAttempts must not be negative", ErrInvalidPolicy)
+	case p.Backoff < 0, p.MaxBackoff < 0, p.Timeout < 0:
+		return p, fmt.Errorf("%w: durations must not be negative", ErrInvalidPolicy)
+	case p.BackoffFactor < 0:
+		return p, fmt.Errorf("%w: backoffFactor must not be negative", ErrInvalidPolicy)
+	}
+	switch p.OnFailure {
+	case "", OnFailureBlock, OnFailureCancelSubtree, OnFailureSuggests:
+	case OnFailureFallback:
+		if p.Fallback == "" {
+			return p, fmt.Errorf("%w: onFailure %q needs a fallback", ErrInvalidPolicy, p.OnFailure)
+		}
+	default:
+		return p, fmt.Errorf("%w: unknown onFailure %q", ErrInvalidPolicy, p.OnFailure)
+	}
+	return p, nil
+}
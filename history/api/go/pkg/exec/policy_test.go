@@ -0,0 +1,237 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+func withPolicy(it core.PlanItem, policy map[string]interface{}) core.PlanItem {
+	if it.Metadata == nil {
+		it.Metadata = make(map[string]interface{})
+	}
+	it.Metadata[PolicyKey] = policy
+	return it
+}
+
+func narrative(t *testing.T, doc *core.Document, id, key string) string {
+	t.Helper()
+	n := graph.New(doc.Plan).Node(id)
+	require.NotNil(t, n, id)
+	return n.Item.Narrative[key]
+}
+
+// flaky returns a registry whose "flaky" runner fails each item's first
+// failures[id] attempts.
+func flaky(failures map[string]int) *Registry {
+	reg := NewRegistry()
+	reg.Register("flaky", RunnerFunc(func(_ context.Context, step Step) (map[string]interface{}, error) {
+		if step.Attempt <= failures[step.ID] {
+			return nil, errors.New("boom")
+		}
+		return map[string]interface{}{"id": step.ID}, nil
+	}))
+	return reg
+}
+
+func TestPolicyOf(t *testing.T) {
+	def := Policy{MaxAttempts: 2, Timeout: time.Minute}
+	tests := []struct {
+		name   string
+		policy interface{}
+		want   Policy
+		err    bool
+	}{
+		{"none", nil, def, false},
+		{"overrides", map[string]interface{}{
+			"maxAttempts": 5, "backoff": "1s", "backoffFactor": 3, "maxBackoff": "10s", "onFailure": "fallback", "fallback": "alt",
+		}, Policy{MaxAttempts: 5, Backoff: time.Second, BackoffFactor: 3, MaxBackoff: 10 * time.Second, Timeout: time.Minute, OnFailure: OnFailureFallback, Fallback: "alt"}, false},
+		{"bad duration", map[string]interface{}{"timeout": "soon"}, Policy{}, true},
+		{"negative", map[string]interface{}{"maxAttempts": -1}, Policy{}, true},
+		{"unknown action", map[string]interface{}{"onFailure": "retry"}, Policy{}, true},
+		{"fallback without item", map[string]interface{}{"onFailure": "fallback"}, Policy{}, true},
+		{"not an object", "3 attempts", Policy{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := item("a")
+			if tt.policy != nil {
+				it.Metadata = map[string]interface{}{PolicyKey: tt.policy}
+			}
+			p, err := PolicyOf(&it, def)
+			if tt.err {
+				assert.ErrorIs(t, err, ErrInvalidPolicy)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, p)
+		})
+	}
+}
+
+func TestPolicy_Delay(t *testing.T) {
+	p := Policy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, p.delay(1))
+	assert.Equal(t, 2*time.Second, p.delay(2))
+	assert.Equal(t, 4*time.Second, p.delay(3))
+	assert.Equal(t, 5*time.Second, p.delay(4))
+
+	p = Policy{Backoff: time.Second, MaxBackoff: time.Minute}
+	assert.Equal(t, time.Minute, p.delay(40), "large attempts clamp rather than overflow")
+	assert.Equal(t, time.Minute, p.delay(5000))
+	p.MaxBackoff = 0
+	assert.Equal(t, time.Duration(math.MaxInt64), p.delay(100))
+}
+
+func TestRun_Retry(t *testing.T) {
+	doc := plan([]core.Edge{edge("a", "b", core.EdgeBlocks)},
+		withPolicy(item("a"), map[string]interface{}{"maxAttempts": 3, "backoff": "20ms"}), item("b"))
+	e := NewExecutorWithOptions(flaky(map[string]int{"a": 1}), Options{DefaultRunner: "flaky"})
+
+	began := time.Now()
+	report, err := e.Run(context.Background(), doc)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(began), 20*time.Millisecond, "the retry waits for its backoff")
+	assert.Equal(t, []string{"a", "b"}, report.Completed)
+	assert.Equal(t, []string{"a"}, report.Retried)
+	assert.Empty(t, report.Failed)
+	assert.Equal(t, core.PlanStatusCompleted, doc.Plan.Status)
+
+	s := stateOf(t, doc, "a")
+	assert.Equal(t, 2, s.Attempts)
+	assert.Empty(t, s.Error)
+	obs := strings.Split(narrative(t, doc, "a", NarrativeObservation), "\n")
+	require.Len(t, obs, 2)
+	assert.Contains(t, obs[0], "Attempt 1 failed after")
+	assert.Contains(t, obs[0], ": boom. Retrying in 20ms.")
+	assert.Contains(t, obs[1], "Attempt 2 succeeded after")
+	assert.Equal(t, "Completed on attempt 2.", narrative(t, doc, "a", NarrativeResult))
+}
+
+func TestRun_RetryExhausted(t *testing.T) {
+	doc := plan(nil, item("a"))
+	e := NewExecutorWithOptions(flaky(map[string]int{"a": 5}), Options{
+		DefaultRunner: "flaky",
+		Policy:        Policy{MaxAttempts: 3},
+	})
+	report, err := e.Run(context.Background(), doc)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "a"}, report.Retried)
+	assert.Equal(t, []string{"a"}, report.Failed)
+	assert.Equal(t, core.PlanItemStatusBlocked, status(t, doc, "a"))
+	assert.True(t, stateOf(t, doc, "a").Failed)
+	assert.Equal(t, "Failed after 3 attempts: boom.", narrative(t, doc, "a", NarrativeResult))
+}
+
+func TestRun_Timeout(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register("wait", RunnerFunc(func(ctx context.Context, _ Step) (map[string]interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	doc := plan(nil, withPolicy(item("a"), map[string]interface{}{"timeout": "10ms"}))
+	report, err := NewExecutorWithOptions(reg, Options{DefaultRunner: "wait"}).Run(context.Background(), doc)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, report.Failed)
+	assert.ErrorIs(t, report.Errors["a"], ErrTimeout)
+	assert.Empty(t, report.Interrupted)
+}
+
+func TestRun_OnFailure(t *testing.T) {
+	// pipeline with build's policy set, and two optional items: prebuilt,
+	// a fallback for build, and notify, which build suggests.
+	doc := func(policy map[string]interface{}) *core.Document {
+		d := pipeline()
+		d.Plan.Items[2] = withPolicy(d.Plan.Items[2], policy)
+		d.Plan.Items = append(d.Plan.Items, item("prebuilt"), item("notify"))
+		d.Plan.Edges = append(d.Plan.Edges, edge("build", "notify", core.EdgeSuggests))
+		return d
+	}
+
+	t.Run("cancelSubtree", func(t *testing.T) {
+		d := doc(map[string]interface{}{"onFailure": "cancelSubtree"})
+		report, err := NewExecutorWithOptions(flaky(map[string]int{"build": 1}), Options{DefaultRunner: "flaky"}).Run(context.Background(), d)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"build"}, report.Failed)
+		assert.Equal(t, []string{"package", "package.linux", "package.mac", "deploy"}, report.Cancelled)
+		assert.Empty(t, report.Pending)
+		assert.Equal(t, core.PlanItemStatusBlocked, status(t, d, "build"))
+		assert.Equal(t, core.PlanItemStatusCancelled, status(t, d, "deploy"))
+		assert.Equal(t, "Failed after 1 attempt: boom; cancelled package, package.linux, package.mac, deploy.",
+			narrative(t, d, "build", NarrativeResult))
+		assert.Equal(t, "Cancelled: depends on build, which failed.", narrative(t, d, "package.mac", NarrativeResult))
+	})
+
+	t.Run("suggests", func(t *testing.T) {
+		d := doc(map[string]interface{}{"onFailure": "suggests"})
+		report, err := NewExecutorWithOptions(flaky(map[string]int{"build": 1}), Options{DefaultRunner: "flaky"}).Run(context.Background(), d)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"build"}, report.Failed)
+		assert.Contains(t, report.Completed, "notify")
+		assert.Contains(t, report.Completed, "deploy", "successors continue")
+		assert.Equal(t, core.PlanItemStatusCancelled, status(t, d, "build"))
+		assert.Equal(t, "Failed after 1 attempt: boom; continuing with notify.", narrative(t, d, "build", NarrativeResult))
+		assert.Equal(t, core.PlanStatusCompleted, d.Plan.Status)
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		d := doc(map[string]interface{}{"onFailure": "fallback", "fallback": "prebuilt"})
+		rec := &recorder{fail: map[string]bool{"build": true}}
+		reg := NewRegistry()
+		reg.Register("rec", rec)
+		report, err := NewExecutorWithOptions(reg, Options{Workers: 1, DefaultRunner: "rec"}).Run(context.Background(), d)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"build"}, report.Failed)
+		assert.Empty(t, report.Pending)
+		assert.Contains(t, report.Completed, "notify", "suggested items are held back only by a suggests policy")
+		assert.Less(t, rec.index("prebuilt"), rec.index("package.linux"), "successors wait for the fallback")
+		assert.Contains(t, report.Completed, "deploy")
+		assert.Equal(t, "Failed after 1 attempt: boom; falling back to prebuilt.", narrative(t, d, "build", NarrativeResult))
+	})
+
+	t.Run("not needed", func(t *testing.T) {
+		d := doc(map[string]interface{}{"onFailure": "fallback", "fallback": "prebuilt"})
+		report, err := NewExecutorWithOptions(flaky(nil), Options{DefaultRunner: "flaky"}).Run(context.Background(), d)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"prebuilt"}, report.Skipped)
+		assert.Equal(t, "Skipped: not needed, as build did not fail.", narrative(t, d, "prebuilt", NarrativeResult))
+		assert.Equal(t, core.PlanStatusCompleted, d.Plan.Status)
+	})
+
+	t.Run("fallback fails", func(t *testing.T) {
+		d := doc(map[string]interface{}{"onFailure": "fallback", "fallback": "prebuilt"})
+		report, err := NewExecutorWithOptions(flaky(map[string]int{"build": 1, "prebuilt": 1}), Options{DefaultRunner: "flaky"}).Run(context.Background(), d)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"build", "prebuilt"}, report.Failed)
+		assert.Equal(t, []string{"package.linux", "package.mac", "deploy"}, report.Pending)
+	})
+}
+
+func TestRun_InvalidPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  *core.Document
+	}{
+		{"unknown action", plan(nil, withPolicy(item("a"), map[string]interface{}{"onFailure": "shrug"}))},
+		{"missing fallback", plan(nil, withPolicy(item("a"), map[string]interface{}{"onFailure": "fallback", "fallback": "b"}))},
+		{"self fallback", plan(nil, withPolicy(item("a"), map[string]interface{}{"onFailure": "fallback", "fallback": "a"}))},
+		{"fallback cycle", plan(nil,
+			withPolicy(item("a"), map[string]interface{}{"onFailure": "fallback", "fallback": "b"}),
+			withPolicy(item("b"), map[string]interface{}{"onFailure": "fallback", "fallback": "a"}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewExecutorWithOptions(echo(), Options{DefaultRunner: "echo"}).Run(context.Background(), tt.doc)
+			assert.ErrorIs(t, err, ErrInvalidPolicy)
+			assert.Equal(t, core.PlanItemStatusPending, status(t, tt.doc, "a"))
+		})
+	}
+}
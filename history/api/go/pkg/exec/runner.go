@@ -0,0 +1,111 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	osexec "os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// RunnerKey is the item metadata key naming the runner for an item, e.g.
+// "metadata": {"runner": "shell"}.
+const RunnerKey = "runner"
+
+// CommandKey is the item metadata key holding the command Shell runs.
+const CommandKey = "command"
+
+// Step is one item handed to a Runner.
+type Step struct {
+	// ID is the item's hierarchical ID.
+	ID string
+	// Item is a copy of the item; changes to it are discarded.
+	Item *core.PlanItem
+	// Attempt counts the times the item has been started, including this
+	// one.
+	Attempt int
+}
+
+// Runner does the work of a plan item. It returns the item's outputs, if
+// any, or an error if the item failed. Runners are called concurrently
+// and should stop promptly when ctx is cancelled.
+type Runner interface {
+	Run(ctx context.Context, step Step) (map[string]interface{}, error)
+}
+
+// RunnerFunc adapts a function to the Runner interface.
+type RunnerFunc func(ctx context.Context, step Step) (map[string]interface{}, error)
+
+// Run calls f.
+func (f RunnerFunc) Run(ctx context.Context, step Step) (map[string]interface{}, error) {
+	return f(ctx, step)
+}
+
+// Registry maps runner names to runners. It is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	runners map[string]Runner
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{runners: make(map[string]Runner)}
+}
+
+// Register adds a runner under name, replacing any runner already there.
+func (r *Registry) Register(name string, runner Runner) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runners[name] = runner
+}
+
+// Lookup returns the runner registered under name.
+func (r *Registry) Lookup(name string) (Runner, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	runner, ok := r.runners[name]
+	return runner, ok
+}
+
+// Names returns the registered runner names, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.runners))
+	for name := range r.runners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ShellWaitDelay is how long Shell waits, once ctx is done and the
+// command has been killed, for the command's output to close before
+// giving up on it.
+const ShellWaitDelay = 100 * time.Millisecond
+
+// Shell runs the item's metadata[CommandKey] with "sh -c". Its outputs
+// are the command's "stdout" and "stderr". When ctx is done, the whole
+// process group is killed, so processes the command started stop with
+// it. It is not registered by default.
+var Shell = RunnerFunc(func(ctx context.Context, step Step) (map[string]interface{}, error) {
+	command, _ := step.Item.Metadata[CommandKey].(string)
+	if command == "" {
+		return nil, fmt.Errorf("%s: metadata.%s is not set", step.ID, CommandKey)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := osexec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	killGroup(cmd)
+	cmd.WaitDelay = ShellWaitDelay
+	err := cmd.Run()
+	out := map[string]interface{}{"stdout": stdout.String(), "stderr": stderr.String()}
+	if err != nil {
+		return out, fmt.Errorf("%s: %w", step.ID, err)
+	}
+	return out, nil
+})
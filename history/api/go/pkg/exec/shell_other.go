@@ -0,0 +1,9 @@
+//go:build !unix
+
+package exec
+
+import osexec "os/exec"
+
+// killGroup leaves cmd alone; without process groups, cancelling kills
+// only the shell and WaitDelay stops Shell waiting on its children.
+func killGroup(cmd *osexec.Cmd) {}
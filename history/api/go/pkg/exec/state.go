@@ -0,0 +1,69 @@
+package exec
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// StateKey is the item metadata key under which a run records each item's
+// execution state, so that a saved document is also a checkpoint.
+const StateKey = "exec"
+
+// State is an item's execution state, kept in metadata[StateKey].
+type State struct {
+	// Attempts counts the times the item has been started.
+	Attempts int `json:"attempts"`
+	// StartedAt is when the latest attempt started.
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	// FinishedAt is when the latest attempt ended, if it has.
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	// Output is the latest attempt's runner output.
+	Output map[string]interface{} `json:"output,omitempty"`
+	// Error is the latest attempt's error, if it failed or was
+	// interrupted.
+	Error string `json:"error,omitempty"`
+	// RetryAt is when a failed item that has attempts left may start
+	// again.
+	RetryAt *time.Time `json:"retryAt,omitempty"`
+	// Failed is set once the item has failed its last attempt.
+	Failed bool `json:"failed,omitempty"`
+}
+
+// StateOf returns the execution state recorded on item. It reports false
+// if there is none or it cannot be read.
+func StateOf(item *core.PlanItem) (State, bool) {
+	var s State
+	raw, ok := item.Metadata[StateKey]
+	if !ok {
+		return s, false
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return s, false
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return State{}, false
+	}
+	return s, true
+}
+
+// setState records s on item. It is stored as plain JSON values, exactly
+// as it reads back from a saved document.
+func setState(item *core.PlanItem, s State) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("%w: recording state: %w", ErrCheckpoint, err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("%w: recording state: %w", ErrCheckpoint, err)
+	}
+	if item.Metadata == nil {
+		item.Metadata = make(map[string]interface{})
+	}
+	item.Metadata[StateKey] = raw
+	return nil
+}
@@ -0,0 +1,225 @@
+package github
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+// Options configures Export.
+type Options struct {
+	// Owner and Repo name the repository. They default to "{owner}" and
+	// "{repo}", which `gh api` fills in from the current checkout.
+	Owner, Repo string
+	// NextIssue and NextMilestone are the numbers GitHub will assign to the
+	// next created issue and milestone. Both default to 1, which holds for
+	// a repository without issues, pull requests or milestones.
+	NextIssue, NextMilestone int
+}
+
+// Export converts a plan into the GitHub REST requests that mirror it: one
+// label creation per distinct label, then milestones, then issues in
+// document order. Creating a label that already exists fails with 422,
+// which callers can ignore.
+//
+// Items linked by githubMilestone or githubIssue metadata are updated with
+// PATCH; others are created with POST and, if closed, closed by a following
+// PATCH. Blocks edges are written as "Blocked by #N" only when both ends are
+// issues; edges involving milestones or task-list entries are dropped.
+func Export(plan *core.Plan, opts Options) ([]Request, error) {
+	if plan == nil {
+		return nil, core.ErrNoPlan
+	}
+	owner, repo := opts.Owner, opts.Repo
+	if owner == "" {
+		owner = "{owner}"
+	}
+	if repo == "" {
+		repo = "{repo}"
+	}
+	base := fmt.Sprintf("repos/%s/%s/", owner, repo)
+	nextIssue, nextMilestone := max(opts.NextIssue, 1), max(opts.NextMilestone, 1)
+
+	g := graph.New(plan)
+	milestones := make(map[*graph.Node]int)
+	issues := make(map[*graph.Node]int)
+	var milestoneNodes, issueNodes []*graph.Node
+	created := make(map[*graph.Node]bool)
+	assign := func(n *graph.Node, key string, next *int) int {
+		if num, ok := intValue(n.Item.Metadata[key]); ok {
+			return num
+		}
+		created[n] = true
+		num := *next
+		*next++
+		return num
+	}
+	for _, root := range g.Roots() {
+		if root.IsLeaf() {
+			issues[root] = assign(root, MetadataIssue, &nextIssue)
+			issueNodes = append(issueNodes, root)
+			continue
+		}
+		milestones[root] = assign(root, MetadataMilestone, &nextMilestone)
+		milestoneNodes = append(milestoneNodes, root)
+		for _, c := range root.Children {
+			issues[c] = assign(c, MetadataIssue, &nextIssue)
+			issueNodes = append(issueNodes, c)
+		}
+	}
+
+	var requests []Request
+	seen := make(map[string]bool)
+	for _, n := range issueNodes {
+		for _, l := range issueLabels(n.Item) {
+			if !seen[l] {
+				seen[l] = true
+				requests = append(requests, Request{Method: "POST", Path: base + "labels", Body: LabelPayload{Name: l}})
+			}
+		}
+	}
+
+	for _, n := range milestoneNodes {
+		item := n.Item
+		state, _ := closedState(item.Status)
+		body := MilestonePayload{
+			Title:       item.Title,
+			State:       state,
+			Description: item.Narrative["Overview"],
+			DueOn:       item.DueDate,
+		}
+		req := Request{Method: "POST", Path: base + "milestones", Number: milestones[n], ItemID: itemRef(n), Body: body}
+		if !created[n] {
+			req.Method, req.Path = "PATCH", fmt.Sprintf("%smilestones/%d", base, milestones[n])
+		}
+		requests = append(requests, req)
+	}
+
+	for _, n := range issueNodes {
+		item := n.Item
+		payload := IssuePayload{
+			Title:     item.Title,
+			Body:      issueBody(g, n, issues),
+			Labels:    issueLabels(item),
+			Assignees: assignees(item),
+		}
+		if n.Parent != nil {
+			num := milestones[n.Parent]
+			payload.Milestone = &num
+		}
+		state, reason := closedState(item.Status)
+		path := fmt.Sprintf("%sissues/%d", base, issues[n])
+		if !created[n] {
+			payload.State, payload.StateReason = state, reason
+			requests = append(requests, Request{Method: "PATCH", Path: path, Number: issues[n], ItemID: itemRef(n), Body: payload})
+			continue
+		}
+		requests = append(requests, Request{Method: "POST", Path: base + "issues", Number: issues[n], ItemID: itemRef(n), Body: payload})
+		if state == StateClosed {
+			requests = append(requests, Request{Method: "PATCH", Path: path, Number: issues[n], ItemID: itemRef(n),
+				Body: IssuePayload{State: state, StateReason: reason}})
+		}
+	}
+	return requests, nil
+}
+
+func itemRef(n *graph.Node) string {
+	if n.ID != "" {
+		return n.ID
+	}
+	return n.Path
+}
+
+func issueLabels(item *core.PlanItem) []string {
+	labels := append([]string(nil), item.Tags...)
+	return append(labels, statusLabels(item)...)
+}
+
+func assignees(item *core.PlanItem) []string {
+	switch v := item.Metadata[MetadataAssignees].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		var out []string
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	if s, ok := item.Metadata["assignee"].(string); ok && s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
+// issueBody renders the Overview narrative, the other narratives as
+// sections, sub-items as a task list and blocking issues.
+func issueBody(g *graph.Graph, n *graph.Node, issues map[*graph.Node]int) string {
+	var parts []string
+	item := n.Item
+	if s := item.Narrative["Overview"]; s != "" {
+		parts = append(parts, s)
+	}
+	keys := make([]string, 0, len(item.Narrative))
+	for k := range item.Narrative {
+		if k != "Overview" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, "### "+k+"\n\n"+item.Narrative[k])
+	}
+
+	if len(item.SubItems) > 0 {
+		var b strings.Builder
+		writeTaskList(&b, item.SubItems, 0)
+		parts = append(parts, strings.TrimSuffix(b.String(), "\n"))
+	}
+
+	if n.ID != "" && g.Node(n.ID) == n {
+		var refs []string
+		for _, e := range g.Incoming(n.ID) {
+			if e.Type != core.EdgeBlocks {
+				continue
+			}
+			if num, ok := issues[g.Node(e.From)]; ok {
+				refs = append(refs, fmt.Sprintf("#%d", num))
+			}
+		}
+		if len(refs) > 0 {
+			parts = append(parts, "Blocked by "+strings.Join(refs, ", "))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func writeTaskList(b *strings.Builder, items []core.PlanItem, depth int) {
+	for i := range items {
+		mark := " "
+		if items[i].Status == core.PlanItemStatusCompleted {
+			mark = "x"
+		}
+		fmt.Fprintf(b, "%s- [%s] %s\n", strings.Repeat("  ", depth), mark, items[i].Title)
+		writeTaskList(b, items[i].SubItems, depth+1)
+	}
+}
+
+// intValue reads an issue or milestone number stored in metadata, which is
+// a float64 after a JSON round trip.
+func intValue(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, n > 0
+	case int64:
+		return int(n), n > 0
+	case float64:
+		return int(n), n > 0 && n == float64(int(n))
+	}
+	return 0, false
+}
@@ -0,0 +1,178 @@
+// Package github mirrors vBRIEF plans to GitHub Issues without talking to
+// GitHub itself.
+//
+// Export turns a plan into an ordered list of REST requests (labels,
+// milestones, then issues) that a caller can replay with `gh api` or any
+// HTTP client. Import reads a saved `gh api repos/{owner}/{repo}/issues`
+// dump back into a plan.
+//
+// Items map onto GitHub as follows:
+//
+//	top-level item with subItems   milestone
+//	its subItems                   issues in the milestone
+//	other top-level items          issues without a milestone
+//	deeper subItems                task list ("- [ ] ...") in the issue body
+//	tags                           labels
+//	priority                       "priority: <level>" label
+//	blocked, inProgress            "blocked" and "in progress" labels
+//	completed, cancelled           closed as completed or not planned
+//	Overview narrative             issue or milestone description
+//	blocks edges between issues    "Blocked by #N" line in the body
+package github
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// ErrInvalidDump is returned when an issues dump cannot be read.
+var ErrInvalidDump = errors.New("github: invalid issues dump")
+
+// Labels used to carry vBRIEF status and priority.
+const (
+	LabelBlocked        = "blocked"
+	LabelInProgress     = "in progress"
+	LabelPriorityPrefix = "priority: "
+)
+
+// Metadata keys linking plan items to existing GitHub objects. Export
+// updates the linked issue or milestone instead of creating a new one;
+// Import sets them.
+const (
+	MetadataIssue     = "githubIssue"
+	MetadataMilestone = "githubMilestone"
+	MetadataURL       = "githubUrl"
+	MetadataAssignees = "assignees"
+)
+
+// Issue states and state reasons.
+const (
+	StateOpen             = "open"
+	StateClosed           = "closed"
+	StateReasonCompleted  = "completed"
+	StateReasonNotPlanned = "not_planned"
+)
+
+// Request is one GitHub REST call.
+type Request struct {
+	// Method is the HTTP method, e.g. "POST".
+	Method string `json:"method"`
+	// Path is relative to the API root, in `gh api` form, e.g.
+	// "repos/{owner}/{repo}/issues".
+	Path string `json:"path"`
+	// Number is the issue or milestone number the request creates or
+	// updates. Numbers of created objects are predicted from
+	// Options.NextIssue and Options.NextMilestone; labels have none.
+	Number int `json:"number,omitempty"`
+	// ItemID is the hierarchical ID (or path, for items without an ID) of
+	// the plan item the request mirrors.
+	ItemID string `json:"itemId,omitempty"`
+	// Body is a LabelPayload, MilestonePayload or IssuePayload.
+	Body interface{} `json:"body"`
+}
+
+// LabelPayload creates a label.
+type LabelPayload struct {
+	Name string `json:"name"`
+}
+
+// MilestonePayload creates or updates a milestone.
+type MilestonePayload struct {
+	Title       string     `json:"title"`
+	State       string     `json:"state,omitempty"`
+	Description string     `json:"description,omitempty"`
+	DueOn       *time.Time `json:"due_on,omitempty"`
+}
+
+// IssuePayload creates or updates an issue.
+type IssuePayload struct {
+	Title       string   `json:"title,omitempty"`
+	Body        string   `json:"body,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Assignees   []string `json:"assignees,omitempty"`
+	Milestone   *int     `json:"milestone,omitempty"`
+	State       string   `json:"state,omitempty"`
+	StateReason string   `json:"state_reason,omitempty"`
+}
+
+// issue is the subset of the REST issue object that Import reads.
+type issue struct {
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Body        string     `json:"body"`
+	State       string     `json:"state"`
+	StateReason string     `json:"state_reason"`
+	HTMLURL     string     `json:"html_url"`
+	Labels      []label    `json:"labels"`
+	Assignees   []user     `json:"assignees"`
+	Milestone   *milestone `json:"milestone"`
+	CreatedAt   *time.Time `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+	ClosedAt    *time.Time `json:"closed_at"`
+	PullRequest *struct{}  `json:"pull_request"`
+}
+
+type label struct {
+	Name string `json:"name"`
+}
+
+type user struct {
+	Login string `json:"login"`
+}
+
+type milestone struct {
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	State       string     `json:"state"`
+	HTMLURL     string     `json:"html_url"`
+	DueOn       *time.Time `json:"due_on"`
+}
+
+// statusLabels returns the labels that carry an item's status and priority.
+func statusLabels(item *core.PlanItem) []string {
+	var labels []string
+	switch item.Status {
+	case core.PlanItemStatusBlocked:
+		labels = append(labels, LabelBlocked)
+	case core.PlanItemStatusRunning, core.PlanItemStatusInProgress:
+		labels = append(labels, LabelInProgress)
+	}
+	if item.Priority != "" {
+		labels = append(labels, LabelPriorityPrefix+string(item.Priority))
+	}
+	return labels
+}
+
+// issueStatus derives an item status from an issue's state and labels.
+func issueStatus(is issue) core.PlanItemStatus {
+	if is.State == StateClosed {
+		if is.StateReason == StateReasonNotPlanned {
+			return core.PlanItemStatusCancelled
+		}
+		return core.PlanItemStatusCompleted
+	}
+	for _, l := range is.Labels {
+		switch strings.ToLower(l.Name) {
+		case LabelBlocked:
+			return core.PlanItemStatusBlocked
+		case LabelInProgress, "in-progress":
+			return core.PlanItemStatusRunning
+		}
+	}
+	return core.PlanItemStatusPending
+}
+
+func closedState(s core.PlanItemStatus) (state, reason string) {
+	switch s {
+	case core.PlanItemStatusCompleted:
+		return StateClosed, StateReasonCompleted
+	case core.PlanItemStatusCancelled:
+		return StateClosed, StateReasonNotPlanned
+	default:
+		return StateOpen, ""
+	}
+}
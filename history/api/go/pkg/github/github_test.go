@@ -0,0 +1,215 @@
+package github
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+func date(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func releasePlan() *core.Plan {
+	return &core.Plan{
+		Title:  "Shop v2",
+		Status: core.PlanStatusInProgress,
+		Items: []core.PlanItem{
+			{
+				ID: "checkout", Title: "Checkout rewrite", Status: core.PlanItemStatusInProgress,
+				DueDate:   date("2025-04-01T00:00:00Z"),
+				Narrative: map[string]string{"Overview": "Single-page checkout"},
+				SubItems: []core.PlanItem{
+					{ID: "api", Title: "Payments API", Status: core.PlanItemStatusCompleted, Tags: []string{"backend"}},
+					{ID: "page", Title: "Checkout page", Status: core.PlanItemStatusBlocked, Priority: core.PriorityHigh,
+						Tags:      []string{"frontend"},
+						Narrative: map[string]string{"Overview": "New page.", "Risks": "Lost carts."},
+						Metadata:  map[string]interface{}{MetadataAssignees: []interface{}{"ana"}},
+						SubItems: []core.PlanItem{
+							{Title: "Layout", Status: core.PlanItemStatusCompleted},
+							{Title: "Validation", Status: core.PlanItemStatusPending, SubItems: []core.PlanItem{
+								{Title: "Card number", Status: core.PlanItemStatusPending},
+							}},
+						}},
+				},
+			},
+			{ID: "notes", Title: "Release notes", Status: core.PlanItemStatusPending, Tags: []string{"docs"},
+				Metadata: map[string]interface{}{MetadataIssue: float64(41)}},
+			{ID: "widget", Title: "Old cart widget", Status: core.PlanItemStatusCancelled},
+		},
+		Edges: []core.Edge{
+			{From: "checkout.api", To: "checkout.page", Type: core.EdgeBlocks},
+			{From: "checkout.page", To: "notes", Type: core.EdgeBlocks},
+			{From: "checkout", To: "notes", Type: core.EdgeBlocks},
+			{From: "checkout.api", To: "notes", Type: core.EdgeInforms},
+		},
+	}
+}
+
+func TestExport(t *testing.T) {
+	requests, err := Export(releasePlan(), Options{Owner: "acme", Repo: "shop", NextIssue: 7})
+	require.NoError(t, err)
+
+	milestone := 1
+	assert.Equal(t, []Request{
+		{Method: "POST", Path: "repos/acme/shop/labels", Body: LabelPayload{Name: "backend"}},
+		{Method: "POST", Path: "repos/acme/shop/labels", Body: LabelPayload{Name: "frontend"}},
+		{Method: "POST", Path: "repos/acme/shop/labels", Body: LabelPayload{Name: "blocked"}},
+		{Method: "POST", Path: "repos/acme/shop/labels", Body: LabelPayload{Name: "priority: high"}},
+		{Method: "POST", Path: "repos/acme/shop/labels", Body: LabelPayload{Name: "docs"}},
+		{Method: "POST", Path: "repos/acme/shop/milestones", Number: 1, ItemID: "checkout", Body: MilestonePayload{
+			Title: "Checkout rewrite", State: StateOpen, Description: "Single-page checkout", DueOn: date("2025-04-01T00:00:00Z"),
+		}},
+		{Method: "POST", Path: "repos/acme/shop/issues", Number: 7, ItemID: "checkout.api", Body: IssuePayload{
+			Title: "Payments API", Labels: []string{"backend"}, Milestone: &milestone,
+		}},
+		{Method: "PATCH", Path: "repos/acme/shop/issues/7", Number: 7, ItemID: "checkout.api", Body: IssuePayload{
+			State: StateClosed, StateReason: StateReasonCompleted,
+		}},
+		{Method: "POST", Path: "repos/acme/shop/issues", Number: 8, ItemID: "checkout.page", Body: IssuePayload{
+			Title: "Checkout page",
+			Body: "New page.\n\n### Risks\n\nLost carts.\n\n" +
+				"- [x] Layout\n- [ ] Validation\n  - [ ] Card number\n\n" +
+				"Blocked by #7",
+			Labels:    []string{"frontend", "blocked", "priority: high"},
+			Assignees: []string{"ana"},
+			Milestone: &milestone,
+		}},
+		{Method: "PATCH", Path: "repos/acme/shop/issues/41", Number: 41, ItemID: "notes", Body: IssuePayload{
+			Title: "Release notes", Body: "Blocked by #8", Labels: []string{"docs"}, State: StateOpen,
+		}},
+		{Method: "POST", Path: "repos/acme/shop/issues", Number: 9, ItemID: "widget", Body: IssuePayload{
+			Title: "Old cart widget",
+		}},
+		{Method: "PATCH", Path: "repos/acme/shop/issues/9", Number: 9, ItemID: "widget", Body: IssuePayload{
+			State: StateClosed, StateReason: StateReasonNotPlanned,
+		}},
+	}, requests)
+
+	t.Run("gh api placeholders", func(t *testing.T) {
+		requests, err := Export(&core.Plan{Items: []core.PlanItem{{Title: "A", Status: core.PlanItemStatusPending}}}, Options{})
+		require.NoError(t, err)
+		require.Len(t, requests, 1)
+		assert.Equal(t, "repos/{owner}/{repo}/issues", requests[0].Path)
+		assert.Equal(t, 1, requests[0].Number)
+		assert.Equal(t, "plan.items[0]", requests[0].ItemID)
+
+		data, err := json.Marshal(requests[0])
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"method":"POST","path":"repos/{owner}/{repo}/issues","number":1,"itemId":"plan.items[0]","body":{"title":"A"}}`, string(data))
+	})
+
+	t.Run("no plan", func(t *testing.T) {
+		_, err := Export(nil, Options{})
+		assert.ErrorIs(t, err, core.ErrNoPlan)
+	})
+}
+
+func TestImport(t *testing.T) {
+	data, err := os.ReadFile("testdata/issues.json")
+	require.NoError(t, err)
+
+	doc, err := Import(data, "")
+	require.NoError(t, err)
+	plan := doc.Plan
+	assert.Equal(t, core.SpecVersion, doc.Info.Version)
+	assert.Equal(t, "GitHub issues", plan.Title)
+
+	require.Len(t, plan.Items, 3, "milestone, then issues without one; pull request #4 is skipped")
+	v2 := plan.Items[0]
+	assert.Equal(t, "milestone-1", v2.ID)
+	assert.Equal(t, "v2.0", v2.Title)
+	assert.Equal(t, core.PlanItemStatusRunning, v2.Status)
+	assert.Equal(t, map[string]string{"Overview": "Checkout rewrite"}, v2.Narrative)
+	assert.Equal(t, date("2025-04-01T07:00:00Z"), v2.DueDate)
+	assert.Equal(t, 1, v2.Metadata[MetadataMilestone])
+
+	require.Len(t, v2.SubItems, 2)
+	api := v2.SubItems[0]
+	assert.Equal(t, "issue-2", api.ID)
+	assert.Equal(t, core.PlanItemStatusCompleted, api.Status)
+	assert.Equal(t, date("2025-03-02T17:00:00Z"), api.Completed)
+	assert.Equal(t, []string{"backend"}, api.Tags)
+	assert.Nil(t, api.Narrative)
+
+	page := v2.SubItems[1]
+	assert.Equal(t, "Checkout page", page.Title)
+	assert.Equal(t, core.PlanItemStatusRunning, page.Status)
+	assert.Equal(t, core.PriorityHigh, page.Priority)
+	assert.Equal(t, []string{"frontend"}, page.Tags)
+	assert.Equal(t, map[string]string{
+		"Overview": "New single-page checkout.",
+		"Risks":    "Cart state may be lost on reload.",
+	}, page.Narrative)
+	assert.Equal(t, map[string]interface{}{
+		MetadataIssue:     3,
+		MetadataURL:       "https://github.com/acme/shop/issues/3",
+		MetadataAssignees: []interface{}{"ana", "sam"},
+	}, page.Metadata)
+	assert.Equal(t, []core.PlanItem{
+		{Title: "Layout", Status: core.PlanItemStatusCompleted},
+		{Title: "Validation", Status: core.PlanItemStatusPending, SubItems: []core.PlanItem{
+			{Title: "Card number", Status: core.PlanItemStatusPending},
+			{Title: "Postcode", Status: core.PlanItemStatusCompleted},
+		}},
+	}, page.SubItems)
+
+	widget := plan.Items[1]
+	assert.Equal(t, "issue-1", widget.ID)
+	assert.Equal(t, core.PlanItemStatusCancelled, widget.Status)
+	assert.Nil(t, widget.Completed)
+
+	notes := plan.Items[2]
+	assert.Equal(t, "issue-5", notes.ID)
+	assert.Equal(t, core.PriorityLow, notes.Priority)
+	assert.Equal(t, map[string]string{"Overview": "Summarise the changes for users."}, notes.Narrative)
+
+	assert.Equal(t, []core.Edge{
+		{From: "milestone-1.issue-2", To: "milestone-1.issue-3", Type: core.EdgeBlocks},
+		{From: "milestone-1.issue-3", To: "issue-5", Type: core.EdgeBlocks},
+	}, plan.Edges)
+}
+
+func TestImport_Dumps(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		count int
+		err   bool
+	}{
+		{"single array", `[{"number":1,"title":"A","state":"open"}]`, 1, false},
+		{"slurped pages", `[[{"number":1,"title":"A","state":"open"}],[{"number":2,"title":"B","state":"open"}]]`, 2, false},
+		{"single issue", `{"number":1,"title":"A","state":"open"}`, 1, false},
+		{"empty", ``, 0, false},
+		{"not json", `<html>`, 0, true},
+		{"missing number", `[{"title":"A"}]`, 0, true},
+		{"duplicate", `[{"number":1,"title":"A"}] [{"number":1,"title":"A"}]`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Import([]byte(tt.data), "Repo")
+			if tt.err {
+				assert.ErrorIs(t, err, ErrInvalidDump)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, doc.Plan.Items, tt.count)
+		})
+	}
+}
+
+func TestStatusLabels(t *testing.T) {
+	for _, s := range []core.PlanItemStatus{core.PlanItemStatusRunning, core.PlanItemStatusInProgress} {
+		assert.Equal(t, []string{LabelInProgress}, statusLabels(&core.PlanItem{Status: s}), s)
+	}
+	assert.Equal(t, core.PlanItemStatusRunning, issueStatus(issue{State: "open", Labels: []label{{Name: "In Progress"}}}))
+}
@@ -0,0 +1,302 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+var (
+	taskRe      = regexp.MustCompile(`^(\s*)[-*+] \[([ xX])\] (.+)$`)
+	blockedByRe = regexp.MustCompile(`(?i)^\s*blocked by:?\s+(.*)$`)
+	issueRefRe  = regexp.MustCompile(`#(\d+)\b`)
+	sectionRe   = regexp.MustCompile(`^###\s+(.+?)\s*$`)
+)
+
+// Import builds a Plan document titled title from a saved issues dump, as
+// written by `gh api repos/{owner}/{repo}/issues`. Paginated dumps, which
+// are several JSON arrays back to back (--paginate) or an array of arrays
+// (--slurp), are accepted; pull requests are skipped.
+//
+// Milestones become top-level items holding their issues; issues without a
+// milestone are top-level items. Items are ordered by milestone and issue
+// number and get the IDs "milestone-N" and "issue-N". An issue body's task
+// list becomes subItems, "### Heading" sections become narratives of that
+// name, the rest becomes the Overview narrative, and each "#N" on a
+// "Blocked by" line becomes a blocks edge from issue N.
+func Import(data []byte, title string) (*core.Document, error) {
+	issues, err := decodeDump(data)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Number < issues[j].Number })
+	if title == "" {
+		title = "GitHub issues"
+	}
+
+	type entry struct {
+		item      core.PlanItem
+		milestone int
+		blockedBy []int
+	}
+	var entries []*entry
+	byNumber := make(map[int]*entry)
+	milestones := make(map[int]*milestone)
+	for _, is := range issues {
+		if byNumber[is.Number] != nil {
+			return nil, fmt.Errorf("%w: duplicate issue #%d", ErrInvalidDump, is.Number)
+		}
+		e := &entry{item: issueItem(is)}
+		e.item.Narrative, e.item.SubItems, e.blockedBy = parseBody(is.Body)
+		if is.Milestone != nil {
+			e.milestone = is.Milestone.Number
+			milestones[is.Milestone.Number] = is.Milestone
+		}
+		entries = append(entries, e)
+		byNumber[is.Number] = e
+	}
+
+	plan := &core.Plan{
+		Title:      title,
+		Status:     core.PlanStatusDraft,
+		Narratives: make(map[string]string),
+	}
+	fullID := make(map[int]string)
+	numbers := make([]int, 0, len(milestones))
+	for n := range milestones {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	for _, n := range numbers {
+		m := milestones[n]
+		item := milestoneItem(m)
+		for _, e := range entries {
+			if e.milestone == n {
+				fullID[issueNumber(e.item)] = graph.JoinID(item.ID, e.item.ID)
+				item.SubItems = append(item.SubItems, e.item)
+			}
+		}
+		if m.State != StateClosed {
+			item.Status = aggregateStatus(item.SubItems)
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	for _, e := range entries {
+		if e.milestone == 0 {
+			fullID[issueNumber(e.item)] = e.item.ID
+			plan.Items = append(plan.Items, e.item)
+		}
+	}
+
+	for _, e := range entries {
+		to := fullID[issueNumber(e.item)]
+		for _, n := range e.blockedBy {
+			if from, ok := fullID[n]; ok && from != to {
+				plan.Edges = append(plan.Edges, core.Edge{From: from, To: to, Type: core.EdgeBlocks})
+			}
+		}
+	}
+
+	return &core.Document{
+		Info: core.Info{Version: core.SpecVersion},
+		Plan: plan,
+	}, nil
+}
+
+// decodeDump reads every issue from a sequence of JSON values, each an
+// issue, an array of issues or an array of such arrays.
+func decodeDump(data []byte) ([]issue, error) {
+	var issues []issue
+	var collect func(raw json.RawMessage) error
+	collect = func(raw json.RawMessage) error {
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '[' {
+			var list []json.RawMessage
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return err
+			}
+			for _, r := range list {
+				if err := collect(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		var is issue
+		if err := json.Unmarshal(raw, &is); err != nil {
+			return err
+		}
+		if is.Number <= 0 {
+			return fmt.Errorf("issue without a number")
+		}
+		if is.PullRequest == nil {
+			issues = append(issues, is)
+		}
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDump, err)
+		}
+		if err := collect(raw); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDump, err)
+		}
+	}
+	return issues, nil
+}
+
+func issueItem(is issue) core.PlanItem {
+	item := core.PlanItem{
+		ID:        "issue-" + strconv.Itoa(is.Number),
+		Title:     is.Title,
+		Status:    issueStatus(is),
+		Created:   is.CreatedAt,
+		Updated:   is.UpdatedAt,
+		Completed: is.ClosedAt,
+		Metadata:  map[string]interface{}{MetadataIssue: is.Number},
+	}
+	if item.Status != core.PlanItemStatusCompleted {
+		item.Completed = nil
+	}
+	if is.HTMLURL != "" {
+		item.Metadata[MetadataURL] = is.HTMLURL
+	}
+	for _, l := range is.Labels {
+		name := strings.ToLower(l.Name)
+		switch {
+		case name == LabelBlocked || name == LabelInProgress || name == "in-progress":
+		case strings.HasPrefix(name, LabelPriorityPrefix) && core.Priority(strings.TrimPrefix(name, LabelPriorityPrefix)).IsValid():
+			item.Priority = core.Priority(strings.TrimPrefix(name, LabelPriorityPrefix))
+		default:
+			item.Tags = append(item.Tags, l.Name)
+		}
+	}
+	if len(is.Assignees) > 0 {
+		logins := make([]interface{}, len(is.Assignees))
+		for i, a := range is.Assignees {
+			logins[i] = a.Login
+		}
+		item.Metadata[MetadataAssignees] = logins
+	}
+	return item
+}
+
+func milestoneItem(m *milestone) core.PlanItem {
+	item := core.PlanItem{
+		ID:       "milestone-" + strconv.Itoa(m.Number),
+		Title:    m.Title,
+		Status:   core.PlanItemStatusCompleted,
+		DueDate:  m.DueOn,
+		Metadata: map[string]interface{}{MetadataMilestone: m.Number},
+	}
+	if m.Description != "" {
+		item.Narrative = map[string]string{"Overview": m.Description}
+	}
+	if m.HTMLURL != "" {
+		item.Metadata[MetadataURL] = m.HTMLURL
+	}
+	return item
+}
+
+func issueNumber(item core.PlanItem) int {
+	n, _ := item.Metadata[MetadataIssue].(int)
+	return n
+}
+
+// aggregateStatus is the status of an open milestone: running once any of
+// its issues has been started or finished, pending otherwise.
+func aggregateStatus(items []core.PlanItem) core.PlanItemStatus {
+	for _, it := range items {
+		switch it.Status {
+		case core.PlanItemStatusRunning, core.PlanItemStatusInProgress, core.PlanItemStatusCompleted:
+			return core.PlanItemStatusRunning
+		}
+	}
+	return core.PlanItemStatusPending
+}
+
+// parseBody splits an issue body into narratives, task-list sub-items and
+// the numbers of blocking issues.
+func parseBody(body string) (map[string]string, []core.PlanItem, []int) {
+	narrative := make(map[string]string)
+	var blockedBy []int
+	section := "Overview"
+	var text []string
+	flush := func() {
+		if s := strings.TrimSpace(strings.Join(text, "\n")); s != "" {
+			narrative[section] = s
+		}
+		text = nil
+	}
+
+	// Task items are collected with their indentation and nested afterwards.
+	type task struct {
+		indent int
+		item   core.PlanItem
+	}
+	var tasks []task
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if m := taskRe.FindStringSubmatch(line); m != nil {
+			status := core.PlanItemStatusPending
+			if m[2] != " " {
+				status = core.PlanItemStatusCompleted
+			}
+			tasks = append(tasks, task{indent: len(m[1]), item: core.PlanItem{Title: strings.TrimSpace(m[3]), Status: status}})
+			continue
+		}
+		if m := blockedByRe.FindStringSubmatch(line); m != nil {
+			for _, ref := range issueRefRe.FindAllStringSubmatch(m[1], -1) {
+				n, _ := strconv.Atoi(ref[1])
+				blockedBy = append(blockedBy, n)
+			}
+			continue
+		}
+		if m := sectionRe.FindStringSubmatch(line); m != nil {
+			flush()
+			section = m[1]
+			continue
+		}
+		text = append(text, line)
+	}
+	flush()
+	if len(narrative) == 0 {
+		narrative = nil
+	}
+
+	var nest func(i, indent int) ([]core.PlanItem, int)
+	nest = func(i, indent int) ([]core.PlanItem, int) {
+		var items []core.PlanItem
+		for i < len(tasks) && tasks[i].indent >= indent {
+			item := tasks[i].item
+			next := i + 1
+			if next < len(tasks) && tasks[next].indent > tasks[i].indent {
+				item.SubItems, next = nest(next, tasks[next].indent)
+			}
+			items = append(items, item)
+			i = next
+		}
+		return items, i
+	}
+	var subItems []core.PlanItem
+	for i := 0; i < len(tasks); {
+		var items []core.PlanItem
+		items, i = nest(i, tasks[i].indent)
+		subItems = append(subItems, items...)
+	}
+	return narrative, subItems, blockedBy
+}
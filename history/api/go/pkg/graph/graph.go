@@ -0,0 +1,334 @@
+// Package graph builds dependency graphs over Plan items and edges.
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+var (
+	// ErrCycle is returned when plan edges do not form a DAG.
+	ErrCycle = errors.New("cycle detected")
+)
+
+// Node is a plan item placed in the graph.
+type Node struct {
+	// ID is the item's hierarchical ID, or "" if the item (or one of its
+	// ancestors) has no ID and therefore cannot be referenced by edges.
+	ID       string
+	Item     *core.PlanItem
+	Parent   *Node
+	Children []*Node
+	// Path is the item's position in the plan, e.g. "plan.items[0].subItems[1]".
+	Path string
+}
+
+// IsLeaf returns true if the node has no sub-items.
+func (n *Node) IsLeaf() bool {
+	return len(n.Children) == 0
+}
+
+// Graph is a read-only view over a plan's item tree and edges.
+//
+// The graph holds pointers into the plan; it does not copy items. Rebuild
+// the graph after structural changes to the plan.
+type Graph struct {
+	nodes    []*Node
+	roots    []*Node
+	byID     map[string]*Node
+	edges    []core.Edge
+	dangling []core.Edge
+	out      map[string][]core.Edge
+	in       map[string][]core.Edge
+}
+
+// New builds a graph from a plan.
+func New(plan *core.Plan) *Graph {
+	g := &Graph{
+		byID: make(map[string]*Node),
+		out:  make(map[string][]core.Edge),
+		in:   make(map[string][]core.Edge),
+	}
+	if plan == nil {
+		return g
+	}
+
+	for i := range plan.Items {
+		g.roots = append(g.roots, g.add(&plan.Items[i], nil, fmt.Sprintf("plan.items[%d]", i)))
+	}
+
+	for _, e := range plan.Edges {
+		if g.byID[e.From] == nil || g.byID[e.To] == nil {
+			g.dangling = append(g.dangling, e)
+			continue
+		}
+		g.edges = append(g.edges, e)
+		g.out[e.From] = append(g.out[e.From], e)
+		g.in[e.To] = append(g.in[e.To], e)
+	}
+	return g
+}
+
+func (g *Graph) add(item *core.PlanItem, parent *Node, path string) *Node {
+	n := &Node{Item: item, Parent: parent, Path: path}
+	if item.ID != "" && (parent == nil || parent.ID != "") {
+		n.ID = JoinID(parentID(parent), item.ID)
+		if _, exists := g.byID[n.ID]; !exists {
+			g.byID[n.ID] = n
+		}
+	}
+	g.nodes = append(g.nodes, n)
+	for i := range item.SubItems {
+		n.Children = append(n.Children, g.add(&item.SubItems[i], n, fmt.Sprintf("%s.subItems[%d]", path, i)))
+	}
+	return n
+}
+
+func parentID(n *Node) string {
+	if n == nil {
+		return ""
+	}
+	return n.ID
+}
+
+// JoinID joins a parent hierarchical ID and a child's local ID.
+func JoinID(parent, id string) string {
+	if parent == "" {
+		return id
+	}
+	return parent + "." + id
+}
+
+// SplitID splits a hierarchical ID into its parent ID and local ID.
+func SplitID(id string) (parent, local string) {
+	if i := strings.LastIndexByte(id, '.'); i >= 0 {
+		return id[:i], id[i+1:]
+	}
+	return "", id
+}
+
+// Nodes returns all nodes in document (pre-order) order.
+func (g *Graph) Nodes() []*Node {
+	return g.nodes
+}
+
+// Roots returns the nodes for the plan's top-level items.
+func (g *Graph) Roots() []*Node {
+	return g.roots
+}
+
+// Node returns the node with the given hierarchical ID, or nil.
+func (g *Graph) Node(id string) *Node {
+	return g.byID[id]
+}
+
+// Edges returns edges whose endpoints both resolve to items.
+func (g *Graph) Edges() []core.Edge {
+	return g.edges
+}
+
+// Dangling returns edges that reference unknown item IDs.
+func (g *Graph) Dangling() []core.Edge {
+	return g.dangling
+}
+
+// Outgoing returns resolved edges whose source is id.
+func (g *Graph) Outgoing(id string) []core.Edge {
+	return g.out[id]
+}
+
+// Incoming returns resolved edges whose target is id.
+func (g *Graph) Incoming(id string) []core.Edge {
+	return g.in[id]
+}
+
+// Cycle returns the IDs along one cycle formed by the plan's blocks edges,
+// with the first ID repeated at the end, or nil if they form a DAG. Other
+// edge types do not order work, so loops through them are not cycles.
+func (g *Graph) Cycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(g.byID))
+	var stack []string
+	var found []string
+
+	var visit func(id string) bool
+	visit = func(id string) bool {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, e := range g.out[id] {
+			if e.Type != core.EdgeBlocks {
+				continue
+			}
+			switch state[e.To] {
+			case visiting:
+				for i, s := range stack {
+					if s == e.To {
+						found = append(append([]string{}, stack[i:]...), e.To)
+						break
+					}
+				}
+				return true
+			case unvisited:
+				if visit(e.To) {
+					return true
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = visited
+		return false
+	}
+
+	for _, n := range g.nodes {
+		if n.ID != "" && state[n.ID] == unvisited && visit(n.ID) {
+			return found
+		}
+	}
+	return nil
+}
+
+// TopoSort returns addressable nodes ordered so that every blocks edge's
+// source precedes its target. Ties are broken by document order. It
+// returns ErrCycle if the blocks edges do not form a DAG.
+func (g *Graph) TopoSort() ([]*Node, error) {
+	indegree := make(map[string]int, len(g.byID))
+	for _, e := range g.edges {
+		if e.Type == core.EdgeBlocks {
+			indegree[e.To]++
+		}
+	}
+
+	var queue, order []*Node
+	for _, n := range g.nodes {
+		if n.ID != "" && g.byID[n.ID] == n && indegree[n.ID] == 0 {
+			queue = append(queue, n)
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		order = append(order, n)
+		for _, e := range g.out[n.ID] {
+			if e.Type != core.EdgeBlocks {
+				continue
+			}
+			indegree[e.To]--
+			if indegree[e.To] == 0 {
+				queue = append(queue, g.byID[e.To])
+			}
+		}
+	}
+
+	if len(order) != len(g.byID) {
+		return nil, fmt.Errorf("%w: %s", ErrCycle, strings.Join(g.Cycle(), " -> "))
+	}
+	return order, nil
+}
+
+// Done reports whether a node's work is finished: the item is completed, or
+// it has sub-items and all of them are done.
+func Done(n *Node) bool {
+	if n.Item.Status == core.PlanItemStatusCompleted {
+		return true
+	}
+	if n.IsLeaf() {
+		return false
+	}
+	for _, c := range n.Children {
+		if !Done(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Blockers returns the nodes that must be done before n can start: sources
+// of blocks edges targeting n or any of its ancestors.
+func (g *Graph) Blockers(n *Node) []*Node {
+	var out []*Node
+	for cur := n; cur != nil; cur = cur.Parent {
+		if cur.ID == "" {
+			continue
+		}
+		for _, e := range g.in[cur.ID] {
+			if e.Type == core.EdgeBlocks {
+				out = append(out, g.byID[e.From])
+			}
+		}
+	}
+	return out
+}
+
+// Ready returns pending leaf items whose blockers are all done, in document
+// order. Parent items are containers and are never reported as ready.
+func (g *Graph) Ready() []*Node {
+	var ready []*Node
+	for _, n := range g.nodes {
+		if !n.IsLeaf() || n.Item.Status != core.PlanItemStatusPending {
+			continue
+		}
+		if g.unblocked(n) {
+			ready = append(ready, n)
+		}
+	}
+	return ready
+}
+
+func (g *Graph) unblocked(n *Node) bool {
+	for _, b := range g.Blockers(n) {
+		if !Done(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// CriticalPath returns the heaviest chain of nodes connected by blocks edges.
+// weight assigns each node its cost; a nil weight counts every node as 1.
+// It returns ErrCycle if the blocks edges do not form a DAG.
+func (g *Graph) CriticalPath(weight func(*Node) float64) ([]*Node, error) {
+	if weight == nil {
+		weight = func(*Node) float64 { return 1 }
+	}
+	order, err := g.TopoSort()
+	if err != nil {
+		return nil, err
+	}
+
+	dist := make(map[string]float64, len(order))
+	prev := make(map[string]string, len(order))
+	var best *Node
+	for _, n := range order {
+		w := weight(n)
+		d := w
+		for _, e := range g.in[n.ID] {
+			if e.Type != core.EdgeBlocks {
+				continue
+			}
+			if cand := dist[e.From] + w; cand > d {
+				d = cand
+				prev[n.ID] = e.From
+			}
+		}
+		dist[n.ID] = d
+		if best == nil || d > dist[best.ID] {
+			best = n
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	var path []*Node
+	for id := best.ID; id != ""; id = prev[id] {
+		path = append([]*Node{g.byID[id]}, path...)
+	}
+	return path, nil
+}
@@ -0,0 +1,178 @@
+package graph
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+func pipelinePlan() *core.Plan {
+	return &core.Plan{
+		Title:  "Pipeline",
+		Status: core.PlanStatusInProgress,
+		Items: []core.PlanItem{
+			{ID: "lint", Title: "Lint", Status: core.PlanItemStatusCompleted},
+			{ID: "test", Title: "Test", Status: core.PlanItemStatusPending},
+			{ID: "build", Title: "Build", Status: core.PlanItemStatusPending, SubItems: []core.PlanItem{
+				{ID: "compile", Title: "Compile", Status: core.PlanItemStatusPending},
+				{ID: "package", Title: "Package", Status: core.PlanItemStatusPending},
+			}},
+			{ID: "deploy", Title: "Deploy", Status: core.PlanItemStatusPending},
+			{Title: "Untracked", Status: core.PlanItemStatusPending},
+		},
+		Edges: []core.Edge{
+			{From: "lint", To: "test", Type: core.EdgeBlocks},
+			{From: "test", To: "build", Type: core.EdgeBlocks},
+			{From: "build.compile", To: "build.package", Type: core.EdgeBlocks},
+			{From: "build", To: "deploy", Type: core.EdgeBlocks},
+			{From: "lint", To: "deploy", Type: core.EdgeInforms},
+			{From: "lint", To: "missing", Type: core.EdgeBlocks},
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	g := New(pipelinePlan())
+
+	t.Run("resolves hierarchical IDs", func(t *testing.T) {
+		n := g.Node("build.package")
+		require.NotNil(t, n)
+		assert.Equal(t, "Package", n.Item.Title)
+		assert.Equal(t, "build", n.Parent.ID)
+		assert.Equal(t, "plan.items[2].subItems[1]", n.Path)
+	})
+
+	t.Run("keeps items without IDs", func(t *testing.T) {
+		assert.Len(t, g.Nodes(), 7)
+		assert.Len(t, g.Roots(), 5)
+		assert.Empty(t, g.Roots()[4].ID)
+	})
+
+	t.Run("separates dangling edges", func(t *testing.T) {
+		assert.Len(t, g.Edges(), 5)
+		require.Len(t, g.Dangling(), 1)
+		assert.Equal(t, "missing", g.Dangling()[0].To)
+		assert.Len(t, g.Outgoing("lint"), 2)
+		assert.Len(t, g.Incoming("deploy"), 2)
+	})
+
+	t.Run("handles nil plan", func(t *testing.T) {
+		assert.Empty(t, New(nil).Nodes())
+	})
+}
+
+func TestIDHelpers(t *testing.T) {
+	assert.Equal(t, "a", JoinID("", "a"))
+	assert.Equal(t, "a.b", JoinID("a", "b"))
+
+	parent, local := SplitID("a.b.c")
+	assert.Equal(t, "a.b", parent)
+	assert.Equal(t, "c", local)
+
+	parent, local = SplitID("a")
+	assert.Empty(t, parent)
+	assert.Equal(t, "a", local)
+}
+
+func TestGraph_Cycle(t *testing.T) {
+	t.Run("acyclic plan has no cycle", func(t *testing.T) {
+		g := New(pipelinePlan())
+		assert.Nil(t, g.Cycle())
+		order, err := g.TopoSort()
+		require.NoError(t, err)
+		assert.Equal(t, "lint", order[0].ID)
+	})
+
+	t.Run("reports cycle path", func(t *testing.T) {
+		plan := pipelinePlan()
+		plan.Edges = append(plan.Edges, core.Edge{From: "deploy", To: "lint", Type: core.EdgeBlocks})
+		g := New(plan)
+
+		cycle := g.Cycle()
+		require.NotEmpty(t, cycle)
+		assert.Equal(t, cycle[0], cycle[len(cycle)-1])
+
+		_, err := g.TopoSort()
+		assert.True(t, errors.Is(err, ErrCycle))
+	})
+
+	t.Run("only blocks edges order work", func(t *testing.T) {
+		plan := pipelinePlan()
+		plan.Edges = append(plan.Edges,
+			core.Edge{From: "deploy", To: "lint", Type: core.EdgeInforms},
+			core.Edge{From: "build", To: "test", Type: core.EdgeSuggests},
+		)
+		g := New(plan)
+		assert.Nil(t, g.Cycle())
+
+		order, err := g.TopoSort()
+		require.NoError(t, err)
+		assert.Equal(t, "lint", order[0].ID)
+
+		path, err := g.CriticalPath(nil)
+		require.NoError(t, err)
+		assert.Len(t, path, 4)
+	})
+}
+
+func TestGraph_Ready(t *testing.T) {
+	t.Run("returns unblocked pending leaves", func(t *testing.T) {
+		g := New(pipelinePlan())
+		var ids []string
+		for _, n := range g.Ready() {
+			ids = append(ids, n.Path)
+		}
+		assert.Equal(t, []string{"plan.items[1]", "plan.items[4]"}, ids)
+	})
+
+	t.Run("ancestor blockers apply to children", func(t *testing.T) {
+		plan := pipelinePlan()
+		plan.Items[1].Status = core.PlanItemStatusCompleted
+		g := New(plan)
+
+		var ids []string
+		for _, n := range g.Ready() {
+			ids = append(ids, n.ID)
+		}
+		assert.Contains(t, ids, "build.compile")
+		assert.NotContains(t, ids, "build.package")
+		assert.NotContains(t, ids, "deploy")
+	})
+
+	t.Run("parent is done when all children are done", func(t *testing.T) {
+		plan := pipelinePlan()
+		plan.Items[2].SubItems[0].Status = core.PlanItemStatusCompleted
+		plan.Items[2].SubItems[1].Status = core.PlanItemStatusCompleted
+		g := New(plan)
+		assert.True(t, Done(g.Node("build")))
+		assert.False(t, Done(g.Node("test")))
+	})
+}
+
+func TestGraph_CriticalPath(t *testing.T) {
+	g := New(pipelinePlan())
+
+	t.Run("uses unit weights by default", func(t *testing.T) {
+		path, err := g.CriticalPath(nil)
+		require.NoError(t, err)
+		var ids []string
+		for _, n := range path {
+			ids = append(ids, n.ID)
+		}
+		assert.Equal(t, []string{"lint", "test", "build", "deploy"}, ids)
+	})
+
+	t.Run("honours custom weights", func(t *testing.T) {
+		path, err := g.CriticalPath(func(n *Node) float64 {
+			if n.ID == "build.compile" {
+				return 10
+			}
+			return 1
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "build.package", path[len(path)-1].ID)
+	})
+}
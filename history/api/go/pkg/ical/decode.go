@@ -0,0 +1,431 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+// Decode reads the first VCALENDAR in data into a Plan document.
+//
+// VTODO and VEVENT components become plan items, nested by
+// RELATED-TO;RELTYPE=PARENT and linked by blocks edges for
+// RELATED-TO;RELTYPE=DEPENDS-ON. Edges are only created between items that
+// have hierarchical IDs (from X-VBRIEF-ID). Other components, unknown
+// properties and RRULE frequencies vBRIEF cannot express are ignored.
+func Decode(data []byte) (*core.Document, error) {
+	cal, err := parseCalendar(data)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &core.Plan{
+		Title:      "Calendar",
+		Status:     core.PlanStatusDraft,
+		Narratives: make(map[string]string),
+	}
+	if p, ok := cal.get("X-WR-CALNAME"); ok {
+		plan.Title = unescapeText(p.Value)
+	}
+	if p, ok := cal.get("X-WR-CALDESC"); ok {
+		plan.Narratives["Overview"] = unescapeText(p.Value)
+	}
+
+	type entry struct {
+		comp     *component
+		item     core.PlanItem
+		uid      string
+		parent   string
+		children []*entry
+	}
+	var entries []*entry
+	byUID := make(map[string]*entry)
+	for i, c := range cal.Components {
+		if c.Name != "VTODO" && c.Name != "VEVENT" {
+			continue
+		}
+		item, err := decodeItem(c)
+		if err != nil {
+			return nil, fmt.Errorf("%s %d: %w", c.Name, i, err)
+		}
+		e := &entry{comp: c, item: item, uid: item.UID}
+		if e.uid == "" {
+			e.uid = fmt.Sprintf("\x00%d", i)
+		}
+		for _, rel := range c.all("RELATED-TO") {
+			if t := rel.Params["RELTYPE"]; t == "" || strings.EqualFold(t, "PARENT") {
+				e.parent = rel.Value
+			}
+		}
+		entries = append(entries, e)
+		if _, dup := byUID[e.uid]; !dup {
+			byUID[e.uid] = e
+		}
+	}
+
+	var roots []*entry
+	for _, e := range entries {
+		if p := byUID[e.parent]; p != nil && p != e {
+			p.children = append(p.children, e)
+		} else {
+			roots = append(roots, e)
+		}
+	}
+
+	// Build the item tree, recording each entry's hierarchical ID. Entries
+	// caught in a parent cycle are never reached from a root; they are
+	// promoted to top-level items.
+	fullIDs := make(map[string]string)
+	done := make(map[*entry]bool)
+	var build func(e *entry, parentID string, addressable bool) core.PlanItem
+	build = func(e *entry, parentID string, addressable bool) core.PlanItem {
+		done[e] = true
+		item := e.item
+		id := ""
+		if addressable && item.ID != "" {
+			id = graph.JoinID(parentID, item.ID)
+			fullIDs[e.uid] = id
+		}
+		for _, c := range e.children {
+			if !done[c] {
+				item.SubItems = append(item.SubItems, build(c, id, id != ""))
+			}
+		}
+		return item
+	}
+	for _, e := range roots {
+		plan.Items = append(plan.Items, build(e, "", true))
+	}
+	for _, e := range entries {
+		if !done[e] {
+			plan.Items = append(plan.Items, build(e, "", true))
+		}
+	}
+
+	for _, e := range entries {
+		to, ok := fullIDs[e.uid]
+		if !ok {
+			continue
+		}
+		for _, rel := range e.comp.all("RELATED-TO") {
+			if !strings.EqualFold(rel.Params["RELTYPE"], "DEPENDS-ON") {
+				continue
+			}
+			if from, ok := fullIDs[rel.Value]; ok {
+				plan.Edges = append(plan.Edges, core.Edge{From: from, To: to, Type: core.EdgeBlocks})
+			}
+		}
+	}
+
+	return &core.Document{
+		Info: core.Info{Version: core.SpecVersion},
+		Plan: plan,
+	}, nil
+}
+
+// decodeItem maps a VTODO or VEVENT onto a PlanItem, without sub-items.
+func decodeItem(c *component) (core.PlanItem, error) {
+	item := core.PlanItem{Status: core.PlanItemStatusPending}
+	var err error
+	for _, p := range c.Props {
+		switch p.Name {
+		case "UID":
+			item.UID = p.Value
+		case "SUMMARY":
+			item.Title = unescapeText(p.Value)
+		case "DESCRIPTION":
+			item.Narrative = map[string]string{"Overview": unescapeText(p.Value)}
+		case "STATUS":
+			item.Status = statusFromICal(p.Value)
+		case "PRIORITY":
+			n, convErr := strconv.Atoi(p.Value)
+			if convErr != nil {
+				return item, fmt.Errorf("%w: PRIORITY %q", ErrSyntax, p.Value)
+			}
+			item.Priority = priorityFromICal(n)
+		case "PERCENT-COMPLETE":
+			f, convErr := strconv.ParseFloat(p.Value, 64)
+			if convErr != nil {
+				return item, fmt.Errorf("%w: PERCENT-COMPLETE %q", ErrSyntax, p.Value)
+			}
+			item.PercentComplete = &f
+		case "CATEGORIES":
+			item.Tags = append(item.Tags, splitList(p.Value)...)
+		case "DTSTART":
+			item.StartDate, err = parseTimeProp(p)
+		case "DTEND":
+			item.EndDate, err = parseTimeProp(p)
+		case "DUE":
+			item.DueDate, err = parseTimeProp(p)
+		case "COMPLETED":
+			item.Completed, err = parseTimeProp(p)
+		case "RRULE":
+			item.Recurrence, err = parseRRule(p.Value)
+		}
+		if err != nil {
+			return item, err
+		}
+	}
+	// X-VBRIEF-* properties take precedence regardless of order.
+	if p, ok := c.get(propStatus); ok {
+		if s := core.PlanItemStatus(unescapeText(p.Value)); s.IsValid() {
+			item.Status = s
+		}
+	}
+	if p, ok := c.get(propID); ok {
+		item.ID = unescapeText(p.Value)
+	}
+	for _, a := range c.Components {
+		if a.Name == "VALARM" {
+			item.Reminders = append(item.Reminders, decodeAlarm(a, c.Name, &item))
+		}
+	}
+	return item, nil
+}
+
+// decodeAlarm reads a VALARM of a decoded item. A relative TRIGGER counts
+// from DTSTART, or with RELATED=END from DUE (DTEND for a VEVENT); unless
+// that is the item's due date, it is resolved to a timestamp.
+func decodeAlarm(a *component, name string, item *core.PlanItem) core.Reminder {
+	var r core.Reminder
+	if p, ok := a.get("ACTION"); ok {
+		if strings.EqualFold(p.Value, actionWebhook) {
+			r.Action = core.ReminderWebhook
+		} else {
+			r.Action = core.ReminderAction(strings.ToLower(p.Value))
+		}
+	}
+	if p, ok := a.get("TRIGGER"); ok {
+		r.Trigger = p.Value
+		if t, err := parseTimeProp(p); err == nil && strings.EqualFold(p.Params["VALUE"], "DATE-TIME") {
+			r.Trigger = t.Format(time.RFC3339)
+		} else if base := triggerBase(p, name, item); base != nil {
+			if d, err := parseDuration(p.Value); err == nil {
+				r.Trigger = base.Add(d).Format(time.RFC3339)
+			}
+		}
+	}
+	if p, ok := a.get("DESCRIPTION"); ok {
+		r.Description = unescapeText(p.Value)
+	}
+	return r
+}
+
+// triggerBase returns the time a relative TRIGGER counts from, or nil when
+// that is the item's due date, or is unknown, and the trigger is kept as is.
+func triggerBase(p property, name string, item *core.PlanItem) *time.Time {
+	if strings.EqualFold(p.Params["RELATED"], "END") {
+		if name == "VTODO" {
+			return nil
+		}
+		return item.EndDate
+	}
+	if item.DueDate == nil {
+		// Without a due date a start-relative trigger is kept, as Encode
+		// writes one for items that have no due date.
+		return nil
+	}
+	return item.StartDate
+}
+
+var durationRe = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration parses an RFC 5545 duration such as "-PT15M" or "P1DT2H".
+func parseDuration(v string) (time.Duration, error) {
+	m := durationRe.FindStringSubmatch(v)
+	if m == nil || strings.HasSuffix(v, "P") || strings.HasSuffix(v, "T") {
+		return 0, fmt.Errorf("%w: duration %q", ErrSyntax, v)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+2] != "" {
+			n, _ := strconv.Atoi(m[i+2])
+			d += time.Duration(n) * unit
+		}
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+// parseTimeProp parses a DATE or DATE-TIME property value. Values with a
+// TZID are interpreted in that zone when it is known; floating times are
+// treated as UTC.
+func parseTimeProp(p property) (*time.Time, error) {
+	loc := time.UTC
+	if tzid := p.Params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	for _, layout := range []string{dateTimeUTC, dateTime, date} {
+		if t, err := time.ParseInLocation(layout, p.Value, loc); err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s %q is not a date or date-time", ErrSyntax, p.Name, p.Value)
+}
+
+// parseRRule parses an RRULE value. It returns nil for frequencies vBRIEF
+// cannot express, such as HOURLY.
+func parseRRule(v string) (*core.RecurrenceRule, error) {
+	r := &core.RecurrenceRule{}
+	for _, part := range strings.Split(v, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: RRULE part %q", ErrSyntax, part)
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Frequency = core.Frequency(strings.ToLower(val))
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(val)
+		case "COUNT":
+			r.Count, err = strconv.Atoi(val)
+		case "UNTIL":
+			r.Until, err = parseTimeProp(property{Name: "UNTIL", Value: val})
+		case "BYDAY":
+			r.ByDay = strings.Split(val, ",")
+		case "BYMONTH":
+			r.ByMonth, err = splitInts(val)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = splitInts(val)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: RRULE %s=%q", ErrSyntax, key, val)
+		}
+	}
+	switch r.Frequency {
+	case core.FrequencyDaily, core.FrequencyWeekly, core.FrequencyMonthly, core.FrequencyYearly:
+		return r, nil
+	default:
+		return nil, nil
+	}
+}
+
+func splitInts(s string) ([]int, error) {
+	var ns []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, n)
+	}
+	return ns, nil
+}
+
+// parseCalendar unfolds and parses content lines, returning the first
+// VCALENDAR component.
+func parseCalendar(data []byte) (*component, error) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var stack []*component
+	for i, line := range lines {
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d", err, i+1)
+		}
+		switch p.Name {
+		case "BEGIN":
+			c := &component{Name: strings.ToUpper(p.Value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, c)
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(p.Value) {
+				return nil, fmt.Errorf("%w: line %d: unexpected END:%s", ErrSyntax, i+1, p.Value)
+			}
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 && c.Name == "VCALENDAR" {
+				return c, nil
+			}
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("%w: line %d: property outside a component", ErrSyntax, i+1)
+			}
+			top := stack[len(stack)-1]
+			top.Props = append(top.Props, p)
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("%w: unterminated %s", ErrSyntax, stack[len(stack)-1].Name)
+	}
+	return nil, ErrNoCalendar
+}
+
+// parseLine splits an unfolded content line into name, parameters and value.
+func parseLine(line string) (property, error) {
+	var p property
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return p, fmt.Errorf("%w: %q", ErrSyntax, line)
+	}
+	p.Name = strings.ToUpper(line[:i])
+	for line[i] == ';' {
+		line = line[i+1:]
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return p, fmt.Errorf("%w: bad parameter in %s", ErrSyntax, p.Name)
+		}
+		key := strings.ToUpper(line[:eq])
+		line = line[eq+1:]
+		var val string
+		if strings.HasPrefix(line, `"`) {
+			end := strings.IndexByte(line[1:], '"')
+			if end < 0 {
+				return p, fmt.Errorf("%w: unterminated quote in %s", ErrSyntax, p.Name)
+			}
+			val, line = line[1:end+1], line[end+2:]
+			i = 0
+		} else {
+			i = strings.IndexAny(line, ";:")
+			if i < 0 {
+				return p, fmt.Errorf("%w: missing value in %s", ErrSyntax, p.Name)
+			}
+			val = line[:i]
+			line = line[i:]
+			i = 0
+		}
+		if p.Params == nil {
+			p.Params = make(map[string]string)
+		}
+		p.Params[key] = val
+		if len(line) == 0 {
+			return p, fmt.Errorf("%w: missing value in %s", ErrSyntax, p.Name)
+		}
+	}
+	if line[i] != ':' {
+		return p, fmt.Errorf("%w: %q", ErrSyntax, line)
+	}
+	p.Value = line[i+1:]
+	return p, nil
+}
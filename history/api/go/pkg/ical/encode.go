@@ -0,0 +1,230 @@
+package ical
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+// Options controls encoding.
+type Options struct {
+	// Now is written as each component's DTSTAMP. The zero value means the
+	// current time.
+	Now time.Time
+}
+
+var uidUnsafeRe = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Encode writes a document's plan as an iCalendar VCALENDAR.
+//
+// Each plan item becomes a VTODO (or a VEVENT, see the package comment)
+// carrying:
+//
+//	title → SUMMARY                     narrative "Overview" → DESCRIPTION
+//	status → STATUS                     priority → PRIORITY
+//	percentComplete → PERCENT-COMPLETE  tags → CATEGORIES
+//	startDate → DTSTART                 dueDate → DUE, endDate → DTEND
+//	completed → COMPLETED               recurrence → RRULE
+//	reminders → VALARM                  uid → UID
+//
+// Items without a uid get a stable one derived from their hierarchical ID
+// and the plan title.
+func Encode(doc *core.Document, opts Options) ([]byte, error) {
+	if doc == nil || doc.Plan == nil {
+		return nil, core.ErrNoPlan
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	plan := doc.Plan
+	g := graph.New(plan)
+	uids := make(map[*graph.Node]string, len(g.Nodes()))
+	domain := strings.Trim(uidUnsafeRe.ReplaceAllString(strings.ToLower(plan.Title), "-"), "-")
+	if domain == "" {
+		domain = "plan"
+	}
+	for _, n := range g.Nodes() {
+		switch {
+		case n.Item.UID != "":
+			uids[n] = n.Item.UID
+		case n.ID != "" && g.Node(n.ID) == n:
+			uids[n] = n.ID + "@" + domain + ".vbrief"
+		default:
+			uids[n] = uidUnsafeRe.ReplaceAllString(n.Path, "-") + "@" + domain + ".vbrief"
+		}
+	}
+
+	cal := &component{Name: "VCALENDAR"}
+	cal.add("VERSION", "2.0")
+	cal.add("PRODID", ProdID)
+	cal.add("CALSCALE", "GREGORIAN")
+	if plan.Title != "" {
+		cal.add("X-WR-CALNAME", escapeText(plan.Title))
+	}
+	if overview := plan.Narratives["Overview"]; overview != "" {
+		cal.add("X-WR-CALDESC", escapeText(overview))
+	}
+
+	stamp := now.UTC().Format(dateTimeUTC)
+	for _, n := range g.Nodes() {
+		c := itemComponent(n.Item, uids[n], stamp)
+		if n.Parent != nil {
+			c.addParam("RELATED-TO", "RELTYPE", "PARENT", uids[n.Parent])
+		}
+		if n.ID != "" {
+			for _, e := range g.Incoming(n.ID) {
+				if e.Type == core.EdgeBlocks {
+					c.addParam("RELATED-TO", "RELTYPE", "DEPENDS-ON", uids[g.Node(e.From)])
+				}
+			}
+		}
+		dueRelative := c.Name == "VTODO" && n.Item.DueDate != nil
+		for _, r := range n.Item.Reminders {
+			c.Components = append(c.Components, alarmComponent(r, n.Item.Title, dueRelative))
+		}
+		cal.Components = append(cal.Components, c)
+	}
+
+	var b strings.Builder
+	writeComponent(&b, cal)
+	return []byte(b.String()), nil
+}
+
+// itemComponent builds the VTODO or VEVENT for an item, without relations or
+// alarms.
+func itemComponent(item *core.PlanItem, uid, stamp string) *component {
+	name := "VTODO"
+	if item.StartDate != nil && item.EndDate != nil && item.DueDate == nil {
+		name = "VEVENT"
+	}
+	c := &component{Name: name}
+	c.add("UID", uid)
+	c.add("DTSTAMP", stamp)
+	c.add("SUMMARY", escapeText(item.Title))
+	if overview := item.Narrative["Overview"]; overview != "" {
+		c.add("DESCRIPTION", escapeText(overview))
+	}
+	status, lossless := statusToICal(name, item.Status)
+	c.add("STATUS", status)
+	if !lossless {
+		c.add(propStatus, escapeText(string(item.Status)))
+	}
+	if item.ID != "" {
+		c.add(propID, escapeText(item.ID))
+	}
+	if p := priorityToICal(item.Priority); p != 0 {
+		c.add("PRIORITY", strconv.Itoa(p))
+	}
+	if item.PercentComplete != nil && name == "VTODO" {
+		c.add("PERCENT-COMPLETE", strconv.Itoa(int(item.Progress()+0.5)))
+	}
+	if item.StartDate != nil {
+		c.add("DTSTART", formatTime(*item.StartDate))
+	}
+	if name == "VEVENT" {
+		c.add("DTEND", formatTime(*item.EndDate))
+	}
+	if item.DueDate != nil {
+		c.add("DUE", formatTime(*item.DueDate))
+	}
+	if item.Completed != nil && name == "VTODO" {
+		c.add("COMPLETED", formatTime(*item.Completed))
+	}
+	if len(item.Tags) > 0 {
+		tags := make([]string, len(item.Tags))
+		for i, t := range item.Tags {
+			tags[i] = escapeText(t)
+		}
+		c.add("CATEGORIES", strings.Join(tags, ","))
+	}
+	if item.Recurrence != nil {
+		c.add("RRULE", formatRRule(item.Recurrence))
+	}
+	return c
+}
+
+// alarmComponent builds a VALARM for a reminder. Relative reminders count
+// from the due date, so with dueRelative they are written RELATED=END,
+// which a VTODO resolves against DUE.
+func alarmComponent(r core.Reminder, title string, dueRelative bool) *component {
+	a := &component{Name: "VALARM"}
+	action := strings.ToUpper(string(r.Action))
+	if r.Action == core.ReminderWebhook {
+		action = actionWebhook
+	}
+	a.add("ACTION", action)
+	if t, err := time.Parse(time.RFC3339, r.Trigger); err == nil {
+		a.addParam("TRIGGER", "VALUE", "DATE-TIME", formatTime(t))
+	} else if dueRelative {
+		a.addParam("TRIGGER", "RELATED", "END", r.Trigger)
+	} else {
+		a.add("TRIGGER", r.Trigger)
+	}
+	desc := r.Description
+	if desc == "" {
+		desc = title
+	}
+	a.add("DESCRIPTION", escapeText(desc))
+	if r.Action == core.ReminderEmail {
+		a.add("SUMMARY", escapeText(title))
+	}
+	return a
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(dateTimeUTC)
+}
+
+func formatRRule(r *core.RecurrenceRule) string {
+	parts := []string{"FREQ=" + strings.ToUpper(string(r.Frequency))}
+	if r.Interval > 0 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+formatTime(*r.Until))
+	}
+	if len(r.ByDay) > 0 {
+		parts = append(parts, "BYDAY="+strings.Join(r.ByDay, ","))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
+
+func writeComponent(b *strings.Builder, c *component) {
+	fmt.Fprintf(b, "BEGIN:%s\r\n", c.Name)
+	for _, p := range c.Props {
+		line := p.Name
+		for k, v := range p.Params {
+			line += ";" + k + "=" + v
+		}
+		b.WriteString(foldLine(line + ":" + p.Value))
+		b.WriteString("\r\n")
+	}
+	for _, sub := range c.Components {
+		writeComponent(b, sub)
+	}
+	fmt.Fprintf(b, "END:%s\r\n", c.Name)
+}
@@ -0,0 +1,224 @@
+// Package ical converts vBRIEF plans to and from iCalendar (RFC 5545).
+//
+// Plan items map to VTODO components so deadlines, progress and reminders
+// show up in ordinary calendar and task apps. Items that describe a fixed
+// time span (a startDate and endDate but no dueDate) map to VEVENT instead.
+// Nesting is expressed with RELATED-TO;RELTYPE=PARENT and blocks edges with
+// RELATED-TO;RELTYPE=DEPENDS-ON (RFC 9253).
+//
+// vBRIEF values without an iCalendar equivalent are carried in X-VBRIEF-*
+// properties so that an exported plan can be imported again unchanged.
+package ical
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+var (
+	// ErrSyntax is returned when iCalendar data is malformed.
+	ErrSyntax = errors.New("ical: syntax error")
+	// ErrNoCalendar is returned when the data contains no VCALENDAR.
+	ErrNoCalendar = errors.New("ical: no VCALENDAR component")
+)
+
+const (
+	// ProdID identifies this encoder in exported calendars.
+	ProdID = "-//vBRIEF//vBRIEF Go API//EN"
+
+	propID     = "X-VBRIEF-ID"
+	propStatus = "X-VBRIEF-STATUS"
+
+	actionWebhook = "X-VBRIEF-WEBHOOK"
+
+	dateTimeUTC = "20060102T150405Z"
+	dateTime    = "20060102T150405"
+	date        = "20060102"
+)
+
+// property is a single iCalendar content line.
+type property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// component is a BEGIN/END block and its properties and sub-components.
+type component struct {
+	Name       string
+	Props      []property
+	Components []*component
+}
+
+func (c *component) add(name, value string) {
+	c.Props = append(c.Props, property{Name: name, Value: value})
+}
+
+func (c *component) addParam(name, param, paramValue, value string) {
+	c.Props = append(c.Props, property{Name: name, Params: map[string]string{param: paramValue}, Value: value})
+}
+
+// get returns the first property with the given name.
+func (c *component) get(name string) (property, bool) {
+	for _, p := range c.Props {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return property{}, false
+}
+
+// all returns every property with the given name.
+func (c *component) all(name string) []property {
+	var props []property
+	for _, p := range c.Props {
+		if p.Name == name {
+			props = append(props, p)
+		}
+	}
+	return props
+}
+
+// todoStatuses maps vBRIEF item statuses to VTODO STATUS values.
+var todoStatuses = map[core.PlanItemStatus]string{
+	core.PlanItemStatusPending:    "NEEDS-ACTION",
+	core.PlanItemStatusRunning:    "IN-PROCESS",
+	core.PlanItemStatusInProgress: "IN-PROCESS",
+	core.PlanItemStatusCompleted:  "COMPLETED",
+	core.PlanItemStatusCancelled:  "CANCELLED",
+	core.PlanItemStatusBlocked:    "NEEDS-ACTION",
+}
+
+// statusToICal returns the iCalendar STATUS for a vBRIEF status in the given
+// component, and whether the mapping is lossless.
+func statusToICal(comp string, s core.PlanItemStatus) (string, bool) {
+	if comp == "VEVENT" {
+		if s == core.PlanItemStatusCancelled {
+			return "CANCELLED", true
+		}
+		return "CONFIRMED", s == core.PlanItemStatusPending
+	}
+	v, ok := todoStatuses[s]
+	if !ok {
+		return "NEEDS-ACTION", false
+	}
+	// The legacy inProgress reads back as running, which means the same.
+	return v, statusFromICal(v) == s || s == core.PlanItemStatusInProgress
+}
+
+// statusFromICal returns the vBRIEF status for a VTODO or VEVENT STATUS.
+func statusFromICal(v string) core.PlanItemStatus {
+	switch strings.ToUpper(v) {
+	case "IN-PROCESS":
+		return core.PlanItemStatusRunning
+	case "COMPLETED":
+		return core.PlanItemStatusCompleted
+	case "CANCELLED":
+		return core.PlanItemStatusCancelled
+	default:
+		return core.PlanItemStatusPending
+	}
+}
+
+// priorityToICal maps a vBRIEF priority onto the iCalendar 1 (highest) to 9
+// (lowest) scale. Zero means undefined.
+func priorityToICal(p core.Priority) int {
+	switch p {
+	case core.PriorityCritical:
+		return 1
+	case core.PriorityHigh:
+		return 3
+	case core.PriorityMedium:
+		return 5
+	case core.PriorityLow:
+		return 9
+	default:
+		return 0
+	}
+}
+
+func priorityFromICal(n int) core.Priority {
+	switch {
+	case n == 1:
+		return core.PriorityCritical
+	case n >= 2 && n <= 4:
+		return core.PriorityHigh
+	case n == 5:
+		return core.PriorityMedium
+	case n >= 6 && n <= 9:
+		return core.PriorityLow
+	default:
+		return ""
+	}
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escapeText escapes a TEXT value.
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// unescapeText reverses escapeText.
+func unescapeText(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// splitList splits a comma-separated list of TEXT values, honouring escapes.
+func splitList(s string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, unescapeText(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, unescapeText(s[start:]))
+}
+
+// foldLine splits a content line into 75-octet physical lines without
+// breaking UTF-8 sequences.
+func foldLine(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+	var b strings.Builder
+	max := limit
+	for len(line) > max {
+		cut := max
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		max = limit - 1
+	}
+	b.WriteString(line)
+	return b.String()
+}
@@ -0,0 +1,336 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // TZID lookups must not depend on the host's zoneinfo
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+func ts(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func releasePlan() *core.Document {
+	pct := 40.0
+	return &core.Document{
+		Info: core.Info{Version: "0.5"},
+		Plan: &core.Plan{
+			Title:      "Release 1.2",
+			Status:     core.PlanStatusInProgress,
+			Narratives: map[string]string{"Overview": "Ship it, safely."},
+			Items: []core.PlanItem{
+				{
+					ID:        "backend",
+					Title:     "Backend",
+					Status:    core.PlanItemStatusRunning,
+					Narrative: map[string]string{"Overview": "Schema; invoices, and\nline items"},
+					Priority:  core.PriorityHigh,
+					DueDate:   ts("2025-03-01T17:00:00Z"),
+					Tags:      []string{"db", "billing,eu"},
+					Reminders: []core.Reminder{{Trigger: "-P1D", Action: core.ReminderDisplay, Description: "Backend due tomorrow"}},
+					SubItems: []core.PlanItem{
+						{ID: "schema", Title: "Migrate schema", Status: core.PlanItemStatusCompleted, Completed: ts("2025-02-01T09:30:00Z")},
+						{ID: "invoices", Title: "Rewrite invoices", Status: core.PlanItemStatusBlocked, PercentComplete: &pct},
+					},
+				},
+				{
+					ID:        "standup",
+					Title:     "Release standup",
+					Status:    core.PlanItemStatusPending,
+					StartDate: ts("2025-02-03T09:00:00Z"),
+					EndDate:   ts("2025-02-03T09:15:00Z"),
+					Recurrence: &core.RecurrenceRule{
+						Frequency: core.FrequencyWeekly,
+						Interval:  1,
+						ByDay:     []string{"MO", "WE"},
+						Until:     ts("2025-03-01T00:00:00Z"),
+					},
+					Reminders: []core.Reminder{
+						{Trigger: "-PT10M", Action: core.ReminderDisplay, Description: "Standup in 10 minutes"},
+						{Trigger: "2025-02-03T08:00:00Z", Action: core.ReminderWebhook, Description: "ping bot"},
+					},
+				},
+				{Title: "Untracked chore", Status: core.PlanItemStatusCancelled},
+			},
+			Edges: []core.Edge{
+				{From: "backend.schema", To: "backend.invoices", Type: core.EdgeBlocks},
+				{From: "backend", To: "standup", Type: core.EdgeInforms},
+			},
+		},
+	}
+}
+
+var stamp = time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func TestEncode(t *testing.T) {
+	data, err := Encode(releasePlan(), Options{Now: stamp})
+	require.NoError(t, err)
+	out := string(data)
+
+	t.Run("calendar envelope", func(t *testing.T) {
+		assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:"+ProdID+"\r\n"))
+		assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+		assert.Contains(t, out, "X-WR-CALNAME:Release 1.2\r\n")
+		assert.Contains(t, out, "X-WR-CALDESC:Ship it\\, safely.\r\n")
+		assert.Equal(t, 4, strings.Count(out, "BEGIN:VTODO"))
+		assert.Equal(t, 1, strings.Count(out, "BEGIN:VEVENT"))
+	})
+
+	t.Run("todo properties", func(t *testing.T) {
+		assert.Contains(t, out, "UID:backend@release-1.2.vbrief\r\nDTSTAMP:20250115T120000Z\r\nSUMMARY:Backend\r\n")
+		assert.Contains(t, out, "DESCRIPTION:Schema\\; invoices\\, and\\nline items\r\n")
+		assert.Contains(t, out, "STATUS:IN-PROCESS\r\n")
+		assert.Contains(t, out, "PRIORITY:3\r\n")
+		assert.Contains(t, out, "DUE:20250301T170000Z\r\n")
+		assert.Contains(t, out, "CATEGORIES:db,billing\\,eu\r\n")
+		assert.Contains(t, out, "COMPLETED:20250201T093000Z\r\n")
+		assert.Contains(t, out, "PERCENT-COMPLETE:40\r\n")
+	})
+
+	t.Run("lossy status is preserved", func(t *testing.T) {
+		assert.Contains(t, out, "STATUS:NEEDS-ACTION\r\nX-VBRIEF-STATUS:blocked\r\n")
+	})
+
+	t.Run("relations", func(t *testing.T) {
+		assert.Contains(t, out, "RELATED-TO;RELTYPE=PARENT:backend@release-1.2.vbrief\r\n")
+		assert.Contains(t, out, "RELATED-TO;RELTYPE=DEPENDS-ON:backend.schema@release-1.2.vbrief\r\n")
+		assert.Equal(t, 1, strings.Count(out, "DEPENDS-ON"), "only blocks edges are exported")
+	})
+
+	t.Run("event with recurrence and alarms", func(t *testing.T) {
+		assert.Contains(t, out, "DTSTART:20250203T090000Z\r\nDTEND:20250203T091500Z\r\n")
+		assert.Contains(t, out, "RRULE:FREQ=WEEKLY;INTERVAL=1;UNTIL=20250301T000000Z;BYDAY=MO,WE\r\n")
+		assert.Contains(t, out, "BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT10M\r\nDESCRIPTION:Standup in 10 minutes\r\nEND:VALARM\r\n")
+		assert.Contains(t, out, "ACTION:X-VBRIEF-WEBHOOK\r\nTRIGGER;VALUE=DATE-TIME:20250203T080000Z\r\n")
+	})
+
+	t.Run("todo alarms count from the due date", func(t *testing.T) {
+		assert.Contains(t, out, "BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER;RELATED=END:-P1D\r\n")
+	})
+
+	t.Run("items without IDs get path UIDs", func(t *testing.T) {
+		assert.Contains(t, out, "UID:plan.items-2-@release-1.2.vbrief\r\n")
+	})
+
+	t.Run("no plan", func(t *testing.T) {
+		_, err := Encode(&core.Document{}, Options{})
+		assert.ErrorIs(t, err, core.ErrNoPlan)
+	})
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := releasePlan()
+	data, err := Encode(want, Options{Now: stamp})
+	require.NoError(t, err)
+
+	got, err := Decode(data)
+	require.NoError(t, err)
+	require.NotNil(t, got.Plan)
+
+	assert.Equal(t, "Release 1.2", got.Plan.Title)
+	assert.Equal(t, "Ship it, safely.", got.Plan.Narratives["Overview"])
+	require.Len(t, got.Plan.Items, 3)
+
+	backend := got.Plan.Items[0]
+	assert.Equal(t, "backend@release-1.2.vbrief", backend.UID)
+	backend.UID = ""
+	for i := range backend.SubItems {
+		backend.SubItems[i].UID = ""
+	}
+	assert.Equal(t, want.Plan.Items[0], backend)
+
+	standup := got.Plan.Items[1]
+	standup.UID = ""
+	assert.Equal(t, want.Plan.Items[1], standup)
+
+	assert.Equal(t, "Untracked chore", got.Plan.Items[2].Title)
+	assert.Equal(t, core.PlanItemStatusCancelled, got.Plan.Items[2].Status)
+
+	assert.Equal(t, []core.Edge{{From: "backend.schema", To: "backend.invoices", Type: core.EdgeBlocks}}, got.Plan.Edges)
+}
+
+// appleCalendar is shaped like an export from a desktop calendar app: folded
+// lines, a VTIMEZONE, TZID parameters, date-only values and no vBRIEF
+// extensions.
+const appleCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Apple Inc.//macOS 14.0//EN\r\n" +
+	"X-WR-CALNAME:Team\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Europe/Berlin\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:A1\r\n" +
+	"SUMMARY:Prepare the quarterly planning document for the leadership offsite \r\n" +
+	" and circulate it\r\n" +
+	"DUE;TZID=Europe/Berlin:20250301T170000\r\n" +
+	"PRIORITY:1\r\n" +
+	"STATUS:IN-PROCESS\r\n" +
+	"PERCENT-COMPLETE:25\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:A2\r\n" +
+	"RELATED-TO:A1\r\n" +
+	"SUMMARY:Collect numbers\r\n" +
+	"DUE;VALUE=DATE:20250220\r\n" +
+	"PRIORITY:7\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:E1\r\n" +
+	"SUMMARY:Offsite\r\n" +
+	"STATUS:TENTATIVE\r\n" +
+	"DTSTART;VALUE=DATE:20250310\r\n" +
+	"DTEND;VALUE=DATE:20250312\r\n" +
+	"RRULE:FREQ=HOURLY;COUNT=2\r\n" +
+	"ATTENDEE;CN=\"Doe, Jane\";ROLE=REQ-PARTICIPANT:mailto:jane@example.com\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestDecode(t *testing.T) {
+	doc, err := Decode([]byte(appleCalendar))
+	require.NoError(t, err)
+
+	assert.Equal(t, core.SpecVersion, doc.Info.Version)
+	assert.Equal(t, "Team", doc.Plan.Title)
+	assert.Equal(t, core.PlanStatusDraft, doc.Plan.Status)
+	require.Len(t, doc.Plan.Items, 2)
+
+	todo := doc.Plan.Items[0]
+	assert.Equal(t, "Prepare the quarterly planning document for the leadership offsite and circulate it", todo.Title)
+	assert.Equal(t, core.PlanItemStatusRunning, todo.Status)
+	assert.Equal(t, core.PriorityCritical, todo.Priority)
+	require.NotNil(t, todo.PercentComplete)
+	assert.Equal(t, 25.0, *todo.PercentComplete)
+	require.NotNil(t, todo.DueDate)
+	assert.Equal(t, "2025-03-01T16:00:00Z", todo.DueDate.Format(time.RFC3339), "TZID is honoured")
+
+	require.Len(t, todo.SubItems, 1)
+	child := todo.SubItems[0]
+	assert.Equal(t, "Collect numbers", child.Title)
+	assert.Equal(t, core.PlanItemStatusPending, child.Status)
+	assert.Equal(t, core.PriorityLow, child.Priority)
+	assert.Equal(t, "2025-02-20T00:00:00Z", child.DueDate.Format(time.RFC3339))
+
+	event := doc.Plan.Items[1]
+	assert.Equal(t, "Offsite", event.Title)
+	assert.Equal(t, core.PlanItemStatusPending, event.Status)
+	assert.Equal(t, "2025-03-10T00:00:00Z", event.StartDate.Format(time.RFC3339))
+	assert.Equal(t, "2025-03-12T00:00:00Z", event.EndDate.Format(time.RFC3339))
+	assert.Nil(t, event.Recurrence, "unsupported frequencies are dropped")
+	assert.Empty(t, doc.Plan.Edges)
+}
+
+func TestDecode_AlarmTriggers(t *testing.T) {
+	data := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+		"BEGIN:VTODO\r\nUID:T1\r\nSUMMARY:Report\r\n" +
+		"DTSTART:20250301T090000Z\r\nDUE:20250303T170000Z\r\n" +
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT15M\r\nEND:VALARM\r\n" +
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER;RELATED=END:-PT1H\r\nEND:VALARM\r\n" +
+		"END:VTODO\r\n" +
+		"BEGIN:VEVENT\r\nUID:E1\r\nSUMMARY:Demo\r\n" +
+		"DTSTART:20250304T100000Z\r\nDTEND:20250304T110000Z\r\n" +
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER;RELATED=END:-PT5M\r\nEND:VALARM\r\n" +
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT10M\r\nEND:VALARM\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+	doc, err := Decode([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, doc.Plan.Items, 2)
+
+	triggers := func(item core.PlanItem) []string {
+		var out []string
+		for _, r := range item.Reminders {
+			out = append(out, r.Trigger)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"2025-03-01T08:45:00Z", "-PT1H"}, triggers(doc.Plan.Items[0]),
+		"start-relative triggers are resolved; due-relative ones are kept")
+	assert.Equal(t, []string{"2025-03-04T10:55:00Z", "-PT10M"}, triggers(doc.Plan.Items[1]),
+		"an event's end is not a due date; without one, start-relative triggers are kept")
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"PT15M":    15 * time.Minute,
+		"-PT1H30M": -90 * time.Minute,
+		"+P1DT2H":  26 * time.Hour,
+		"P2W":      14 * 24 * time.Hour,
+		"PT0S":     0,
+	}
+	for v, want := range tests {
+		t.Run(v, func(t *testing.T) {
+			d, err := parseDuration(v)
+			require.NoError(t, err)
+			assert.Equal(t, want, d)
+		})
+	}
+	for _, v := range []string{"", "P", "-P", "PT", "P1DT", "15M", "P1H"} {
+		_, err := parseDuration(v)
+		assert.ErrorIs(t, err, ErrSyntax, v)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"empty", "", ErrNoCalendar},
+		{"no calendar", "BEGIN:VTODO\r\nEND:VTODO\r\n", ErrNoCalendar},
+		{"unterminated", "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\n", ErrSyntax},
+		{"mismatched end", "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nEND:VEVENT\r\n", ErrSyntax},
+		{"property outside component", "SUMMARY:x\r\n", ErrSyntax},
+		{"missing colon", "BEGIN:VCALENDAR\r\nSUMMARY\r\nEND:VCALENDAR\r\n", ErrSyntax},
+		{"bad date", "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nDUE:tomorrow\r\nEND:VTODO\r\nEND:VCALENDAR\r\n", ErrSyntax},
+		{"bad priority", "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nPRIORITY:high\r\nEND:VTODO\r\nEND:VCALENDAR\r\n", ErrSyntax},
+		{"bad rrule", "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nRRULE:FREQ=DAILY;COUNT=x\r\nEND:VTODO\r\nEND:VCALENDAR\r\n", ErrSyntax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.data))
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestStatusMapping(t *testing.T) {
+	tests := []struct {
+		status   core.PlanItemStatus
+		todo     string
+		lossless bool
+	}{
+		{core.PlanItemStatusPending, "NEEDS-ACTION", true},
+		{core.PlanItemStatusRunning, "IN-PROCESS", true},
+		{core.PlanItemStatusInProgress, "IN-PROCESS", true},
+		{core.PlanItemStatusCompleted, "COMPLETED", true},
+		{core.PlanItemStatusCancelled, "CANCELLED", true},
+		{core.PlanItemStatusBlocked, "NEEDS-ACTION", false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			v, lossless := statusToICal("VTODO", tt.status)
+			assert.Equal(t, tt.todo, v)
+			assert.Equal(t, tt.lossless, lossless)
+		})
+	}
+}
+
+func TestFoldLine(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("ü", 60)
+	folded := foldLine(line)
+	for _, physical := range strings.Split(folded, "\r\n") {
+		assert.LessOrEqual(t, len(physical), 75)
+	}
+	assert.Equal(t, line, strings.ReplaceAll(folded, "\r\n ", ""))
+	assert.Equal(t, "SHORT:x", foldLine("SHORT:x"))
+}
@@ -0,0 +1,123 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// DecodeCSV imports a Jira CSV export. Columns Jira repeats, such as Labels
+// and issue links, are read in full. Parents are found from the Parent,
+// Parent id or Epic Link columns, by issue key or numeric issue id.
+func DecodeCSV(data []byte, opts Options) (*core.Document, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: missing header row", ErrInvalidExport)
+	}
+	header := rows[0]
+	keyColumn := -1
+	for i, h := range header {
+		header[i] = strings.TrimSpace(h)
+		if strings.EqualFold(header[i], "Issue key") {
+			keyColumn = i
+		}
+	}
+	if keyColumn < 0 {
+		return nil, fmt.Errorf("%w: no %q column", ErrInvalidExport, "Issue key")
+	}
+
+	var records []*record
+	keyByID := make(map[string]string)
+	for n, row := range rows[1:] {
+		rowNum := n + 2
+		// cells collects every non-empty value per lower-cased header.
+		cells := make(map[string][]string)
+		for i, v := range row {
+			if i >= len(header) {
+				break
+			}
+			if v = strings.TrimSpace(v); v != "" {
+				h := strings.ToLower(header[i])
+				cells[h] = append(cells[h], v)
+			}
+		}
+		if len(cells) == 0 {
+			continue
+		}
+		first := func(names ...string) string {
+			for _, name := range names {
+				if v := cells[strings.ToLower(name)]; len(v) > 0 {
+					return v[0]
+				}
+			}
+			return ""
+		}
+
+		rec := &record{
+			key:         first("Issue key"),
+			summary:     first("Summary"),
+			description: first("Description"),
+			issueType:   first("Issue Type"),
+			status:      opts.status(first("Status"), first("Status Category")),
+			priority:    priority(first("Priority")),
+			labels:      cells["labels"],
+			parent:      first("Parent key", "Parent", "Parent id"),
+			blockedBy:   cells["inward issue link (blocks)"],
+			blocks:      cells["outward issue link (blocks)"],
+		}
+		if rec.parent == "" {
+			rec.parent = first(opts.epicLinkFields()...)
+		}
+		if id := first("Issue id"); id != "" {
+			keyByID[id] = rec.key
+		}
+		if name := first("Assignee"); name != "" {
+			id := first("Assignee Id")
+			if id == "" {
+				id = name
+			}
+			rec.assignee = &core.Participant{ID: id, Name: name, Role: core.RoleAssignee}
+		}
+		if s := first(opts.storyPointsFields()...); s != "" {
+			points, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: row %d: invalid story points %q", ErrInvalidExport, rowNum, s)
+			}
+			rec.storyPoints = &points
+		}
+		for _, d := range []struct {
+			dst   **time.Time
+			names []string
+		}{
+			{&rec.created, []string{"Created"}},
+			{&rec.updated, []string{"Updated"}},
+			{&rec.resolved, []string{"Resolved"}},
+			{&rec.due, []string{"Due Date", "Due"}},
+		} {
+			t, err := parseDate(first(d.names...))
+			if err != nil {
+				return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidExport, rowNum, err)
+			}
+			*d.dst = t
+		}
+		records = append(records, rec)
+	}
+
+	for _, rec := range records {
+		if key, ok := keyByID[rec.parent]; ok {
+			rec.parent = key
+		}
+	}
+	return build(records, opts)
+}
@@ -0,0 +1,312 @@
+// Package jira imports Jira issue exports into vBRIEF plans.
+//
+// Both the JSON returned by the REST search API (or a saved list of issues)
+// and the CSV written by "Export issues → CSV (all fields)" are supported.
+// Issues map onto plan items as follows:
+//
+//	issue key                  id, and metadata jiraKey
+//	summary, description       title, Overview narrative
+//	parent / Epic Link         nesting: epics hold stories, stories hold sub-tasks
+//	"is blocked by" links      blocks edges
+//	status                     status, through Options.StatusMap
+//	priority                   priority (Highest/Blocker/Critical → critical, ...)
+//	labels                     tags
+//	assignee                   participant with the assignee role
+//	story points               metadata storyPoints
+//	issue type                 metadata issueType
+//	created, updated, resolved created, updated, completed
+//	due date                   dueDate
+package jira
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+// ErrInvalidExport is returned when a Jira export cannot be read.
+var ErrInvalidExport = errors.New("jira: invalid export")
+
+// Metadata keys set on imported items.
+const (
+	MetadataKey         = "jiraKey"
+	MetadataIssueType   = "issueType"
+	MetadataStoryPoints = "storyPoints"
+)
+
+// DefaultStatusMap maps common Jira status names, lower-cased, onto item
+// statuses. Statuses it does not list fall back to their Jira status
+// category (To Do, In Progress, Done), and to pending when that is unknown.
+var DefaultStatusMap = map[string]core.PlanItemStatus{
+	"backlog":                  core.PlanItemStatusPending,
+	"open":                     core.PlanItemStatusPending,
+	"to do":                    core.PlanItemStatusPending,
+	"selected for development": core.PlanItemStatusPending,
+	"reopened":                 core.PlanItemStatusPending,
+	"in progress":              core.PlanItemStatusRunning,
+	"in review":                core.PlanItemStatusRunning,
+	"code review":              core.PlanItemStatusRunning,
+	"blocked":                  core.PlanItemStatusBlocked,
+	"on hold":                  core.PlanItemStatusBlocked,
+	"done":                     core.PlanItemStatusCompleted,
+	"closed":                   core.PlanItemStatusCompleted,
+	"resolved":                 core.PlanItemStatusCompleted,
+	"won't do":                 core.PlanItemStatusCancelled,
+	"cancelled":                core.PlanItemStatusCancelled,
+	"canceled":                 core.PlanItemStatusCancelled,
+}
+
+// Default field names. JSON exports are matched by field ID and CSV exports
+// by column header, both case-insensitively.
+var (
+	DefaultStoryPointsFields = []string{
+		"customfield_10016", "customfield_10026",
+		"Custom field (Story Points)", "Custom field (Story point estimate)",
+	}
+	DefaultEpicLinkFields = []string{"customfield_10014", "Custom field (Epic Link)"}
+)
+
+// Options configures Decode.
+type Options struct {
+	// Title is the plan title. Defaults to "Jira import".
+	Title string
+	// StatusMap maps lower-cased Jira status names onto item statuses. It
+	// is consulted before DefaultStatusMap.
+	StatusMap map[string]core.PlanItemStatus
+	// StoryPointsFields and EpicLinkFields name the custom fields holding
+	// story points and the classic Epic Link. They default to
+	// DefaultStoryPointsFields and DefaultEpicLinkFields.
+	StoryPointsFields []string
+	EpicLinkFields    []string
+}
+
+func (o Options) storyPointsFields() []string {
+	if len(o.StoryPointsFields) > 0 {
+		return o.StoryPointsFields
+	}
+	return DefaultStoryPointsFields
+}
+
+func (o Options) epicLinkFields() []string {
+	if len(o.EpicLinkFields) > 0 {
+		return o.EpicLinkFields
+	}
+	return DefaultEpicLinkFields
+}
+
+// status maps a Jira status name and status category key onto an item
+// status.
+func (o Options) status(name, category string) core.PlanItemStatus {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if s, ok := o.StatusMap[key]; ok {
+		return s
+	}
+	if s, ok := DefaultStatusMap[key]; ok {
+		return s
+	}
+	switch strings.ToLower(category) {
+	case "indeterminate", "in progress":
+		return core.PlanItemStatusRunning
+	case "done":
+		return core.PlanItemStatusCompleted
+	default:
+		return core.PlanItemStatusPending
+	}
+}
+
+func priority(name string) core.Priority {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "highest", "blocker", "critical":
+		return core.PriorityCritical
+	case "high", "major":
+		return core.PriorityHigh
+	case "medium":
+		return core.PriorityMedium
+	case "low", "lowest", "minor", "trivial":
+		return core.PriorityLow
+	default:
+		return ""
+	}
+}
+
+// Decode imports a Jira export, detecting JSON or CSV from its first
+// non-blank byte.
+func Decode(data []byte, opts Options) (*core.Document, error) {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\ufeff")), " \t\r\n")
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return DecodeJSON(data, opts)
+	}
+	return DecodeCSV(data, opts)
+}
+
+// record is an issue in the shape shared by the JSON and CSV readers.
+type record struct {
+	key         string
+	summary     string
+	description string
+	issueType   string
+	status      core.PlanItemStatus
+	priority    core.Priority
+	labels      []string
+	assignee    *core.Participant
+	storyPoints *float64
+	parent      string
+	blockedBy   []string
+	blocks      []string
+	created     *time.Time
+	updated     *time.Time
+	resolved    *time.Time
+	due         *time.Time
+}
+
+// build nests records by parent and resolves blocking links into edges.
+// A record whose parent is not in the export becomes a top-level item.
+func build(records []*record, opts Options) (*core.Document, error) {
+	byKey := make(map[string]*record, len(records))
+	for _, r := range records {
+		if r.key == "" {
+			return nil, fmt.Errorf("%w: issue %q has no key", ErrInvalidExport, r.summary)
+		}
+		if byKey[r.key] != nil {
+			return nil, fmt.Errorf("%w: duplicate issue %s", ErrInvalidExport, r.key)
+		}
+		byKey[r.key] = r
+	}
+
+	children := make(map[string][]*record)
+	var roots []*record
+	for _, r := range records {
+		if p := byKey[r.parent]; p != nil && !cyclic(byKey, r) {
+			children[r.parent] = append(children[r.parent], r)
+		} else {
+			roots = append(roots, r)
+		}
+	}
+
+	fullID := make(map[string]string, len(records))
+	var toItem func(r *record, parentID string) core.PlanItem
+	toItem = func(r *record, parentID string) core.PlanItem {
+		id := graph.JoinID(parentID, r.key)
+		fullID[r.key] = id
+		item := core.PlanItem{
+			ID:       r.key,
+			Title:    r.summary,
+			Status:   r.status,
+			Priority: r.priority,
+			Tags:     r.labels,
+			Created:  r.created,
+			Updated:  r.updated,
+			DueDate:  r.due,
+			Metadata: map[string]interface{}{MetadataKey: r.key},
+		}
+		if item.Title == "" {
+			item.Title = r.key
+		}
+		if r.description != "" {
+			item.Narrative = map[string]string{"Overview": r.description}
+		}
+		if r.issueType != "" {
+			item.Metadata[MetadataIssueType] = r.issueType
+		}
+		if r.storyPoints != nil {
+			item.Metadata[MetadataStoryPoints] = *r.storyPoints
+		}
+		if r.assignee != nil {
+			item.Participants = []core.Participant{*r.assignee}
+		}
+		if item.Status == core.PlanItemStatusCompleted {
+			item.Completed = r.resolved
+		}
+		for _, c := range children[r.key] {
+			item.SubItems = append(item.SubItems, toItem(c, id))
+		}
+		return item
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = "Jira import"
+	}
+	plan := &core.Plan{
+		Title:      title,
+		Status:     core.PlanStatusDraft,
+		Narratives: make(map[string]string),
+	}
+	for _, r := range roots {
+		plan.Items = append(plan.Items, toItem(r, ""))
+	}
+
+	// Each link usually appears on both issues; keep one edge per pair.
+	seen := make(map[[2]string]bool)
+	addEdge := func(from, to string) {
+		f, t := fullID[from], fullID[to]
+		if f == "" || t == "" || f == t || seen[[2]string{f, t}] {
+			return
+		}
+		seen[[2]string{f, t}] = true
+		plan.Edges = append(plan.Edges, core.Edge{From: f, To: t, Type: core.EdgeBlocks})
+	}
+	for _, r := range records {
+		for _, b := range r.blockedBy {
+			addEdge(b, r.key)
+		}
+		for _, b := range r.blocks {
+			addEdge(r.key, b)
+		}
+	}
+
+	return &core.Document{
+		Info: core.Info{Version: core.SpecVersion},
+		Plan: plan,
+	}, nil
+}
+
+// cyclic reports whether following parents from r leads back to r.
+func cyclic(byKey map[string]*record, r *record) bool {
+	seen := map[string]bool{r.key: true}
+	for p := byKey[r.parent]; p != nil; p = byKey[p.parent] {
+		if seen[p.key] {
+			return true
+		}
+		seen[p.key] = true
+	}
+	return false
+}
+
+var dateLayouts = []string{
+	"2006-01-02T15:04:05.000-0700",
+	time.RFC3339,
+	time.DateOnly,
+	"2006-01-02 15:04",
+	"02/Jan/06 3:04 PM",
+	"02/Jan/2006 3:04 PM",
+	"02/Jan/06",
+	"1/2/2006 15:04",
+}
+
+func parseDate(s string) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid date %q", s)
+}
+
+func matchesAny(name string, candidates []string) bool {
+	for _, c := range candidates {
+		if strings.EqualFold(strings.TrimSpace(name), c) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package jira
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+	return data
+}
+
+func date(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+// TestDecode checks that the JSON and CSV exports of the same backlog import
+// to the same plan structure.
+func TestDecode(t *testing.T) {
+	for _, name := range []string{"search.json", "export.csv"} {
+		t.Run(name, func(t *testing.T) {
+			doc, err := Decode(fixture(t, name), Options{Title: "Shop"})
+			require.NoError(t, err)
+
+			plan := doc.Plan
+			assert.Equal(t, core.SpecVersion, doc.Info.Version)
+			assert.Equal(t, "Shop", plan.Title)
+			require.Len(t, plan.Items, 1)
+
+			epic := plan.Items[0]
+			assert.Equal(t, "SHOP-1", epic.ID)
+			assert.Equal(t, "Checkout rewrite", epic.Title)
+			assert.Equal(t, core.PlanItemStatusRunning, epic.Status)
+			assert.Equal(t, core.PriorityCritical, epic.Priority)
+			assert.Equal(t, []string{"q2"}, epic.Tags)
+			assert.Equal(t, "Epic", epic.Metadata[MetadataIssueType])
+			assert.Equal(t, date("2025-04-30T00:00:00Z"), epic.DueDate)
+			assert.Equal(t, date("2025-03-05T10:30:00Z").Unix(), epic.Updated.Unix())
+			assert.Contains(t, epic.Narrative["Overview"], "Replace the legacy checkout.")
+
+			require.Len(t, epic.SubItems, 2)
+			api := epic.SubItems[0]
+			assert.Equal(t, "SHOP-2", api.ID)
+			assert.Equal(t, core.PlanItemStatusCompleted, api.Status)
+			assert.Equal(t, core.PriorityHigh, api.Priority)
+			assert.Equal(t, []string{"backend", "payments"}, api.Tags)
+			assert.Equal(t, 5.0, api.Metadata[MetadataStoryPoints])
+			assert.Equal(t, "SHOP-2", api.Metadata[MetadataKey])
+			require.Len(t, api.Participants, 1)
+			assert.Equal(t, "Ana Lima", api.Participants[0].Name)
+			assert.Equal(t, core.RoleAssignee, api.Participants[0].Role)
+			require.NotNil(t, api.Completed)
+			assert.Equal(t, date("2025-03-04T16:00:00Z").Unix(), api.Completed.Unix())
+
+			page := epic.SubItems[1]
+			assert.Equal(t, "SHOP-3", page.ID)
+			assert.Equal(t, core.PlanItemStatusPending, page.Status)
+			assert.Equal(t, 8.0, page.Metadata[MetadataStoryPoints])
+			assert.Nil(t, page.Narrative)
+
+			require.Len(t, page.SubItems, 1)
+			card := page.SubItems[0]
+			assert.Equal(t, "SHOP-4", card.ID)
+			assert.Equal(t, core.PlanItemStatusCancelled, card.Status)
+			assert.Nil(t, card.Completed, "only completed items keep the resolution date")
+
+			assert.Equal(t, []core.Edge{
+				{From: "SHOP-1.SHOP-2", To: "SHOP-1.SHOP-3", Type: core.EdgeBlocks},
+			}, plan.Edges, "the link appears on both issues but yields one edge; SHOP-9 is not exported")
+		})
+	}
+}
+
+func TestDecodeJSON_Details(t *testing.T) {
+	doc, err := DecodeJSON(fixture(t, "search.json"), Options{})
+	require.NoError(t, err)
+	epic := doc.Plan.Items[0]
+	assert.Equal(t, "Jira import", doc.Plan.Title)
+	assert.Equal(t, "Replace the legacy checkout.\n\n- Single page\n- Guest checkout", epic.Narrative["Overview"])
+	assert.Equal(t, core.Participant{
+		ID:    "5b10a2844c20165700ede21g",
+		Name:  "Ana Lima",
+		Email: "ana@example.com",
+		Role:  core.RoleAssignee,
+	}, epic.SubItems[0].Participants[0])
+}
+
+func TestOptions(t *testing.T) {
+	t.Run("status map", func(t *testing.T) {
+		doc, err := Decode(fixture(t, "search.json"), Options{StatusMap: map[string]core.PlanItemStatus{
+			"awaiting design": core.PlanItemStatusBlocked,
+			"done":            core.PlanItemStatusInProgress,
+		}})
+		require.NoError(t, err)
+		epic := doc.Plan.Items[0]
+		assert.Equal(t, core.PlanItemStatusInProgress, epic.SubItems[0].Status, "custom entries override the defaults")
+		assert.Equal(t, core.PlanItemStatusBlocked, epic.SubItems[1].Status)
+	})
+
+	t.Run("custom fields", func(t *testing.T) {
+		data := "Issue key,Summary,Estimate,Epic\nA-1,Epic,,\nA-2,Story,3,A-1\n"
+		doc, err := DecodeCSV([]byte(data), Options{StoryPointsFields: []string{"estimate"}, EpicLinkFields: []string{"Epic"}})
+		require.NoError(t, err)
+		require.Len(t, doc.Plan.Items, 1)
+		require.Len(t, doc.Plan.Items[0].SubItems, 1)
+		assert.Equal(t, 3.0, doc.Plan.Items[0].SubItems[0].Metadata[MetadataStoryPoints])
+	})
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name, category string
+		want           core.PlanItemStatus
+	}{
+		{"To Do", "", core.PlanItemStatusPending},
+		{" IN REVIEW ", "", core.PlanItemStatusRunning},
+		{"Blocked", "", core.PlanItemStatusBlocked},
+		{"Resolved", "", core.PlanItemStatusCompleted},
+		{"Canceled", "", core.PlanItemStatusCancelled},
+		{"QA", "indeterminate", core.PlanItemStatusRunning},
+		{"Shipped", "done", core.PlanItemStatusCompleted},
+		{"Triage", "", core.PlanItemStatusPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Options{}.status(tt.name, tt.category))
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		msg  string
+	}{
+		{"bad json", `{"issues": [`, "unexpected EOF"},
+		{"missing key", `[{"fields": {"summary": "A"}}]`, `issue "A" has no key`},
+		{"duplicate key", `[{"key": "A-1"}, {"key": "A-1"}]`, "duplicate issue A-1"},
+		{"bad date", `[{"key": "A-1", "fields": {"created": "yesterday"}}]`, `invalid date "yesterday"`},
+		{"no key column", "Summary\nA\n", `no "Issue key" column`},
+		{"bad points", "Issue key,Summary,Custom field (Story Points)\nA-1,A,lots\n", `row 2: invalid story points "lots"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.data), Options{})
+			require.ErrorIs(t, err, ErrInvalidExport)
+			assert.Contains(t, err.Error(), tt.msg)
+		})
+	}
+}
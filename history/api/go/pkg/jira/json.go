@@ -0,0 +1,267 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+type jsonIssue struct {
+	Key    string                     `json:"key"`
+	Fields map[string]json.RawMessage `json:"fields"`
+}
+
+type jsonFields struct {
+	Summary     string          `json:"summary"`
+	Description json.RawMessage `json:"description"`
+	IssueType   *struct {
+		Name string `json:"name"`
+	} `json:"issuetype"`
+	Status *struct {
+		Name           string `json:"name"`
+		StatusCategory *struct {
+			Key string `json:"key"`
+		} `json:"statusCategory"`
+	} `json:"status"`
+	Priority *struct {
+		Name string `json:"name"`
+	} `json:"priority"`
+	Labels   []string  `json:"labels"`
+	Assignee *jsonUser `json:"assignee"`
+	Parent   *struct {
+		Key string `json:"key"`
+	} `json:"parent"`
+	IssueLinks []struct {
+		Type struct {
+			Name    string `json:"name"`
+			Inward  string `json:"inward"`
+			Outward string `json:"outward"`
+		} `json:"type"`
+		InwardIssue *struct {
+			Key string `json:"key"`
+		} `json:"inwardIssue"`
+		OutwardIssue *struct {
+			Key string `json:"key"`
+		} `json:"outwardIssue"`
+	} `json:"issuelinks"`
+	Created        string `json:"created"`
+	Updated        string `json:"updated"`
+	ResolutionDate string `json:"resolutiondate"`
+	DueDate        string `json:"duedate"`
+}
+
+type jsonUser struct {
+	AccountID    string `json:"accountId"`
+	Name         string `json:"name"`
+	DisplayName  string `json:"displayName"`
+	EmailAddress string `json:"emailAddress"`
+}
+
+// DecodeJSON imports a Jira JSON export: a REST search response
+// ({"issues": [...]}), several of them back to back, an array of issues or
+// a single issue. Descriptions in Atlassian Document Format are flattened
+// to plain text.
+func DecodeJSON(data []byte, opts Options) (*core.Document, error) {
+	var issues []jsonIssue
+	var collect func(raw json.RawMessage) error
+	collect = func(raw json.RawMessage) error {
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '[' {
+			var list []json.RawMessage
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return err
+			}
+			for _, r := range list {
+				if err := collect(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		var page struct {
+			Issues []json.RawMessage `json:"issues"`
+			jsonIssue
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		if page.Key == "" && page.Issues != nil {
+			for _, r := range page.Issues {
+				if err := collect(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		issues = append(issues, page.jsonIssue)
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = collect(raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+		}
+	}
+
+	records := make([]*record, 0, len(issues))
+	for _, is := range issues {
+		r, err := jsonRecord(is, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidExport, is.Key, err)
+		}
+		records = append(records, r)
+	}
+	return build(records, opts)
+}
+
+func jsonRecord(is jsonIssue, opts Options) (*record, error) {
+	// Decode the known fields through a re-marshalled object so custom
+	// fields stay available by ID.
+	raw, err := json.Marshal(is.Fields)
+	if err != nil {
+		return nil, err
+	}
+	var f jsonFields
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, err
+	}
+
+	r := &record{
+		key:         is.Key,
+		summary:     strings.TrimSpace(f.Summary),
+		description: description(f.Description),
+		labels:      f.Labels,
+	}
+	if f.IssueType != nil {
+		r.issueType = f.IssueType.Name
+	}
+	var statusName, category string
+	if f.Status != nil {
+		statusName = f.Status.Name
+		if f.Status.StatusCategory != nil {
+			category = f.Status.StatusCategory.Key
+		}
+	}
+	r.status = opts.status(statusName, category)
+	if f.Priority != nil {
+		r.priority = priority(f.Priority.Name)
+	}
+	if u := f.Assignee; u != nil {
+		id := u.AccountID
+		if id == "" {
+			id = u.Name
+		}
+		r.assignee = &core.Participant{ID: id, Name: u.DisplayName, Email: u.EmailAddress, Role: core.RoleAssignee}
+	}
+	if f.Parent != nil {
+		r.parent = f.Parent.Key
+	}
+	for id, v := range is.Fields {
+		switch {
+		case r.parent == "" && matchesAny(id, opts.epicLinkFields()):
+			var key string
+			if json.Unmarshal(v, &key) == nil {
+				r.parent = key
+			}
+		case matchesAny(id, opts.storyPointsFields()):
+			var points float64
+			if json.Unmarshal(v, &points) == nil && string(v) != "null" {
+				r.storyPoints = &points
+			}
+		}
+	}
+	for _, l := range f.IssueLinks {
+		if !isBlocksLink(l.Type.Name, l.Type.Inward) {
+			continue
+		}
+		if l.InwardIssue != nil {
+			r.blockedBy = append(r.blockedBy, l.InwardIssue.Key)
+		}
+		if l.OutwardIssue != nil {
+			r.blocks = append(r.blocks, l.OutwardIssue.Key)
+		}
+	}
+
+	if r.created, err = parseDate(f.Created); err != nil {
+		return nil, err
+	}
+	if r.updated, err = parseDate(f.Updated); err != nil {
+		return nil, err
+	}
+	if r.resolved, err = parseDate(f.ResolutionDate); err != nil {
+		return nil, err
+	}
+	if r.due, err = parseDate(f.DueDate); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// isBlocksLink reports whether a link type is Jira's "Blocks" type, whose
+// inward description is "is blocked by".
+func isBlocksLink(name, inward string) bool {
+	return strings.EqualFold(name, "blocks") || strings.Contains(strings.ToLower(inward), "blocked by")
+}
+
+// description returns a plain-text description from either a string or an
+// Atlassian Document Format object.
+func description(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	var doc adfNode
+	if json.Unmarshal(raw, &doc) != nil {
+		return ""
+	}
+	var b strings.Builder
+	doc.write(&b)
+	return strings.TrimSpace(b.String())
+}
+
+type adfNode struct {
+	Type    string    `json:"type"`
+	Text    string    `json:"text"`
+	Content []adfNode `json:"content"`
+}
+
+func (n adfNode) write(b *strings.Builder) {
+	switch n.Type {
+	case "text":
+		b.WriteString(n.Text)
+		return
+	case "hardBreak":
+		b.WriteString("\n")
+		return
+	case "listItem":
+		var item strings.Builder
+		for _, c := range n.Content {
+			c.write(&item)
+		}
+		b.WriteString("- " + strings.TrimSpace(item.String()) + "\n")
+		return
+	}
+	for _, c := range n.Content {
+		c.write(b)
+	}
+	switch n.Type {
+	case "paragraph", "heading", "codeBlock", "blockquote", "bulletList", "orderedList":
+		b.WriteString("\n\n")
+	}
+}
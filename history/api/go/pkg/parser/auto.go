@@ -2,23 +2,31 @@ package parser
 
 import (
 	"bytes"
+	"errors"
 	"io"
-	"strings"
 
 	"github.com/visionik/vBRIEF/api/go/pkg/core"
 )
 
 // autoParser automatically detects the format and parses accordingly.
-type autoParser struct{}
+type autoParser struct {
+	opts Options
+}
 
-// NewAutoParser creates a new auto-detecting parser.
+// NewAutoParser creates a new auto-detecting parser with DefaultOptions.
 func NewAutoParser() Parser {
-	return &autoParser{}
+	return &autoParser{opts: DefaultOptions()}
+}
+
+// NewAutoParserWithOptions creates a new auto-detecting parser with custom
+// limits, which apply whichever format is detected.
+func NewAutoParserWithOptions(opts Options) Parser {
+	return &autoParser{opts: opts.withDefaults()}
 }
 
 // Parse reads and parses a document, auto-detecting the format.
 func (p *autoParser) Parse(r io.Reader) (*core.Document, error) {
-	data, err := readAllLimited(r)
+	data, err := readAllMax(r, p.opts.MaxDocumentSize)
 	if err != nil {
 		return nil, err
 	}
@@ -27,34 +35,68 @@ func (p *autoParser) Parse(r io.Reader) (*core.Document, error) {
 
 // ParseBytes parses a document, auto-detecting the format from a byte slice.
 func (p *autoParser) ParseBytes(data []byte) (*core.Document, error) {
+	if err := p.opts.checkSize(data); err != nil {
+		return nil, err
+	}
+	doc, err := p.detect(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDocument(doc, p.opts); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (p *autoParser) detect(data []byte) (*core.Document, error) {
 	// Try JSON first (starts with '{')
 	trimmed := bytes.TrimSpace(data)
 	if len(trimmed) > 0 && trimmed[0] == '{' {
-		jsonParser := NewJSONParser()
+		jsonParser := NewJSONParserWithOptions(p.opts)
 		doc, err := jsonParser.ParseBytes(data)
-		if err == nil {
+		if err == nil || isLimitError(err) {
+			return doc, err
+		}
+	}
+
+	if looksLikeICal(trimmed) {
+		return NewICalParser().ParseBytes(data)
+	}
+
+	// A YAML document may open with a "# comment", which reads as a
+	// Markdown heading, so one with a top-level vBRIEFInfo key is tried as
+	// YAML first.
+	if yamlRoot.Match(trimmed) && looksLikeYAML(trimmed) {
+		if doc, err := NewYAMLParser().ParseBytes(data); err == nil {
+			return doc, nil
+		}
+	}
+
+	// Markdown starts with a heading or list item, or has a heading or
+	// task list after an opening paragraph
+	if looksLikeMarkdown(trimmed) {
+		return NewMarkdownParser().ParseBytes(data)
+	}
+
+	// YAML and TROY documents are mappings without TRON's class and
+	// constructor lines; anything that fails as YAML is tried as TRON.
+	if looksLikeYAML(trimmed) {
+		if doc, err := NewYAMLParser().ParseBytes(data); err == nil {
 			return doc, nil
 		}
 	}
 
 	// Fall back to TRON
-	tronParser := NewTRONParser()
+	tronParser := NewTRONParserWithOptions(p.opts)
 	return tronParser.ParseBytes(data)
 }
 
 // ParseString parses a document, auto-detecting the format from a string.
 func (p *autoParser) ParseString(s string) (*core.Document, error) {
-	// Try JSON first (starts with '{')
-	trimmed := strings.TrimSpace(s)
-	if len(trimmed) > 0 && trimmed[0] == '{' {
-		jsonParser := NewJSONParser()
-		doc, err := jsonParser.ParseString(s)
-		if err == nil {
-			return doc, nil
-		}
-	}
+	return p.ParseBytes([]byte(s))
+}
 
-	// Fall back to TRON
-	tronParser := NewTRONParser()
-	return tronParser.ParseString(s)
+func isLimitError(err error) bool {
+	return errors.Is(err, ErrDocumentTooLarge) || errors.Is(err, ErrTooDeep) ||
+		errors.Is(err, ErrTooManyItems) || errors.Is(err, ErrStringTooLong)
 }
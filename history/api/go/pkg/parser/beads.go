@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/beads"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// BeadsParser parses Beads issues.jsonl files into Plan documents. See
+// package beads for how issues map onto plan items.
+type BeadsParser struct{}
+
+// NewBeadsParser creates a new Beads JSONL parser.
+func NewBeadsParser() Parser {
+	return &BeadsParser{}
+}
+
+// Parse reads and parses Beads issues from a reader.
+func (p *BeadsParser) Parse(r io.Reader) (*core.Document, error) {
+	data, err := readAllLimited(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseBytes(data)
+}
+
+// ParseBytes parses Beads issues from a byte slice.
+func (p *BeadsParser) ParseBytes(data []byte) (*core.Document, error) {
+	issues, err := beads.ReadIssues(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return beads.Import(issues, "")
+}
+
+// ParseString parses Beads issues from a string.
+func (p *BeadsParser) ParseString(s string) (*core.Document, error) {
+	return p.ParseBytes([]byte(s))
+}
@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"io"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/table"
+)
+
+// CSVParser parses delimited tables of plan items, one item per row, into
+// Plan documents. See package table for the column layout.
+type CSVParser struct {
+	opts table.Options
+}
+
+// NewCSVParser creates a parser for comma-separated tables.
+func NewCSVParser() Parser {
+	return &CSVParser{opts: table.Options{Comma: ','}}
+}
+
+// NewTSVParser creates a parser for tab-separated tables.
+func NewTSVParser() Parser {
+	return &CSVParser{opts: table.Options{Comma: '\t'}}
+}
+
+// Parse reads and parses a table from a reader.
+func (p *CSVParser) Parse(r io.Reader) (*core.Document, error) {
+	data, err := readAllLimited(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseBytes(data)
+}
+
+// ParseBytes parses a table from a byte slice.
+func (p *CSVParser) ParseBytes(data []byte) (*core.Document, error) {
+	return table.Decode(data, p.opts)
+}
+
+// ParseString parses a table from a string.
+func (p *CSVParser) ParseString(s string) (*core.Document, error) {
+	return p.ParseBytes([]byte(s))
+}
@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"io"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/github"
+)
+
+// GitHubParser parses saved `gh api` issue dumps into Plan documents. See
+// package github for how issues map onto plan items.
+type GitHubParser struct{}
+
+// NewGitHubParser creates a new GitHub issues dump parser.
+func NewGitHubParser() Parser {
+	return &GitHubParser{}
+}
+
+// Parse reads and parses a GitHub issues dump from a reader.
+func (p *GitHubParser) Parse(r io.Reader) (*core.Document, error) {
+	data, err := readAllLimited(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseBytes(data)
+}
+
+// ParseBytes parses a GitHub issues dump from a byte slice.
+func (p *GitHubParser) ParseBytes(data []byte) (*core.Document, error) {
+	return github.Import(data, "")
+}
+
+// ParseString parses a GitHub issues dump from a string.
+func (p *GitHubParser) ParseString(s string) (*core.Document, error) {
+	return p.ParseBytes([]byte(s))
+}
@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/ical"
+)
+
+// ICalParser parses iCalendar (RFC 5545) data into Plan documents.
+// See package ical for how components map onto plan items.
+type ICalParser struct{}
+
+// NewICalParser creates a new iCalendar parser.
+func NewICalParser() Parser {
+	return &ICalParser{}
+}
+
+// Parse reads and parses iCalendar data from a reader.
+func (p *ICalParser) Parse(r io.Reader) (*core.Document, error) {
+	data, err := readAllLimited(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseBytes(data)
+}
+
+// ParseBytes parses iCalendar data from a byte slice.
+func (p *ICalParser) ParseBytes(data []byte) (*core.Document, error) {
+	return ical.Decode(data)
+}
+
+// ParseString parses iCalendar data from a string.
+func (p *ICalParser) ParseString(s string) (*core.Document, error) {
+	return p.ParseBytes([]byte(s))
+}
+
+// looksLikeICal reports whether data starts with a VCALENDAR.
+func looksLikeICal(data []byte) bool {
+	return len(data) >= 15 && bytes.EqualFold(data[:15], []byte("BEGIN:VCALENDAR"))
+}
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/ical"
+)
+
+const validICal = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"X-WR-CALNAME:Deadlines\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:1\r\n" +
+	"SUMMARY:File taxes\r\n" +
+	"STATUS:COMPLETED\r\n" +
+	"DUE:20250415T000000Z\r\n" +
+	"END:VTODO\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestICalParser(t *testing.T) {
+	parser := NewICalParser()
+
+	t.Run("parses reader", func(t *testing.T) {
+		doc, err := parser.Parse(strings.NewReader(validICal))
+
+		require.NoError(t, err)
+		require.NotNil(t, doc.Plan)
+		assert.Equal(t, "Deadlines", doc.Plan.Title)
+		require.Len(t, doc.Plan.Items, 1)
+		assert.Equal(t, core.PlanItemStatusCompleted, doc.Plan.Items[0].Status)
+	})
+
+	t.Run("rejects malformed data", func(t *testing.T) {
+		_, err := parser.ParseString("BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\n")
+
+		assert.ErrorIs(t, err, ical.ErrSyntax)
+	})
+}
+
+func TestAutoParser_ICal(t *testing.T) {
+	doc, err := NewAutoParser().ParseString("\r\n" + strings.ToLower(validICal[:15]) + validICal[15:])
+
+	require.NoError(t, err)
+	require.NotNil(t, doc.Plan)
+	assert.Equal(t, "File taxes", doc.Plan.Items[0].Title)
+}
@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"io"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/jira"
+)
+
+// JiraParser parses Jira JSON and CSV issue exports into Plan documents.
+// See package jira for how issues map onto plan items.
+type JiraParser struct {
+	opts jira.Options
+}
+
+// NewJiraParser creates a new Jira export parser using the default status
+// table.
+func NewJiraParser() Parser {
+	return &JiraParser{}
+}
+
+// NewJiraParserWithOptions creates a new Jira export parser with a custom
+// title, status table or field names.
+func NewJiraParserWithOptions(opts jira.Options) Parser {
+	return &JiraParser{opts: opts}
+}
+
+// Parse reads and parses a Jira export from a reader.
+func (p *JiraParser) Parse(r io.Reader) (*core.Document, error) {
+	data, err := readAllLimited(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseBytes(data)
+}
+
+// ParseBytes parses a Jira export from a byte slice.
+func (p *JiraParser) ParseBytes(data []byte) (*core.Document, error) {
+	return jira.Decode(data, p.opts)
+}
+
+// ParseString parses a Jira export from a string.
+func (p *JiraParser) ParseString(s string) (*core.Document, error) {
+	return p.ParseBytes([]byte(s))
+}
@@ -1,40 +1,360 @@
 package parser
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"reflect"
+	"strings"
+	"sync"
 
 	"github.com/visionik/vBRIEF/api/go/pkg/core"
 )
 
 // JSONParser parses documents in JSON format.
-type JSONParser struct{}
+//
+// The input is decoded as a token stream, so the limits in Options are
+// enforced while reading rather than after the whole document has been
+// built.
+type JSONParser struct {
+	opts Options
+}
 
-// NewJSONParser creates a new JSON parser.
+// NewJSONParser creates a new JSON parser with DefaultOptions.
 func NewJSONParser() Parser {
-	return &JSONParser{}
+	return &JSONParser{opts: DefaultOptions()}
+}
+
+// NewJSONParserWithOptions creates a new JSON parser with custom limits.
+func NewJSONParserWithOptions(opts Options) Parser {
+	return &JSONParser{opts: opts.withDefaults()}
 }
 
 // Parse reads and parses a JSON document from a reader.
 func (p *JSONParser) Parse(r io.Reader) (*core.Document, error) {
-	var doc core.Document
-	decoder := json.NewDecoder(r)
-	if err := decoder.Decode(&doc); err != nil {
+	return decodeJSON(r, p.opts, nil)
+}
+
+// ParseBytes parses a JSON document from a byte slice. Syntax and type
+// errors are returned as *ParseError, located by line and column.
+func (p *JSONParser) ParseBytes(data []byte) (*core.Document, error) {
+	doc, err := p.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, positionError(data, err)
+	}
+	return doc, nil
+}
+
+// ParseString parses a JSON document from a string.
+func (p *JSONParser) ParseString(s string) (*core.Document, error) {
+	return p.ParseBytes([]byte(s))
+}
+
+// ItemFunc receives a plan item read by ParseItems. path is the item's
+// position in the plan, e.g. "plan.items[0].subItems[1]".
+type ItemFunc func(path string, item *core.PlanItem) error
+
+// ParseItems streams a JSON plan, calling fn for each plan item as soon as
+// it has been read, so that plans too large to hold in memory can be
+// processed. Items are passed without their sub-items, which are reported
+// separately, before the item that contains them. The returned document
+// holds everything else: the info, narratives, edges and any todo list.
+//
+// An error returned by fn stops parsing and is returned as is. ParseItems
+// applies DefaultOptions without the document size and item count limits.
+func ParseItems(r io.Reader, fn ItemFunc) (*core.Document, error) {
+	opts := DefaultOptions()
+	opts.MaxDocumentSize = -1
+	opts.MaxItems = -1
+	return ParseItemsWithOptions(r, opts, fn)
+}
+
+// ParseItemsWithOptions is ParseItems with custom limits.
+func ParseItemsWithOptions(r io.Reader, opts Options, fn ItemFunc) (*core.Document, error) {
+	if fn == nil {
+		return nil, errors.New("parser: nil ItemFunc")
+	}
+	return decodeJSON(r, opts.withDefaults(), fn)
+}
+
+func decodeJSON(r io.Reader, opts Options, fn ItemFunc) (*core.Document, error) {
+	s := &jsonStream{dec: json.NewDecoder(limitReader(r, opts.MaxDocumentSize)), opts: opts, fn: fn}
+	doc, err := s.document()
+	if err != nil {
 		return nil, err
 	}
-	return &doc, nil
+	if _, err := s.dec.Token(); err != io.EOF {
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.New("json: unexpected data after document")
+	}
+	if err := checkDocument(doc, opts); err != nil {
+		return nil, err
+	}
+	return doc, nil
 }
 
-// ParseBytes parses a JSON document from a byte slice.
-func (p *JSONParser) ParseBytes(data []byte) (*core.Document, error) {
+// jsonStream decodes a document token by token. Each object's fields are
+// decoded straight from the stream into the matching struct field, except
+// for the plan's items and sub-items, which are decoded one item at a
+// time. Like encoding/json, keys match case-insensitively.
+type jsonStream struct {
+	dec   *json.Decoder
+	opts  Options
+	fn    ItemFunc
+	items int
+}
+
+func (s *jsonStream) document() (*core.Document, error) {
+	if err := s.expect('{'); err != nil {
+		return nil, err
+	}
 	var doc core.Document
-	if err := json.Unmarshal(data, &doc); err != nil {
+	err := s.fields("", &doc, func(key string) (bool, error) {
+		if !strings.EqualFold(key, "plan") {
+			return false, nil
+		}
+		var err error
+		doc.Plan, err = s.plan()
+		return true, err
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &doc, nil
 }
 
-// ParseString parses a JSON document from a string.
-func (p *JSONParser) ParseString(s string) (*core.Document, error) {
-	return p.ParseBytes([]byte(s))
+// plan reads the plan object, streaming its items.
+func (s *jsonStream) plan() (*core.Plan, error) {
+	if ok, err := s.open('{'); !ok || err != nil {
+		return nil, err
+	}
+	var plan core.Plan
+	err := s.fields("plan", &plan, func(key string) (bool, error) {
+		if !strings.EqualFold(key, "items") {
+			return false, nil
+		}
+		var err error
+		plan.Items, err = s.itemList("plan.items", 1)
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// itemList reads an array of plan items. When s.fn is set, items are
+// passed to it and not retained.
+func (s *jsonStream) itemList(path string, depth int) ([]core.PlanItem, error) {
+	if ok, err := s.open('['); !ok || err != nil {
+		return nil, err
+	}
+	// Like encoding/json, an empty array decodes to an empty slice and
+	// null to nil, so that linters can tell them apart.
+	var items []core.PlanItem
+	if s.fn == nil {
+		items = []core.PlanItem{}
+	}
+	for i := 0; s.dec.More(); i++ {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		if err := s.opts.checkDepth(p, depth); err != nil {
+			return nil, err
+		}
+		s.items++
+		if err := s.opts.checkItems(s.items); err != nil {
+			return nil, err
+		}
+		item, err := s.item(p, depth)
+		if err != nil {
+			return nil, err
+		}
+		if s.fn != nil {
+			// The document check never sees streamed items.
+			if err := checkStrings(reflect.ValueOf(item), s.opts); err != nil {
+				return nil, err
+			}
+			if err := s.fn(p, item); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		items = append(items, *item)
+	}
+	return items, s.expect(']')
+}
+
+func (s *jsonStream) item(path string, depth int) (*core.PlanItem, error) {
+	if ok, err := s.open('{'); err != nil || !ok {
+		if err == nil {
+			err = errors.New("json: plan item is null")
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var item core.PlanItem
+	err := s.fields(path, &item, func(key string) (bool, error) {
+		if !strings.EqualFold(key, "subItems") {
+			return false, nil
+		}
+		var err error
+		item.SubItems, err = s.itemList(path+".subItems", depth+1)
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// fields reads the rest of an object whose '{' has been read, decoding
+// each value into the matching field of v, a pointer to a struct, unless
+// special consumes it. Values of unknown keys are skipped. Decoding errors
+// are prefixed with path, the object's position in the document.
+func (s *jsonStream) fields(path string, v interface{}, special func(key string) (bool, error)) error {
+	rv := reflect.ValueOf(v).Elem()
+	index := jsonFields(rv.Type())
+	for s.dec.More() {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+		if err := s.opts.checkString(key); err != nil {
+			return err
+		}
+		handled, err := special(key)
+		if err != nil {
+			return err
+		}
+		if handled {
+			continue
+		}
+		i, ok := index.lookup(key)
+		if !ok {
+			if err := s.skipValue(); err != nil {
+				return err
+			}
+			continue
+		}
+		// A repeated key replaces the earlier value rather than merging
+		// into it, as it would in a map of raw values.
+		f := rv.Field(i)
+		f.SetZero()
+		if err := s.dec.Decode(f.Addr().Interface()); err != nil {
+			if path != "" {
+				err = fmt.Errorf("%s: %w", path, err)
+			}
+			return err
+		}
+	}
+	return s.expect('}')
+}
+
+// skipValue reads past the next value, checking string lengths.
+func (s *jsonStream) skipValue() error {
+	tok, err := s.dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		isObject := t == '{'
+		for s.dec.More() {
+			if isObject {
+				k, err := s.dec.Token()
+				if err != nil {
+					return err
+				}
+				if err := s.opts.checkString(k.(string)); err != nil {
+					return err
+				}
+			}
+			if err := s.skipValue(); err != nil {
+				return err
+			}
+		}
+		_, err := s.dec.Token()
+		return err
+	case string:
+		return s.opts.checkString(t)
+	}
+	return nil
+}
+
+// open reads the opening delimiter of the next value. It returns false
+// when the value is null.
+func (s *jsonStream) open(want json.Delim) (bool, error) {
+	tok, err := s.dec.Token()
+	if err != nil {
+		return false, err
+	}
+	if tok == nil {
+		return false, nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return false, fmt.Errorf("json: expected %q, got %v", want, tok)
+	}
+	return true, nil
+}
+
+func (s *jsonStream) expect(want json.Delim) error {
+	tok, err := s.dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("json: expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+// fieldIndex maps the JSON keys of a struct type to its field indices.
+type fieldIndex struct {
+	names []string
+	index map[string]int
+}
+
+var fieldIndexes sync.Map // reflect.Type -> *fieldIndex
+
+// jsonFields returns the field index of struct type t, named as
+// encoding/json names them.
+func jsonFields(t reflect.Type) *fieldIndex {
+	if fi, ok := fieldIndexes.Load(t); ok {
+		return fi.(*fieldIndex)
+	}
+	fi := &fieldIndex{index: make(map[string]int)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fi.names = append(fi.names, name)
+		fi.index[name] = i
+	}
+	fieldIndexes.Store(t, fi)
+	return fi
+}
+
+// lookup finds the field for key, preferring an exact match and otherwise
+// matching case-insensitively, like encoding/json.
+func (fi *fieldIndex) lookup(key string) (int, bool) {
+	if i, ok := fi.index[key]; ok {
+		return i, true
+	}
+	for _, name := range fi.names {
+		if strings.EqualFold(name, key) {
+			return fi.index[name], true
+		}
+	}
+	return 0, false
 }
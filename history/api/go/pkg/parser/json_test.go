@@ -0,0 +1,330 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/diag"
+	"github.com/visionik/vBRIEF/api/go/pkg/validator"
+)
+
+const nestedPlanJSON = `{
+  "vBRIEFInfo": {"version": "0.5"},
+  "plan": {
+    "title": "Release",
+    "status": "running",
+    "items": [
+      {"id": "api", "subItems": [
+        {"id": "schema", "title": "Schema", "status": "completed"},
+        {"id": "handlers", "title": "Handlers", "status": "pending", "subItems": null}
+      ], "title": "Build API", "status": "inProgress", "metadata": {"points": 3}},
+      {"id": "docs", "title": "Write docs", "status": "pending"}
+    ],
+    "narratives": {"Overview": "Ship it"},
+    "edges": [{"from": "api", "to": "docs", "type": "blocks"}]
+  }
+}`
+
+func TestJSONParser_Streaming(t *testing.T) {
+	doc, err := NewJSONParser().ParseString(nestedPlanJSON)
+	require.NoError(t, err)
+	require.NotNil(t, doc.Plan)
+	require.Len(t, doc.Plan.Items, 2)
+	api := doc.Plan.Items[0]
+	assert.Equal(t, "Build API", api.Title)
+	assert.Equal(t, 3.0, api.Metadata["points"])
+	require.Len(t, api.SubItems, 2)
+	assert.Equal(t, "schema", api.SubItems[0].ID)
+	assert.Nil(t, api.SubItems[1].SubItems)
+
+	empty, err := NewJSONParser().ParseString(`{"vBRIEFInfo": {"version": "0.5"}, "plan": {"items": [{"subItems": []}]}}`)
+	require.NoError(t, err)
+	assert.NotNil(t, empty.Plan.Items[0].SubItems, "an empty array stays empty, not nil")
+	assert.Empty(t, empty.Plan.Items[0].SubItems)
+	assert.Equal(t, "Ship it", doc.Plan.Narratives["Overview"])
+	assert.Len(t, doc.Plan.Edges, 1)
+
+	t.Run("matches keys case-insensitively", func(t *testing.T) {
+		doc, err := NewJSONParser().ParseString(`{"vBRIEFInfo": {"version": "0.5"}, "Plan": {"title": "T", "status": "draft",
+			"Items": [{"id": "a", "title": "A", "status": "pending", "SUBITEMS": [{"id": "b", "title": "B", "status": "pending"}]}]}}`)
+		require.NoError(t, err)
+		require.NotNil(t, doc.Plan)
+		require.Len(t, doc.Plan.Items, 1)
+		require.Len(t, doc.Plan.Items[0].SubItems, 1)
+		assert.Equal(t, "b", doc.Plan.Items[0].SubItems[0].ID)
+
+		var paths []string
+		_, err = ParseItems(strings.NewReader(`{"plan": {"Items": [{"id": "a"}]}}`), func(path string, item *core.PlanItem) error {
+			paths = append(paths, path)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"plan.items[0]"}, paths, "streamed under any spelling")
+	})
+
+	t.Run("rejects malformed input", func(t *testing.T) {
+		for _, in := range []string{
+			`[]`,
+			`{"plan": {"items": [1]}}`,
+			`{"plan": {"items": [null]}}`,
+			`{"plan": {"items": [{"title": 5}]}}`,
+			`{"vBRIEFInfo": {"version": "0.5"}} {}`,
+			`{"vBRIEFInfo": {"version": "0.5"}`,
+		} {
+			_, err := NewJSONParser().ParseString(in)
+			assert.Error(t, err, in)
+		}
+	})
+}
+
+func TestParseItems(t *testing.T) {
+	var paths []string
+	doc, err := ParseItems(strings.NewReader(nestedPlanJSON), func(path string, item *core.PlanItem) error {
+		assert.Nil(t, item.SubItems, "sub-items are reported separately")
+		paths = append(paths, path+" "+item.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"plan.items[0].subItems[0] schema",
+		"plan.items[0].subItems[1] handlers",
+		"plan.items[0] api",
+		"plan.items[1] docs",
+	}, paths)
+	assert.Nil(t, doc.Plan.Items)
+	assert.Equal(t, "Release", doc.Plan.Title)
+	assert.Len(t, doc.Plan.Edges, 1)
+
+	t.Run("stops on callback error", func(t *testing.T) {
+		stop := errors.New("stop")
+		calls := 0
+		_, err := ParseItems(strings.NewReader(nestedPlanJSON), func(string, *core.PlanItem) error {
+			calls++
+			return stop
+		})
+		assert.Same(t, stop, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("streams large plans without the size limit", func(t *testing.T) {
+		count := 0
+		_, err := ParseItems(largePlan(20000), func(string, *core.PlanItem) error {
+			count++
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 20000, count)
+	})
+
+	t.Run("requires a callback", func(t *testing.T) {
+		_, err := ParseItems(strings.NewReader(nestedPlanJSON), nil)
+		assert.Error(t, err)
+	})
+}
+
+// largePlan generates a plan of n items, about 1 KiB each, without holding
+// it in memory.
+func largePlan(n int) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		pad := strings.Repeat("x", 1000)
+		fmt.Fprint(pw, `{"vBRIEFInfo":{"version":"0.5"},"plan":{"title":"Big","status":"draft","items":[`)
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				fmt.Fprint(pw, ",")
+			}
+			fmt.Fprintf(pw, `{"id":"i%d","title":%q,"status":"pending"}`, i, pad)
+		}
+		fmt.Fprint(pw, `]}}`)
+		pw.Close()
+	}()
+	return pr
+}
+
+func TestOptions(t *testing.T) {
+	deep := `{"vBRIEFInfo":{"version":"0.5"},"plan":{"title":"P","status":"draft","items":[` +
+		`{"id":"a","title":"A","status":"pending","subItems":[{"id":"b","title":"B","status":"pending"}]}]}}`
+	deepTRON := "vBRIEFInfo: {version: \"0.5\"}\n" +
+		"plan: {title: \"P\", status: \"draft\", narratives: {}, items: [{id: \"a\", title: \"A\", status: \"pending\", subItems: [{id: \"b\", title: \"B\", status: \"pending\"}]}]}\n"
+
+	tests := []struct {
+		name string
+		opts Options
+		want error
+	}{
+		{"defaults accept", Options{}, nil},
+		{"document size", Options{MaxDocumentSize: 50}, ErrDocumentTooLarge},
+		{"depth", Options{MaxDepth: 1}, ErrTooDeep},
+		{"items", Options{MaxItems: 1}, ErrTooManyItems},
+		{"string length", Options{MaxStringLength: 4}, ErrStringTooLong},
+		{"negative disables", Options{MaxDocumentSize: -1, MaxDepth: -1, MaxItems: -1, MaxStringLength: -1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, p := range map[string]Parser{
+				"json": NewJSONParserWithOptions(tt.opts),
+				"auto": NewAutoParserWithOptions(tt.opts),
+			} {
+				_, err := p.Parse(strings.NewReader(deep))
+				if tt.want == nil {
+					assert.NoError(t, err, name)
+				} else {
+					assert.ErrorIs(t, err, tt.want, name)
+				}
+			}
+
+			_, err := NewTRONParserWithOptions(tt.opts).ParseString(deepTRON)
+			if tt.want == nil {
+				assert.NoError(t, err, "tron")
+			} else {
+				assert.ErrorIs(t, err, tt.want, "tron")
+			}
+		})
+	}
+
+	t.Run("depth errors name the item", func(t *testing.T) {
+		_, err := NewJSONParserWithOptions(Options{MaxDepth: 1}).ParseString(deep)
+		assert.ErrorContains(t, err, "plan.items[0].subItems[0]")
+	})
+
+	t.Run("todo items count", func(t *testing.T) {
+		_, err := NewJSONParserWithOptions(Options{MaxItems: 1}).ParseString(validJSON)
+		assert.ErrorIs(t, err, ErrTooManyItems)
+	})
+}
+
+func TestParseJSONWithSourceMap(t *testing.T) {
+	doc, sm, err := ParseJSONWithSourceMap([]byte(nestedPlanJSON))
+	require.NoError(t, err)
+	assert.Equal(t, "Release", doc.Plan.Title)
+
+	tests := []struct {
+		path      string
+		line, col int
+	}{
+		{diag.RootPath, 1, 1},
+		{"plan.title", 4, 14},
+		{"plan.items[0].subItems[1].status", 9, 59},
+		{"plan.items[1]", 11, 7},
+		{"plan.narratives.Overview", 13, 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			span, ok := sm[tt.path]
+			require.True(t, ok)
+			assert.Equal(t, tt.line, span.Start.Line)
+			assert.Equal(t, tt.col, span.Start.Column)
+		})
+	}
+
+	t.Run("errors carry positions", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			in        string
+			code      string
+			path      string
+			line, col int
+		}{
+			{"syntax", "{\n  \"plan\": {,}\n}", CodeSyntax, diag.RootPath, 2, 12},
+			{"type", "{\n  \"plan\": {\n    \"title\": 5\n  }\n}", CodeType, "plan.title", 3, 14},
+			{"structure", `{"plan": []}`, CodeType, "plan", 1, 10},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, _, err := ParseJSONWithSourceMap([]byte(tt.in))
+				var perr *ParseError
+				require.ErrorAs(t, err, &perr)
+				assert.Equal(t, tt.code, perr.Diagnostic.Code)
+				assert.Equal(t, tt.path, perr.Diagnostic.Path)
+				require.NotNil(t, perr.Diagnostic.Span)
+				assert.Equal(t, tt.line, perr.Diagnostic.Span.Start.Line)
+				assert.Equal(t, tt.col, perr.Diagnostic.Span.Start.Column)
+				assert.Contains(t, err.Error(), fmt.Sprintf("%d:%d: error[%s]", tt.line, tt.col, tt.code))
+			})
+		}
+	})
+}
+
+func TestValidateJSON(t *testing.T) {
+	src := `{
+  "vBRIEFInfo": {"version": "0.5"},
+  "plan": {
+    "title": "Release",
+    "status": "draft",
+    "narratives": {"proposal": "Ship it"},
+    "items": [
+      {"id": "a", "title": "", "status": "pending"},
+      {"id": "b", "title": "B", "status": "inprogress"}
+    ]
+  }
+}`
+
+	diags := ValidateJSON([]byte(src))
+	require.Len(t, diags, 2)
+
+	assert.Equal(t, validator.CodeTitleRequired, diags[0].Code)
+	require.NotNil(t, diags[0].Span)
+	assert.Equal(t, 8, diags[0].Span.Start.Line)
+	assert.Equal(t, 28, diags[0].Span.Start.Column)
+
+	assert.Equal(t, validator.CodeInvalidStatus, diags[1].Code)
+	require.NotNil(t, diags[1].Span)
+	assert.Equal(t, 9, diags[1].Span.Start.Line)
+	require.NotNil(t, diags[1].Fix)
+	assert.Equal(t, `"inProgress"`, diags[1].Fix.Replacement)
+
+	t.Run("with other rules", func(t *testing.T) {
+		src := `{"vBRIEFInfo": {"version": "0.5"},
+  "plan": {"title": "Release", "status": "inProgress", "narratives": {"Proposal": "Ship it"}, "items": []}}`
+		diags := ValidateJSONWith(validator.NewLinter(), []byte(src))
+		require.Len(t, diags, 2, "lint findings are reported as well")
+		for _, d := range diags {
+			assert.Equal(t, "plan.status", d.Path)
+			assert.Equal(t, 2, d.Span.Start.Line)
+		}
+	})
+
+	t.Run("missing values point at their parent", func(t *testing.T) {
+		diags := ValidateJSON([]byte(`{"vBRIEFInfo": {}, "todoList": {"items": []}}`))
+		require.Len(t, diags, 1)
+		assert.Equal(t, validator.CodeVersionRequired, diags[0].Code)
+		require.NotNil(t, diags[0].Span)
+		assert.Equal(t, 16, diags[0].Span.Start.Column)
+	})
+
+	t.Run("parse errors", func(t *testing.T) {
+		diags := ValidateJSON([]byte("{\n  \"plan\": {\"title\": 5}\n}"))
+		require.Len(t, diags, 1)
+		assert.Equal(t, CodeType, diags[0].Code)
+		assert.Equal(t, "plan.title", diags[0].Path)
+		assert.Equal(t, 2, diags[0].Span.Start.Line)
+	})
+}
+
+func BenchmarkJSONParser_ParseBytes(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteString(`{"vBRIEFInfo":{"version":"0.5"},"plan":{"title":"Bench","status":"running","narratives":{"Proposal":"Measure it"},"items":[`)
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `{"id":"i%d","title":"Item %d","status":"pending","tags":["a","b"],"narrative":{"Action":"Do %d"},"metadata":{"points":%d},"subItems":[{"id":"i%d.1","title":"Step","status":"completed"}]}`, i, i, i, i%8, i)
+	}
+	sb.WriteString(`],"edges":[{"from":"i0","to":"i1","type":"blocks"}]}}`)
+	data := []byte(sb.String())
+	p := NewJSONParserWithOptions(Options{MaxDocumentSize: -1, MaxItems: -1})
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for b.Loop() {
+		if _, err := p.ParseBytes(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
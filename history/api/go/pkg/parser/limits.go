@@ -4,25 +4,222 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
 )
 
 const (
 	// MaxDocumentSize limits how much data is read from an io.Reader when parsing.
 	// This prevents unbounded memory use when parsing from untrusted sources.
 	MaxDocumentSize = 10 << 20 // 10 MiB
+
+	// DefaultMaxDepth limits how deeply subItems may nest.
+	DefaultMaxDepth = 64
+	// DefaultMaxItems limits the number of plan and todo items in a document.
+	DefaultMaxItems = 250000
+	// DefaultMaxStringLength limits the length, in runes, of any string.
+	DefaultMaxStringLength = 1 << 20
 )
 
 var (
 	ErrDocumentTooLarge = errors.New("document too large")
+	// ErrTooDeep is returned when subItems nest deeper than Options.MaxDepth.
+	ErrTooDeep = errors.New("items nested too deeply")
+	// ErrTooManyItems is returned when a document has more than
+	// Options.MaxItems items.
+	ErrTooManyItems = errors.New("too many items")
+	// ErrStringTooLong is returned when a string is longer than
+	// Options.MaxStringLength.
+	ErrStringTooLong = errors.New("string too long")
 )
 
+// Options limits what a parser accepts. A zero field selects its default
+// and a negative field disables the limit.
+type Options struct {
+	// MaxDocumentSize is the most bytes read from the input. Defaults to
+	// MaxDocumentSize.
+	MaxDocumentSize int64
+	// MaxDepth is the deepest item nesting allowed; top-level items are
+	// at depth 1. Defaults to DefaultMaxDepth.
+	MaxDepth int
+	// MaxItems is the most plan or todo items allowed. Defaults to
+	// DefaultMaxItems.
+	MaxItems int
+	// MaxStringLength is the longest string allowed, in runes, including
+	// object keys. Defaults to DefaultMaxStringLength.
+	MaxStringLength int
+}
+
+// DefaultOptions returns the limits used by parsers created without
+// options.
+func DefaultOptions() Options {
+	return Options{
+		MaxDocumentSize: MaxDocumentSize,
+		MaxDepth:        DefaultMaxDepth,
+		MaxItems:        DefaultMaxItems,
+		MaxStringLength: DefaultMaxStringLength,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.MaxDocumentSize == 0 {
+		o.MaxDocumentSize = d.MaxDocumentSize
+	}
+	if o.MaxDepth == 0 {
+		o.MaxDepth = d.MaxDepth
+	}
+	if o.MaxItems == 0 {
+		o.MaxItems = d.MaxItems
+	}
+	if o.MaxStringLength == 0 {
+		o.MaxStringLength = d.MaxStringLength
+	}
+	return o
+}
+
+func (o Options) checkDepth(path string, depth int) error {
+	if o.MaxDepth > 0 && depth > o.MaxDepth {
+		return fmt.Errorf("%w: %s is at depth %d, max=%d", ErrTooDeep, path, depth, o.MaxDepth)
+	}
+	return nil
+}
+
+func (o Options) checkItems(n int) error {
+	if o.MaxItems > 0 && n > o.MaxItems {
+		return fmt.Errorf("%w: max=%d", ErrTooManyItems, o.MaxItems)
+	}
+	return nil
+}
+
+func (o Options) checkString(s string) error {
+	if o.MaxStringLength > 0 && len(s) > o.MaxStringLength && utf8.RuneCountInString(s) > o.MaxStringLength {
+		return fmt.Errorf("%w: %d runes, max=%d", ErrStringTooLong, utf8.RuneCountInString(s), o.MaxStringLength)
+	}
+	return nil
+}
+
+func (o Options) checkSize(data []byte) error {
+	if o.MaxDocumentSize >= 0 && int64(len(data)) > o.MaxDocumentSize {
+		return fmt.Errorf("%w: max=%d", ErrDocumentTooLarge, o.MaxDocumentSize)
+	}
+	return nil
+}
+
 func readAllLimited(r io.Reader) ([]byte, error) {
-	data, err := io.ReadAll(io.LimitReader(r, MaxDocumentSize+1))
+	return readAllMax(r, MaxDocumentSize)
+}
+
+func readAllMax(r io.Reader, max int64) ([]byte, error) {
+	if max < 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, max+1))
 	if err != nil {
 		return nil, err
 	}
-	if len(data) > MaxDocumentSize {
-		return nil, fmt.Errorf("%w: max=%d", ErrDocumentTooLarge, MaxDocumentSize)
+	if int64(len(data)) > max {
+		return nil, fmt.Errorf("%w: max=%d", ErrDocumentTooLarge, max)
 	}
 	return data, nil
 }
+
+// limitedReader fails with ErrDocumentTooLarge once more than max bytes
+// have been read, so that streaming decoders stop at the limit.
+type limitedReader struct {
+	r    io.Reader
+	read int64
+	max  int64
+}
+
+func limitReader(r io.Reader, max int64) io.Reader {
+	if max < 0 {
+		return r
+	}
+	return &limitedReader{r: r, max: max}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.read > l.max {
+		return 0, fmt.Errorf("%w: max=%d", ErrDocumentTooLarge, l.max)
+	}
+	if room := l.max + 1 - l.read; int64(len(p)) > room {
+		p = p[:room]
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.max {
+		return 0, fmt.Errorf("%w: max=%d", ErrDocumentTooLarge, l.max)
+	}
+	return n, err
+}
+
+// checkDocument applies the depth, item and string limits to a parsed
+// document.
+func checkDocument(doc *core.Document, opts Options) error {
+	count := 0
+	if doc.TodoList != nil {
+		count += len(doc.TodoList.Items)
+	}
+	var walk func(items []core.PlanItem, path string, depth int) error
+	walk = func(items []core.PlanItem, path string, depth int) error {
+		for i := range items {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			if err := opts.checkDepth(p, depth); err != nil {
+				return err
+			}
+			count++
+			if err := walk(items[i].SubItems, p+".subItems", depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if doc.Plan != nil {
+		if err := walk(doc.Plan.Items, "plan.items", 1); err != nil {
+			return err
+		}
+	}
+	if err := opts.checkItems(count); err != nil {
+		return err
+	}
+	return checkStrings(reflect.ValueOf(doc), opts)
+}
+
+func checkStrings(v reflect.Value, opts Options) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			return checkStrings(v.Elem(), opts)
+		}
+	case reflect.String:
+		return opts.checkString(v.String())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				if err := checkStrings(v.Field(i), opts); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := checkStrings(v.Index(i), opts); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := checkStrings(iter.Key(), opts); err != nil {
+				return err
+			}
+			if err := checkStrings(iter.Value(), opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
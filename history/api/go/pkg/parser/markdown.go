@@ -0,0 +1,273 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+var (
+	mdHeadingRe  = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	mdListItemRe = regexp.MustCompile(`^([ \t]*)(?:[-*+]|\d+[.)])\s+(?:\[([ xX/-])\](?:\s+|$))?(.*)$`)
+	mdTagRe      = regexp.MustCompile(`(?:^|\s)#(\p{L}[\p{L}\p{N}_/-]*)`)
+	mdDueRe      = regexp.MustCompile(`(?:^|\s)due:(\S+)`)
+	mdFenceRe    = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// MarkdownParser parses Markdown task lists into Plan documents.
+//
+// The first level-one heading becomes the plan title and any prose beneath it
+// becomes the "Overview" narrative. Prose under other headings becomes a
+// narrative keyed by the heading text. List items become plan items in source
+// order, nested into SubItems by indentation, and record the heading they
+// appear under in Metadata["section"].
+//
+// Checkbox state maps to item status:
+//
+//	[ ] pending    [x] completed    [/] inProgress    [-] cancelled
+//
+// Inline "#tag" tokens become Tags and a "due:YYYY-MM-DD" token sets DueDate;
+// both are removed from the item title.
+type MarkdownParser struct{}
+
+// NewMarkdownParser creates a new Markdown parser.
+func NewMarkdownParser() Parser {
+	return &MarkdownParser{}
+}
+
+// Parse reads and parses a Markdown document from a reader.
+func (p *MarkdownParser) Parse(r io.Reader) (*core.Document, error) {
+	data, err := readAllLimited(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseBytes(data)
+}
+
+// ParseBytes parses a Markdown document from a byte slice.
+func (p *MarkdownParser) ParseBytes(data []byte) (*core.Document, error) {
+	b := &mdBuilder{
+		plan: &core.Plan{
+			Status:     core.PlanStatusDraft,
+			Narratives: make(map[string]string),
+		},
+		prose: make(map[string][]string),
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxDocumentSize)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if err := b.line(scanner.Text()); err != nil {
+			return nil, fmt.Errorf("markdown: line %d: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return b.build(), nil
+}
+
+// ParseString parses a Markdown document from a string.
+func (p *MarkdownParser) ParseString(s string) (*core.Document, error) {
+	return p.ParseBytes([]byte(s))
+}
+
+// mdNode is a plan item under construction. Items are built as a pointer tree
+// so children can be appended after their parent has been placed.
+type mdNode struct {
+	item     core.PlanItem
+	indent   int
+	children []*mdNode
+}
+
+type mdBuilder struct {
+	plan     *core.Plan
+	roots    []*mdNode
+	stack    []*mdNode
+	section  string
+	keys     []string
+	prose    map[string][]string
+	inFence  bool
+	hasTitle bool
+}
+
+func (b *mdBuilder) line(raw string) error {
+	if mdFenceRe.MatchString(raw) {
+		b.inFence = !b.inFence
+		b.addProse(raw)
+		return nil
+	}
+	if b.inFence {
+		b.addProse(raw)
+		return nil
+	}
+
+	if m := mdHeadingRe.FindStringSubmatch(raw); m != nil {
+		b.stack = nil
+		if len(m[1]) == 1 && !b.hasTitle {
+			b.plan.Title = m[2]
+			b.hasTitle = true
+			b.section = ""
+			return nil
+		}
+		b.section = m[2]
+		return nil
+	}
+
+	if m := mdListItemRe.FindStringSubmatch(raw); m != nil {
+		return b.addItem(indentWidth(m[1]), m[2], m[3])
+	}
+
+	if strings.TrimSpace(raw) == "" {
+		b.addProse("")
+		return nil
+	}
+
+	// Indented text inside a list continues the most recent item.
+	if len(b.stack) > 0 && indentWidth(raw) > 0 {
+		top := b.stack[len(b.stack)-1]
+		if top.item.Narrative == nil {
+			top.item.Narrative = make(map[string]string)
+		}
+		text := strings.TrimSpace(raw)
+		if prev := top.item.Narrative["Overview"]; prev != "" {
+			text = prev + "\n" + text
+		}
+		top.item.Narrative["Overview"] = text
+		return nil
+	}
+
+	b.stack = nil
+	b.addProse(raw)
+	return nil
+}
+
+func (b *mdBuilder) addItem(indent int, box, text string) error {
+	item, err := parseMarkdownItem(box, text)
+	if err != nil {
+		return err
+	}
+	if b.section != "" {
+		item.Metadata = map[string]interface{}{"section": b.section}
+	}
+
+	node := &mdNode{item: item, indent: indent}
+	for len(b.stack) > 0 && b.stack[len(b.stack)-1].indent >= indent {
+		b.stack = b.stack[:len(b.stack)-1]
+	}
+	if len(b.stack) == 0 {
+		b.roots = append(b.roots, node)
+	} else {
+		parent := b.stack[len(b.stack)-1]
+		parent.children = append(parent.children, node)
+	}
+	b.stack = append(b.stack, node)
+	return nil
+}
+
+func (b *mdBuilder) addProse(line string) {
+	key := b.section
+	if key == "" {
+		key = "Overview"
+	}
+	if _, ok := b.prose[key]; !ok {
+		if strings.TrimSpace(line) == "" {
+			return
+		}
+		b.keys = append(b.keys, key)
+	}
+	b.prose[key] = append(b.prose[key], line)
+}
+
+func (b *mdBuilder) build() *core.Document {
+	for _, key := range b.keys {
+		if text := strings.TrimSpace(strings.Join(b.prose[key], "\n")); text != "" {
+			b.plan.Narratives[key] = text
+		}
+	}
+	for _, n := range b.roots {
+		b.plan.Items = append(b.plan.Items, n.build())
+	}
+	return &core.Document{
+		Info: core.Info{Version: core.SpecVersion},
+		Plan: b.plan,
+	}
+}
+
+func (n *mdNode) build() core.PlanItem {
+	item := n.item
+	for _, c := range n.children {
+		item.SubItems = append(item.SubItems, c.build())
+	}
+	return item
+}
+
+// parseMarkdownItem extracts status, tags and due date from a list item.
+func parseMarkdownItem(box, text string) (core.PlanItem, error) {
+	item := core.PlanItem{Status: core.PlanItemStatusPending}
+	switch box {
+	case "x", "X":
+		item.Status = core.PlanItemStatusCompleted
+	case "/":
+		item.Status = core.PlanItemStatusRunning
+	case "-":
+		item.Status = core.PlanItemStatusCancelled
+	}
+
+	if m := mdDueRe.FindStringSubmatch(text); m != nil {
+		due, err := time.Parse("2006-01-02", m[1])
+		if err != nil {
+			return item, fmt.Errorf("invalid due date %q: %w", m[1], err)
+		}
+		item.DueDate = &due
+		text = mdDueRe.ReplaceAllString(text, " ")
+	}
+
+	for _, m := range mdTagRe.FindAllStringSubmatch(text, -1) {
+		item.Tags = append(item.Tags, m[1])
+	}
+	text = mdTagRe.ReplaceAllString(text, " ")
+
+	item.Title = strings.Join(strings.Fields(text), " ")
+	return item, nil
+}
+
+// indentWidth returns the visual width of leading whitespace, counting tabs
+// as four columns.
+func indentWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// looksLikeMarkdown reports whether data starts with a Markdown heading or
+// list item, or has a heading or task-list item further down, after an
+// opening paragraph.
+func looksLikeMarkdown(data []byte) bool {
+	for i, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimRight(raw, "\r")
+		if mdHeadingRe.MatchString(line) {
+			return true
+		}
+		if m := mdListItemRe.FindStringSubmatch(line); m != nil && (i == 0 || m[2] != "") {
+			return true
+		}
+	}
+	return false
+}
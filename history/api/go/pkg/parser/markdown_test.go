@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+const validMarkdown = `# Release 1.2
+
+Ship the billing rewrite.
+
+## Backend
+
+- [x] Migrate schema #db
+- [ ] Rewrite invoices #billing due:2025-03-01
+  - [ ] Line items
+    Split tax from subtotal.
+  - [/] PDF export
+- [-] Legacy exporter
+
+## Risks
+
+Vendor API may change.
+`
+
+func TestMarkdownParser(t *testing.T) {
+	parser := NewMarkdownParser()
+
+	t.Run("maps headings to title and narratives", func(t *testing.T) {
+		doc, err := parser.ParseString(validMarkdown)
+
+		require.NoError(t, err)
+		require.NotNil(t, doc.Plan)
+		assert.Equal(t, core.SpecVersion, doc.Info.Version)
+		assert.Equal(t, "Release 1.2", doc.Plan.Title)
+		assert.Equal(t, core.PlanStatusDraft, doc.Plan.Status)
+		assert.Equal(t, "Ship the billing rewrite.", doc.Plan.Narratives["Overview"])
+		assert.Equal(t, "Vendor API may change.", doc.Plan.Narratives["Risks"])
+		assert.NotContains(t, doc.Plan.Narratives, "Backend")
+	})
+
+	t.Run("maps list items in order with checkbox status", func(t *testing.T) {
+		doc, err := parser.ParseString(validMarkdown)
+
+		require.NoError(t, err)
+		items := doc.Plan.Items
+		require.Len(t, items, 3)
+		assert.Equal(t, "Migrate schema", items[0].Title)
+		assert.Equal(t, core.PlanItemStatusCompleted, items[0].Status)
+		assert.Equal(t, "Rewrite invoices", items[1].Title)
+		assert.Equal(t, core.PlanItemStatusPending, items[1].Status)
+		assert.Equal(t, "Legacy exporter", items[2].Title)
+		assert.Equal(t, core.PlanItemStatusCancelled, items[2].Status)
+		assert.Equal(t, "Backend", items[0].Metadata["section"])
+	})
+
+	t.Run("nests indented items as subItems", func(t *testing.T) {
+		doc, err := parser.ParseString(validMarkdown)
+
+		require.NoError(t, err)
+		subs := doc.Plan.Items[1].SubItems
+		require.Len(t, subs, 2)
+		assert.Equal(t, "Line items", subs[0].Title)
+		assert.Equal(t, "Split tax from subtotal.", subs[0].Narrative["Overview"])
+		assert.Equal(t, "PDF export", subs[1].Title)
+		assert.Equal(t, core.PlanItemStatusRunning, subs[1].Status)
+	})
+
+	t.Run("extracts tags and due dates", func(t *testing.T) {
+		doc, err := parser.ParseString(validMarkdown)
+
+		require.NoError(t, err)
+		item := doc.Plan.Items[1]
+		assert.Equal(t, []string{"billing"}, item.Tags)
+		require.NotNil(t, item.DueDate)
+		assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), *item.DueDate)
+		assert.Equal(t, []string{"db"}, doc.Plan.Items[0].Tags)
+	})
+
+	t.Run("ignores checklist syntax inside code fences", func(t *testing.T) {
+		doc, err := parser.ParseString("# T\n\n```\n- [ ] not an item\n```\n")
+
+		require.NoError(t, err)
+		assert.Empty(t, doc.Plan.Items)
+		assert.Contains(t, doc.Plan.Narratives["Overview"], "- [ ] not an item")
+	})
+
+	t.Run("returns error for invalid due date", func(t *testing.T) {
+		_, err := parser.ParseString("- [ ] Task due:2025-13-01")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "line 1")
+	})
+
+	t.Run("parses from reader", func(t *testing.T) {
+		doc, err := parser.Parse(strings.NewReader(validMarkdown))
+
+		require.NoError(t, err)
+		assert.Equal(t, "Release 1.2", doc.Plan.Title)
+	})
+}
+
+func TestAutoParser_Markdown(t *testing.T) {
+	parser := NewAutoParser()
+
+	t.Run("detects heading", func(t *testing.T) {
+		doc, err := parser.ParseString("\n" + validMarkdown)
+
+		require.NoError(t, err)
+		require.NotNil(t, doc.Plan)
+		assert.Equal(t, "Release 1.2", doc.Plan.Title)
+	})
+
+	t.Run("detects bare checklist", func(t *testing.T) {
+		doc, err := parser.ParseString("- [ ] one\n- [x] two\n")
+
+		require.NoError(t, err)
+		require.NotNil(t, doc.Plan)
+		assert.Len(t, doc.Plan.Items, 2)
+	})
+
+	t.Run("detects checklist after a paragraph", func(t *testing.T) {
+		doc, err := parser.ParseString("Some intro\n\n- [ ] a\n")
+
+		require.NoError(t, err)
+		require.NotNil(t, doc.Plan)
+		require.Len(t, doc.Plan.Items, 1)
+		assert.Equal(t, "a", doc.Plan.Items[0].Title)
+	})
+}
@@ -34,6 +34,26 @@ const (
 	FormatJSON Format = "json"
 	// FormatTRON represents TRON format.
 	FormatTRON Format = "tron"
+	// FormatMarkdown represents Markdown task lists.
+	FormatMarkdown Format = "markdown"
+	// FormatICal represents iCalendar (RFC 5545) data.
+	FormatICal Format = "ical"
+	// FormatCSV represents a comma-separated table of plan items.
+	FormatCSV Format = "csv"
+	// FormatTSV represents a tab-separated table of plan items.
+	FormatTSV Format = "tsv"
+	// FormatTodoTxt represents todo.txt task lists.
+	FormatTodoTxt Format = "todotxt"
+	// FormatBeads represents Beads issues.jsonl files.
+	FormatBeads Format = "beads"
+	// FormatGitHub represents saved GitHub REST API issue dumps.
+	FormatGitHub Format = "github"
+	// FormatJira represents Jira JSON and CSV issue exports.
+	FormatJira Format = "jira"
+	// FormatYAML represents plain YAML documents.
+	FormatYAML Format = "yaml"
+	// FormatTROY represents TROY, YAML with tagged class instances.
+	FormatTROY Format = "troy"
 	// FormatAuto automatically detects the format.
 	FormatAuto Format = "auto"
 )
@@ -45,6 +65,24 @@ func New(format Format) (Parser, error) {
 		return NewJSONParser(), nil
 	case FormatTRON:
 		return NewTRONParser(), nil
+	case FormatMarkdown:
+		return NewMarkdownParser(), nil
+	case FormatICal:
+		return NewICalParser(), nil
+	case FormatCSV:
+		return NewCSVParser(), nil
+	case FormatTSV:
+		return NewTSVParser(), nil
+	case FormatTodoTxt:
+		return NewTodoTxtParser(), nil
+	case FormatBeads:
+		return NewBeadsParser(), nil
+	case FormatGitHub:
+		return NewGitHubParser(), nil
+	case FormatJira:
+		return NewJiraParser(), nil
+	case FormatYAML, FormatTROY:
+		return NewYAMLParser(), nil
 	case FormatAuto:
 		return NewAutoParser(), nil
 	default:
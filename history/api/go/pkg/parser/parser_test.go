@@ -126,6 +126,42 @@ func TestAutoParser(t *testing.T) {
 		require.NotNil(t, doc)
 		assert.Equal(t, "0.2", doc.Info.Version)
 	})
+
+	t.Run("detects and parses YAML", func(t *testing.T) {
+		doc, err := parser.ParseString("vBRIEFInfo:\n  version: \"0.2\"\ntodoList:\n  items:\n    - title: Task 1\n      status: pending\n")
+
+		require.NoError(t, err)
+		require.NotNil(t, doc.TodoList)
+		assert.Equal(t, "0.2", doc.Info.Version)
+		assert.Equal(t, "Task 1", doc.TodoList.Items[0].Title)
+	})
+
+	t.Run("detects and parses TROY", func(t *testing.T) {
+		doc, err := parser.ParseString("classes:\n  TodoItem: [title, status]\nvBRIEFInfo:\n  version: \"0.2\"\ntodoList:\n  items:\n    - !TodoItem [Task 1, pending]\n")
+
+		require.NoError(t, err)
+		require.NotNil(t, doc.TodoList)
+		assert.Equal(t, core.StatusPending, doc.TodoList.Items[0].Status)
+	})
+
+	t.Run("detects YAML opening with a comment", func(t *testing.T) {
+		doc, err := parser.ParseString("# Release plan, edited by hand\nvBRIEFInfo:\n  version: \"0.5\"\nplan:\n  title: Release\n  status: draft\n  narratives:\n    Proposal: Ship it\n  items:\n    - title: Build\n      status: pending\n")
+
+		require.NoError(t, err)
+		require.NotNil(t, doc.Plan)
+		assert.Equal(t, "Release", doc.Plan.Title)
+		assert.Equal(t, map[string]string{"Proposal": "Ship it"}, doc.Plan.Narratives)
+		require.Len(t, doc.Plan.Items, 1)
+		assert.Equal(t, "Build", doc.Plan.Items[0].Title)
+	})
+
+	t.Run("still detects Markdown headings", func(t *testing.T) {
+		doc, err := parser.ParseString("# Release\n\n- [ ] Build\n")
+
+		require.NoError(t, err)
+		require.NotNil(t, doc.Plan)
+		assert.Equal(t, "Release", doc.Plan.Title)
+	})
 }
 
 func TestTRONParser(t *testing.T) {
@@ -137,6 +173,19 @@ func TestTRONParser(t *testing.T) {
 		assert.NotNil(t, parser)
 	})
 
+	t.Run("parses a plan with classes", func(t *testing.T) {
+		doc, err := parser.ParseString("class Edge: from, to, type\nclass PlanItem: id, title, status\n\n" +
+			"vBRIEFInfo: {version: \"0.5\"}\n" +
+			"plan: {title: \"P\", status: \"draft\", narratives: {}, items: [PlanItem(\"a\", \"A\", \"pending\"), PlanItem(\"b\", \"B\", null)], edges: [Edge(\"a\", \"b\", \"blocks\")]}\n")
+
+		require.NoError(t, err)
+		require.NotNil(t, doc.Plan)
+		require.Len(t, doc.Plan.Items, 2)
+		assert.Equal(t, "A", doc.Plan.Items[0].Title)
+		assert.Equal(t, core.PlanItemStatus(""), doc.Plan.Items[1].Status)
+		assert.Equal(t, []core.Edge{{From: "a", To: "b", Type: core.EdgeBlocks}}, doc.Plan.Edges)
+	})
+
 	t.Run("returns error for invalid TRON", func(t *testing.T) {
 		_, err := parser.ParseString("invalid tron")
 		assert.Error(t, err)
@@ -188,6 +237,16 @@ func TestNew(t *testing.T) {
 	}{
 		{"JSON format", FormatJSON, "*parser.JSONParser"},
 		{"TRON format", FormatTRON, "*parser.TRONParser"},
+		{"Markdown format", FormatMarkdown, "*parser.MarkdownParser"},
+		{"iCalendar format", FormatICal, "*parser.ICalParser"},
+		{"CSV format", FormatCSV, "*parser.CSVParser"},
+		{"TSV format", FormatTSV, "*parser.CSVParser"},
+		{"todo.txt format", FormatTodoTxt, "*parser.TodoTxtParser"},
+		{"Beads format", FormatBeads, "*parser.BeadsParser"},
+		{"GitHub format", FormatGitHub, "*parser.GitHubParser"},
+		{"Jira format", FormatJira, "*parser.JiraParser"},
+		{"YAML format", FormatYAML, "*parser.YAMLParser"},
+		{"TROY format", FormatTROY, "*parser.YAMLParser"},
 		{"Auto format", FormatAuto, "auto"},
 		{"Unknown format errors", Format("unknown"), ""},
 	}
@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/diag"
+)
+
+// Rule codes for diagnostics reported by the parser.
+const (
+	// CodeSyntax marks input that is not well-formed JSON.
+	CodeSyntax = "VB0001"
+	// CodeType marks a JSON value of the wrong type for its field.
+	CodeType = "VB0002"
+)
+
+// Rules describes the parser's rule codes.
+var Rules = []diag.Rule{
+	{Code: CodeSyntax, Name: "syntax", Description: "The document must be well-formed JSON.", Severity: diag.SeverityError},
+	{Code: CodeType, Name: "value-type", Description: "Each field must hold a value of the type the schema requires.", Severity: diag.SeverityError},
+}
+
+// ParseError is a decoding error located in the source.
+type ParseError struct {
+	Diagnostic diag.Diagnostic
+	// Err is the underlying decoding error.
+	Err error
+}
+
+// Error returns the message prefixed with the line and column.
+func (e *ParseError) Error() string {
+	return e.Diagnostic.String()
+}
+
+// Unwrap returns the underlying decoding error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseJSONWithSourceMap parses a JSON document and records the span of
+// every value, keyed by the paths the validator reports, such as
+// "plan.items[3].title". Syntax and type errors are returned as
+// *ParseError.
+func ParseJSONWithSourceMap(data []byte) (*core.Document, diag.SourceMap, error) {
+	doc, err := NewJSONParser().ParseBytes(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	sm, err := JSONSourceMap(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, sm, nil
+}
+
+// JSONSourceMap records the span of every value in a JSON document.
+// Object members are keyed "parent.key" and array elements "parent[i]";
+// the whole document is diag.RootPath.
+func JSONSourceMap(data []byte) (diag.SourceMap, error) {
+	b := &spanBuilder{
+		dec:   json.NewDecoder(bytes.NewReader(data)),
+		data:  data,
+		lines: diag.NewLines(data),
+		spans: make(diag.SourceMap),
+	}
+	b.dec.UseNumber()
+	if err := b.value(diag.RootPath); err != nil {
+		return nil, locate(data, err, b.spans)
+	}
+	return b.spans, nil
+}
+
+type spanBuilder struct {
+	dec   *json.Decoder
+	data  []byte
+	lines *diag.Lines
+	spans diag.SourceMap
+}
+
+// start returns the offset of the next value, skipping the separators the
+// decoder has not consumed yet.
+func (b *spanBuilder) start() int {
+	off := int(b.dec.InputOffset())
+	for off < len(b.data) && strings.IndexByte(" \t\r\n,:", b.data[off]) >= 0 {
+		off++
+	}
+	return off
+}
+
+func (b *spanBuilder) value(path string) error {
+	start := b.start()
+	tok, err := b.dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); ok {
+		for i := 0; b.dec.More(); i++ {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			if d == '{' {
+				k, err := b.dec.Token()
+				if err != nil {
+					return err
+				}
+				child = joinPath(path, k.(string))
+			}
+			if err := b.value(child); err != nil {
+				return err
+			}
+		}
+		if _, err := b.dec.Token(); err != nil {
+			return err
+		}
+	}
+	b.spans[path] = b.lines.Span(start, int(b.dec.InputOffset()))
+	return nil
+}
+
+func joinPath(parent, key string) string {
+	if parent == diag.RootPath {
+		return key
+	}
+	return parent + "." + key
+}
+
+// locate turns a decoding error into a *ParseError where the error's
+// position is known, and returns other errors unchanged.
+func locate(data []byte, err error, spans diag.SourceMap) error {
+	lines := diag.NewLines(data)
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		// The offset is just past the offending character.
+		pos := lines.Position(int(syntax.Offset) - 1)
+		return &ParseError{Err: err, Diagnostic: diag.Diagnostic{
+			Code:     CodeSyntax,
+			Severity: diag.SeverityError,
+			Path:     diag.RootPath,
+			Message:  syntax.Error(),
+			Span:     &diag.Span{Start: pos, End: pos},
+		}}
+	}
+	var typ *json.UnmarshalTypeError
+	if errors.As(err, &typ) {
+		d := diag.Diagnostic{
+			Code:     CodeType,
+			Severity: diag.SeverityError,
+			Message:  fmt.Sprintf("cannot use %s as %s", typ.Value, typ.Type),
+		}
+		// The error's offset is just past the bad value; report the
+		// innermost value that contains it.
+		if path, span, ok := innermost(spans, int(typ.Offset)-1); ok {
+			d.Path, d.Span = path, &span
+		} else {
+			pos := lines.Position(int(typ.Offset))
+			d.Path, d.Span = typ.Field, &diag.Span{Start: pos, End: pos}
+		}
+		return &ParseError{Err: err, Diagnostic: d}
+	}
+	return err
+}
+
+func innermost(spans diag.SourceMap, offset int) (string, diag.Span, bool) {
+	var (
+		bestPath string
+		best     diag.Span
+		found    bool
+	)
+	for path, s := range spans {
+		if s.Start.Offset > offset || s.End.Offset <= offset {
+			continue
+		}
+		size := s.End.Offset - s.Start.Offset
+		if !found || size < best.End.Offset-best.Start.Offset {
+			bestPath, best, found = path, s, true
+		}
+	}
+	return bestPath, best, found
+}
+
+// positionError locates a JSON decoding error in data. The streaming
+// decoder reports type errors against re-encoded fragments, so those are
+// reproduced with encoding/json against the original input.
+func positionError(data []byte, err error) error {
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		return locate(data, err, nil)
+	}
+	if isLimitError(err) {
+		return err
+	}
+	var doc core.Document
+	uerr := json.Unmarshal(data, &doc)
+	var typ *json.UnmarshalTypeError
+	if !errors.As(uerr, &typ) {
+		return err
+	}
+	spans, smErr := JSONSourceMap(data)
+	if smErr != nil {
+		return err
+	}
+	return locate(data, uerr, spans)
+}
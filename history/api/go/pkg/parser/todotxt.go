@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"io"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/todotxt"
+)
+
+// TodoTxtParser parses todo.txt task lists into Plan documents titled
+// "todo.txt". See package todotxt for how lines map onto plan items.
+type TodoTxtParser struct{}
+
+// NewTodoTxtParser creates a new todo.txt parser.
+func NewTodoTxtParser() Parser {
+	return &TodoTxtParser{}
+}
+
+// Parse reads and parses a todo.txt list from a reader.
+func (p *TodoTxtParser) Parse(r io.Reader) (*core.Document, error) {
+	data, err := readAllLimited(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseBytes(data)
+}
+
+// ParseBytes parses a todo.txt list from a byte slice.
+func (p *TodoTxtParser) ParseBytes(data []byte) (*core.Document, error) {
+	return todotxt.Decode(data, "todo.txt")
+}
+
+// ParseString parses a todo.txt list from a string.
+func (p *TodoTxtParser) ParseString(s string) (*core.Document, error) {
+	return p.ParseBytes([]byte(s))
+}
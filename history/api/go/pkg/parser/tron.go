@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"encoding/json"
 	"io"
 
 	"github.com/tron-format/trongo/pkg/tron"
@@ -8,16 +9,26 @@ import (
 )
 
 // TRONParser parses documents in TRON format.
-type TRONParser struct{}
+//
+// TRON input is read whole; the depth, item and string limits in Options
+// are checked once it has been decoded.
+type TRONParser struct {
+	opts Options
+}
 
-// NewTRONParser creates a new TRON parser.
+// NewTRONParser creates a new TRON parser with DefaultOptions.
 func NewTRONParser() Parser {
-	return &TRONParser{}
+	return &TRONParser{opts: DefaultOptions()}
+}
+
+// NewTRONParserWithOptions creates a new TRON parser with custom limits.
+func NewTRONParserWithOptions(opts Options) Parser {
+	return &TRONParser{opts: opts.withDefaults()}
 }
 
 // Parse reads and parses a TRON document from a reader.
 func (p *TRONParser) Parse(r io.Reader) (*core.Document, error) {
-	data, err := readAllLimited(r)
+	data, err := readAllMax(r, p.opts.MaxDocumentSize)
 	if err != nil {
 		return nil, err
 	}
@@ -25,9 +36,26 @@ func (p *TRONParser) Parse(r io.Reader) (*core.Document, error) {
 }
 
 // ParseBytes parses a TRON document from a byte slice.
+//
+// The document is read into generic values and then through the JSON model,
+// since tron.Unmarshal cannot fill pointer fields such as Document.Plan.
 func (p *TRONParser) ParseBytes(data []byte) (*core.Document, error) {
+	if err := p.opts.checkSize(data); err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := tron.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
 	var doc core.Document
-	if err := tron.Unmarshal(data, &doc); err != nil {
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	if err := checkDocument(&doc, p.opts); err != nil {
 		return nil, err
 	}
 	return &doc, nil
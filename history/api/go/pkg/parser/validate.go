@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"errors"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/diag"
+	"github.com/visionik/vBRIEF/api/go/pkg/validator"
+)
+
+// ValidateJSON parses and validates a JSON document with the core rules,
+// returning every problem as a diagnostic located in the source, in source
+// order. A document that cannot be decoded yields only its parse error.
+func ValidateJSON(data []byte) []diag.Diagnostic {
+	return ValidateJSONWith(validator.NewValidator(), data)
+}
+
+// ValidateJSONWith is ValidateJSON using v's rules, reporting warnings and
+// info findings as well as errors.
+func ValidateJSONWith(v validator.Validator, data []byte) []diag.Diagnostic {
+	doc, sm, err := ParseJSONWithSourceMap(data)
+	if err != nil {
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			return []diag.Diagnostic{perr.Diagnostic}
+		}
+		return []diag.Diagnostic{{
+			Code:     CodeSyntax,
+			Severity: diag.SeverityError,
+			Path:     diag.RootPath,
+			Message:  err.Error(),
+		}}
+	}
+	diags := validator.Check(v, doc).WithSourceMap(sm).Diagnostics()
+	diag.Sort(diags)
+	return diags
+}
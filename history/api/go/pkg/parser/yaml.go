@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"io"
+	"regexp"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/yaml"
+)
+
+// YAMLParser parses plain YAML and TROY documents. Both are read by the
+// same parser: TROY is YAML with a top-level "classes" mapping and tagged
+// class instances.
+type YAMLParser struct{}
+
+// NewYAMLParser creates a new YAML and TROY parser.
+func NewYAMLParser() Parser {
+	return &YAMLParser{}
+}
+
+// Parse reads and parses a YAML document from a reader.
+func (p *YAMLParser) Parse(r io.Reader) (*core.Document, error) {
+	data, err := readAllLimited(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseBytes(data)
+}
+
+// ParseBytes parses a YAML document from a byte slice. Use
+// yaml.DecodeWithComments to keep the document's comments.
+func (p *YAMLParser) ParseBytes(data []byte) (*core.Document, error) {
+	return yaml.Decode(data)
+}
+
+// ParseString parses a YAML document from a string.
+func (p *YAMLParser) ParseString(s string) (*core.Document, error) {
+	return p.ParseBytes([]byte(s))
+}
+
+// tronLine matches the lines TRON documents are made of: class definitions
+// and constructor calls, neither of which is valid YAML.
+var tronLine = regexp.MustCompile(`(?m)^\s*(class\s+\w+\s*:|[\w"]+\s*:\s*[A-Za-z_]\w*\()`)
+
+// yamlRoot matches a block-style top-level vBRIEFInfo key, which TRON
+// writes without the space after the colon.
+var yamlRoot = regexp.MustCompile(`(?m)^vBRIEFInfo:(\s|$)`)
+
+// looksLikeYAML reports whether data is a YAML mapping rather than TRON.
+func looksLikeYAML(data []byte) bool {
+	return len(data) > 0 && !tronLine.Match(data)
+}
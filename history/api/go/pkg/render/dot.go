@@ -0,0 +1,110 @@
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+// DOT renders a plan as a Graphviz digraph.
+//
+// Items are filled by status. Items with sub-items are drawn as clusters that
+// contain the parent item and its children. Edges that reference unknown
+// items are omitted.
+func DOT(plan *core.Plan, opts Options) ([]byte, error) {
+	if plan == nil {
+		return nil, core.ErrNoPlan
+	}
+	g := graph.New(plan)
+	h, err := computeHighlights(g, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := nodeKeys(g)
+	var b strings.Builder
+	b.WriteString("digraph plan {\n")
+	fmt.Fprintf(&b, "  label=%s;\n", strconv.Quote(plan.Title))
+	b.WriteString("  labelloc=t;\n")
+	fmt.Fprintf(&b, "  rankdir=%s;\n", opts.direction())
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+
+	var cluster int
+	var writeNode func(n *graph.Node, indent string)
+	writeNode = func(n *graph.Node, indent string) {
+		inner := indent
+		if !n.IsLeaf() {
+			cluster++
+			fmt.Fprintf(&b, "%ssubgraph cluster_%d {\n", indent, cluster)
+			fmt.Fprintf(&b, "%s  label=%s;\n", indent, strconv.Quote(n.Item.Title))
+			fmt.Fprintf(&b, "%s  style=dashed;\n", indent)
+			inner = indent + "  "
+		}
+
+		attrs := []string{
+			"label=" + strconv.Quote(n.Item.Title),
+			"fillcolor=" + strconv.Quote(statusColor(n.Item.Status)),
+		}
+		if !n.IsLeaf() {
+			attrs = append(attrs, "shape=folder")
+		}
+		switch {
+		case h.critical[n]:
+			attrs = append(attrs, "color="+strconv.Quote(highlightColor), "penwidth=3")
+		case h.ready[n]:
+			attrs = append(attrs, "color="+strconv.Quote(readyColor), "penwidth=3")
+		}
+		if n.Item.Status == core.PlanItemStatusCancelled {
+			attrs = append(attrs, "fontcolor=\"#777777\"")
+		}
+		fmt.Fprintf(&b, "%s%s [%s];\n", inner, strconv.Quote(ids[n]), strings.Join(attrs, ", "))
+
+		if n.IsLeaf() {
+			return
+		}
+		for _, c := range n.Children {
+			writeNode(c, inner)
+		}
+		fmt.Fprintf(&b, "%s}\n", indent)
+	}
+	for _, n := range g.Roots() {
+		writeNode(n, "  ")
+	}
+
+	for _, e := range g.Edges() {
+		st := styleFor(e.Type)
+		color := st.color
+		attrs := []string{"style=" + st.dash}
+		if h.onPath(e) {
+			color = highlightColor
+			attrs = append(attrs, "penwidth=3")
+		}
+		attrs = append(attrs, "color="+strconv.Quote(color))
+		if !e.Type.IsCore() {
+			attrs = append(attrs, "label="+strconv.Quote(string(e.Type)))
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n",
+			strconv.Quote(ids[g.Node(e.From)]), strconv.Quote(ids[g.Node(e.To)]), strings.Join(attrs, ", "))
+	}
+
+	b.WriteString("}\n")
+	return []byte(b.String()), nil
+}
+
+// nodeKeys assigns each node a unique key: its hierarchical ID when it has
+// one, otherwise its document path.
+func nodeKeys(g *graph.Graph) map[*graph.Node]string {
+	keys := make(map[*graph.Node]string, len(g.Nodes()))
+	for _, n := range g.Nodes() {
+		if n.ID != "" && g.Node(n.ID) == n {
+			keys[n] = n.ID
+		} else {
+			keys[n] = n.Path
+		}
+	}
+	return keys
+}
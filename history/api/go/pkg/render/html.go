@@ -0,0 +1,205 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// HTML renders a document as a single self-contained HTML page: plan
+// metadata, narratives rendered from Markdown, a collapsible item tree with
+// status badges and progress bars, and an inline SVG of the plan's DAG.
+//
+// The page has no external assets, scripts or network requests, so it can be
+// published directly as a CI artifact.
+func HTML(doc *core.Document, opts Options) ([]byte, error) {
+	if doc == nil || doc.Plan == nil {
+		return nil, core.ErrNoPlan
+	}
+
+	// A cyclic plan still gets a report, just without the diagram.
+	svg, _ := SVG(doc.Plan, opts)
+
+	var root core.PlanItem
+	root.SubItems = doc.Plan.Items
+	view := htmlView{
+		Doc:        doc,
+		Plan:       doc.Plan,
+		Narratives: narrativeViews(doc.Plan.Narratives),
+		Items:      itemViews(doc.Plan.Items),
+		Progress:   root.Progress(),
+		Diagram:    template.HTML(svg),
+		Metadata:   metadataViews(doc.Info.Metadata),
+	}
+
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, view); err != nil {
+		return nil, fmt.Errorf("render html: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+type htmlView struct {
+	Doc        *core.Document
+	Plan       *core.Plan
+	Narratives []narrativeView
+	Items      []itemView
+	Progress   float64
+	Diagram    template.HTML
+	Metadata   []keyValue
+}
+
+type narrativeView struct {
+	Key  string
+	Body template.HTML
+}
+
+type keyValue struct {
+	Key   string
+	Value string
+}
+
+type itemView struct {
+	Item       core.PlanItem
+	Progress   float64
+	Narratives []narrativeView
+	Children   []itemView
+}
+
+func itemViews(items []core.PlanItem) []itemView {
+	views := make([]itemView, 0, len(items))
+	for _, it := range items {
+		views = append(views, itemView{
+			Item:       it,
+			Progress:   it.Progress(),
+			Narratives: narrativeViews(it.Narrative),
+			Children:   itemViews(it.SubItems),
+		})
+	}
+	return views
+}
+
+func narrativeViews(m map[string]string) []narrativeView {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	views := make([]narrativeView, 0, len(keys))
+	for _, k := range keys {
+		views = append(views, narrativeView{
+			Key:  k,
+			Body: template.HTML(markdownHTML(m[k])),
+		})
+	}
+	return views
+}
+
+func metadataViews(m map[string]interface{}) []keyValue {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]keyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, keyValue{Key: k, Value: fmt.Sprint(m[k])})
+	}
+	return kvs
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"color": func(s core.PlanItemStatus) string { return statusColor(s) },
+	"pct":   func(f float64) string { return fmt.Sprintf("%.0f", f) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Plan.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem auto; max-width: 960px; color: #222; line-height: 1.45; padding: 0 1rem; }
+h1 { margin-bottom: .25rem; }
+dl.meta { display: grid; grid-template-columns: max-content 1fr; gap: .2rem 1rem; font-size: .9rem; }
+dl.meta dt { color: #666; }
+.badge { display: inline-block; border-radius: 3px; padding: 0 .4rem; font-size: .75rem; border: 1px solid #999; vertical-align: middle; }
+.bar { display: inline-block; width: 120px; height: 8px; background: #eee; border-radius: 4px; overflow: hidden; vertical-align: middle; }
+.bar span { display: block; height: 100%; background: #4caf50; }
+.pct { font-size: .75rem; color: #666; margin-left: .3rem; }
+details { margin: .2rem 0 .2rem 1.2rem; }
+summary { cursor: pointer; }
+.leaf { margin: .2rem 0 .2rem 2.2rem; }
+.tag { font-size: .75rem; color: #1f77b4; margin-left: .3rem; }
+.due { font-size: .75rem; color: #a33; margin-left: .3rem; }
+.narrative { margin: .3rem 0 .3rem 1.2rem; font-size: .9rem; }
+.diagram { overflow-x: auto; border: 1px solid #ddd; padding: .5rem; }
+pre { background: #f6f8fa; padding: .5rem; overflow-x: auto; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Plan.Title}}</h1>
+<span class="badge" style="background:#f0f0f0">{{.Plan.Status}}</span>
+<span class="bar"><span style="width:{{pct .Progress}}%"></span></span><span class="pct">{{pct .Progress}}%</span>
+<dl class="meta">
+<dt>vBRIEF version</dt><dd>{{.Doc.Info.Version}}</dd>
+{{- with .Doc.Info.Author}}
+<dt>Author</dt><dd>{{.}}</dd>
+{{- end}}
+{{- with .Doc.Info.Description}}
+<dt>Description</dt><dd>{{.}}</dd>
+{{- end}}
+{{- range .Metadata}}
+<dt>{{.Key}}</dt><dd>{{.Value}}</dd>
+{{- end}}
+</dl>
+</header>
+{{- if .Narratives}}
+<section>
+<h2>Narratives</h2>
+{{- range .Narratives}}
+<h3>{{.Key}}</h3>
+{{.Body}}
+{{- end}}
+</section>
+{{- end}}
+{{- if .Items}}
+<section>
+<h2>Items</h2>
+{{- template "items" .Items}}
+</section>
+{{- end}}
+{{- if .Diagram}}
+<section>
+<h2>Graph</h2>
+<div class="diagram">
+{{.Diagram}}
+</div>
+</section>
+{{- end}}
+</body>
+</html>
+{{define "item"}}<span class="badge" style="background:{{color .Item.Status}}">{{.Item.Status}}</span> {{.Item.Title}}
+<span class="bar"><span style="width:{{pct .Progress}}%"></span></span><span class="pct">{{pct .Progress}}%</span>
+{{- range .Item.Tags}}<span class="tag">#{{.}}</span>{{end}}
+{{- with .Item.DueDate}}<span class="due">due {{.Format "2006-01-02"}}</span>{{end}}
+{{- end}}
+{{define "items"}}
+{{- range .}}
+{{- if or .Children .Narratives}}
+<details open>
+<summary>{{template "item" .}}</summary>
+{{- range .Narratives}}
+<div class="narrative"><strong>{{.Key}}</strong>{{.Body}}</div>
+{{- end}}
+{{- template "items" .Children}}
+</details>
+{{- else}}
+<div class="leaf">{{template "item" .}}</div>
+{{- end}}
+{{- end}}
+{{- end}}
+`))
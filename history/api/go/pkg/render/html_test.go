@@ -0,0 +1,113 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+func reportDoc() *core.Document {
+	pct := 40.0
+	plan := dagPlan()
+	plan.Narratives = map[string]string{
+		"Proposal": "Ship **fast** with `make`.\n\n- one\n- two\n\n<script>alert(1)</script>",
+	}
+	plan.Items[1].PercentComplete = &pct
+	plan.Items[1].Tags = []string{"ci"}
+	return &core.Document{
+		Info: core.Info{Version: "0.5", Author: "team", Metadata: map[string]interface{}{"sprint": 7}},
+		Plan: plan,
+	}
+}
+
+func TestHTML(t *testing.T) {
+	t.Run("renders a self-contained report", func(t *testing.T) {
+		data, err := HTML(reportDoc(), DefaultOptions())
+		require.NoError(t, err)
+		out := string(data)
+
+		assert.True(t, strings.HasPrefix(out, "<!DOCTYPE html>"))
+		assert.Contains(t, out, "<title>Build &#34;Pipeline&#34;</title>")
+		assert.Contains(t, out, "<dt>Author</dt><dd>team</dd>")
+		assert.Contains(t, out, "<dt>sprint</dt><dd>7</dd>")
+		assert.Contains(t, out, "<strong>fast</strong>")
+		assert.Contains(t, out, "<code>make</code>")
+		assert.Contains(t, out, "<li>one</li>")
+		assert.Contains(t, out, "<details open>")
+		assert.Contains(t, out, "<svg ")
+		assert.Contains(t, out, `<span class="tag">#ci</span>`)
+		assert.NotContains(t, out, "<script")
+		assert.NotContains(t, out, "<link")
+		assert.NotContains(t, out, "src=")
+	})
+
+	t.Run("progress bars use percentComplete and children", func(t *testing.T) {
+		data, err := HTML(reportDoc(), DefaultOptions())
+		require.NoError(t, err)
+		out := string(data)
+
+		assert.Contains(t, out, `Test
+<span class="bar"><span style="width:40%"></span></span>`)
+		// lint 100, test 40, build 0, docs cancelled => 140/3
+		assert.Contains(t, out, `<span class="pct">47%</span>`)
+	})
+
+	t.Run("omits diagram for cyclic plans", func(t *testing.T) {
+		doc := reportDoc()
+		doc.Plan.Edges = append(doc.Plan.Edges, core.Edge{From: "test", To: "lint", Type: core.EdgeBlocks})
+		data, err := HTML(doc, DefaultOptions())
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "<svg")
+	})
+
+	t.Run("requires a plan", func(t *testing.T) {
+		_, err := HTML(&core.Document{}, DefaultOptions())
+		assert.ErrorIs(t, err, core.ErrNoPlan)
+	})
+}
+
+func TestSVG(t *testing.T) {
+	t.Run("lays out nodes in columns by edge depth", func(t *testing.T) {
+		data, err := SVG(dagPlan(), Options{HighlightCriticalPath: true})
+		require.NoError(t, err)
+		out := string(data)
+
+		assert.Contains(t, out, `<rect x="10" y="10"`)
+		assert.Contains(t, out, `<rect x="250" y="10"`)
+		assert.Contains(t, out, `stroke-dasharray="6 4"`)
+		assert.Contains(t, out, `stroke="#d62728" stroke-width="3"`)
+	})
+
+	t.Run("returns nothing without edges", func(t *testing.T) {
+		data, err := SVG(&core.Plan{Items: []core.PlanItem{{ID: "a", Title: "A"}}}, DefaultOptions())
+		require.NoError(t, err)
+		assert.Empty(t, data)
+	})
+}
+
+func TestMarkdownHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"paragraph", "hello\nworld", "<p>hello world</p>\n"},
+		{"escapes html", "<b>x</b>", "<p>&lt;b&gt;x&lt;/b&gt;</p>\n"},
+		{"emphasis", "*a* and **b**", "<p><em>a</em> and <strong>b</strong></p>\n"},
+		{"heading is demoted", "# Top", "<h3>Top</h3>\n"},
+		{"ordered list", "1. a\n2. b", "<ol>\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"code fence", "```\n<x>\n```", "<pre><code>&lt;x&gt;\n</code></pre>\n"},
+		{"safe link", "[docs](https://x.dev/a_b_c)", `<p><a href="https://x.dev/a_b_c">docs</a></p>` + "\n"},
+		{"unsafe link dropped", "[x](javascript:alert)", "<p>x</p>\n"},
+		{"code span keeps markers", "`*a*`", "<p><code>*a*</code></p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, markdownHTML(tt.in))
+		})
+	}
+}
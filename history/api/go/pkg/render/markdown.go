@@ -0,0 +1,149 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	mdCodeSpanRe = regexp.MustCompile("`([^`]+)`")
+	mdStrongRe   = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdEmRe       = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+	mdLinkRe     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdHeadRe     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdBulletRe   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	mdOrderedRe  = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+)
+
+// markdownHTML converts a small, safe subset of Markdown to HTML: headings,
+// paragraphs, bullet and numbered lists, fenced code blocks, code spans,
+// emphasis and links. All text is escaped; raw HTML in the input is never
+// passed through, and links are limited to http, https, mailto and relative
+// URLs.
+func markdownHTML(src string) string {
+	var b strings.Builder
+	var para []string
+	list := ""
+	inFence := false
+
+	flushPara := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>")
+			b.WriteString(inlineHTML(strings.Join(para, " ")))
+			b.WriteString("</p>\n")
+			para = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		if list != tag {
+			closeList()
+			b.WriteString("<" + tag + ">\n")
+			list = tag
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if inFence {
+				b.WriteString("</code></pre>\n")
+			} else {
+				flushPara()
+				closeList()
+				b.WriteString("<pre><code>")
+			}
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			b.WriteString(html.EscapeString(line))
+			b.WriteByte('\n')
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flushPara()
+			closeList()
+			continue
+		}
+		if m := mdHeadRe.FindStringSubmatch(line); m != nil {
+			flushPara()
+			closeList()
+			// Narratives sit below the report's own headings, so demote by two.
+			level := len(m[1]) + 2
+			if level > 6 {
+				level = 6
+			}
+			tag := fmt.Sprintf("h%d", level)
+			b.WriteString("<" + tag + ">" + inlineHTML(m[2]) + "</" + tag + ">\n")
+			continue
+		}
+		if m := mdBulletRe.FindStringSubmatch(line); m != nil {
+			flushPara()
+			openList("ul")
+			b.WriteString("<li>" + inlineHTML(m[1]) + "</li>\n")
+			continue
+		}
+		if m := mdOrderedRe.FindStringSubmatch(line); m != nil {
+			flushPara()
+			openList("ol")
+			b.WriteString("<li>" + inlineHTML(m[1]) + "</li>\n")
+			continue
+		}
+		closeList()
+		para = append(para, strings.TrimSpace(line))
+	}
+	if inFence {
+		b.WriteString("</code></pre>\n")
+	}
+	flushPara()
+	closeList()
+	return b.String()
+}
+
+// inlineHTML escapes text and applies inline Markdown formatting.
+func inlineHTML(s string) string {
+	// Code spans and link targets are swapped for placeholders so emphasis
+	// markers inside them are left alone.
+	var held []string
+	hold := func(fragment string) string {
+		held = append(held, fragment)
+		return fmt.Sprintf("\x00%d\x00", len(held)-1)
+	}
+
+	s = mdCodeSpanRe.ReplaceAllStringFunc(s, func(m string) string {
+		return hold("<code>" + html.EscapeString(m[1:len(m)-1]) + "</code>")
+	})
+	s = html.EscapeString(s)
+	s = mdLinkRe.ReplaceAllStringFunc(s, func(m string) string {
+		parts := mdLinkRe.FindStringSubmatch(m)
+		href := html.UnescapeString(parts[2])
+		if !safeURL(href) {
+			return parts[1]
+		}
+		return hold(`<a href="`+html.EscapeString(href)+`">`) + parts[1] + hold("</a>")
+	})
+	s = mdStrongRe.ReplaceAllString(s, "<strong>$1$2</strong>")
+	s = mdEmRe.ReplaceAllString(s, "<em>$1$2</em>")
+
+	for i := len(held) - 1; i >= 0; i-- {
+		s = strings.Replace(s, fmt.Sprintf("\x00%d\x00", i), held[i], 1)
+	}
+	return s
+}
+
+func safeURL(u string) bool {
+	lower := strings.ToLower(strings.TrimSpace(u))
+	if i := strings.IndexByte(lower, ':'); i >= 0 && !strings.ContainsAny(lower[:i], "/?#") {
+		scheme := lower[:i]
+		return scheme == "http" || scheme == "https" || scheme == "mailto"
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+// Mermaid renders a plan as a Mermaid flowchart.
+//
+// Items are classed by status. Items with sub-items become subgraphs, which
+// Mermaid allows as edge endpoints. Edges that reference unknown items are
+// omitted.
+func Mermaid(plan *core.Plan, opts Options) ([]byte, error) {
+	if plan == nil {
+		return nil, core.ErrNoPlan
+	}
+	g := graph.New(plan)
+	h, err := computeHighlights(g, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[*graph.Node]string, len(g.Nodes()))
+	for i, n := range g.Nodes() {
+		ids[n] = fmt.Sprintf("n%d", i)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "flowchart %s\n", opts.direction())
+
+	byStatus := make(map[core.PlanItemStatus][]string)
+	var styles []string
+	var writeNode func(n *graph.Node, indent string)
+	writeNode = func(n *graph.Node, indent string) {
+		id := ids[n]
+		if n.IsLeaf() {
+			fmt.Fprintf(&b, "%s%s[\"%s\"]\n", indent, id, mermaidEscape(n.Item.Title))
+			byStatus[n.Item.Status] = append(byStatus[n.Item.Status], id)
+		} else {
+			fmt.Fprintf(&b, "%ssubgraph %s [\"%s\"]\n", indent, id, mermaidEscape(n.Item.Title))
+			for _, c := range n.Children {
+				writeNode(c, indent+"  ")
+			}
+			fmt.Fprintf(&b, "%send\n", indent)
+			styles = append(styles, fmt.Sprintf("style %s fill:%s,stroke-dasharray:4 4", id, statusColor(n.Item.Status)))
+		}
+		switch {
+		case h.critical[n]:
+			styles = append(styles, fmt.Sprintf("style %s stroke:%s,stroke-width:3px", id, highlightColor))
+		case h.ready[n]:
+			styles = append(styles, fmt.Sprintf("style %s stroke:%s,stroke-width:3px", id, readyColor))
+		}
+	}
+	for _, n := range g.Roots() {
+		writeNode(n, "  ")
+	}
+
+	var links []string
+	for i, e := range g.Edges() {
+		from, to := ids[g.Node(e.From)], ids[g.Node(e.To)]
+		st := styleFor(e.Type)
+		arrow := "-->"
+		if st.dash != "solid" {
+			arrow = "-.->"
+		}
+		if e.Type.IsCore() {
+			fmt.Fprintf(&b, "  %s %s %s\n", from, arrow, to)
+		} else {
+			fmt.Fprintf(&b, "  %s %s|%s| %s\n", from, arrow, mermaidEscape(string(e.Type)), to)
+		}
+
+		style := "stroke:" + st.color
+		switch st.dash {
+		case "dashed":
+			style += ",stroke-dasharray:6 4"
+		case "dotted":
+			style += ",stroke-dasharray:2 2"
+		}
+		if h.onPath(e) {
+			style = "stroke:" + highlightColor + ",stroke-width:3px"
+		}
+		links = append(links, fmt.Sprintf("linkStyle %d %s", i, style))
+	}
+
+	statuses := make([]string, 0, len(byStatus))
+	for s := range byStatus {
+		statuses = append(statuses, string(s))
+	}
+	sort.Strings(statuses)
+	for _, s := range statuses {
+		class := mermaidClass(s)
+		fmt.Fprintf(&b, "  classDef %s fill:%s\n", class, statusColor(core.PlanItemStatus(s)))
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(byStatus[core.PlanItemStatus(s)], ","), class)
+	}
+	for _, s := range styles {
+		fmt.Fprintf(&b, "  %s\n", s)
+	}
+	for _, l := range links {
+		fmt.Fprintf(&b, "  %s\n", l)
+	}
+	return []byte(b.String()), nil
+}
+
+// mermaidEscape escapes text for use inside a quoted Mermaid label.
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", " ", "|", "#124;").Replace(s)
+}
+
+// mermaidClass returns a class name for a status value.
+func mermaidClass(status string) string {
+	var b strings.Builder
+	b.WriteString("status_")
+	for _, r := range status {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
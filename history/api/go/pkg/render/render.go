@@ -0,0 +1,116 @@
+// Package render draws plan DAGs as Graphviz DOT and Mermaid diagrams.
+package render
+
+import (
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+// Options controls diagram output.
+type Options struct {
+	// Direction is the layout direction: "TB", "LR", "BT" or "RL".
+	// Defaults to "TB".
+	Direction string
+
+	// HighlightCriticalPath emphasises the longest chain of blocks edges.
+	HighlightCriticalPath bool
+
+	// HighlightReady emphasises pending items whose blockers are done.
+	HighlightReady bool
+}
+
+// DefaultOptions returns options for a top-to-bottom diagram without
+// highlighting.
+func DefaultOptions() Options {
+	return Options{Direction: "TB"}
+}
+
+func (o Options) direction() string {
+	switch o.Direction {
+	case "TB", "LR", "BT", "RL":
+		return o.Direction
+	default:
+		return "TB"
+	}
+}
+
+// statusColors maps item status to a node fill color.
+var statusColors = map[core.PlanItemStatus]string{
+	core.PlanItemStatusPending:    "#e0e0e0",
+	core.PlanItemStatusRunning:    "#ffeb99",
+	core.PlanItemStatusInProgress: "#ffeb99",
+	core.PlanItemStatusCompleted:  "#90ee90",
+	core.PlanItemStatusBlocked:    "#ffcccc",
+	core.PlanItemStatusCancelled:  "#d0d0d0",
+}
+
+const (
+	defaultColor   = "#e0e0e0"
+	highlightColor = "#d62728"
+	readyColor     = "#1f77b4"
+)
+
+func statusColor(s core.PlanItemStatus) string {
+	if c, ok := statusColors[s]; ok {
+		return c
+	}
+	return defaultColor
+}
+
+// edgeStyle describes how an edge type is drawn.
+type edgeStyle struct {
+	dash  string // "solid", "dashed" or "dotted"
+	color string
+}
+
+func styleFor(t core.EdgeType) edgeStyle {
+	switch t {
+	case core.EdgeBlocks:
+		return edgeStyle{dash: "solid", color: "#333333"}
+	case core.EdgeInforms:
+		return edgeStyle{dash: "dashed", color: "#333333"}
+	case core.EdgeInvalidates:
+		return edgeStyle{dash: "solid", color: "#d62728"}
+	case core.EdgeSuggests:
+		return edgeStyle{dash: "dotted", color: "#333333"}
+	default:
+		return edgeStyle{dash: "solid", color: "#999999"}
+	}
+}
+
+// highlights records which nodes and edges should be emphasised.
+type highlights struct {
+	critical map[*graph.Node]bool
+	path     map[[2]string]bool
+	ready    map[*graph.Node]bool
+}
+
+func computeHighlights(g *graph.Graph, opts Options) (highlights, error) {
+	h := highlights{
+		critical: make(map[*graph.Node]bool),
+		path:     make(map[[2]string]bool),
+		ready:    make(map[*graph.Node]bool),
+	}
+	if opts.HighlightCriticalPath {
+		path, err := g.CriticalPath(nil)
+		if err != nil {
+			return h, err
+		}
+		for i, n := range path {
+			h.critical[n] = true
+			if i > 0 {
+				h.path[[2]string{path[i-1].ID, n.ID}] = true
+			}
+		}
+	}
+	if opts.HighlightReady {
+		for _, n := range g.Ready() {
+			h.ready[n] = true
+		}
+	}
+	return h, nil
+}
+
+func (h highlights) onPath(e core.Edge) bool {
+	return e.Type == core.EdgeBlocks && h.path[[2]string{e.From, e.To}]
+}
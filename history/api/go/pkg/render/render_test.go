@@ -0,0 +1,106 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+func dagPlan() *core.Plan {
+	return &core.Plan{
+		Title:  "Build \"Pipeline\"",
+		Status: core.PlanStatusInProgress,
+		Items: []core.PlanItem{
+			{ID: "lint", Title: "Lint", Status: core.PlanItemStatusCompleted},
+			{ID: "test", Title: "Test", Status: core.PlanItemStatusPending},
+			{ID: "build", Title: "Build", Status: core.PlanItemStatusPending, SubItems: []core.PlanItem{
+				{ID: "compile", Title: "Compile", Status: core.PlanItemStatusPending},
+			}},
+			{ID: "docs", Title: "Docs", Status: core.PlanItemStatusCancelled},
+		},
+		Edges: []core.Edge{
+			{From: "lint", To: "test", Type: core.EdgeBlocks},
+			{From: "test", To: "build", Type: core.EdgeInforms},
+			{From: "lint", To: "docs", Type: core.EdgeInvalidates},
+			{From: "test", To: "docs", Type: core.EdgeSuggests},
+			{From: "docs", To: "build.compile", Type: core.EdgeType("mentions")},
+			{From: "lint", To: "nowhere", Type: core.EdgeBlocks},
+		},
+	}
+}
+
+func TestDOT(t *testing.T) {
+	t.Run("renders nodes, clusters and styled edges", func(t *testing.T) {
+		data, err := DOT(dagPlan(), DefaultOptions())
+		require.NoError(t, err)
+		out := string(data)
+
+		assert.True(t, strings.HasPrefix(out, "digraph plan {"))
+		assert.Contains(t, out, `label="Build \"Pipeline\""`)
+		assert.Contains(t, out, `"lint" [label="Lint", fillcolor="#90ee90"]`)
+		assert.Contains(t, out, "subgraph cluster_1 {")
+		assert.Contains(t, out, `"build.compile" [label="Compile"`)
+		assert.Contains(t, out, `"lint" -> "test" [style=solid, color="#333333"]`)
+		assert.Contains(t, out, `"test" -> "build" [style=dashed`)
+		assert.Contains(t, out, `"lint" -> "docs" [style=solid, color="#d62728"]`)
+		assert.Contains(t, out, `"test" -> "docs" [style=dotted`)
+		assert.Contains(t, out, `label="mentions"`)
+		assert.NotContains(t, out, "nowhere")
+	})
+
+	t.Run("highlights critical path and ready set", func(t *testing.T) {
+		data, err := DOT(dagPlan(), Options{Direction: "LR", HighlightCriticalPath: true, HighlightReady: true})
+		require.NoError(t, err)
+		out := string(data)
+
+		assert.Contains(t, out, "rankdir=LR;")
+		assert.Contains(t, out, `"lint" -> "test" [style=solid, penwidth=3, color="#d62728"]`)
+		assert.Contains(t, out, `"build.compile" [label="Compile", fillcolor="#e0e0e0", color="#1f77b4", penwidth=3]`)
+	})
+
+	t.Run("nil plan returns ErrNoPlan", func(t *testing.T) {
+		_, err := DOT(nil, DefaultOptions())
+		assert.ErrorIs(t, err, core.ErrNoPlan)
+	})
+}
+
+func TestMermaid(t *testing.T) {
+	t.Run("renders flowchart with subgraphs and link styles", func(t *testing.T) {
+		data, err := Mermaid(dagPlan(), DefaultOptions())
+		require.NoError(t, err)
+		out := string(data)
+
+		assert.True(t, strings.HasPrefix(out, "flowchart TB\n"))
+		assert.Contains(t, out, `n0["Lint"]`)
+		assert.Contains(t, out, `subgraph n2 ["Build"]`)
+		assert.Contains(t, out, "n0 --> n1")
+		assert.Contains(t, out, "n1 -.-> n2")
+		assert.Contains(t, out, "n4 -->|mentions| n3")
+		assert.Contains(t, out, "classDef status_completed fill:#90ee90")
+		assert.Contains(t, out, "linkStyle 1 stroke:#333333,stroke-dasharray:6 4")
+		assert.Contains(t, out, "linkStyle 2 stroke:#d62728")
+		assert.Contains(t, out, "linkStyle 3 stroke:#333333,stroke-dasharray:2 2")
+	})
+
+	t.Run("highlights critical path", func(t *testing.T) {
+		data, err := Mermaid(dagPlan(), Options{HighlightCriticalPath: true})
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "linkStyle 0 stroke:#d62728,stroke-width:3px")
+		assert.Contains(t, string(data), "style n0 stroke:#d62728,stroke-width:3px")
+	})
+
+	t.Run("escapes labels", func(t *testing.T) {
+		plan := &core.Plan{Title: "T", Items: []core.PlanItem{{Title: `say "hi"`, Status: core.PlanItemStatusPending}}}
+		data, err := Mermaid(plan, DefaultOptions())
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `n0["say #quot;hi#quot;"]`)
+	})
+}
+
+func TestStatusColor_Running(t *testing.T) {
+	assert.Equal(t, statusColor(core.PlanItemStatusInProgress), statusColor(core.PlanItemStatusRunning))
+	assert.NotEqual(t, statusColor(core.PlanItemStatusPending), statusColor(core.PlanItemStatusRunning))
+}
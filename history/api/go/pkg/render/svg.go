@@ -0,0 +1,137 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+const (
+	svgNodeWidth  = 170
+	svgNodeHeight = 40
+	svgColGap     = 70
+	svgRowGap     = 20
+	svgMargin     = 10
+	svgMaxLabel   = 22
+)
+
+// SVG renders a plan's DAG as a standalone SVG image using a simple layered
+// layout: each addressable item is placed in the column given by its longest
+// blocks-edge distance from a source, in document order within the column.
+//
+// It returns an empty result if the plan has no resolvable edges, and
+// graph.ErrCycle if the blocks edges do not form a DAG.
+func SVG(plan *core.Plan, opts Options) ([]byte, error) {
+	if plan == nil {
+		return nil, core.ErrNoPlan
+	}
+	g := graph.New(plan)
+	if len(g.Edges()) == 0 {
+		return nil, nil
+	}
+	order, err := g.TopoSort()
+	if err != nil {
+		return nil, err
+	}
+	h, err := computeHighlights(g, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rank := make(map[string]int, len(order))
+	for _, n := range order {
+		for _, e := range g.Incoming(n.ID) {
+			if e.Type != core.EdgeBlocks {
+				continue
+			}
+			if r := rank[e.From] + 1; r > rank[n.ID] {
+				rank[n.ID] = r
+			}
+		}
+	}
+
+	type point struct{ x, y int }
+	pos := make(map[string]point, len(order))
+	rows := make(map[int]int)
+	width, height := 0, 0
+	for _, n := range g.Nodes() {
+		if n.ID == "" || g.Node(n.ID) != n {
+			continue
+		}
+		col := rank[n.ID]
+		p := point{
+			x: svgMargin + col*(svgNodeWidth+svgColGap),
+			y: svgMargin + rows[col]*(svgNodeHeight+svgRowGap),
+		}
+		rows[col]++
+		pos[n.ID] = p
+		if w := p.x + svgNodeWidth + svgMargin; w > width {
+			width = w
+		}
+		if ht := p.y + svgNodeHeight + svgMargin; ht > height {
+			height = ht
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif" font-size="12">`+"\n",
+		width, height, width, height)
+	b.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="context-stroke"/></marker></defs>` + "\n")
+
+	for _, e := range g.Edges() {
+		from, to := pos[e.From], pos[e.To]
+		x1, y1 := from.x+svgNodeWidth, from.y+svgNodeHeight/2
+		x2, y2 := to.x, to.y+svgNodeHeight/2
+		st := styleFor(e.Type)
+		color, stroke := st.color, 1.5
+		if h.onPath(e) {
+			color, stroke = highlightColor, 3
+		}
+		dash := ""
+		switch st.dash {
+		case "dashed":
+			dash = ` stroke-dasharray="6 4"`
+		case "dotted":
+			dash = ` stroke-dasharray="2 3"`
+		}
+		mid := (x1 + x2) / 2
+		fmt.Fprintf(&b, `<path d="M %d %d C %d %d, %d %d, %d %d" fill="none" stroke="%s" stroke-width="%g"%s marker-end="url(#arrow)"><title>%s</title></path>`+"\n",
+			x1, y1, mid, y1, mid, y2, x2, y2, color, stroke, dash,
+			html.EscapeString(fmt.Sprintf("%s %s %s", e.From, e.Type, e.To)))
+	}
+
+	for _, n := range g.Nodes() {
+		p, ok := pos[n.ID]
+		if !ok || g.Node(n.ID) != n {
+			continue
+		}
+		border, stroke := "#666666", 1
+		switch {
+		case h.critical[n]:
+			border, stroke = highlightColor, 3
+		case h.ready[n]:
+			border, stroke = readyColor, 3
+		}
+		fmt.Fprintf(&b, `<g><title>%s</title><rect x="%d" y="%d" width="%d" height="%d" rx="6" fill="%s" stroke="%s" stroke-width="%d"/>`,
+			html.EscapeString(n.Item.Title+" ("+string(n.Item.Status)+")"),
+			p.x, p.y, svgNodeWidth, svgNodeHeight, statusColor(n.Item.Status), border, stroke)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" dominant-baseline="middle">%s</text></g>`+"\n",
+			p.x+svgNodeWidth/2, p.y+svgNodeHeight/2, html.EscapeString(truncate(n.Item.Title, svgMaxLabel)))
+	}
+
+	b.WriteString("</svg>\n")
+	return []byte(b.String()), nil
+}
+
+// truncate shortens s to at most max runes, adding an ellipsis if cut.
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:max-1]) + "…"
+}
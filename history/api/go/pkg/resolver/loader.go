@@ -0,0 +1,148 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/parser"
+)
+
+// Loader fetches the document a URI names. URIs passed to Load have no
+// fragment and have been normalised by the resolver.
+type Loader interface {
+	Load(ctx context.Context, uri string) (*core.Document, error)
+}
+
+// LoaderFunc adapts a function to the Loader interface.
+type LoaderFunc func(ctx context.Context, uri string) (*core.Document, error)
+
+// Load calls f(ctx, uri).
+func (f LoaderFunc) Load(ctx context.Context, uri string) (*core.Document, error) {
+	return f(ctx, uri)
+}
+
+// FSLoader loads file:// URIs from a file system.
+type FSLoader struct {
+	fsys   fs.FS
+	parser parser.Parser
+}
+
+// NewFSLoader creates a loader that reads file:// URIs from fsys, with
+// the URI path taken relative to its root. A nil fsys reads the operating
+// system's files, so relative paths are relative to the working directory.
+// Documents may be in any format parser.NewAutoParser detects.
+func NewFSLoader(fsys fs.FS) *FSLoader {
+	return &FSLoader{fsys: fsys, parser: parser.NewAutoParser()}
+}
+
+// Load reads and parses the file uri names.
+func (l *FSLoader) Load(ctx context.Context, uri string) (*core.Document, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	name, ok := strings.CutPrefix(uri, "file://")
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedRef, uri)
+	}
+	var data []byte
+	var err error
+	if l.fsys == nil {
+		data, err = os.ReadFile(name)
+	} else {
+		data, err = fs.ReadFile(l.fsys, strings.TrimPrefix(name, "/"))
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, uri)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrLoad, uri, err)
+	}
+	doc, err := l.parser.ParseBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrLoad, uri, err)
+	}
+	return doc, nil
+}
+
+// MemoryLoader serves documents held in memory, keyed by URI. It is safe
+// for concurrent use.
+type MemoryLoader struct {
+	mu   sync.RWMutex
+	docs map[string]*core.Document
+}
+
+// NewMemoryLoader creates a loader serving docs, keyed by URI such as
+// "file://plans/api.json".
+func NewMemoryLoader(docs map[string]*core.Document) *MemoryLoader {
+	l := &MemoryLoader{docs: make(map[string]*core.Document, len(docs))}
+	for uri, doc := range docs {
+		l.docs[uri] = doc
+	}
+	return l
+}
+
+// Add serves doc at uri, replacing any document already there.
+func (l *MemoryLoader) Add(uri string, doc *core.Document) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.docs[uri] = doc
+}
+
+// Load returns the document at uri.
+func (l *MemoryLoader) Load(_ context.Context, uri string) (*core.Document, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	doc, ok := l.docs[uri]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, uri)
+	}
+	return doc, nil
+}
+
+// HTTPLoader loads http:// and https:// URIs.
+type HTTPLoader struct {
+	client *http.Client
+	parser parser.Parser
+}
+
+// NewHTTPLoader creates a loader that fetches documents with client, or
+// http.DefaultClient if client is nil. Responses are parsed with
+// parser.NewAutoParser, so its size limits apply.
+func NewHTTPLoader(client *http.Client) *HTTPLoader {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPLoader{client: client, parser: parser.NewAutoParser()}
+}
+
+// Load fetches and parses the document at uri.
+func (l *HTTPLoader) Load(ctx context.Context, uri string) (*core.Document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrLoad, uri, err)
+	}
+	req.Header.Set("Accept", "application/json, */*;q=0.5")
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrLoad, uri, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, uri)
+	case resp.StatusCode/100 != 2:
+		return nil, fmt.Errorf("%w: %s: %s", ErrLoad, uri, resp.Status)
+	}
+	doc, err := l.parser.Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrLoad, uri, err)
+	}
+	return doc, nil
+}
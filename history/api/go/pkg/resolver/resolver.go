@@ -0,0 +1,339 @@
+// Package resolver resolves planRef URIs: the "#item-id", "file://..." and
+// "https://..." references that let a plan item point at another plan or
+// at an item in one.
+//
+// A Resolver loads documents through pluggable loaders keyed by URI
+// scheme, caches them by normalised URI, resolves fragments to items by
+// hierarchical ID and follows chains of refs with cycle detection:
+//
+//	r := resolver.NewResolver()
+//	t, err := r.Resolve(ctx, "file://plans/programme.json", "file://api.json#setup.db")
+//	// t.URI == "file://plans/api.json", t.Item is the "setup.db" item
+//
+// Relative file:// paths are resolved against the directory of the
+// document holding the ref. Documents fetched over HTTP may not refer to
+// local files.
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/diag"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+	"github.com/visionik/vBRIEF/api/go/pkg/validator"
+)
+
+var (
+	// ErrUnsupportedRef is returned for refs that are not #id, file:// or
+	// http(s)://, and for schemes with no loader.
+	ErrUnsupportedRef = errors.New("unsupported planRef")
+	// ErrNotFound is returned when a ref's document, plan or item does not
+	// exist.
+	ErrNotFound = errors.New("planRef target not found")
+	// ErrLoad is returned when a loader cannot read or parse a document.
+	ErrLoad = errors.New("cannot load planRef target")
+	// ErrCycle is returned when following refs leads back to a target
+	// already visited.
+	ErrCycle = errors.New("planRef cycle")
+)
+
+// CodeUnresolvedRef is the diagnostic code Validate reports.
+const CodeUnresolvedRef = "VB0270"
+
+// Rule describes the check Validate performs, e.g. for
+// diag.SARIFOptions.Rules.
+var Rule = diag.Rule{
+	Code:        CodeUnresolvedRef,
+	Name:        "planref-resolves",
+	Description: "planRef must resolve to a plan, or to an item in one, without cycles.",
+	Severity:    diag.SeverityError,
+}
+
+// Options configures a Resolver.
+type Options struct {
+	// Loaders maps URI schemes ("file", "http", "https") to loaders.
+	// Schemes left out use NewFSLoader(nil) and NewHTTPLoader(nil); map a
+	// scheme to nil to disable it.
+	Loaders map[string]Loader
+}
+
+// Target is what a planRef resolves to.
+type Target struct {
+	// URI is the normalised URI of the target document, without a
+	// fragment. It is "" for a same-document ref in a document that has
+	// no URI.
+	URI string
+	// Fragment is the hierarchical ID of the target item, or "" when the
+	// ref names a whole plan.
+	Fragment string
+	// Document is the target document. It is shared with the resolver's
+	// cache; clone it before changing it.
+	Document *core.Document
+	// Item is the target item, or nil when the ref names a whole plan.
+	Item *core.PlanItem
+}
+
+// String returns the target as a ref: "URI#Fragment".
+func (t *Target) String() string {
+	if t.Fragment == "" {
+		return t.URI
+	}
+	return t.URI + "#" + t.Fragment
+}
+
+// Resolver resolves planRefs. It is safe for concurrent use.
+type Resolver struct {
+	loaders map[string]Loader
+	mu      sync.Mutex
+	cache   map[string]*core.Document
+}
+
+// NewResolver creates a resolver that reads file:// refs from the
+// operating system and http(s):// refs with http.DefaultClient.
+func NewResolver() *Resolver {
+	return NewResolverWithOptions(Options{})
+}
+
+// NewResolverWithOptions creates a resolver with custom loaders.
+func NewResolverWithOptions(opts Options) *Resolver {
+	loaders := map[string]Loader{
+		"file":  NewFSLoader(nil),
+		"http":  NewHTTPLoader(nil),
+		"https": NewHTTPLoader(nil),
+	}
+	for scheme, l := range opts.Loaders {
+		if l == nil {
+			delete(loaders, scheme)
+			continue
+		}
+		loaders[scheme] = l
+	}
+	return &Resolver{loaders: loaders, cache: make(map[string]*core.Document)}
+}
+
+// Normalize resolves ref against base, the URI of the document holding
+// it, and splits it into a document URI and an item fragment. base may be
+// "" for a document with no URI, in which case relative file:// paths
+// stay relative.
+func Normalize(base, ref string) (uri, fragment string, err error) {
+	switch {
+	case strings.HasPrefix(ref, "#"):
+		return base, ref[1:], nil
+	case strings.HasPrefix(ref, "file://"):
+		if strings.HasPrefix(base, "http://") || strings.HasPrefix(base, "https://") {
+			return "", "", fmt.Errorf("%w: %s: remote documents cannot refer to local files", ErrUnsupportedRef, ref)
+		}
+		p, fragment, _ := strings.Cut(strings.TrimPrefix(ref, "file://"), "#")
+		if p == "" {
+			return "", "", fmt.Errorf("%w: %s: no path", ErrUnsupportedRef, ref)
+		}
+		if dir, ok := strings.CutPrefix(base, "file://"); ok && !path.IsAbs(p) {
+			p = path.Join(path.Dir(dir), p)
+		}
+		return "file://" + path.Clean(p), fragment, nil
+	case strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://"):
+		u, err := url.Parse(ref)
+		if err != nil || u.Host == "" {
+			return "", "", fmt.Errorf("%w: %s", ErrUnsupportedRef, ref)
+		}
+		fragment = u.Fragment
+		u.Fragment, u.RawFragment = "", ""
+		return u.String(), fragment, nil
+	default:
+		return "", "", fmt.Errorf("%w: %s", ErrUnsupportedRef, ref)
+	}
+}
+
+// Add caches doc under uri, so refs to uri resolve to it without loading.
+// Use it to register the document refs are resolved from.
+func (r *Resolver) Add(uri string, doc *core.Document) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache[uri] = doc
+}
+
+// Invalidate drops uri from the cache, so the next ref to it loads it
+// again.
+func (r *Resolver) Invalidate(uri string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cache, uri)
+}
+
+// Reset empties the cache.
+func (r *Resolver) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = make(map[string]*core.Document)
+}
+
+// Document returns the document at a normalised URI, loading and caching
+// it on first use.
+func (r *Resolver) Document(ctx context.Context, uri string) (*core.Document, error) {
+	r.mu.Lock()
+	doc, ok := r.cache[uri]
+	r.mu.Unlock()
+	if ok {
+		return doc, nil
+	}
+
+	scheme, _, _ := strings.Cut(uri, "://")
+	l, ok := r.loaders[scheme]
+	if !ok {
+		return nil, fmt.Errorf("%w: no loader for %s", ErrUnsupportedRef, uri)
+	}
+	doc, err := l.Load(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, uri)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cached, ok := r.cache[uri]; ok {
+		return cached, nil
+	}
+	r.cache[uri] = doc
+	return doc, nil
+}
+
+// Resolve resolves one ref held by the document at base.
+func (r *Resolver) Resolve(ctx context.Context, base, ref string) (*Target, error) {
+	return r.resolve(ctx, base, nil, ref)
+}
+
+// Follow resolves ref and, while the target is an item with a planRef of
+// its own, follows that too. It returns the chain of targets, ending at
+// a whole plan or an item without a planRef. A chain that revisits a
+// target fails with ErrCycle.
+func (r *Resolver) Follow(ctx context.Context, base, ref string) ([]*Target, error) {
+	return r.follow(ctx, base, nil, ref)
+}
+
+// Walk calls fn for the document at uri and for every document reachable
+// from it through planRefs, each once, depth first. Cross-document refs
+// that cycle are visited once and are not an error here; Follow and
+// Validate report them. Walk stops at the first error from a ref or fn.
+func (r *Resolver) Walk(ctx context.Context, uri string, fn func(uri string, doc *core.Document) error) error {
+	seen := make(map[string]bool)
+	var visit func(uri string) error
+	visit = func(uri string) error {
+		if seen[uri] {
+			return nil
+		}
+		seen[uri] = true
+		doc, err := r.Document(ctx, uri)
+		if err != nil {
+			return err
+		}
+		if err := fn(uri, doc); err != nil {
+			return err
+		}
+		if doc.Plan == nil {
+			return nil
+		}
+		for _, n := range graph.New(doc.Plan).Nodes() {
+			if n.Item.PlanRef == "" {
+				continue
+			}
+			next, _, err := Normalize(uri, n.Item.PlanRef)
+			if err != nil {
+				return err
+			}
+			if err := visit(next); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return visit(uri)
+}
+
+// Validate checks that every planRef in doc resolves, following chains
+// to catch cycles. base is doc's URI, or "" if it has none. Findings have
+// code CodeUnresolvedRef.
+func (r *Resolver) Validate(ctx context.Context, base string, doc *core.Document) validator.ValidationErrors {
+	if doc == nil || doc.Plan == nil {
+		return nil
+	}
+	var errs validator.ValidationErrors
+	for _, n := range graph.New(doc.Plan).Nodes() {
+		if n.Item.PlanRef == "" {
+			continue
+		}
+		if _, err := r.follow(ctx, base, doc, n.Item.PlanRef); err != nil {
+			errs = append(errs, validator.ValidationError{
+				Field:    n.Path + ".planRef",
+				Message:  err.Error(),
+				Code:     CodeUnresolvedRef,
+				Severity: Rule.Severity,
+			})
+		}
+	}
+	return errs
+}
+
+// resolve resolves ref against base. Same-document refs use doc, if set,
+// rather than loading base.
+func (r *Resolver) resolve(ctx context.Context, base string, doc *core.Document, ref string) (*Target, error) {
+	uri, fragment, err := Normalize(base, ref)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case uri == base && doc != nil:
+	case uri == "":
+		return nil, fmt.Errorf("%w: %s: same-document ref without a document", ErrUnsupportedRef, ref)
+	default:
+		if doc, err = r.Document(ctx, uri); err != nil {
+			return nil, err
+		}
+	}
+
+	t := &Target{URI: uri, Fragment: fragment, Document: doc}
+	if doc.Plan == nil {
+		return nil, fmt.Errorf("%w: %s has no plan", ErrNotFound, t.URI)
+	}
+	if fragment == "" {
+		return t, nil
+	}
+	n := graph.New(doc.Plan).Node(fragment)
+	if n == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, t)
+	}
+	t.Item = n.Item
+	return t, nil
+}
+
+func (r *Resolver) follow(ctx context.Context, base string, doc *core.Document, ref string) ([]*Target, error) {
+	var chain []*Target
+	seen := make(map[string]bool)
+	for {
+		t, err := r.resolve(ctx, base, doc, ref)
+		if err != nil {
+			return chain, err
+		}
+		if seen[t.String()] {
+			steps := make([]string, 0, len(chain)+1)
+			for _, c := range chain {
+				steps = append(steps, c.String())
+			}
+			return chain, fmt.Errorf("%w: %s", ErrCycle, strings.Join(append(steps, t.String()), " -> "))
+		}
+		seen[t.String()] = true
+		chain = append(chain, t)
+		if t.Item == nil || t.Item.PlanRef == "" {
+			return chain, nil
+		}
+		base, doc, ref = t.URI, t.Document, t.Item.PlanRef
+	}
+}
@@ -0,0 +1,281 @@
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+func plan(title string, items ...core.PlanItem) *core.Document {
+	return &core.Document{
+		Info: core.Info{Version: "0.5"},
+		Plan: &core.Plan{Title: title, Status: core.PlanStatusDraft, Items: items},
+	}
+}
+
+func item(id, planRef string, sub ...core.PlanItem) core.PlanItem {
+	return core.PlanItem{ID: id, Title: id, Status: core.PlanItemStatusPending, PlanRef: planRef, SubItems: sub}
+}
+
+// programme is a root plan split across files:
+//
+//	plans/programme.json: api -> api.json, ui -> ui.json#screens, intro -> #api
+//	plans/api.json:       setup{db}, auth -> https://example.com/auth.json#login
+//	plans/ui.json:        screens
+func programme() map[string]*core.Document {
+	return map[string]*core.Document{
+		"file://plans/programme.json": plan("Programme",
+			item("api", "file://api.json"),
+			item("ui", "file://ui.json#screens"),
+			item("intro", "#api"),
+		),
+		"file://plans/api.json": plan("API",
+			item("setup", "", item("db", "")),
+			item("auth", "https://example.com/auth.json#login"),
+		),
+		"file://plans/ui.json": plan("UI", item("screens", "")),
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		base, ref     string
+		uri, fragment string
+		wantErr       bool
+	}{
+		{"file://plans/a.json", "#x", "file://plans/a.json", "x", false},
+		{"", "#x.y", "", "x.y", false},
+		{"file://plans/a.json", "file://b.json#x", "file://plans/b.json", "x", false},
+		{"file://plans/a.json", "file://../shared/c.json", "file://shared/c.json", "", false},
+		{"file:///srv/plans/a.json", "file://./b.json", "file:///srv/plans/b.json", "", false},
+		{"file://plans/a.json", "file:///etc/plan.json#x", "file:///etc/plan.json", "x", false},
+		{"", "file://b.json", "file://b.json", "", false},
+		{"file://a.json", "https://example.com/p.json#setup.db", "https://example.com/p.json", "setup.db", false},
+		{"https://example.com/a.json", "file://b.json", "", "", true},
+		{"", "docs-plan", "", "", true},
+		{"", "file://", "", "", true},
+		{"", "https:///p.json", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.base+" "+tt.ref, func(t *testing.T) {
+			uri, fragment, err := Normalize(tt.base, tt.ref)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrUnsupportedRef)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.uri, uri)
+			assert.Equal(t, tt.fragment, fragment)
+		})
+	}
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	ctx := context.Background()
+	r := NewResolverWithOptions(Options{Loaders: map[string]Loader{"file": NewMemoryLoader(programme())}})
+	const base = "file://plans/programme.json"
+
+	tests := []struct {
+		ref, target, item string
+		err               error
+	}{
+		{ref: "file://api.json", target: "file://plans/api.json"},
+		{ref: "file://api.json#setup.db", target: "file://plans/api.json#setup.db", item: "db"},
+		{ref: "file://ui.json#screens", target: "file://plans/ui.json#screens", item: "screens"},
+		{ref: "#intro", target: base + "#intro", item: "intro"},
+		{ref: "file://api.json#db", err: ErrNotFound},
+		{ref: "file://missing.json", err: ErrNotFound},
+		{ref: "ftp://x/y.json", err: ErrUnsupportedRef},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			target, err := r.Resolve(ctx, base, tt.ref)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.target, target.String())
+			require.NotNil(t, target.Document.Plan)
+			if tt.item == "" {
+				assert.Nil(t, target.Item)
+			} else {
+				require.NotNil(t, target.Item)
+				assert.Equal(t, tt.item, target.Item.ID)
+			}
+		})
+	}
+
+	t.Run("https disabled", func(t *testing.T) {
+		r := NewResolverWithOptions(Options{Loaders: map[string]Loader{"https": nil}})
+		_, err := r.Resolve(ctx, "", "https://example.com/p.json")
+		assert.ErrorIs(t, err, ErrUnsupportedRef)
+	})
+}
+
+func TestResolver_Cache(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemoryLoader(programme())
+	var loads atomic.Int32
+	r := NewResolverWithOptions(Options{Loaders: map[string]Loader{
+		"file": LoaderFunc(func(ctx context.Context, uri string) (*core.Document, error) {
+			loads.Add(1)
+			return mem.Load(ctx, uri)
+		}),
+	}})
+
+	for _, ref := range []string{"file://api.json", "file://./api.json#setup", "file://../plans/api.json#auth"} {
+		_, err := r.Resolve(ctx, "file://plans/programme.json", ref)
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, loads.Load(), "normalised URIs share a cache entry")
+
+	mem.Add("file://plans/api.json", plan("API v2", item("setup", "")))
+	r.Invalidate("file://plans/api.json")
+	target, err := r.Resolve(ctx, "", "file://plans/api.json")
+	require.NoError(t, err)
+	assert.Equal(t, "API v2", target.Document.Plan.Title)
+	assert.EqualValues(t, 2, loads.Load())
+
+	r.Reset()
+	_, err = r.Document(ctx, "file://plans/api.json")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, loads.Load())
+}
+
+func TestResolver_Follow(t *testing.T) {
+	ctx := context.Background()
+	docs := programme()
+	docs["file://plans/ui.json"].Plan.Items[0].PlanRef = "file://screens.json"
+	docs["file://plans/screens.json"] = plan("Screens", item("home", "file://loop.json#a"))
+	docs["file://plans/loop.json"] = plan("Loop", item("a", "#b"), item("b", "file://loop.json#a"))
+	r := NewResolverWithOptions(Options{Loaders: map[string]Loader{"file": NewMemoryLoader(docs)}})
+	const base = "file://plans/programme.json"
+
+	chain, err := r.Follow(ctx, base, "#ui")
+	require.NoError(t, err)
+	var got []string
+	for _, target := range chain {
+		got = append(got, target.String())
+	}
+	assert.Equal(t, []string{
+		base + "#ui",
+		"file://plans/ui.json#screens",
+		"file://plans/screens.json",
+	}, got)
+
+	_, err = r.Follow(ctx, "file://plans/screens.json", "#home")
+	assert.ErrorIs(t, err, ErrCycle)
+	assert.ErrorContains(t, err, "file://plans/loop.json#a -> file://plans/loop.json#b -> file://plans/loop.json#a")
+}
+
+func TestResolver_Walk(t *testing.T) {
+	ctx := context.Background()
+	docs := programme()
+	docs["file://plans/api.json"].Plan.Items[1].PlanRef = "file://ui.json"
+	docs["file://plans/ui.json"].Plan.Items[0].PlanRef = "file://programme.json#api"
+	r := NewResolverWithOptions(Options{Loaders: map[string]Loader{"file": NewMemoryLoader(docs)}})
+
+	var visited []string
+	err := r.Walk(ctx, "file://plans/programme.json", func(uri string, doc *core.Document) error {
+		visited = append(visited, uri)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"file://plans/programme.json", "file://plans/api.json", "file://plans/ui.json"}, visited)
+
+	docs["file://plans/ui.json"].Plan.Items[0].PlanRef = "https://example.com/p.json"
+	r = NewResolverWithOptions(Options{Loaders: map[string]Loader{"file": NewMemoryLoader(docs), "https": nil}})
+	err = r.Walk(ctx, "file://plans/programme.json", func(string, *core.Document) error { return nil })
+	assert.ErrorIs(t, err, ErrUnsupportedRef)
+}
+
+func TestResolver_Validate(t *testing.T) {
+	ctx := context.Background()
+	r := NewResolverWithOptions(Options{Loaders: map[string]Loader{"file": NewMemoryLoader(programme())}})
+	doc := plan("Root",
+		item("ok", "file://plans/api.json#setup.db"),
+		item("local", "#ok"),
+		item("gone", "file://plans/api.json#nope"),
+		item("parent", "", item("self", "#parent.self")),
+		item("bad", "api"),
+	)
+
+	errs := r.Validate(ctx, "", doc)
+	got := make(map[string]string)
+	for _, e := range errs {
+		assert.Equal(t, CodeUnresolvedRef, e.Code)
+		got[e.Field] = e.Message
+	}
+	assert.Equal(t, map[string]string{
+		"plan.items[2].planRef":             "planRef target not found: file://plans/api.json#nope",
+		"plan.items[3].subItems[0].planRef": "planRef cycle: #parent.self -> #parent.self",
+		"plan.items[4].planRef":             "unsupported planRef: api",
+	}, got)
+	assert.Len(t, errs.Diagnostics(), 3)
+}
+
+func TestLoaders(t *testing.T) {
+	ctx := context.Background()
+	const planJSON = `{"vBRIEFInfo": {"version": "0.5"}, "plan": {"title": "Remote", "status": "draft",
+		"items": [{"id": "login", "title": "Login", "status": "pending"}]}}`
+
+	t.Run("fs", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"plans/auth.json": {Data: []byte(planJSON)},
+			"plans/bad.json":  {Data: []byte(`{"plan": `)},
+		}
+		r := NewResolverWithOptions(Options{Loaders: map[string]Loader{"file": NewFSLoader(fsys)}})
+		target, err := r.Resolve(ctx, "file:///plans/root.json", "file://auth.json#login")
+		require.NoError(t, err)
+		assert.Equal(t, "Login", target.Item.Title)
+
+		_, err = r.Resolve(ctx, "", "file://plans/none.json")
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = r.Resolve(ctx, "", "file://plans/bad.json")
+		assert.ErrorIs(t, err, ErrLoad)
+	})
+
+	t.Run("http", func(t *testing.T) {
+		var requests atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requests.Add(1)
+			switch req.URL.Path {
+			case "/auth.json":
+				_, _ = w.Write([]byte(planJSON))
+			case "/broken.json":
+				http.Error(w, "boom", http.StatusInternalServerError)
+			default:
+				http.NotFound(w, req)
+			}
+		}))
+		defer srv.Close()
+
+		r := NewResolverWithOptions(Options{Loaders: map[string]Loader{"http": NewHTTPLoader(srv.Client())}})
+		for range 2 {
+			target, err := r.Resolve(ctx, "", srv.URL+"/auth.json#login")
+			require.NoError(t, err)
+			assert.Equal(t, srv.URL+"/auth.json#login", target.String())
+			assert.Equal(t, "Login", target.Item.Title)
+		}
+		assert.EqualValues(t, 1, requests.Load())
+
+		_, err := r.Resolve(ctx, "", srv.URL+"/none.json")
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = r.Resolve(ctx, "", srv.URL+"/broken.json")
+		assert.ErrorIs(t, err, ErrLoad)
+		assert.ErrorContains(t, err, "500")
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = r.Resolve(cancelled, "", srv.URL+"/other.json")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
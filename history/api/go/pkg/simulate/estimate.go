@@ -0,0 +1,113 @@
+package simulate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// DurationKey is the item metadata key holding the item's duration,
+// either fixed or as a three-point estimate, with Go duration strings:
+//
+//	"metadata": {"duration": "2h"}
+//	"metadata": {"duration": {"min": "1h", "likely": "2h", "max": "6h"}}
+const DurationKey = "duration"
+
+// ErrInvalidDuration is returned when an item's duration cannot be read.
+var ErrInvalidDuration = errors.New("invalid duration")
+
+// Estimate is how long an item takes: at least Min, at most Max, and
+// most likely Likely. A fixed duration has all three equal.
+type Estimate struct {
+	Min, Likely, Max time.Duration
+}
+
+// Fixed returns the estimate of an item that always takes d.
+func Fixed(d time.Duration) Estimate {
+	return Estimate{Min: d, Likely: d, Max: d}
+}
+
+// Sample draws a duration from the triangular distribution over the
+// estimate.
+func (e Estimate) Sample(rng *rand.Rand) time.Duration {
+	lo, mode, hi := float64(e.Min), float64(e.Likely), float64(e.Max)
+	if hi <= lo {
+		return e.Likely
+	}
+	u := rng.Float64()
+	if u < (mode-lo)/(hi-lo) {
+		return time.Duration(lo + math.Sqrt(u*(hi-lo)*(mode-lo)))
+	}
+	return time.Duration(hi - math.Sqrt((1-u)*(hi-lo)*(hi-mode)))
+}
+
+// estimateJSON is a three-point estimate as written in metadata.
+type estimateJSON struct {
+	Min    string `json:"min"`
+	Likely string `json:"likely"`
+	Max    string `json:"max"`
+}
+
+// EstimateOf returns the estimate in item's metadata[DurationKey], or def
+// if it has none. A three-point estimate may leave out min or max, which
+// then default to likely.
+func EstimateOf(item *core.PlanItem, def Estimate) (Estimate, error) {
+	raw, ok := item.Metadata[DurationKey]
+	if !ok {
+		return def, nil
+	}
+	if s, ok := raw.(string); ok {
+		d, err := parse(s)
+		if err != nil {
+			return def, err
+		}
+		return Fixed(d), nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return def, fmt.Errorf("%w: %w", ErrInvalidDuration, err)
+	}
+	var ej estimateJSON
+	if err := json.Unmarshal(data, &ej); err != nil {
+		return def, fmt.Errorf("%w: %w", ErrInvalidDuration, err)
+	}
+	if ej.Likely == "" {
+		return def, fmt.Errorf("%w: likely is required", ErrInvalidDuration)
+	}
+	var e Estimate
+	if e.Likely, err = parse(ej.Likely); err != nil {
+		return def, err
+	}
+	e.Min, e.Max = e.Likely, e.Likely
+	if ej.Min != "" {
+		if e.Min, err = parse(ej.Min); err != nil {
+			return def, err
+		}
+	}
+	if ej.Max != "" {
+		if e.Max, err = parse(ej.Max); err != nil {
+			return def, err
+		}
+	}
+	if e.Min > e.Likely || e.Likely > e.Max {
+		return def, fmt.Errorf("%w: want min <= likely <= max, got %s, %s, %s", ErrInvalidDuration, e.Min, e.Likely, e.Max)
+	}
+	return e, nil
+}
+
+func parse(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidDuration, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%w: %s is negative", ErrInvalidDuration, s)
+	}
+	return d, nil
+}
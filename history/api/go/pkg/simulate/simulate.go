@@ -0,0 +1,340 @@
+// Package simulate dry-runs a plan to estimate how long it takes and what
+// runs in parallel.
+//
+// Each item's duration comes from its metadata[DurationKey], fixed or as
+// a three-point estimate. Simulate schedules the plan as package exec
+// would run it: a leaf item starts once the sources of the blocks edges
+// targeting it or its ancestors are finished, in document order, while a
+// worker is free; a container spans its sub-items. Completed and
+// cancelled items, and their sub-items, take no time and are not
+// scheduled; neither are items that cannot be addressed, because they or
+// an ancestor have no ID. MonteCarlo repeats the simulation with sampled
+// durations to forecast the makespan.
+package simulate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+// ErrStalled is returned when items can never start because they wait on
+// each other, through edges or through their ancestors.
+var ErrStalled = errors.New("simulation stalled")
+
+// Options configures a Simulator.
+type Options struct {
+	// Workers is the number of items run at once. Zero or less means no
+	// limit.
+	Workers int
+	// Default is the estimate for items without metadata[DurationKey].
+	// The zero value makes them take no time.
+	Default Estimate
+	// Start is when the schedule begins, for Schedule.Apply. The zero
+	// value means the time Simulate is called.
+	Start time.Time
+	// Runs is the number of MonteCarlo runs. Zero or less means 1000.
+	Runs int
+	// Seed seeds MonteCarlo's random durations, so forecasts can be
+	// repeated.
+	Seed uint64
+}
+
+// Simulator simulates plans.
+type Simulator struct {
+	opts Options
+}
+
+// NewSimulator creates a simulator with unlimited workers.
+func NewSimulator() *Simulator {
+	return NewSimulatorWithOptions(Options{})
+}
+
+// NewSimulatorWithOptions creates a simulator with custom options.
+func NewSimulatorWithOptions(opts Options) *Simulator {
+	if opts.Runs <= 0 {
+		opts.Runs = 1000
+	}
+	return &Simulator{opts: opts}
+}
+
+// Slot is an item's place in a schedule. Times are offsets from the
+// schedule's start.
+type Slot struct {
+	// ID is the item's hierarchical ID.
+	ID    string
+	Title string
+	Start time.Duration
+	End   time.Duration
+	// Worker numbers the worker a leaf item runs on, from 0; it is -1
+	// for containers.
+	Worker int
+}
+
+// Schedule is a simulated run of a plan.
+type Schedule struct {
+	// Start is when the schedule begins.
+	Start time.Time
+	// Slots lists the scheduled items in document order.
+	Slots []Slot
+	// Makespan is when the last item ends.
+	Makespan time.Duration
+}
+
+// Slot returns the slot for the item with the given hierarchical ID.
+func (s *Schedule) Slot(id string) (Slot, bool) {
+	for _, sl := range s.Slots {
+		if sl.ID == id {
+			return sl, true
+		}
+	}
+	return Slot{}, false
+}
+
+// Apply sets the startDate and endDate of the plan's scheduled items from
+// the schedule.
+func (s *Schedule) Apply(plan *core.Plan) {
+	g := graph.New(plan)
+	for _, sl := range s.Slots {
+		if n := g.Node(sl.ID); n != nil {
+			start, end := s.Start.Add(sl.Start), s.Start.Add(sl.End)
+			n.Item.StartDate, n.Item.EndDate = &start, &end
+		}
+	}
+}
+
+// Forecast summarises the makespans of MonteCarlo runs.
+type Forecast struct {
+	// Makespans holds every run's makespan, sorted.
+	Makespans []time.Duration
+	// P50 and P90 are the makespans that half and nine in ten runs
+	// finished within.
+	P50, P90 time.Duration
+	// Mean is the average makespan.
+	Mean time.Duration
+}
+
+// Percentile returns the makespan that p percent of runs finished within,
+// by the nearest-rank method.
+func (f *Forecast) Percentile(p float64) time.Duration {
+	if len(f.Makespans) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(f.Makespans)))) - 1
+	return f.Makespans[min(max(i, 0), len(f.Makespans)-1)]
+}
+
+// Simulate schedules doc's plan with each item's most likely duration.
+// It returns ErrInvalidDuration if an item's duration cannot be read, and
+// ErrStalled if items can never start.
+func (s *Simulator) Simulate(doc *core.Document) (*Schedule, error) {
+	m, err := s.model(doc)
+	if err != nil {
+		return nil, err
+	}
+	sched, err := m.run(s.opts.Workers, func(n *graph.Node) time.Duration { return m.estimates[n].Likely })
+	if err != nil {
+		return nil, err
+	}
+	sched.Start = s.opts.Start
+	if sched.Start.IsZero() {
+		sched.Start = time.Now()
+	}
+	return sched, nil
+}
+
+// MonteCarlo simulates doc's plan Options.Runs times, drawing each item's
+// duration from its estimate, and forecasts the makespan. It returns the
+// errors Simulate does.
+func (s *Simulator) MonteCarlo(doc *core.Document) (*Forecast, error) {
+	m, err := s.model(doc)
+	if err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewPCG(s.opts.Seed, s.opts.Seed))
+	sample := func(n *graph.Node) time.Duration { return m.estimates[n].Sample(rng) }
+
+	f := &Forecast{Makespans: make([]time.Duration, 0, s.opts.Runs)}
+	var total float64
+	for range s.opts.Runs {
+		sched, err := m.run(s.opts.Workers, sample)
+		if err != nil {
+			return nil, err
+		}
+		f.Makespans = append(f.Makespans, sched.Makespan)
+		total += float64(sched.Makespan)
+	}
+	slices.Sort(f.Makespans)
+	f.P50, f.P90 = f.Percentile(50), f.Percentile(90)
+	f.Mean = time.Duration(total / float64(len(f.Makespans)))
+	return f, nil
+}
+
+// model is a plan prepared for simulation.
+type model struct {
+	g *graph.Graph
+	// leaves lists the leaf items to schedule, in document order.
+	leaves    []*graph.Node
+	estimates map[*graph.Node]Estimate
+}
+
+func (s *Simulator) model(doc *core.Document) (*model, error) {
+	if doc == nil || doc.Plan == nil {
+		return nil, core.ErrNoPlan
+	}
+	m := &model{g: graph.New(doc.Plan), estimates: make(map[*graph.Node]Estimate)}
+	for _, n := range m.g.Nodes() {
+		if n.ID == "" || !n.IsLeaf() || settled(n) {
+			continue
+		}
+		e, err := EstimateOf(n.Item, s.opts.Default)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", n.ID, err)
+		}
+		m.leaves = append(m.leaves, n)
+		m.estimates[n] = e
+	}
+	return m, nil
+}
+
+// settled reports whether an item, or an ancestor, is completed or
+// cancelled.
+func settled(n *graph.Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n.Item.Status == core.PlanItemStatusCompleted || n.Item.Status == core.PlanItemStatusCancelled {
+			return true
+		}
+	}
+	return false
+}
+
+// running is a leaf item in progress.
+type running struct {
+	node   *graph.Node
+	end    time.Duration
+	worker int
+}
+
+// run schedules the leaves with the given durations, starting each ready
+// leaf, in document order, on the lowest-numbered free worker.
+func (m *model) run(workers int, duration func(*graph.Node) time.Duration) (*Schedule, error) {
+	slots := make(map[*graph.Node]Slot, len(m.leaves))
+	ends := make(map[*graph.Node]time.Duration, len(m.leaves))
+	pending := slices.Clone(m.leaves)
+	var active []running
+	var busy []bool
+	var now time.Duration
+
+	for len(pending) > 0 || len(active) > 0 {
+		waiting := pending[:0]
+		for _, n := range pending {
+			w := -1
+			if m.ready(n, ends) {
+				w = slices.Index(busy, false)
+				if w < 0 && (workers <= 0 || len(busy) < workers) {
+					busy = append(busy, false)
+					w = len(busy) - 1
+				}
+			}
+			if w < 0 {
+				waiting = append(waiting, n)
+				continue
+			}
+			busy[w] = true
+			end := now + duration(n)
+			active = append(active, running{node: n, end: end, worker: w})
+			slots[n] = Slot{ID: n.ID, Title: n.Item.Title, Start: now, End: end, Worker: w}
+		}
+		pending = waiting
+		if len(active) == 0 {
+			ids := make([]string, len(pending))
+			for i, n := range pending {
+				ids[i] = n.ID
+			}
+			return nil, fmt.Errorf("%w: %s can never start", ErrStalled, strings.Join(ids, ", "))
+		}
+
+		now = active[0].end
+		for _, r := range active[1:] {
+			now = min(now, r.end)
+		}
+		still := active[:0]
+		for _, r := range active {
+			if r.end > now {
+				still = append(still, r)
+				continue
+			}
+			ends[r.node] = r.end
+			busy[r.worker] = false
+		}
+		active = still
+	}
+
+	sched := &Schedule{}
+	for _, n := range m.g.Nodes() {
+		if sl, ok := m.span(n, slots); ok {
+			sched.Slots = append(sched.Slots, sl)
+			sched.Makespan = max(sched.Makespan, sl.End)
+		}
+	}
+	return sched, nil
+}
+
+// ready reports whether every blocker of n has finished.
+func (m *model) ready(n *graph.Node, ends map[*graph.Node]time.Duration) bool {
+	for _, b := range m.g.Blockers(n) {
+		if !m.finished(b, ends) {
+			return false
+		}
+	}
+	return true
+}
+
+// finished reports whether an item needs no more work: it is settled, a
+// scheduled leaf that has ended, or a container whose sub-items have all
+// finished. Unscheduled leaves that are not settled never finish.
+func (m *model) finished(n *graph.Node, ends map[*graph.Node]time.Duration) bool {
+	if settled(n) {
+		return true
+	}
+	if n.IsLeaf() {
+		_, ok := ends[n]
+		return ok
+	}
+	for _, c := range n.Children {
+		if c.ID != "" && !m.finished(c, ends) {
+			return false
+		}
+	}
+	return true
+}
+
+// span returns a leaf's slot, or a container's slot spanning its
+// scheduled sub-items.
+func (m *model) span(n *graph.Node, slots map[*graph.Node]Slot) (Slot, bool) {
+	if n.IsLeaf() {
+		sl, ok := slots[n]
+		return sl, ok
+	}
+	out := Slot{ID: n.ID, Title: n.Item.Title, Worker: -1}
+	found := false
+	for _, c := range n.Children {
+		sl, ok := m.span(c, slots)
+		if !ok {
+			continue
+		}
+		if !found || sl.Start < out.Start {
+			out.Start = sl.Start
+		}
+		out.End = max(out.End, sl.End)
+		found = true
+	}
+	return out, found
+}
@@ -0,0 +1,221 @@
+package simulate
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+// timed returns a pending item that takes duration, a value of the
+// DurationKey metadata, or no time at all when duration is nil.
+func timed(id string, duration interface{}, sub ...core.PlanItem) core.PlanItem {
+	it := core.PlanItem{ID: id, Title: id, Status: core.PlanItemStatusPending, SubItems: sub}
+	if duration != nil {
+		it.Metadata = map[string]interface{}{DurationKey: duration}
+	}
+	return it
+}
+
+// pipeline is a build pipeline:
+//
+//	lint (10m), test (30m) -> build (20m) -> package{linux (15m), mac (25m)} -> deploy (5m)
+func pipeline() *core.Document {
+	return &core.Document{Plan: &core.Plan{
+		Title: "CI", Status: core.PlanStatusRunning,
+		Items: []core.PlanItem{
+			timed("lint", "10m"), timed("test", "30m"), timed("build", "20m"),
+			timed("package", nil, timed("linux", "15m"), timed("mac", "25m")),
+			timed("deploy", "5m"),
+		},
+		Edges: []core.Edge{
+			{From: "lint", To: "build", Type: core.EdgeBlocks},
+			{From: "test", To: "build", Type: core.EdgeBlocks},
+			{From: "build", To: "package", Type: core.EdgeBlocks},
+			{From: "package", To: "deploy", Type: core.EdgeBlocks},
+		},
+	}}
+}
+
+func TestEstimateOf(t *testing.T) {
+	def := Fixed(time.Minute)
+	tests := []struct {
+		name     string
+		duration interface{}
+		want     Estimate
+		err      bool
+	}{
+		{"none", nil, def, false},
+		{"fixed", "2h", Fixed(2 * time.Hour), false},
+		{"three point", map[string]interface{}{"min": "1h", "likely": "2h", "max": "6h"}, Estimate{time.Hour, 2 * time.Hour, 6 * time.Hour}, false},
+		{"likely only", map[string]interface{}{"likely": "2h"}, Fixed(2 * time.Hour), false},
+		{"bad duration", "two hours", Estimate{}, true},
+		{"negative", "-1h", Estimate{}, true},
+		{"no likely", map[string]interface{}{"min": "1h"}, Estimate{}, true},
+		{"out of order", map[string]interface{}{"min": "3h", "likely": "2h"}, Estimate{}, true},
+		{"number", 90, Estimate{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := timed("a", tt.duration)
+			e, err := EstimateOf(&it, def)
+			if tt.err {
+				assert.ErrorIs(t, err, ErrInvalidDuration)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, e)
+		})
+	}
+}
+
+func TestEstimate_Sample(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 1))
+	assert.Equal(t, time.Hour, Fixed(time.Hour).Sample(rng))
+
+	e := Estimate{time.Hour, 2 * time.Hour, 6 * time.Hour}
+	for range 1000 {
+		d := e.Sample(rng)
+		assert.GreaterOrEqual(t, d, e.Min)
+		assert.LessOrEqual(t, d, e.Max)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	m := time.Minute
+	tests := []struct {
+		name     string
+		workers  int
+		slots    []Slot
+		makespan time.Duration
+	}{
+		{"unlimited", 0, []Slot{
+			{ID: "lint", Title: "lint", Start: 0, End: 10 * m, Worker: 0},
+			{ID: "test", Title: "test", Start: 0, End: 30 * m, Worker: 1},
+			{ID: "build", Title: "build", Start: 30 * m, End: 50 * m, Worker: 0},
+			{ID: "package", Title: "package", Start: 50 * m, End: 75 * m, Worker: -1},
+			{ID: "package.linux", Title: "linux", Start: 50 * m, End: 65 * m, Worker: 0},
+			{ID: "package.mac", Title: "mac", Start: 50 * m, End: 75 * m, Worker: 1},
+			{ID: "deploy", Title: "deploy", Start: 75 * m, End: 80 * m, Worker: 0},
+		}, 80 * m},
+		{"one worker", 1, []Slot{
+			{ID: "lint", Title: "lint", Start: 0, End: 10 * m, Worker: 0},
+			{ID: "test", Title: "test", Start: 10 * m, End: 40 * m, Worker: 0},
+			{ID: "build", Title: "build", Start: 40 * m, End: 60 * m, Worker: 0},
+			{ID: "package", Title: "package", Start: 60 * m, End: 100 * m, Worker: -1},
+			{ID: "package.linux", Title: "linux", Start: 60 * m, End: 75 * m, Worker: 0},
+			{ID: "package.mac", Title: "mac", Start: 75 * m, End: 100 * m, Worker: 0},
+			{ID: "deploy", Title: "deploy", Start: 100 * m, End: 105 * m, Worker: 0},
+		}, 105 * m},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSimulatorWithOptions(Options{Workers: tt.workers}).Simulate(pipeline())
+			require.NoError(t, err)
+			assert.Equal(t, tt.slots, s.Slots)
+			assert.Equal(t, tt.makespan, s.Makespan)
+		})
+	}
+}
+
+func TestSimulate_Settled(t *testing.T) {
+	doc := pipeline()
+	doc.Plan.Items[1].Status = core.PlanItemStatusCompleted
+	doc.Plan.Items[3].SubItems[1].Status = core.PlanItemStatusCancelled
+
+	s, err := NewSimulator().Simulate(doc)
+	require.NoError(t, err)
+	_, ok := s.Slot("test")
+	assert.False(t, ok, "completed items are not scheduled")
+	build, _ := s.Slot("build")
+	assert.Equal(t, 10*time.Minute, build.Start)
+	pkg, _ := s.Slot("package")
+	assert.Equal(t, 45*time.Minute, pkg.End, "cancelled sub-items take no time")
+	assert.Equal(t, 50*time.Minute, s.Makespan)
+}
+
+func TestSimulate_Default(t *testing.T) {
+	doc := &core.Document{Plan: &core.Plan{
+		Items: []core.PlanItem{timed("a", nil), timed("b", "1h")},
+		Edges: []core.Edge{{From: "a", To: "b", Type: core.EdgeBlocks}},
+	}}
+	s, err := NewSimulatorWithOptions(Options{Default: Fixed(time.Hour)}).Simulate(doc)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, s.Makespan)
+
+	s, err = NewSimulator().Simulate(doc)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, s.Makespan, "items without a duration take no time")
+}
+
+func TestSimulate_Errors(t *testing.T) {
+	_, err := NewSimulator().Simulate(&core.Document{})
+	assert.ErrorIs(t, err, core.ErrNoPlan)
+
+	_, err = NewSimulator().Simulate(&core.Document{Plan: &core.Plan{Items: []core.PlanItem{timed("a", "soon")}}})
+	assert.ErrorIs(t, err, ErrInvalidDuration)
+
+	// package cannot finish until linux does, which waits for package.
+	doc := pipeline()
+	doc.Plan.Edges = append(doc.Plan.Edges, core.Edge{From: "package", To: "package.linux", Type: core.EdgeBlocks})
+	_, err = NewSimulator().Simulate(doc)
+	assert.ErrorIs(t, err, ErrStalled)
+	assert.ErrorContains(t, err, "package.linux, deploy can never start")
+}
+
+func TestSchedule_Apply(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	doc := pipeline()
+	s, err := NewSimulatorWithOptions(Options{Start: start}).Simulate(doc)
+	require.NoError(t, err)
+	s.Apply(doc.Plan)
+
+	g := graph.New(doc.Plan)
+	pkg := g.Node("package").Item
+	require.NotNil(t, pkg.StartDate)
+	assert.Equal(t, start.Add(50*time.Minute), *pkg.StartDate)
+	assert.Equal(t, start.Add(75*time.Minute), *pkg.EndDate)
+	assert.Equal(t, start.Add(80*time.Minute), *g.Node("deploy").Item.EndDate)
+}
+
+func TestSchedule_Timeline(t *testing.T) {
+	doc := &core.Document{Plan: &core.Plan{
+		Items: []core.PlanItem{timed("a", "10m"), timed("b", "10m"), timed("go", "0s")},
+		Edges: []core.Edge{{From: "a", To: "b", Type: core.EdgeBlocks}},
+	}}
+	s, err := NewSimulator().Simulate(doc)
+	require.NoError(t, err)
+	assert.Equal(t, ""+
+		"a  |#####     | 0s - 10m0s\n"+
+		"b  |     #####| 10m0s - 20m0s\n"+
+		"go |#         | 0s - 0s\n"+
+		"makespan 20m0s\n", s.Timeline(10))
+}
+
+func TestMonteCarlo(t *testing.T) {
+	f, err := NewSimulatorWithOptions(Options{Runs: 10}).MonteCarlo(pipeline())
+	require.NoError(t, err)
+	assert.Len(t, f.Makespans, 10)
+	assert.Equal(t, 80*time.Minute, f.P50, "fixed durations give the same makespan every run")
+	assert.Equal(t, 80*time.Minute, f.P90)
+
+	doc := &core.Document{Plan: &core.Plan{Items: []core.PlanItem{
+		timed("a", map[string]interface{}{"min": "1h", "likely": "2h", "max": "3h"}),
+	}}}
+	sim := NewSimulatorWithOptions(Options{Runs: 2000, Seed: 7})
+	f, err = sim.MonteCarlo(doc)
+	require.NoError(t, err)
+	assert.InDelta(t, float64(2*time.Hour), float64(f.P50), float64(5*time.Minute))
+	assert.InDelta(t, float64(2*time.Hour), float64(f.Mean), float64(5*time.Minute))
+	assert.Greater(t, f.P90, f.P50)
+	assert.Less(t, f.P90, 3*time.Hour)
+	assert.Equal(t, f.Percentile(90), f.P90)
+
+	again, err := sim.MonteCarlo(doc)
+	require.NoError(t, err)
+	assert.Equal(t, f.Makespans, again.Makespans, "the same seed gives the same forecast")
+}
@@ -0,0 +1,48 @@
+package simulate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultTimelineWidth is the bar width Timeline uses when given none.
+const DefaultTimelineWidth = 60
+
+// Timeline draws the schedule as a text Gantt chart: one row per slot in
+// document order, with bars scaled so the makespan is width characters,
+// then the makespan:
+//
+//	lint    |###                 | 0s - 10m0s
+//	package |      ==========    | 30m0s - 1h20m0s
+//	makespan 1h20m0s
+//
+// Leaf items are drawn with '#' and containers with '='. Every item gets
+// at least one character, so instant items still show.
+func (s *Schedule) Timeline(width int) string {
+	if width <= 0 {
+		width = DefaultTimelineWidth
+	}
+	pad := 0
+	for _, sl := range s.Slots {
+		pad = max(pad, len(sl.ID))
+	}
+
+	var b strings.Builder
+	for _, sl := range s.Slots {
+		from, to := 0, 1
+		if s.Makespan > 0 {
+			from = int(int64(sl.Start) * int64(width) / int64(s.Makespan))
+			to = int((int64(sl.End)*int64(width) + int64(s.Makespan) - 1) / int64(s.Makespan))
+		}
+		from = min(from, width-1)
+		to = min(max(to, from+1), width)
+		mark := "#"
+		if sl.Worker < 0 {
+			mark = "="
+		}
+		bar := strings.Repeat(" ", from) + strings.Repeat(mark, to-from) + strings.Repeat(" ", width-to)
+		fmt.Fprintf(&b, "%-*s |%s| %s - %s\n", pad, sl.ID, bar, sl.Start, sl.End)
+	}
+	fmt.Fprintf(&b, "makespan %s\n", s.Makespan)
+	return b.String()
+}
@@ -0,0 +1,253 @@
+package table
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+var dateLayouts = []string{time.DateOnly, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// Decode rebuilds a Plan document from a table with a header row.
+//
+// Headers are matched to columns by name (see Options and the column
+// constants); headers that match no column become metadata keys. Rows are
+// nested under the row whose id is their parent path; a row whose parent is
+// missing becomes a top-level item. Sibling order follows row order.
+//
+// Metadata cells are kept as strings unless the header ends in ":json", in
+// which case each cell is decoded as JSON.
+func Decode(data []byte, opts Options) (*core.Document, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	r.Comma = opts.comma()
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: missing header row", ErrInvalidRow)
+	}
+
+	columns := make([]string, len(records[0]))
+	typed := make([]bool, len(records[0]))
+	hasTitle := false
+	for i, h := range records[0] {
+		col := canonicalColumn(h)
+		if !builtinColumns[col] && !strings.HasPrefix(col, metadataPrefix) && !strings.HasPrefix(col, narrativePrefix) {
+			col = metadataPrefix + col
+		}
+		if strings.HasPrefix(col, metadataPrefix) {
+			col, typed[i] = strings.CutSuffix(col, jsonSuffix)
+		}
+		columns[i] = col
+		hasTitle = hasTitle || col == ColumnTitle
+	}
+	if !hasTitle {
+		return nil, fmt.Errorf("%w: no %q column", ErrInvalidRow, ColumnTitle)
+	}
+
+	type entry struct {
+		row       int
+		item      core.PlanItem
+		parent    string
+		blockedBy []string
+		children  []*entry
+	}
+	var entries []*entry
+	byPath := make(map[string]*entry)
+	for n, record := range records[1:] {
+		rowNum := n + 2
+		if blank(record) {
+			continue
+		}
+		e := &entry{row: rowNum, item: core.PlanItem{Status: core.PlanItemStatusPending}}
+		path := ""
+		for i, value := range record {
+			if i >= len(columns) {
+				break
+			}
+			if columns[i] == ColumnID {
+				path = strings.TrimSpace(value)
+				continue
+			}
+			if columns[i] == ColumnBlockedBy {
+				e.blockedBy = splitList(value)
+				continue
+			}
+			if err := setField(&e.item, columns[i], value, typed[i]); err != nil {
+				return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidRow, rowNum, err)
+			}
+		}
+		if e.item.Title == "" {
+			return nil, fmt.Errorf("%w: row %d: missing title", ErrInvalidRow, rowNum)
+		}
+		if path != "" {
+			if _, dup := byPath[path]; dup {
+				return nil, fmt.Errorf("%w: row %d: duplicate id %q", ErrInvalidRow, rowNum, path)
+			}
+			byPath[path] = e
+			parent, local := graph.SplitID(path)
+			e.parent = parent
+			if !strings.HasPrefix(local, "#") {
+				e.item.ID = local
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	var roots []*entry
+	for _, e := range entries {
+		if p := byPath[e.parent]; e.parent != "" && p != nil {
+			p.children = append(p.children, e)
+		} else {
+			roots = append(roots, e)
+		}
+	}
+
+	var edges []core.Edge
+	var build func(e *entry, parentID string, addressable bool) (core.PlanItem, error)
+	build = func(e *entry, parentID string, addressable bool) (core.PlanItem, error) {
+		item := e.item
+		id := ""
+		if addressable && item.ID != "" {
+			id = graph.JoinID(parentID, item.ID)
+		}
+		if len(e.blockedBy) > 0 && id == "" {
+			return item, fmt.Errorf("%w: row %d: blockedBy requires an id", ErrInvalidRow, e.row)
+		}
+		for _, from := range e.blockedBy {
+			edges = append(edges, core.Edge{From: from, To: id, Type: core.EdgeBlocks})
+		}
+		for _, c := range e.children {
+			child, err := build(c, id, id != "")
+			if err != nil {
+				return item, err
+			}
+			item.SubItems = append(item.SubItems, child)
+		}
+		return item, nil
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = "Imported plan"
+	}
+	plan := &core.Plan{
+		Title:      title,
+		Status:     core.PlanStatusDraft,
+		Narratives: make(map[string]string),
+	}
+	for _, e := range roots {
+		item, err := build(e, "", true)
+		if err != nil {
+			return nil, err
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	plan.Edges = edges
+
+	return &core.Document{
+		Info: core.Info{Version: core.SpecVersion},
+		Plan: plan,
+	}, nil
+}
+
+// setField parses a cell into the item field named by col. typed reports
+// whether a metadata cell is JSON.
+func setField(item *core.PlanItem, col, value string, typed bool) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	var err error
+	switch col {
+	case ColumnUID:
+		item.UID = value
+	case ColumnTitle:
+		item.Title = value
+	case ColumnStatus:
+		item.Status = core.PlanItemStatus(value)
+		if !item.Status.IsValid() {
+			return fmt.Errorf("invalid status %q", value)
+		}
+	case ColumnPriority:
+		item.Priority = core.Priority(strings.ToLower(value))
+		if !item.Priority.IsValid() {
+			return fmt.Errorf("invalid priority %q", value)
+		}
+	case ColumnPercentComplete:
+		f, convErr := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if convErr != nil {
+			return fmt.Errorf("invalid percentComplete %q", value)
+		}
+		item.PercentComplete = &f
+	case ColumnStartDate:
+		item.StartDate, err = parseDate(value)
+	case ColumnDueDate:
+		item.DueDate, err = parseDate(value)
+	case ColumnEndDate:
+		item.EndDate, err = parseDate(value)
+	case ColumnCompleted:
+		item.Completed, err = parseDate(value)
+	case ColumnTags:
+		item.Tags = splitList(value)
+	default:
+		if key, ok := strings.CutPrefix(col, narrativePrefix); ok {
+			if item.Narrative == nil {
+				item.Narrative = make(map[string]string)
+			}
+			item.Narrative[key] = value
+			return nil
+		}
+		if item.Metadata == nil {
+			item.Metadata = make(map[string]interface{})
+		}
+		key := strings.TrimPrefix(col, metadataPrefix)
+		if !typed {
+			item.Metadata[key] = value
+			return nil
+		}
+		var v interface{}
+		if json.Unmarshal([]byte(value), &v) != nil {
+			return fmt.Errorf("invalid JSON %q in %s", value, col)
+		}
+		item.Metadata[key] = v
+	}
+	return err
+}
+
+func parseDate(s string) (*time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid date %q", s)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func blank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
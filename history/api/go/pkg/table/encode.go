@@ -0,0 +1,207 @@
+package table
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+// Encode writes a document's plan as a table with a header row followed by
+// one row per item in document order.
+//
+// Dates at midnight UTC are written as YYYY-MM-DD and other times as RFC
+// 3339. Tags and blockedBy (the sources of blocks edges targeting the item)
+// are comma-separated lists.
+func Encode(doc *core.Document, opts Options) ([]byte, error) {
+	if doc == nil || doc.Plan == nil {
+		return nil, core.ErrNoPlan
+	}
+	plan := doc.Plan
+	columns, err := expandColumns(plan, opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	g := graph.New(plan)
+	blockedBy := make(map[string][]string)
+	for _, e := range g.Edges() {
+		if e.Type == core.EdgeBlocks {
+			blockedBy[e.To] = append(blockedBy[e.To], e.From)
+		}
+	}
+
+	typed := typedColumns(plan, columns)
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col
+		if typed[col] {
+			header[i] += jsonSuffix
+		}
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = opts.comma()
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	var walk func(items []core.PlanItem, parent string) error
+	walk = func(items []core.PlanItem, parent string) error {
+		for i := range items {
+			item := &items[i]
+			local := item.ID
+			if local == "" {
+				local = fmt.Sprintf("#%d", i+1)
+			}
+			path := graph.JoinID(parent, local)
+			row := make([]string, len(columns))
+			for c, col := range columns {
+				row[c] = cell(item, col, path, blockedBy[path], typed[col])
+			}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+			if err := walk(item.SubItems, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(plan.Items, ""); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// expandColumns validates the requested columns and expands wildcards.
+func expandColumns(plan *core.Plan, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		requested = DefaultColumns
+	}
+	var columns []string
+	for _, col := range requested {
+		switch {
+		case col == metadataPrefix+"*":
+			for _, k := range sortedKeys(plan.Items, func(it core.PlanItem) []string { return mapKeys(it.Metadata) }) {
+				columns = append(columns, metadataPrefix+k)
+			}
+		case col == narrativePrefix+"*":
+			for _, k := range sortedKeys(plan.Items, func(it core.PlanItem) []string { return mapKeys(it.Narrative) }) {
+				columns = append(columns, narrativePrefix+k)
+			}
+		case builtinColumns[col],
+			strings.HasPrefix(col, metadataPrefix) && len(col) > len(metadataPrefix),
+			strings.HasPrefix(col, narrativePrefix) && len(col) > len(narrativePrefix):
+			columns = append(columns, col)
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnknownColumn, col)
+		}
+	}
+	return columns, nil
+}
+
+// typedColumns reports the metadata columns that must be written as JSON:
+// those holding a value other than a string, and those whose key already
+// ends in jsonSuffix, which would otherwise lose it on import.
+func typedColumns(plan *core.Plan, columns []string) map[string]bool {
+	typed := make(map[string]bool)
+	var walk func(items []core.PlanItem)
+	walk = func(items []core.PlanItem) {
+		for _, it := range items {
+			for k, v := range it.Metadata {
+				if _, isString := v.(string); !isString && v != nil {
+					typed[metadataPrefix+k] = true
+				}
+			}
+			walk(it.SubItems)
+		}
+	}
+	walk(plan.Items)
+	for _, col := range columns {
+		if strings.HasPrefix(col, metadataPrefix) && strings.HasSuffix(col, jsonSuffix) {
+			typed[col] = true
+		}
+	}
+	return typed
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// cell formats one item field. Metadata in a typed column is written as
+// JSON, strings included.
+func cell(item *core.PlanItem, col, path string, blockedBy []string, typed bool) string {
+	switch col {
+	case ColumnID:
+		return path
+	case ColumnUID:
+		return item.UID
+	case ColumnTitle:
+		return item.Title
+	case ColumnStatus:
+		return string(item.Status)
+	case ColumnPriority:
+		return string(item.Priority)
+	case ColumnPercentComplete:
+		if item.PercentComplete == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*item.PercentComplete, 'f', -1, 64)
+	case ColumnStartDate:
+		return formatDate(item.StartDate)
+	case ColumnDueDate:
+		return formatDate(item.DueDate)
+	case ColumnEndDate:
+		return formatDate(item.EndDate)
+	case ColumnCompleted:
+		return formatDate(item.Completed)
+	case ColumnTags:
+		return strings.Join(item.Tags, ", ")
+	case ColumnBlockedBy:
+		return strings.Join(blockedBy, ", ")
+	}
+	if key, ok := strings.CutPrefix(col, narrativePrefix); ok {
+		return item.Narrative[key]
+	}
+	key := strings.TrimPrefix(col, metadataPrefix)
+	v, ok := item.Metadata[key]
+	if !ok || v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok && !typed {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	u := t.UTC()
+	if u.Equal(u.Truncate(24 * time.Hour)) {
+		return u.Format(time.DateOnly)
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,127 @@
+// Package table projects plans onto rows and columns for spreadsheets, as CSV
+// or TSV, and rebuilds plans from such tables.
+//
+// Each row is one plan item, in document order. The "id" column holds the
+// item's hierarchical ID path ("backend.schema"), which is how nesting
+// survives the round trip. Items without an ID of their own are written with
+// a positional segment, "#" followed by the item's 1-based index among its
+// siblings ("backend.#2"); positional segments are dropped again on import.
+package table
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+var (
+	// ErrUnknownColumn is returned when an export column name is not recognized.
+	ErrUnknownColumn = errors.New("unknown column")
+	// ErrInvalidRow is returned when an imported row cannot become a plan item.
+	ErrInvalidRow = errors.New("invalid row")
+)
+
+// Column names. In addition to these, "metadata.<key>" and
+// "narrative.<key>" select a single metadata value or narrative, and
+// "metadata.*" and "narrative.*" expand to every key used by any item, in
+// sorted order.
+//
+// A metadata column holding any value that is not a string is written with
+// the header "metadata.<key>:json", and every cell in it is JSON: 8 is a
+// number and "8" a string. Cells in other metadata columns are always
+// strings, so values such as "1.10" or "true" survive the round trip.
+const (
+	ColumnID              = "id"
+	ColumnUID             = "uid"
+	ColumnTitle           = "title"
+	ColumnStatus          = "status"
+	ColumnPriority        = "priority"
+	ColumnPercentComplete = "percentComplete"
+	ColumnStartDate       = "startDate"
+	ColumnDueDate         = "dueDate"
+	ColumnEndDate         = "endDate"
+	ColumnCompleted       = "completed"
+	ColumnTags            = "tags"
+	ColumnBlockedBy       = "blockedBy"
+
+	metadataPrefix  = "metadata."
+	narrativePrefix = "narrative."
+
+	// jsonSuffix tags a metadata column header whose cells are JSON.
+	jsonSuffix = ":json"
+)
+
+// DefaultColumns are exported when Options.Columns is empty.
+var DefaultColumns = []string{
+	ColumnID, ColumnTitle, ColumnStatus, ColumnPriority, ColumnPercentComplete,
+	ColumnStartDate, ColumnDueDate, ColumnEndDate, ColumnTags, ColumnBlockedBy,
+}
+
+var builtinColumns = map[string]bool{
+	ColumnID: true, ColumnUID: true, ColumnTitle: true, ColumnStatus: true,
+	ColumnPriority: true, ColumnPercentComplete: true, ColumnStartDate: true,
+	ColumnDueDate: true, ColumnEndDate: true, ColumnCompleted: true,
+	ColumnTags: true, ColumnBlockedBy: true,
+}
+
+// Options controls the table layout.
+type Options struct {
+	// Comma is the field delimiter. Zero means ','; use '\t' for TSV.
+	Comma rune
+	// Columns selects and orders the exported columns. Empty means
+	// DefaultColumns. Ignored on import, where the header row decides.
+	Columns []string
+	// Title is the plan title used on import. Empty means "Imported plan".
+	Title string
+}
+
+func (o Options) comma() rune {
+	if o.Comma == 0 {
+		return ','
+	}
+	return o.Comma
+}
+
+// canonicalColumn maps a header to its column name, matching built-in names
+// case-insensitively and ignoring spaces, dashes and underscores, so headers
+// like "Due Date" or "percent_complete" are recognized.
+func canonicalColumn(header string) string {
+	h := strings.TrimSpace(header)
+	lower := strings.ToLower(h)
+	for _, prefix := range []string{metadataPrefix, narrativePrefix} {
+		if strings.HasPrefix(lower, prefix) {
+			return prefix + h[len(prefix):]
+		}
+	}
+	squashed := strings.NewReplacer(" ", "", "-", "", "_", "").Replace(lower)
+	for name := range builtinColumns {
+		if strings.ToLower(name) == squashed {
+			return name
+		}
+	}
+	return h
+}
+
+// sortedKeys returns the sorted union of keys produced by keysOf for every
+// item in the tree.
+func sortedKeys(items []core.PlanItem, keysOf func(core.PlanItem) []string) []string {
+	seen := make(map[string]bool)
+	var walk func([]core.PlanItem)
+	walk = func(items []core.PlanItem) {
+		for _, it := range items {
+			for _, k := range keysOf(it) {
+				seen[k] = true
+			}
+			walk(it.SubItems)
+		}
+	}
+	walk(items)
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,235 @@
+package table
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+func date(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func releaseDoc() *core.Document {
+	pct := 62.5
+	return &core.Document{
+		Info: core.Info{Version: "0.5"},
+		Plan: &core.Plan{
+			Title:  "Release 1.2",
+			Status: core.PlanStatusInProgress,
+			Items: []core.PlanItem{
+				{
+					ID:       "backend",
+					Title:    "Backend",
+					Status:   core.PlanItemStatusInProgress,
+					Priority: core.PriorityHigh,
+					DueDate:  date("2025-03-01T00:00:00Z"),
+					Tags:     []string{"db", "billing"},
+					Metadata: map[string]interface{}{"owner": "ana", "points": float64(8)},
+					SubItems: []core.PlanItem{
+						{ID: "schema", Title: "Migrate schema", Status: core.PlanItemStatusCompleted},
+						{ID: "invoices", Title: "Rewrite invoices, v2", Status: core.PlanItemStatusPending, PercentComplete: &pct,
+							StartDate: date("2025-02-10T09:30:00Z")},
+						{Title: "Unnamed follow-up", Status: core.PlanItemStatusPending,
+							Narrative: map[string]string{"Overview": "Check \"quoted\"\nnotes"}},
+					},
+				},
+				{ID: "docs", Title: "Docs", Status: core.PlanItemStatusBlocked, Metadata: map[string]interface{}{"owner": "li"}},
+			},
+			Edges: []core.Edge{
+				{From: "backend.schema", To: "backend.invoices", Type: core.EdgeBlocks},
+				{From: "backend", To: "docs", Type: core.EdgeBlocks},
+				{From: "backend", To: "docs", Type: core.EdgeInforms},
+			},
+		},
+	}
+}
+
+func TestEncode(t *testing.T) {
+	t.Run("default columns", func(t *testing.T) {
+		data, err := Encode(releaseDoc(), Options{})
+		require.NoError(t, err)
+
+		assert.Equal(t, strings.Join([]string{
+			"id,title,status,priority,percentComplete,startDate,dueDate,endDate,tags,blockedBy",
+			`backend,Backend,inProgress,high,,,2025-03-01,,"db, billing",`,
+			"backend.schema,Migrate schema,completed,,,,,,,",
+			`backend.invoices,"Rewrite invoices, v2",pending,,62.5,2025-02-10T09:30:00Z,,,,backend.schema`,
+			"backend.#3,Unnamed follow-up,pending,,,,,,,",
+			"docs,Docs,blocked,,,,,,,backend",
+			"",
+		}, "\n"), string(data))
+	})
+
+	t.Run("selected and wildcard columns", func(t *testing.T) {
+		data, err := Encode(releaseDoc(), Options{
+			Comma:   '\t',
+			Columns: []string{ColumnID, ColumnTitle, "metadata.*", "narrative.Overview"},
+		})
+		require.NoError(t, err)
+
+		lines := strings.Split(string(data), "\n")
+		assert.Equal(t, "id\ttitle\tmetadata.owner\tmetadata.points:json\tnarrative.Overview", lines[0])
+		assert.Equal(t, "backend\tBackend\tana\t8\t", lines[1])
+		assert.Equal(t, "backend.#3\tUnnamed follow-up\t\t\t\"Check \"\"quoted\"\"", lines[4])
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		_, err := Encode(releaseDoc(), Options{Columns: []string{"title", "owner"}})
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+	})
+
+	t.Run("no plan", func(t *testing.T) {
+		_, err := Encode(&core.Document{}, Options{})
+		assert.ErrorIs(t, err, core.ErrNoPlan)
+	})
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, comma := range []rune{',', '\t'} {
+		t.Run(string(comma), func(t *testing.T) {
+			want := releaseDoc()
+			opts := Options{
+				Comma: comma,
+				Columns: append(append([]string{}, DefaultColumns...),
+					"metadata.*", "narrative.*"),
+				Title: want.Plan.Title,
+			}
+			data, err := Encode(want, opts)
+			require.NoError(t, err)
+
+			got, err := Decode(data, opts)
+			require.NoError(t, err)
+
+			assert.Equal(t, want.Plan.Title, got.Plan.Title)
+			assert.Equal(t, want.Plan.Items, got.Plan.Items)
+			assert.Equal(t, []core.Edge{
+				{From: "backend.schema", To: "backend.invoices", Type: core.EdgeBlocks},
+				{From: "backend", To: "docs", Type: core.EdgeBlocks},
+			}, got.Plan.Edges, "only blocks edges are tabulated")
+
+			again, err := Encode(got, opts)
+			require.NoError(t, err)
+			assert.Equal(t, string(data), string(again), "output is stable across round trips")
+		})
+	}
+}
+
+func TestRoundTrip_MetadataTypes(t *testing.T) {
+	doc := &core.Document{
+		Info: core.Info{Version: "0.5"},
+		Plan: &core.Plan{Title: "Versions", Status: core.PlanStatusDraft, Items: []core.PlanItem{
+			{ID: "a", Title: "A", Status: core.PlanItemStatusPending, Metadata: map[string]interface{}{
+				"version": "1.10", "flag": "true", "count": "42", "ref": `"quoted"`,
+				"points": float64(3), "labels": []interface{}{"x", "y"},
+			}},
+			{ID: "b", Title: "B", Status: core.PlanItemStatusPending, Metadata: map[string]interface{}{
+				"points": "three", "done:json": "no",
+			}},
+		}},
+	}
+	opts := Options{Columns: []string{ColumnID, ColumnTitle, ColumnStatus, "metadata.*"}}
+	data, err := Encode(doc, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "id,title,status,metadata.count,metadata.done:json:json,metadata.flag,metadata.labels:json,metadata.points:json,metadata.ref,metadata.version",
+		strings.Split(string(data), "\n")[0])
+
+	got, err := Decode(data, opts)
+	require.NoError(t, err)
+	assert.Equal(t, doc.Plan.Items[0].Metadata, got.Plan.Items[0].Metadata, "numeric-looking strings stay strings")
+	assert.Equal(t, doc.Plan.Items[1].Metadata, got.Plan.Items[1].Metadata)
+
+	t.Run("invalid JSON cell", func(t *testing.T) {
+		_, err := Decode([]byte("title,points:json\nA,three\n"), Options{})
+		require.ErrorIs(t, err, ErrInvalidRow)
+		assert.Contains(t, err.Error(), `row 2: invalid JSON "three" in metadata.points`)
+	})
+}
+
+// spreadsheetExport is shaped like a table saved from a spreadsheet: a byte
+// order mark, human-friendly headers, a column vBRIEF does not know, rows
+// re-sorted so a child precedes its parent, and a trailing blank row.
+const spreadsheetExport = "\ufeffID,Title,Status,Due Date,Percent_Complete,Owner,Blocked By\r\n" +
+	"ops.deploy,Deploy,pending,2025-04-01 18:00,,sam,ops.build\r\n" +
+	"ops,Ops,inProgress,,40%,,\r\n" +
+	"ops.build,Build,completed,,,\"jo\",\r\n" +
+	",Loose end,,,,,\r\n" +
+	",,,,,,\r\n"
+
+func TestDecode(t *testing.T) {
+	doc, err := Decode([]byte(spreadsheetExport), Options{})
+	require.NoError(t, err)
+
+	assert.Equal(t, core.SpecVersion, doc.Info.Version)
+	assert.Equal(t, "Imported plan", doc.Plan.Title)
+	require.Len(t, doc.Plan.Items, 2)
+
+	ops := doc.Plan.Items[0]
+	assert.Equal(t, "ops", ops.ID)
+	assert.Equal(t, core.PlanItemStatusInProgress, ops.Status)
+	require.NotNil(t, ops.PercentComplete)
+	assert.Equal(t, 40.0, *ops.PercentComplete)
+	require.Len(t, ops.SubItems, 2)
+	assert.Equal(t, []string{"deploy", "build"}, []string{ops.SubItems[0].ID, ops.SubItems[1].ID}, "row order is kept")
+
+	deploy := ops.SubItems[0]
+	assert.Equal(t, "2025-04-01T18:00:00Z", deploy.DueDate.Format(time.RFC3339))
+	assert.Equal(t, map[string]interface{}{"Owner": "sam"}, deploy.Metadata)
+
+	loose := doc.Plan.Items[1]
+	assert.Equal(t, "Loose end", loose.Title)
+	assert.Empty(t, loose.ID)
+	assert.Equal(t, core.PlanItemStatusPending, loose.Status)
+
+	assert.Equal(t, []core.Edge{{From: "ops.build", To: "ops.deploy", Type: core.EdgeBlocks}}, doc.Plan.Edges)
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		msg  string
+	}{
+		{"empty", "", "missing header row"},
+		{"no title column", "id,status\na,pending\n", `no "title" column`},
+		{"missing title", "id,title\na,\n", "row 2: missing title"},
+		{"invalid status", "title,status\nA,done\n", `row 2: invalid status "done"`},
+		{"invalid priority", "title,priority\nA,urgent\n", `row 2: invalid priority "urgent"`},
+		{"invalid date", "title,dueDate\nA,next week\n", `row 2: invalid date "next week"`},
+		{"invalid percent", "title,percentComplete\nA,most\n", `row 2: invalid percentComplete "most"`},
+		{"duplicate id", "id,title\na,A\na,B\n", `row 3: duplicate id "a"`},
+		{"blockedBy without id", "title,blockedBy\nA,b\n", "row 2: blockedBy requires an id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.data), Options{})
+			require.ErrorIs(t, err, ErrInvalidRow)
+			assert.Contains(t, err.Error(), tt.msg)
+		})
+	}
+}
+
+func TestCanonicalColumn(t *testing.T) {
+	tests := map[string]string{
+		"ID":                 ColumnID,
+		" Due Date ":         ColumnDueDate,
+		"percent-complete":   ColumnPercentComplete,
+		"BLOCKED_BY":         ColumnBlockedBy,
+		"Metadata.Team":      "metadata.Team",
+		"narrative.Overview": "narrative.Overview",
+		"Owner":              "Owner",
+	}
+	for header, want := range tests {
+		t.Run(header, func(t *testing.T) {
+			assert.Equal(t, want, canonicalColumn(header))
+		})
+	}
+}
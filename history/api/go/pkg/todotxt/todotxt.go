@@ -0,0 +1,302 @@
+// Package todotxt converts between todo.txt files and vBRIEF plans.
+//
+// Each non-blank line of a todo.txt file is one plan item:
+//
+//	x (A) 2025-01-12 2025-01-10 Call the bank +finance @phone due:2025-01-15
+//
+// maps as follows:
+//
+//	x                   status completed
+//	(A) .. (Z)          priority: A critical, B high, C medium, D-Z low
+//	completion date     completed
+//	creation date       created
+//	+project            tag, without the sigil ("finance")
+//	@context            tag, keeping the sigil ("@phone")
+//	due:YYYY-MM-DD      dueDate
+//	t:YYYY-MM-DD        startDate (threshold date)
+//	status:<status>     status, for statuses todo.txt cannot express
+//	pri:<letter>        priority of a completed task
+//	other key:value     metadata, preserved on export
+//
+// A description whose first word would be read as one of the prefixes
+// above ("x", "(A)" or a date) is written with a backslash before it,
+// "\x marks the spot", and the backslash is removed again on import.
+//
+// todo.txt is flat, so sub-items are exported as separate lines in document
+// order.
+package todotxt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// ErrInvalidLine is returned when a line cannot be converted.
+var ErrInvalidLine = errors.New("todotxt: invalid line")
+
+const dateLayout = time.DateOnly
+
+var (
+	priorityRe = regexp.MustCompile(`^\(([A-Z])\)$`)
+	dateRe     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	keyValueRe = regexp.MustCompile(`^([A-Za-z][^\s:]*):([^\s:]+)$`)
+	// prefixRe matches a first description word that needs escaping, or
+	// has been escaped, with leading backslashes.
+	prefixRe = regexp.MustCompile(`^\\*(x|\([A-Z]\)|\d{4}-\d{2}-\d{2})$`)
+)
+
+// Decode parses a todo.txt file into a Plan document titled title.
+func Decode(data []byte, title string) (*core.Document, error) {
+	plan := &core.Plan{
+		Title:      title,
+		Status:     core.PlanStatusDraft,
+		Narratives: make(map[string]string),
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		item, err := decodeLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidLine, lineNo, err)
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &core.Document{
+		Info: core.Info{Version: core.SpecVersion},
+		Plan: plan,
+	}, nil
+}
+
+func decodeLine(line string) (core.PlanItem, error) {
+	item := core.PlanItem{Status: core.PlanItemStatusPending}
+	fields := strings.Fields(line)
+
+	if fields[0] == "x" {
+		item.Status = core.PlanItemStatusCompleted
+		fields = fields[1:]
+	}
+	if len(fields) > 0 {
+		if m := priorityRe.FindStringSubmatch(fields[0]); m != nil {
+			item.Priority = priorityFromLetter(m[1])
+			fields = fields[1:]
+		}
+	}
+	// A completed task may carry a completion date followed by a creation
+	// date; an open task only a creation date.
+	var dates []*time.Time
+	for len(fields) > 0 && len(dates) < 2 && dateRe.MatchString(fields[0]) {
+		d, err := time.Parse(dateLayout, fields[0])
+		if err != nil {
+			return item, fmt.Errorf("invalid date %q", fields[0])
+		}
+		dates = append(dates, &d)
+		fields = fields[1:]
+	}
+	switch {
+	case item.Status == core.PlanItemStatusCompleted && len(dates) == 2:
+		item.Completed, item.Created = dates[0], dates[1]
+	case item.Status == core.PlanItemStatusCompleted && len(dates) == 1:
+		item.Completed = dates[0]
+	case len(dates) == 1:
+		item.Created = dates[0]
+	case len(dates) == 2:
+		// The second date is part of the description.
+		item.Created = dates[0]
+		fields = append([]string{dates[1].Format(dateLayout)}, fields...)
+	}
+
+	var words []string
+	for _, f := range fields {
+		if len(f) > 1 && f[0] == '+' {
+			item.Tags = append(item.Tags, f[1:])
+			continue
+		}
+		if len(f) > 1 && f[0] == '@' {
+			item.Tags = append(item.Tags, f)
+			continue
+		}
+		m := keyValueRe.FindStringSubmatch(f)
+		if m == nil || strings.HasPrefix(m[2], "//") {
+			if len(words) == 0 && strings.HasPrefix(f, `\`) && prefixRe.MatchString(f) {
+				f = f[1:]
+			}
+			words = append(words, f)
+			continue
+		}
+		if err := setKeyValue(&item, m[1], m[2]); err != nil {
+			return item, err
+		}
+	}
+	item.Title = strings.Join(words, " ")
+	if item.Title == "" {
+		return item, fmt.Errorf("missing description")
+	}
+	return item, nil
+}
+
+func setKeyValue(item *core.PlanItem, key, value string) error {
+	var err error
+	switch key {
+	case "due":
+		item.DueDate, err = parseDate(key, value)
+	case "t":
+		item.StartDate, err = parseDate(key, value)
+	case "status":
+		s := core.PlanItemStatus(value)
+		if !s.IsValid() {
+			return fmt.Errorf("invalid status %q", value)
+		}
+		item.Status = s
+	case "pri":
+		if p := priorityFromLetter(value); p != "" && item.Priority == "" {
+			item.Priority = p
+			return nil
+		}
+		fallthrough
+	default:
+		if item.Metadata == nil {
+			item.Metadata = make(map[string]interface{})
+		}
+		item.Metadata[key] = value
+	}
+	return err
+}
+
+func parseDate(key, value string) (*time.Time, error) {
+	d, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s date %q", key, value)
+	}
+	return &d, nil
+}
+
+func priorityFromLetter(s string) core.Priority {
+	if len(s) != 1 || s[0] < 'A' || s[0] > 'Z' {
+		return ""
+	}
+	switch s[0] {
+	case 'A':
+		return core.PriorityCritical
+	case 'B':
+		return core.PriorityHigh
+	case 'C':
+		return core.PriorityMedium
+	default:
+		return core.PriorityLow
+	}
+}
+
+func priorityLetter(p core.Priority) string {
+	switch p {
+	case core.PriorityCritical:
+		return "A"
+	case core.PriorityHigh:
+		return "B"
+	case core.PriorityMedium:
+		return "C"
+	case core.PriorityLow:
+		return "D"
+	default:
+		return ""
+	}
+}
+
+// Encode writes a document's plan items as todo.txt lines. Tags starting
+// with "@" are written as contexts and all others as projects. Metadata
+// values that are not single words are omitted, since todo.txt cannot
+// represent them.
+func Encode(doc *core.Document) ([]byte, error) {
+	if doc == nil || doc.Plan == nil {
+		return nil, core.ErrNoPlan
+	}
+	var b bytes.Buffer
+	var walk func(items []core.PlanItem)
+	walk = func(items []core.PlanItem) {
+		for i := range items {
+			b.WriteString(encodeLine(&items[i]))
+			b.WriteByte('\n')
+			walk(items[i].SubItems)
+		}
+	}
+	walk(doc.Plan.Items)
+	return b.Bytes(), nil
+}
+
+func encodeLine(item *core.PlanItem) string {
+	var parts []string
+	done := item.Status == core.PlanItemStatusCompleted
+	if done {
+		parts = append(parts, "x")
+	} else if p := priorityLetter(item.Priority); p != "" {
+		parts = append(parts, "("+p+")")
+	}
+	if done && item.Completed != nil {
+		parts = append(parts, item.Completed.Format(dateLayout))
+	}
+	// todo.txt only allows a creation date on a completed task after its
+	// completion date.
+	if item.Created != nil && (!done || item.Completed != nil) {
+		parts = append(parts, item.Created.Format(dateLayout))
+	}
+	words := strings.Fields(item.Title)
+	if len(words) > 0 && prefixRe.MatchString(words[0]) {
+		words[0] = `\` + words[0]
+	}
+	parts = append(parts, words...)
+
+	for _, t := range item.Tags {
+		t = strings.Join(strings.Fields(t), "_")
+		if t == "" {
+			continue
+		}
+		if t[0] != '@' {
+			t = "+" + t
+		}
+		parts = append(parts, t)
+	}
+
+	if item.DueDate != nil {
+		parts = append(parts, "due:"+item.DueDate.Format(dateLayout))
+	}
+	if item.StartDate != nil {
+		parts = append(parts, "t:"+item.StartDate.Format(dateLayout))
+	}
+	if item.Status != core.PlanItemStatusPending && !done {
+		parts = append(parts, "status:"+string(item.Status))
+	}
+	reserved := map[string]bool{"due": true, "t": true, "status": true}
+	if p := priorityLetter(item.Priority); done && p != "" {
+		parts = append(parts, "pri:"+p)
+		reserved["pri"] = true
+	}
+
+	keys := make([]string, 0, len(item.Metadata))
+	for k := range item.Metadata {
+		if !reserved[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		pair := fmt.Sprintf("%s:%v", k, item.Metadata[k])
+		if keyValueRe.MatchString(pair) {
+			parts = append(parts, pair)
+		}
+	}
+	return strings.Join(parts, " ")
+}
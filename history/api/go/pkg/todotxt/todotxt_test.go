@@ -0,0 +1,169 @@
+package todotxt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+const sampleTodo = `(A) 2025-01-10 Call the bank +finance @phone due:2025-01-15
+x 2025-01-12 2025-01-09 File receipts +finance pri:B
+(E) Tidy desk @home t:2025-02-01 rec:1w
+Read https://example.com/post at 10:30 status:blocked owner:sam
+
+x Buy milk
+`
+
+func day(s string) *time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func TestDecode(t *testing.T) {
+	doc, err := Decode([]byte(sampleTodo), "todo.txt")
+	require.NoError(t, err)
+
+	assert.Equal(t, core.SpecVersion, doc.Info.Version)
+	assert.Equal(t, "todo.txt", doc.Plan.Title)
+	require.Len(t, doc.Plan.Items, 5)
+
+	tests := []struct {
+		name string
+		got  core.PlanItem
+		want core.PlanItem
+	}{
+		{"priority, creation date, project, context and due date", doc.Plan.Items[0], core.PlanItem{
+			Title: "Call the bank", Status: core.PlanItemStatusPending, Priority: core.PriorityCritical,
+			Created: day("2025-01-10"), DueDate: day("2025-01-15"), Tags: []string{"finance", "@phone"},
+		}},
+		{"completion with both dates and pri", doc.Plan.Items[1], core.PlanItem{
+			Title: "File receipts", Status: core.PlanItemStatusCompleted, Priority: core.PriorityHigh,
+			Completed: day("2025-01-12"), Created: day("2025-01-09"), Tags: []string{"finance"},
+		}},
+		{"low priority letter, threshold and unknown pair", doc.Plan.Items[2], core.PlanItem{
+			Title: "Tidy desk", Status: core.PlanItemStatusPending, Priority: core.PriorityLow,
+			StartDate: day("2025-02-01"), Tags: []string{"@home"}, Metadata: map[string]interface{}{"rec": "1w"},
+		}},
+		{"URLs and times stay in the title", doc.Plan.Items[3], core.PlanItem{
+			Title: "Read https://example.com/post at 10:30", Status: core.PlanItemStatusBlocked,
+			Metadata: map[string]interface{}{"owner": "sam"},
+		}},
+		{"bare completion", doc.Plan.Items[4], core.PlanItem{
+			Title: "Buy milk", Status: core.PlanItemStatusCompleted,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.got)
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"missing description", "x 2025-01-02"},
+		{"bad due date", "Pay rent due:tomorrow"},
+		{"bad calendar date", "2025-02-30 Pay rent"},
+		{"bad status", "Pay rent status:later"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte("Fine\n"+tt.line+"\n"), "todo.txt")
+			require.ErrorIs(t, err, ErrInvalidLine)
+			assert.Contains(t, err.Error(), "line 2")
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		doc, err := Decode([]byte(sampleTodo), "todo.txt")
+		require.NoError(t, err)
+
+		data, err := Encode(doc)
+		require.NoError(t, err)
+		assert.Equal(t, `(A) 2025-01-10 Call the bank +finance @phone due:2025-01-15
+x 2025-01-12 2025-01-09 File receipts +finance pri:B
+(D) Tidy desk @home t:2025-02-01 rec:1w
+Read https://example.com/post at 10:30 status:blocked owner:sam
+x Buy milk
+`, string(data))
+
+		again, err := Decode(data, "todo.txt")
+		require.NoError(t, err)
+		assert.Equal(t, doc.Plan.Items[1:], again.Plan.Items[1:])
+	})
+
+	t.Run("flattens plans", func(t *testing.T) {
+		doc := &core.Document{Plan: &core.Plan{Items: []core.PlanItem{{
+			Title:   "Backend",
+			Status:  core.PlanItemStatusInProgress,
+			Tags:    []string{"db", "two words"},
+			Created: day("2025-01-01"),
+			Metadata: map[string]interface{}{
+				"points":  8,
+				"section": "Release plan",
+				"due":     "ignored",
+			},
+			SubItems: []core.PlanItem{{Title: "Schema", Status: core.PlanItemStatusCompleted, Created: day("2025-01-01")}},
+		}}}}
+
+		data, err := Encode(doc)
+		require.NoError(t, err)
+		assert.Equal(t, "2025-01-01 Backend +db +two_words status:inProgress points:8\nx Schema\n", string(data))
+	})
+
+	t.Run("tags round trip", func(t *testing.T) {
+		tags := []string{"db", "+plus", "@phone"}
+		doc := &core.Document{Plan: &core.Plan{Items: []core.PlanItem{
+			{Title: "Tagged", Status: core.PlanItemStatusPending, Tags: tags},
+		}}}
+
+		data, err := Encode(doc)
+		require.NoError(t, err)
+		assert.Equal(t, "Tagged +db ++plus @phone\n", string(data))
+
+		again, err := Decode(data, "todo.txt")
+		require.NoError(t, err)
+		assert.Equal(t, tags, again.Plan.Items[0].Tags)
+	})
+
+	t.Run("escapes descriptions that look like prefixes", func(t *testing.T) {
+		doc := &core.Document{Plan: &core.Plan{Items: []core.PlanItem{
+			{Title: "x marks the spot", Status: core.PlanItemStatusPending},
+			{Title: "(A) team sync", Status: core.PlanItemStatusPending, Priority: core.PriorityHigh},
+			{Title: "2025-03-01 retro notes", Status: core.PlanItemStatusCompleted, Completed: day("2025-03-02")},
+			{Title: "2025-03-01 kickoff", Status: core.PlanItemStatusPending, Created: day("2025-02-01")},
+			{Title: `\x is not escaped text`, Status: core.PlanItemStatusPending},
+			{Title: `\n stays`, Status: core.PlanItemStatusPending},
+		}}}
+
+		data, err := Encode(doc)
+		require.NoError(t, err)
+		assert.Equal(t, `\x marks the spot
+(B) \(A) team sync
+x 2025-03-02 \2025-03-01 retro notes
+2025-02-01 \2025-03-01 kickoff
+\\x is not escaped text
+\n stays
+`, string(data))
+
+		again, err := Decode(data, "todo.txt")
+		require.NoError(t, err)
+		assert.Equal(t, doc.Plan.Items, again.Plan.Items)
+	})
+
+	t.Run("no plan", func(t *testing.T) {
+		_, err := Encode(&core.Document{})
+		assert.ErrorIs(t, err, core.ErrNoPlan)
+	})
+}
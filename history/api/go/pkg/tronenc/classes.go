@@ -0,0 +1,227 @@
+package tronenc
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// class is a declared TRON class.
+type class struct {
+	Name   string
+	Fields []string
+	// instances are the objects written with the class.
+	instances []*node
+	// saved is the estimated token saving, net of the declaration.
+	saved int
+}
+
+func (c *class) header() string {
+	return "class " + c.Name + ": " + strings.Join(keys(c.Fields), ",") + "\n"
+}
+
+func keys(fields []string) []string {
+	out := make([]string, len(fields))
+	for i, f := range fields {
+		out[i] = key(f)
+	}
+	return out
+}
+
+// group collects the objects sharing a role. Struct and map objects are
+// kept apart because only structs accept null parameters.
+type group struct {
+	role      string
+	isStruct  bool
+	instances []*node
+}
+
+// chooseClasses picks classes for the document's objects, marks each object
+// with its class and returns the classes in name order.
+func chooseClasses(root *node, opts Options) []*class {
+	var groups []*group
+	index := make(map[string]*group)
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n.isObject && n.role != "" && len(n.keys) > 0 {
+			id := n.role + "/" + strconv.FormatBool(n.isStruct)
+			g := index[id]
+			if g == nil {
+				g = &group{role: n.role, isStruct: n.isStruct}
+				index[id] = g
+				groups = append(groups, g)
+			}
+			g.instances = append(g.instances, n)
+		}
+		for _, v := range n.vals {
+			walk(v)
+		}
+		for _, e := range n.elems {
+			walk(e)
+		}
+	}
+	walk(root)
+
+	used := make(map[string]bool)
+	var classes []*class
+	for _, g := range groups {
+		remaining := g.instances
+		for len(remaining) >= opts.minRepetitions() {
+			var c *class
+			if g.isStruct {
+				c = bestPrefixClass(g.role, remaining, opts)
+			} else {
+				c = bestExactClass(g.role, remaining, opts)
+			}
+			if c == nil {
+				break
+			}
+			c.Name = uniqueName(g.role, used)
+			for _, n := range c.instances {
+				n.class = c
+			}
+			classes = append(classes, c)
+			remaining = without(remaining, c.instances)
+		}
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i].Name < classes[j].Name })
+	return classes
+}
+
+// bestPrefixClass tries the fields of the instances in order of frequency
+// and returns the prefix that saves the most tokens over writing the
+// objects it covers as objects, or nil when no prefix saves any.
+func bestPrefixClass(role string, instances []*node, opts Options) *class {
+	fields := byFrequency(instances)
+	var best *class
+	for k := 1; k <= len(fields); k++ {
+		c := &class{Name: role, Fields: fields[:k]}
+		allowed := make(map[string]bool, k)
+		for _, f := range c.Fields {
+			allowed[f] = true
+		}
+		for _, n := range instances {
+			if covers(allowed, n) {
+				c.instances = append(c.instances, n)
+			}
+		}
+		if len(c.instances) < opts.minRepetitions() {
+			continue
+		}
+		c.saved = saving(c, opts)
+		if c.saved > 0 && (best == nil || c.saved > best.saved) {
+			best = c
+		}
+	}
+	return best
+}
+
+// bestExactClass returns a class for the most common set of keys among
+// map instances, which can only be written positionally when nothing is
+// missing.
+func bestExactClass(role string, instances []*node, opts Options) *class {
+	bySet := make(map[string]*class)
+	var order []string
+	for _, n := range instances {
+		id := strings.Join(n.keys, "\x00")
+		c := bySet[id]
+		if c == nil {
+			c = &class{Name: role, Fields: n.keys}
+			bySet[id] = c
+			order = append(order, id)
+		}
+		c.instances = append(c.instances, n)
+	}
+	var best *class
+	for _, id := range order {
+		c := bySet[id]
+		if len(c.instances) < opts.minRepetitions() {
+			continue
+		}
+		c.saved = saving(c, opts)
+		if c.saved > 0 && (best == nil || c.saved > best.saved) {
+			best = c
+		}
+	}
+	return best
+}
+
+// byFrequency returns the keys of the instances, most frequent first and
+// in order of first appearance among equals.
+func byFrequency(instances []*node) []string {
+	count := make(map[string]int)
+	var fields []string
+	for _, n := range instances {
+		for _, k := range n.keys {
+			if count[k] == 0 {
+				fields = append(fields, k)
+			}
+			count[k]++
+		}
+	}
+	sort.SliceStable(fields, func(i, j int) bool { return count[fields[i]] > count[fields[j]] })
+	return fields
+}
+
+func covers(allowed map[string]bool, n *node) bool {
+	for _, k := range n.keys {
+		if !allowed[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// saving estimates the tokens a class saves over writing its instances as
+// objects. The values cost the same either way, so only the punctuation,
+// keys and null padding are compared.
+func saving(c *class, opts Options) int {
+	saved := -opts.count(c.header())
+	for _, n := range c.instances {
+		var obj, inst strings.Builder
+		obj.WriteString("{")
+		for i, k := range n.keys {
+			if i > 0 {
+				obj.WriteString(",")
+			}
+			obj.WriteString(key(k) + ":")
+		}
+		obj.WriteString("}")
+
+		inst.WriteString(c.Name + "(")
+		for i, f := range c.Fields {
+			if i > 0 {
+				inst.WriteString(",")
+			}
+			if n.get(f) == nil {
+				inst.WriteString("null")
+			}
+		}
+		inst.WriteString(")")
+		saved += opts.count(obj.String()) - opts.count(inst.String())
+	}
+	return saved
+}
+
+func uniqueName(role string, used map[string]bool) string {
+	name := role
+	for i := 2; used[name]; i++ {
+		name = role + strconv.Itoa(i)
+	}
+	used[name] = true
+	return name
+}
+
+func without(nodes, remove []*node) []*node {
+	drop := make(map[*node]bool, len(remove))
+	for _, n := range remove {
+		drop[n] = true
+	}
+	var out []*node
+	for _, n := range nodes {
+		if !drop[n] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package tronenc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/tron-format/trongo/pkg/tron"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// Report describes an encoding and what it saved.
+type Report struct {
+	// Classes are the declared classes in name order.
+	Classes []Class
+	// Tokens is the size of the encoding.
+	Tokens int
+	// BaselineTokens is the size of the same document from tron.Marshal.
+	BaselineTokens int
+	// JSONTokens is the size of the same document as compact JSON.
+	JSONTokens int
+}
+
+// Class describes a declared class.
+type Class struct {
+	Name string
+	// Fields are the positional parameters, most often set first.
+	Fields []string
+	// Instances is the number of objects written with the class.
+	Instances int
+	// Saved is the estimated number of tokens the class saves, net of its
+	// declaration.
+	Saved int
+}
+
+// Saved returns the tokens saved against tron.Marshal.
+func (r *Report) Saved() int {
+	return r.BaselineTokens - r.Tokens
+}
+
+// SavedVsJSON returns the tokens saved against compact JSON.
+func (r *Report) SavedVsJSON() int {
+	return r.JSONTokens - r.Tokens
+}
+
+// String formats the report for people.
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "tokens: %d\n", r.Tokens)
+	fmt.Fprintf(&b, "tron.Marshal: %d (saved %d, %s)\n", r.BaselineTokens, r.Saved(), percent(r.Saved(), r.BaselineTokens))
+	fmt.Fprintf(&b, "json: %d (saved %d, %s)\n", r.JSONTokens, r.SavedVsJSON(), percent(r.SavedVsJSON(), r.JSONTokens))
+	for _, c := range r.Classes {
+		fmt.Fprintf(&b, "class %s: %d instances, saved %d: %s\n", c.Name, c.Instances, c.Saved, strings.Join(c.Fields, ", "))
+	}
+	return b.String()
+}
+
+func percent(part, whole int) string {
+	if whole == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(part)/float64(whole))
+}
+
+func newReport(doc *core.Document, data []byte, classes []*class, opts Options) (*Report, error) {
+	baseline, err := tron.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{
+		Tokens:         opts.count(string(data)),
+		BaselineTokens: opts.count(string(baseline)),
+		JSONTokens:     opts.count(string(plain)),
+	}
+	for _, c := range classes {
+		r.Classes = append(r.Classes, Class{Name: c.Name, Fields: c.Fields, Instances: len(c.instances), Saved: c.saved})
+	}
+	return r, nil
+}
+
+// EstimateTokens approximates the token count of s under a byte-pair
+// tokenizer of the cl100k kind: words split at case changes count one
+// token per six letters, numbers one per three digits, and every other
+// rune except spaces counts one. It is meant for comparing encodings and
+// planning context budgets, not for exact counts; package budget uses it
+// as its default tokenizer.
+func EstimateTokens(s string) int {
+	tokens := 0
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t':
+			i++
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			tokens += (j - i + 2) / 3
+			i = j
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			j := i + 1
+			for j < len(runes) && runes[j] < unicode.MaxASCII && unicode.IsLetter(runes[j]) &&
+				!(unicode.IsUpper(runes[j]) && unicode.IsLower(runes[j-1])) {
+				j++
+			}
+			tokens += (j - i + 5) / 6
+			i = j
+		default:
+			tokens++
+			i++
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,397 @@
+// Package tronenc writes vBRIEF documents as TRON with classes chosen for
+// the document at hand.
+//
+// tron.Marshal declares a class only for objects whose set of non-empty
+// fields matches exactly, so plan items that differ in a single optional
+// field never share one, and its class names (A, B, ...) depend on map
+// order. This encoder instead groups objects by role (plan items and their
+// subItems, edges, reminders, metadata, ...), orders each class's
+// parameters by how often the fields are set, pads missing struct fields
+// with null, and keeps a class only when it saves tokens:
+//
+//	class Edge: from,to,type
+//	class PlanItem: id,title,status,subItems
+//
+//	vBRIEFInfo:{version:"0.5"}
+//	plan:{title:"Release",status:"draft",narratives:{},items:[PlanItem("api","Build API","running",null)]}
+//
+// Map-valued fields such as metadata and narratives are only written
+// positionally when an instance has exactly the class's keys, since a null
+// parameter would add the key to the map. The output is read by
+// tron.Unmarshal and the TRON parser.
+package tronenc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// ErrInvalidDocument is returned when a document cannot be encoded.
+var ErrInvalidDocument = errors.New("tronenc: invalid document")
+
+// Options configures Encode.
+type Options struct {
+	// MinRepetitions is the fewest objects a class must cover to be
+	// declared. Defaults to 2.
+	MinRepetitions int
+	// CountTokens estimates the tokens in a string. It drives the choice
+	// of classes and the report. Defaults to EstimateTokens.
+	CountTokens func(string) int
+	// Indent, if set, puts each element of a non-empty object or array on
+	// its own line, indented by one copy of Indent per level of nesting,
+	// as json.MarshalIndent does. Class instances stay on one line, apart
+	// from objects and arrays among their parameters. The classes chosen
+	// do not depend on indentation.
+	Indent string
+	// Prefix begins every line after the first, when Indent is set.
+	Prefix string
+}
+
+func (o Options) minRepetitions() int {
+	if o.MinRepetitions > 0 {
+		return o.MinRepetitions
+	}
+	return 2
+}
+
+func (o Options) count(s string) int {
+	if o.CountTokens != nil {
+		return o.CountTokens(s)
+	}
+	return EstimateTokens(s)
+}
+
+// Encode writes a document as TRON.
+func Encode(doc *core.Document, opts Options) ([]byte, error) {
+	data, _, err := encode(doc, opts)
+	return data, err
+}
+
+// EncodeWithReport writes a document as TRON and reports the classes it
+// declared and the tokens saved against tron.Marshal and JSON.
+func EncodeWithReport(doc *core.Document, opts Options) ([]byte, *Report, error) {
+	data, classes, err := encode(doc, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	report, err := newReport(doc, data, classes, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, report, nil
+}
+
+func encode(doc *core.Document, opts Options) ([]byte, []*class, error) {
+	if doc == nil {
+		return nil, nil, fmt.Errorf("%w: nil document", ErrInvalidDocument)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, err := readNode(dec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	annotate(root, reflect.TypeOf(doc))
+	assignRoles(root, "", "")
+
+	classes := chooseClasses(root, opts)
+
+	var b strings.Builder
+	p := &printer{w: &b, prefix: opts.Prefix, indent: opts.Indent}
+	for _, c := range classes {
+		b.WriteString(c.header())
+	}
+	if len(classes) > 0 {
+		b.WriteString("\n")
+	}
+	// The document is written as TRON's implicit root object, one
+	// top-level key per line.
+	for i, k := range root.keys {
+		if opts.Indent != "" && (i > 0 || len(classes) > 0) {
+			b.WriteString(opts.Prefix)
+		}
+		b.WriteString(key(k))
+		b.WriteString(":")
+		p.write(root.vals[i], 0)
+		b.WriteString("\n")
+	}
+	return []byte(b.String()), classes, nil
+}
+
+// node is a JSON value that keeps object keys in document order.
+type node struct {
+	// raw is the JSON text of a scalar.
+	raw   string
+	keys  []string
+	vals  []*node
+	elems []*node
+	// isObject and isArray distinguish the composite kinds.
+	isObject, isArray bool
+	// isStruct is set for objects decoded into a Go struct, whose fields
+	// may be given as null.
+	isStruct bool
+	// role names the class an object may be written as; objects without
+	// a role are always written as objects.
+	role string
+	// class is the class chosen for the object, if any.
+	class *class
+}
+
+func readNode(dec *json.Decoder) (*node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		n := &node{isObject: t == '{', isArray: t == '['}
+		for dec.More() {
+			if n.isObject {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, k.(string))
+			}
+			v, err := readNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			if n.isObject {
+				n.vals = append(n.vals, v)
+			} else {
+				n.elems = append(n.elems, v)
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case string:
+		return &node{raw: quote(t)}, nil
+	case json.Number:
+		return &node{raw: t.String()}, nil
+	case bool:
+		if t {
+			return &node{raw: "true"}, nil
+		}
+		return &node{raw: "false"}, nil
+	case nil:
+		return &node{raw: "null"}, nil
+	}
+	return nil, fmt.Errorf("unexpected token %v", tok)
+}
+
+// annotate marks the objects that decode into Go structs, following t
+// through the tree.
+func annotate(n *node, t reflect.Type) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case n.isArray:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for _, e := range n.elems {
+			annotate(e, elem)
+		}
+	case n.isObject:
+		n.isStruct = t != nil && t.Kind() == reflect.Struct
+		for i, k := range n.keys {
+			var child reflect.Type
+			switch {
+			case n.isStruct:
+				child = fieldType(t, k)
+			case t != nil && t.Kind() == reflect.Map:
+				child = t.Elem()
+			}
+			annotate(n.vals[i], child)
+		}
+	}
+}
+
+// fieldType returns the type of the struct field encoded under name.
+func fieldType(t reflect.Type, name string) reflect.Type {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if tag == name || (tag == "" && f.Name == name) {
+			return f.Type
+		}
+	}
+	return nil
+}
+
+// assignRoles names each object's role after the key holding it: array
+// elements take the singular of the array's key, and plan and todo items
+// are PlanItem and TodoItem at any depth.
+func assignRoles(n *node, name, section string) {
+	switch {
+	case n.isArray:
+		role := singular(name)
+		if name == "items" || name == "subItems" {
+			role = section + "Item"
+		}
+		for _, e := range n.elems {
+			if e.isObject {
+				e.role = className(role)
+			}
+			assignRoles(e, name, section)
+		}
+	case n.isObject:
+		for i, k := range n.keys {
+			s := section
+			switch k {
+			case "plan":
+				s = "Plan"
+			case "todoList":
+				s = "Todo"
+			}
+			if v := n.vals[i]; v.isObject {
+				v.role = className(k)
+			}
+			assignRoles(n.vals[i], k, s)
+		}
+	}
+}
+
+func singular(s string) string {
+	if len(s) > 1 && strings.HasSuffix(s, "s") && !strings.HasSuffix(s, "ss") {
+		return s[:len(s)-1]
+	}
+	return s
+}
+
+// className turns a key into a class name, or "" when the key does not
+// make an identifier.
+func className(s string) string {
+	if s == "" {
+		return ""
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	name := string(r)
+	if !IsIdentifier(name) {
+		return ""
+	}
+	return name
+}
+
+// IsIdentifier reports whether s can be written unquoted, following the
+// TRON tokenizer's identifier rules.
+func IsIdentifier(s string) bool {
+	switch s {
+	case "", "class", "true", "false", "null":
+		return false
+	}
+	for i, r := range s {
+		if r == '_' || unicode.IsLetter(r) {
+			continue
+		}
+		if i > 0 && (unicode.IsDigit(r) || unicode.IsMark(r)) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func key(k string) string {
+	if IsIdentifier(k) {
+		return k
+	}
+	return quote(k)
+}
+
+func quote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// printer writes nodes as TRON, indenting them if indent is set.
+type printer struct {
+	w              io.StringWriter
+	prefix, indent string
+}
+
+func (p *printer) write(n *node, depth int) {
+	w := p.w
+	switch {
+	case n.isArray:
+		_, _ = w.WriteString("[")
+		for i, e := range n.elems {
+			if i > 0 {
+				_, _ = w.WriteString(",")
+			}
+			p.newline(depth + 1)
+			p.write(e, depth+1)
+		}
+		if len(n.elems) > 0 {
+			p.newline(depth)
+		}
+		_, _ = w.WriteString("]")
+	case n.isObject && n.class != nil:
+		_, _ = w.WriteString(n.class.Name + "(")
+		for i, f := range n.class.Fields {
+			if i > 0 {
+				_, _ = w.WriteString(",")
+			}
+			if v := n.get(f); v != nil {
+				p.write(v, depth)
+			} else {
+				_, _ = w.WriteString("null")
+			}
+		}
+		_, _ = w.WriteString(")")
+	case n.isObject:
+		_, _ = w.WriteString("{")
+		for i, k := range n.keys {
+			if i > 0 {
+				_, _ = w.WriteString(",")
+			}
+			p.newline(depth + 1)
+			_, _ = w.WriteString(key(k) + ":")
+			p.write(n.vals[i], depth+1)
+		}
+		if len(n.keys) > 0 {
+			p.newline(depth)
+		}
+		_, _ = w.WriteString("}")
+	default:
+		_, _ = w.WriteString(n.raw)
+	}
+}
+
+// newline starts a line at the given depth, if indenting.
+func (p *printer) newline(depth int) {
+	if p.indent == "" {
+		return
+	}
+	_, _ = p.w.WriteString("\n" + p.prefix + strings.Repeat(p.indent, depth))
+}
+
+func (n *node) get(k string) *node {
+	for i, kk := range n.keys {
+		if kk == k {
+			return n.vals[i]
+		}
+	}
+	return nil
+}
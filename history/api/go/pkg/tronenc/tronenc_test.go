@@ -0,0 +1,168 @@
+package tronenc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/parser"
+)
+
+// sampleDoc has items that differ in their optional fields, which
+// tron.Marshal cannot put in one class.
+func sampleDoc() *core.Document {
+	return &core.Document{
+		Info: core.Info{Version: core.SpecVersion},
+		Plan: &core.Plan{
+			Title:      "Release",
+			Status:     core.PlanStatusDraft,
+			Narratives: map[string]string{"Overview": "Ship <it> & rest"},
+			Items: []core.PlanItem{
+				{ID: "api", Title: "Build API", Status: core.PlanItemStatusInProgress, Priority: core.PriorityHigh,
+					Metadata: map[string]interface{}{"points": 3.0, "sprint": 1.0, "team": "core"},
+					SubItems: []core.PlanItem{
+						{ID: "schema", Title: "Schema", Status: core.PlanItemStatusCompleted,
+							Metadata: map[string]interface{}{"points": 1.0, "sprint": 1.0, "team": "core"}},
+						{ID: "handlers", Title: "Handlers", Status: core.PlanItemStatusPending, Tags: []string{"go"},
+							Metadata: map[string]interface{}{"points": 2.0, "sprint": 1.0, "team": "core"}},
+					}},
+				{ID: "docs", Title: "Write docs", Status: core.PlanItemStatusPending,
+					Metadata: map[string]interface{}{"points": 1.0, "sprint": 1.0, "team": "docs"}},
+				{ID: "ship", Title: "Ship", Status: core.PlanItemStatusPending, Priority: core.PriorityLow,
+					Metadata: map[string]interface{}{"points": 2.0, "my-key": true}},
+			},
+			Edges: []core.Edge{
+				{From: "api", To: "docs", Type: core.EdgeBlocks},
+				{From: "docs", To: "ship", Type: core.EdgeBlocks},
+			},
+		},
+	}
+}
+
+func jsonOf(t *testing.T, doc *core.Document) string {
+	t.Helper()
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestEncode(t *testing.T) {
+	doc := sampleDoc()
+	data, err := Encode(doc, Options{})
+	require.NoError(t, err)
+	out := string(data)
+
+	assert.True(t, strings.HasPrefix(out, "class Edge: from,to,type\nclass Metadata: points,sprint,team\nclass PlanItem: id,title,status,"),
+		"classes are sorted by name and fields by frequency:\n%s", out)
+	assert.Contains(t, out, `PlanItem("schema","Schema","completed"`, "subItems share the PlanItem class")
+	assert.Contains(t, out, `Edge("api","docs","blocks")`)
+	assert.Contains(t, out, `Metadata(3,1,"core")`)
+	assert.Contains(t, out, `{"my-key":true,points:2}`, "map objects with other keys stay objects and odd keys are quoted")
+	assert.Contains(t, out, `narratives:{Overview:"Ship <it> & rest"}`)
+
+	back, err := parser.NewTRONParser().ParseBytes(data)
+	require.NoError(t, err)
+	assert.Equal(t, jsonOf(t, doc), jsonOf(t, back))
+}
+
+func TestEncode_Indent(t *testing.T) {
+	doc := sampleDoc()
+	compact, err := Encode(doc, Options{})
+	require.NoError(t, err)
+	data, err := Encode(doc, Options{Indent: "  "})
+	require.NoError(t, err)
+	out := string(data)
+
+	header := func(s string) string { return s[:strings.Index(s, "\n\n")] }
+	assert.Equal(t, header(string(compact)), header(out), "indenting does not change the classes")
+	assert.Contains(t, out, "\nplan:{\n  title:\"Release\",\n")
+	assert.Contains(t, out, "  edges:[\n    Edge(\"api\",\"docs\",\"blocks\"),\n")
+	assert.Contains(t, out, "narratives:{\n    Overview:")
+
+	back, err := parser.NewTRONParser().ParseBytes(data)
+	require.NoError(t, err)
+	assert.Equal(t, jsonOf(t, doc), jsonOf(t, back))
+
+	data, err = Encode(&core.Document{Info: core.Info{Version: core.SpecVersion}}, Options{Prefix: "> ", Indent: "\t"})
+	require.NoError(t, err)
+	assert.Equal(t, "vBRIEFInfo:{\n> \tversion:\"0.5\"\n> }\n", string(data))
+}
+
+func TestEncode_MinRepetitions(t *testing.T) {
+	data, err := Encode(sampleDoc(), Options{MinRepetitions: 5})
+	require.NoError(t, err)
+	out := string(data)
+	assert.NotContains(t, out, "class Edge", "two edges")
+	assert.NotContains(t, out, "class Metadata", "four with the same keys")
+	assert.Contains(t, out, "class PlanItem: ", "all five items fit a class when it includes tags")
+}
+
+func TestEncode_TodoList(t *testing.T) {
+	doc := &core.Document{
+		Info: core.Info{Version: core.SpecVersion},
+		TodoList: &core.TodoList{Items: []core.TodoItem{
+			{Title: "a", Status: core.StatusPending},
+			{Title: "b", Status: core.StatusCompleted},
+			{Title: "c", Status: core.StatusPending},
+			{Title: "d", Status: core.StatusPending},
+			{Title: "e", Status: core.StatusPending},
+			{Title: "f", Status: core.StatusPending},
+		}},
+	}
+	data, err := Encode(doc, Options{})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `items:[TodoItem("a","pending"),`)
+
+	back, err := parser.NewTRONParser().ParseBytes(data)
+	require.NoError(t, err)
+	assert.Equal(t, jsonOf(t, doc), jsonOf(t, back))
+}
+
+func TestEncode_NilDocument(t *testing.T) {
+	_, err := Encode(nil, Options{})
+	assert.ErrorIs(t, err, ErrInvalidDocument)
+}
+
+func TestEncodeWithReport(t *testing.T) {
+	data, report, err := EncodeWithReport(sampleDoc(), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, EstimateTokens(string(data)), report.Tokens)
+	assert.Greater(t, report.Saved(), 0)
+	assert.Greater(t, report.SavedVsJSON(), report.Saved())
+
+	require.Len(t, report.Classes, 3)
+	assert.Equal(t, "PlanItem", report.Classes[2].Name)
+	assert.Equal(t, 4, report.Classes[2].Instances, "the handlers item has tags, which no class includes")
+	assert.Greater(t, report.Classes[2].Saved, 0)
+	assert.Contains(t, report.String(), "class Edge: 2 instances")
+
+	t.Run("custom token counter", func(t *testing.T) {
+		data, report, err := EncodeWithReport(sampleDoc(), Options{CountTokens: func(s string) int { return len(s) }})
+		require.NoError(t, err)
+		assert.Equal(t, len(data), report.Tokens)
+	})
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"status", 1},
+		{"subItems", 2},
+		{"internationalization", 4},
+		{"2025", 2},
+		{`{"a":1}`, 7},
+		{"a b\tc", 3},
+		{"héllo", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			assert.Equal(t, tt.want, EstimateTokens(tt.in))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package validator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/diag"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ConfigFile is the conventional name of a validation config file.
+const ConfigFile = ".vbrief-validate.yaml"
+
+// Config selects which rules a validator runs and at what severity. Rules
+// are named by code ("VB0014") or name ("invalid-status").
+//
+//	profile: core
+//	extensions: [timestamps, identifiers]
+//	disable: [empty-narrative]
+//	severity:
+//	  duplicate-tag: error
+type Config struct {
+	// Profile is ProfileCore (the default), ProfileStrict, or the name of
+	// an extension or custom profile.
+	Profile string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	// Extensions adds the rules of the named extensions.
+	Extensions []string `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+	// Enable turns on rules the profile leaves off.
+	Enable []string `json:"enable,omitempty" yaml:"enable,omitempty"`
+	// Disable turns off rules, overriding Profile, Extensions and Enable.
+	Disable []string `json:"disable,omitempty" yaml:"disable,omitempty"`
+	// Severity overrides the severity of rules.
+	Severity map[string]diag.Severity `json:"severity,omitempty" yaml:"severity,omitempty"`
+}
+
+// ParseConfig parses a YAML or JSON config. Unknown fields are an error,
+// so that misspelt keys do not silently change nothing.
+func ParseConfig(data []byte) (Config, error) {
+	var cfg Config
+	dec := yamlv3.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("parse validation config: %w", err)
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads and parses a config file.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	return ParseConfig(data)
+}
@@ -0,0 +1,432 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/diag"
+)
+
+// ProfileLint runs the core rules plus warnings for the spec's SHOULD
+// conventions.
+const ProfileLint = "lint"
+
+// Lint rule codes.
+const (
+	CodeNarrativeKeyCase      = "VB0101"
+	CodeNarrativeVocabulary   = "VB0102"
+	CodeCompletedTimestamp    = "VB0103"
+	CodeIdlePlan              = "VB0104"
+	CodeEmptySubItems         = "VB0105"
+	CodeDuplicateSiblingTitle = "VB0106"
+	CodePlanRefSyntax         = "VB0107"
+	CodeLegacyStatus          = "VB0108"
+	CodeItemsRequired         = "VB0109"
+)
+
+var (
+	// ErrFixConflict is returned when a fix would overwrite existing data.
+	ErrFixConflict = errors.New("fix conflicts with existing data")
+	// ErrFixTarget is returned when a finding's field no longer exists.
+	ErrFixTarget = errors.New("fix target not found")
+)
+
+// FixFunc applies the fix for one of a rule's findings to doc. Fixes must
+// be safe: applying one never loses information or changes the meaning of
+// the document.
+type FixFunc func(doc *core.Document, finding ValidationError) error
+
+// NarrativeVocabulary is the spec's recommended set of narrative keys.
+var NarrativeVocabulary = []string{
+	"Proposal", "Overview", "Background", "Problem", "Constraint", "Hypothesis", "Alternative",
+	"Risk", "Test", "Action", "Observation", "Result", "Reflection", "Outcome", "Strengths",
+	"Weaknesses", "Lessons",
+}
+
+// NewLinter creates a validator that runs the lint profile.
+func NewLinter() Validator {
+	rules, _ := builtin.Select(Config{Profile: ProfileLint})
+	return &validator{registry: builtin, config: Config{Profile: ProfileLint}, rules: rules}
+}
+
+// Fixable reports whether the rule that produced finding can fix it.
+func (r *Registry) Fixable(finding ValidationError) bool {
+	rule, ok := r.Lookup(finding.Code)
+	return ok && rule.Fix != nil
+}
+
+// Fix applies the safe fixes for findings to doc, in order, and returns the
+// findings it fixed. Findings without a fix are skipped. A fix that fails
+// does not stop the others; the failures are joined into the error.
+func (r *Registry) Fix(doc *core.Document, findings ValidationErrors) (ValidationErrors, error) {
+	var fixed ValidationErrors
+	var errs []error
+	for _, f := range findings {
+		rule, ok := r.Lookup(f.Code)
+		if !ok || rule.Fix == nil {
+			continue
+		}
+		if err := rule.Fix(doc, f); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", f.Code, f.Field, err))
+			continue
+		}
+		fixed = append(fixed, f)
+	}
+	return fixed, errors.Join(errs...)
+}
+
+func lintRules() []Rule {
+	rule := func(code, name, description string) diag.Rule {
+		return diag.Rule{Code: code, Name: name, Description: description, Severity: diag.SeverityWarning}
+	}
+	return []Rule{
+		{Rule: rule(CodeNarrativeKeyCase, "narrative-key-case", "Narrative keys should be TitleCase."),
+			Scope: ScopePlan | ScopeItem, Profile: ProfileLint, Check: checkNarrativeKeyCase, Fix: fixNarrativeKeyCase},
+		{Rule: rule(CodeNarrativeVocabulary, "narrative-vocabulary", "Narrative keys should come from the recommended vocabulary."),
+			Scope: ScopePlan | ScopeItem, Profile: ProfileLint, Check: checkNarrativeVocabulary},
+		{Rule: rule(CodeCompletedTimestamp, "completed-timestamp", "Completed items should record when they were completed."),
+			Scope: ScopeItem, Profile: ProfileLint, Check: checkCompletedTimestamp},
+		{Rule: rule(CodeIdlePlan, "idle-plan", "A running plan should have a running item."),
+			Scope: ScopePlan, Profile: ProfileLint, Check: checkIdlePlan},
+		{Rule: rule(CodeEmptySubItems, "empty-subitems", "Items without sub-items should omit subItems."),
+			Scope: ScopeItem, Profile: ProfileLint, Check: checkEmptySubItems, Fix: fixEmptySubItems},
+		{Rule: rule(CodeDuplicateSiblingTitle, "duplicate-sibling-title", "Sibling items should have distinct titles."),
+			Scope: ScopePlan, Profile: ProfileLint, Check: checkDuplicateSiblingTitles},
+		{Rule: rule(CodePlanRefSyntax, "planref-syntax", "planRef should be #id, file:// or http(s):// with an ID fragment."),
+			Scope: ScopeItem, Profile: ProfileLint, Check: checkPlanRef},
+		{Rule: rule(CodeLegacyStatus, "legacy-status", "Use \"running\" rather than the pre-0.5 \"inProgress\"."),
+			Scope: ScopePlan | ScopeItem, Profile: ProfileLint, Check: checkLegacyStatus, Fix: fixLegacyStatus},
+		{Rule: rule(CodeItemsRequired, "items-required", "Plans should always carry an items array, even an empty one."),
+			Scope: ScopePlan, Profile: ProfileLint, Check: checkItemsRequired, Fix: fixItemsRequired},
+	}
+}
+
+// narratives returns the subject's narratives and the path prefix of
+// their keys.
+func narratives(s *Subject) (map[string]string, string) {
+	if s.Item != nil {
+		return s.Item.Narrative, s.Path + ".narrative."
+	}
+	if s.TodoItem == nil && s.Plan != nil {
+		return s.Plan.Narratives, "plan.narratives."
+	}
+	return nil, ""
+}
+
+// TitleCase converts a narrative key to TitleCase: "next_steps" and
+// "next steps" become "NextSteps" and "proposal" becomes "Proposal".
+func TitleCase(key string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range key {
+		switch {
+		case r == '_' || r == '-' || unicode.IsSpace(r):
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func checkNarrativeKeyCase(s *Subject) ValidationErrors {
+	m, prefix := narratives(s)
+	var errs ValidationErrors
+	for _, key := range sortedKeys(m) {
+		want := TitleCase(key)
+		if want == key || want == "" {
+			continue
+		}
+		errs = append(errs, ValidationError{
+			Field:   prefix + key,
+			Message: fmt.Sprintf("narrative key %q should be TitleCase", key),
+			Fix:     &diag.Fix{Description: fmt.Sprintf("rename %q to %q", key, want)},
+		})
+	}
+	return errs
+}
+
+func fixNarrativeKeyCase(doc *core.Document, f ValidationError) error {
+	m, key, err := narrativeAt(doc, f.Field)
+	if err != nil {
+		return err
+	}
+	want := TitleCase(key)
+	if _, ok := m[want]; ok {
+		return fmt.Errorf("%w: %q already exists", ErrFixConflict, want)
+	}
+	m[want] = m[key]
+	delete(m, key)
+	return nil
+}
+
+func checkNarrativeVocabulary(s *Subject) ValidationErrors {
+	m, prefix := narratives(s)
+	var errs ValidationErrors
+	for _, key := range sortedKeys(m) {
+		name := TitleCase(key)
+		if inVocabulary(name) {
+			continue
+		}
+		err := ValidationError{
+			Field:   prefix + key,
+			Message: fmt.Sprintf("narrative %q is not in the recommended vocabulary", key),
+		}
+		if v := closest(name, NarrativeVocabulary); v != "" {
+			err.Fix = &diag.Fix{Description: fmt.Sprintf("use %q", v)}
+		}
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func inVocabulary(key string) bool {
+	for _, v := range NarrativeVocabulary {
+		if v == key {
+			return true
+		}
+	}
+	return false
+}
+
+func checkCompletedTimestamp(s *Subject) ValidationErrors {
+	if s.Item == nil || s.Item.Status != core.PlanItemStatusCompleted || s.Item.Completed != nil {
+		return nil
+	}
+	return ValidationErrors{{
+		Field:   s.Path + ".completed",
+		Message: "completed item has no completed timestamp",
+		Fix:     &diag.Fix{Description: "set completed to when the item was finished"},
+	}}
+}
+
+// isRunning reports whether a status means work is under way, accepting
+// both the current "running" and the legacy "inProgress".
+func isRunning(status string) bool {
+	return status == "running" || status == "inProgress"
+}
+
+func checkIdlePlan(s *Subject) ValidationErrors {
+	if !isRunning(string(s.Plan.Status)) {
+		return nil
+	}
+	for _, n := range s.Graph.Nodes() {
+		if isRunning(string(n.Item.Status)) {
+			return nil
+		}
+	}
+	return ValidationErrors{{
+		Field:   "plan.status",
+		Message: fmt.Sprintf("plan is %s but no item is", s.Plan.Status),
+	}}
+}
+
+func checkEmptySubItems(s *Subject) ValidationErrors {
+	if s.Item == nil || s.Item.SubItems == nil || len(s.Item.SubItems) > 0 {
+		return nil
+	}
+	return ValidationErrors{{
+		Field:   s.Path + ".subItems",
+		Message: "subItems is empty",
+		Fix:     &diag.Fix{Description: "remove the empty subItems array"},
+	}}
+}
+
+func fixEmptySubItems(doc *core.Document, f ValidationError) error {
+	item, err := itemAt(doc, strings.TrimSuffix(f.Field, ".subItems"))
+	if err != nil {
+		return err
+	}
+	if len(item.SubItems) > 0 {
+		return fmt.Errorf("%w: subItems is no longer empty", ErrFixConflict)
+	}
+	item.SubItems = nil
+	return nil
+}
+
+func checkDuplicateSiblingTitles(s *Subject) ValidationErrors {
+	var errs ValidationErrors
+	check := func(items []core.PlanItem, prefix string) {
+		first := make(map[string]int)
+		for i, item := range items {
+			title := strings.ToLower(strings.TrimSpace(item.Title))
+			if title == "" {
+				continue
+			}
+			if j, ok := first[title]; ok {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s[%d].title", prefix, i),
+					Message: fmt.Sprintf("title %q repeats sibling %s[%d]", item.Title, prefix, j),
+				})
+				continue
+			}
+			first[title] = i
+		}
+	}
+	check(s.Plan.Items, "plan.items")
+	for _, n := range s.Graph.Nodes() {
+		check(n.Item.SubItems, n.Path+".subItems")
+	}
+	return errs
+}
+
+var (
+	planRefPattern = regexp.MustCompile(`^(#[a-zA-Z0-9_.-]+|file://.*|https?://.*)$`)
+	bareRefPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+(\.[a-zA-Z0-9_-]+)*$`)
+)
+
+func checkPlanRef(s *Subject) ValidationErrors {
+	if s.Item == nil || s.Item.PlanRef == "" {
+		return nil
+	}
+	ref := s.Item.PlanRef
+	field := s.Path + ".planRef"
+	if !planRefPattern.MatchString(ref) {
+		err := ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("planRef %q is not #id, file:// or http(s)://", ref),
+		}
+		if bareRefPattern.MatchString(ref) && !strings.HasSuffix(ref, ".json") {
+			err.Fix = &diag.Fix{Description: fmt.Sprintf("use %q for an item in this plan", "#"+ref)}
+		}
+		return ValidationErrors{err}
+	}
+	if strings.HasPrefix(ref, "#") {
+		return nil
+	}
+	if i := strings.IndexByte(ref, '#'); i >= 0 && !idPattern.MatchString(ref[i+1:]) {
+		return ValidationErrors{{
+			Field:   field,
+			Message: fmt.Sprintf("planRef fragment %q is not an item ID", ref[i+1:]),
+		}}
+	}
+	return nil
+}
+
+func checkLegacyStatus(s *Subject) ValidationErrors {
+	var status string
+	switch {
+	case s.Item != nil:
+		status = string(s.Item.Status)
+	case s.TodoItem != nil:
+		status = string(s.TodoItem.Status)
+	default:
+		status = string(s.Plan.Status)
+	}
+	if status != "inProgress" {
+		return nil
+	}
+	return ValidationErrors{{
+		Field:   s.Path + ".status",
+		Message: "status \"inProgress\" was renamed to \"running\"",
+		Fix:     &diag.Fix{Description: "replace with \"running\"", Replacement: `"running"`},
+	}}
+}
+
+func fixLegacyStatus(doc *core.Document, f ValidationError) error {
+	path := strings.TrimSuffix(f.Field, ".status")
+	switch {
+	case path == "plan" && doc.Plan != nil:
+		if doc.Plan.Status == core.PlanStatusInProgress {
+			doc.Plan.Status = core.PlanStatusRunning
+		}
+	case strings.HasPrefix(path, "todoList.items[") && doc.TodoList != nil:
+		var i int
+		if _, err := fmt.Sscanf(path, "todoList.items[%d]", &i); err != nil || i < 0 || i >= len(doc.TodoList.Items) {
+			return fmt.Errorf("%w: %s", ErrFixTarget, f.Field)
+		}
+		if item := &doc.TodoList.Items[i]; item.Status == core.StatusInProgress {
+			item.Status = core.StatusRunning
+		}
+	default:
+		item, err := itemAt(doc, path)
+		if err != nil {
+			return err
+		}
+		if item.Status == core.PlanItemStatusInProgress {
+			item.Status = core.PlanItemStatusRunning
+		}
+	}
+	return nil
+}
+
+func checkItemsRequired(s *Subject) ValidationErrors {
+	if s.Plan.Items != nil {
+		return nil
+	}
+	return ValidationErrors{{
+		Field:   "plan.items",
+		Message: "plan has no items array",
+		Fix:     &diag.Fix{Description: "add an empty items array", Replacement: "[]"},
+	}}
+}
+
+func fixItemsRequired(doc *core.Document, f ValidationError) error {
+	if doc.Plan == nil {
+		return fmt.Errorf("%w: %s", ErrFixTarget, f.Field)
+	}
+	if doc.Plan.Items == nil {
+		doc.Plan.Items = []core.PlanItem{}
+	}
+	return nil
+}
+
+// itemAt returns the plan item at a path such as "plan.items[0].subItems[2]".
+func itemAt(doc *core.Document, path string) (*core.PlanItem, error) {
+	if doc.Plan == nil || !strings.HasPrefix(path, "plan.items[") {
+		return nil, fmt.Errorf("%w: %s", ErrFixTarget, path)
+	}
+	items := doc.Plan.Items
+	var item *core.PlanItem
+	rest := strings.TrimPrefix(path, "plan.items")
+	for rest != "" {
+		if item != nil {
+			if !strings.HasPrefix(rest, ".subItems") {
+				return nil, fmt.Errorf("%w: %s", ErrFixTarget, path)
+			}
+			rest = strings.TrimPrefix(rest, ".subItems")
+			items = item.SubItems
+		}
+		end := strings.IndexByte(rest, ']')
+		if !strings.HasPrefix(rest, "[") || end < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrFixTarget, path)
+		}
+		i, err := strconv.Atoi(rest[1:end])
+		if err != nil || i < 0 || i >= len(items) {
+			return nil, fmt.Errorf("%w: %s", ErrFixTarget, path)
+		}
+		item, rest = &items[i], rest[end+1:]
+	}
+	return item, nil
+}
+
+// narrativeAt returns the narrative map and key that a path such as
+// "plan.narratives.proposal" or "plan.items[1].narrative.result" names.
+func narrativeAt(doc *core.Document, path string) (map[string]string, string, error) {
+	if doc.Plan == nil {
+		return nil, "", fmt.Errorf("%w: %s", ErrFixTarget, path)
+	}
+	var m map[string]string
+	var key string
+	if k, ok := strings.CutPrefix(path, "plan.narratives."); ok {
+		m, key = doc.Plan.Narratives, k
+	} else if i := strings.Index(path, ".narrative."); i >= 0 {
+		item, err := itemAt(doc, path[:i])
+		if err != nil {
+			return nil, "", err
+		}
+		m, key = item.Narrative, path[i+len(".narrative."):]
+	}
+	if _, ok := m[key]; !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrFixTarget, path)
+	}
+	return m, key, nil
+}
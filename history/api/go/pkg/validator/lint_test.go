@@ -0,0 +1,173 @@
+package validator
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/diag"
+)
+
+const lintJSON = `{
+  "vBRIEFInfo": {"version": "0.5"},
+  "plan": {
+    "title": "Release",
+    "status": "inProgress",
+    "narratives": {"proposal": "Ship it", "Risks": "Late", "next_steps": "Plan v2"},
+    "items": [
+      {"id": "build", "title": "Build", "status": "completed", "subItems": []},
+      {"id": "docs", "title": "build ", "status": "pending", "planRef": "docs-plan",
+       "narrative": {"result": "Done"}},
+      {"id": "api", "title": "API", "status": "pending", "planRef": "file://api.json#bad ref"},
+      {"id": "ok", "title": "OK", "status": "pending", "planRef": "https://example.com/p.json#setup.db"}
+    ]
+  }
+}`
+
+func decodeJSON(s string) (*core.Document, error) {
+	var doc core.Document
+	err := json.Unmarshal([]byte(s), &doc)
+	return &doc, err
+}
+
+func TestLint(t *testing.T) {
+	doc, err := decodeJSON(lintJSON)
+	require.NoError(t, err)
+	require.NoError(t, NewValidator().Validate(doc), "lint findings are not core errors")
+
+	findings := Check(NewLinter(), doc)
+	got := make(map[string]string)
+	for _, f := range findings {
+		assert.Equal(t, diag.SeverityWarning, f.Severity, f.Field)
+		got[f.Field+" "+f.Code] = f.Message
+	}
+
+	tests := []struct {
+		field, code, message string
+	}{
+		{"plan.narratives.proposal", CodeNarrativeKeyCase, `narrative key "proposal" should be TitleCase`},
+		{"plan.narratives.next_steps", CodeNarrativeKeyCase, `narrative key "next_steps" should be TitleCase`},
+		{"plan.narratives.Risks", CodeNarrativeVocabulary, `narrative "Risks" is not in the recommended vocabulary`},
+		{"plan.narratives.next_steps", CodeNarrativeVocabulary, `narrative "next_steps" is not in the recommended vocabulary`},
+		{"plan.items[1].narrative.result", CodeNarrativeKeyCase, `narrative key "result" should be TitleCase`},
+		{"plan.items[0].completed", CodeCompletedTimestamp, "completed item has no completed timestamp"},
+		{"plan.status", CodeIdlePlan, "plan is inProgress but no item is"},
+		{"plan.status", CodeLegacyStatus, `status "inProgress" was renamed to "running"`},
+		{"plan.items[0].subItems", CodeEmptySubItems, "subItems is empty"},
+		{"plan.items[1].title", CodeDuplicateSiblingTitle, `title "build " repeats sibling plan.items[0]`},
+		{"plan.items[1].planRef", CodePlanRefSyntax, `planRef "docs-plan" is not #id, file:// or http(s)://`},
+		{"plan.items[2].planRef", CodePlanRefSyntax, `planRef fragment "bad ref" is not an item ID`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code+" "+tt.field, func(t *testing.T) {
+			assert.Equal(t, tt.message, got[tt.field+" "+tt.code])
+		})
+	}
+	assert.Len(t, findings, len(tests), "proposal and result are in the vocabulary once TitleCased")
+
+	t.Run("suggestions", func(t *testing.T) {
+		for _, f := range findings {
+			switch f.Field + " " + f.Code {
+			case "plan.narratives.Risks " + CodeNarrativeVocabulary:
+				require.NotNil(t, f.Fix)
+				assert.Equal(t, `use "Risk"`, f.Fix.Description)
+			case "plan.items[1].planRef " + CodePlanRefSyntax:
+				require.NotNil(t, f.Fix)
+				assert.Equal(t, `use "#docs-plan" for an item in this plan`, f.Fix.Description)
+			}
+		}
+	})
+}
+
+func TestRegistry_Fix(t *testing.T) {
+	doc, err := decodeJSON(lintJSON)
+	require.NoError(t, err)
+	reg := DefaultRegistry()
+
+	findings := Check(NewLinter(), doc)
+	fixed, err := reg.Fix(doc, findings)
+	require.NoError(t, err)
+
+	var codes []string
+	for _, f := range fixed {
+		assert.True(t, reg.Fixable(f))
+		codes = append(codes, f.Code+" "+f.Field)
+	}
+	assert.ElementsMatch(t, []string{
+		CodeNarrativeKeyCase + " plan.narratives.next_steps",
+		CodeNarrativeKeyCase + " plan.narratives.proposal",
+		CodeNarrativeKeyCase + " plan.items[1].narrative.result",
+		CodeEmptySubItems + " plan.items[0].subItems",
+		CodeLegacyStatus + " plan.status",
+	}, codes)
+
+	assert.Equal(t, map[string]string{"Proposal": "Ship it", "Risks": "Late", "NextSteps": "Plan v2"}, doc.Plan.Narratives)
+	assert.Equal(t, map[string]string{"Result": "Done"}, doc.Plan.Items[1].Narrative)
+	assert.Nil(t, doc.Plan.Items[0].SubItems)
+	assert.Equal(t, core.PlanStatusRunning, doc.Plan.Status)
+	assert.NoError(t, NewValidator().Validate(doc), "fixed documents stay valid")
+
+	for _, f := range Check(NewLinter(), doc) {
+		assert.False(t, reg.Fixable(f), "%s %s was not fixed", f.Code, f.Field)
+	}
+
+	t.Run("conflicts are reported", func(t *testing.T) {
+		doc := &core.Document{
+			Info: core.Info{Version: "0.5"},
+			Plan: &core.Plan{
+				Title:      "P",
+				Status:     core.PlanStatusDraft,
+				Narratives: map[string]string{"Proposal": "A", "proposal": "B"},
+				Items:      []core.PlanItem{},
+			},
+		}
+		fixed, err := reg.Fix(doc, Check(NewLinter(), doc))
+		assert.Empty(t, fixed)
+		assert.ErrorIs(t, err, ErrFixConflict)
+		assert.Len(t, doc.Plan.Narratives, 2)
+	})
+
+	t.Run("stale findings", func(t *testing.T) {
+		_, err := reg.Fix(&core.Document{}, ValidationErrors{{Code: CodeEmptySubItems, Field: "plan.items[4].subItems"}})
+		assert.ErrorIs(t, err, ErrFixTarget)
+	})
+}
+
+func TestLint_CompletedAndIdle(t *testing.T) {
+	done := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	doc := &core.Document{
+		Info: core.Info{Version: "0.5"},
+		Plan: &core.Plan{
+			Title:      "P",
+			Status:     core.PlanStatus("running"),
+			Narratives: map[string]string{"Proposal": "A"},
+			Items: []core.PlanItem{
+				{Title: "A", Status: core.PlanItemStatusCompleted, Completed: &done},
+				{Title: "B", Status: core.PlanItemStatusPending, SubItems: []core.PlanItem{
+					{Title: "B1", Status: core.PlanItemStatus("running")},
+				}},
+			},
+		},
+	}
+	for _, f := range Check(NewLinter(), doc) {
+		assert.NotEqual(t, CodeCompletedTimestamp, f.Code)
+		assert.NotEqual(t, CodeIdlePlan, f.Code, "a running sub-item keeps the plan busy")
+	}
+}
+
+func TestTitleCase(t *testing.T) {
+	tests := map[string]string{
+		"proposal":    "Proposal",
+		"Proposal":    "Proposal",
+		"next_steps":  "NextSteps",
+		"open-issues": "OpenIssues",
+		"key risks":   "KeyRisks",
+		"camelCase":   "CamelCase",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, TitleCase(in), in)
+	}
+}
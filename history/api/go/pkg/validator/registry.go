@@ -0,0 +1,344 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/diag"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+var (
+	// ErrInvalidRule is returned when registering a rule without a code,
+	// name, scope or check.
+	ErrInvalidRule = errors.New("invalid rule")
+	// ErrDuplicateRule is returned when registering a rule whose code or
+	// name is already taken.
+	ErrDuplicateRule = errors.New("duplicate rule")
+	// ErrUnknownRule is returned when a config names a rule that is not
+	// registered.
+	ErrUnknownRule = errors.New("unknown rule")
+	// ErrUnknownProfile is returned when a config names an unknown profile.
+	ErrUnknownProfile = errors.New("unknown profile")
+)
+
+// Profiles select groups of rules. Every profile includes the core rules;
+// a profile named after an extension adds that extension's rules.
+const (
+	// ProfileCore runs the rules every valid document must pass.
+	ProfileCore = "core"
+	// ProfileStrict runs every registered rule.
+	ProfileStrict = "strict"
+)
+
+// Scope is the kind of value a rule checks. Scopes combine with |.
+type Scope uint8
+
+const (
+	// ScopeDocument rules run once per document.
+	ScopeDocument Scope = 1 << iota
+	// ScopePlan rules run once per plan.
+	ScopePlan
+	// ScopeItem rules run for every plan item, at any depth, and every
+	// todo item.
+	ScopeItem
+	// ScopeEdge rules run for every plan edge.
+	ScopeEdge
+)
+
+// String returns the scope names joined with "|", e.g. "plan|item".
+func (s Scope) String() string {
+	var names []string
+	for _, n := range []struct {
+		scope Scope
+		name  string
+	}{
+		{ScopeDocument, "document"},
+		{ScopePlan, "plan"},
+		{ScopeItem, "item"},
+		{ScopeEdge, "edge"},
+	} {
+		if s&n.scope != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// Subject is the value a rule is checking, with its context.
+type Subject struct {
+	// Doc is the document being validated.
+	Doc *core.Document
+	// Path is the subject's document path, e.g. "plan.items[2]", or
+	// diag.RootPath for the document.
+	Path string
+	// Plan is the document's plan, if it has one.
+	Plan *core.Plan
+	// Graph is built over Plan. It is nil for todo lists.
+	Graph *graph.Graph
+	// Item and Node are set for plan items.
+	Item *core.PlanItem
+	Node *graph.Node
+	// TodoItem is set for todo items.
+	TodoItem *core.TodoItem
+	// Edge is set for edges.
+	Edge *core.Edge
+}
+
+// CheckFunc checks a subject and returns its findings. Each finding needs
+// only a Field, a Message and, optionally, a Fix; the rule supplies the
+// code and severity.
+type CheckFunc func(s *Subject) ValidationErrors
+
+// Rule is a registered validation check.
+type Rule struct {
+	diag.Rule
+	// Scope is the kind of value the rule checks.
+	Scope Scope
+	// Extension names the extension the rule belongs to, e.g.
+	// "timestamps", or "" for rules outside any extension.
+	Extension string
+	// Profile names the narrowest profile that runs the rule; "" means
+	// ProfileCore. Extension rules leave it empty.
+	Profile string
+	Check   CheckFunc
+	// Fix, if set, repairs the rule's findings; see Registry.Fix.
+	Fix FixFunc
+}
+
+// Registry holds validation rules. It is safe for concurrent use.
+type Registry struct {
+	mu    sync.RWMutex
+	rules []Rule
+	index map[string]int
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{index: make(map[string]int)}
+}
+
+// DefaultRegistry creates a registry holding the built-in rules. Each call
+// returns a new registry, so custom rules registered in one do not leak
+// into others.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	if err := r.Register(builtinRules()...); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Register adds rules to the registry. Rules run in registration order.
+func (r *Registry) Register(rules ...Rule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rule := range rules {
+		if rule.Code == "" || rule.Name == "" || rule.Scope == 0 || rule.Check == nil {
+			return fmt.Errorf("%w: %q needs a code, name, scope and check", ErrInvalidRule, rule.Code)
+		}
+		if rule.Severity == "" {
+			rule.Severity = diag.SeverityError
+		}
+		for _, key := range []string{rule.Code, rule.Name} {
+			if _, ok := r.index[key]; ok {
+				return fmt.Errorf("%w: %s", ErrDuplicateRule, key)
+			}
+		}
+		r.index[rule.Code] = len(r.rules)
+		r.index[rule.Name] = len(r.rules)
+		r.rules = append(r.rules, rule)
+	}
+	return nil
+}
+
+// Lookup returns the rule with the given code or name.
+func (r *Registry) Lookup(id string) (Rule, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	i, ok := r.index[id]
+	if !ok {
+		return Rule{}, false
+	}
+	return r.rules[i], true
+}
+
+// Rules returns the registered rules in registration order.
+func (r *Registry) Rules() []Rule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Rule(nil), r.rules...)
+}
+
+// Descriptors describes the registered rules, e.g. for diag.SARIFOptions.
+func (r *Registry) Descriptors() []diag.Rule {
+	rules := r.Rules()
+	out := make([]diag.Rule, len(rules))
+	for i, rule := range rules {
+		out[i] = rule.Rule
+	}
+	return out
+}
+
+// Extensions returns the names of extensions with registered rules, sorted.
+func (r *Registry) Extensions() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, rule := range r.Rules() {
+		if rule.Extension != "" && !seen[rule.Extension] {
+			seen[rule.Extension] = true
+			names = append(names, rule.Extension)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Select returns the rules cfg enables, in registration order, with
+// cfg's severity overrides applied.
+func (r *Registry) Select(cfg Config) ([]Rule, error) {
+	rules := r.Rules()
+
+	profiles := map[string]bool{ProfileCore: true}
+	extensions := make(map[string]bool)
+	known := map[string]bool{ProfileCore: true, ProfileStrict: true}
+	for _, rule := range rules {
+		if rule.Profile != "" {
+			known[rule.Profile] = true
+		}
+		if rule.Extension != "" {
+			known[rule.Extension] = true
+		}
+	}
+	if cfg.Profile != "" {
+		if !known[cfg.Profile] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, cfg.Profile)
+		}
+		profiles[cfg.Profile] = true
+		extensions[cfg.Profile] = true
+	}
+	for _, ext := range cfg.Extensions {
+		if !hasExtension(rules, ext) {
+			return nil, fmt.Errorf("%w: %s", ErrExtensionsNotSupported, ext)
+		}
+		extensions[ext] = true
+	}
+
+	overrides := make(map[string]bool)
+	for _, list := range [][]string{cfg.Enable, cfg.Disable} {
+		for _, id := range list {
+			if _, ok := r.Lookup(id); !ok {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownRule, id)
+			}
+		}
+	}
+	for _, id := range cfg.Enable {
+		rule, _ := r.Lookup(id)
+		overrides[rule.Code] = true
+	}
+	for _, id := range cfg.Disable {
+		rule, _ := r.Lookup(id)
+		overrides[rule.Code] = false
+	}
+	severity := make(map[string]diag.Severity)
+	for id, sev := range cfg.Severity {
+		rule, ok := r.Lookup(id)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRule, id)
+		}
+		switch sev {
+		case diag.SeverityError, diag.SeverityWarning, diag.SeverityInfo:
+		default:
+			return nil, fmt.Errorf("%w: %s: severity %q", ErrInvalidRule, id, sev)
+		}
+		severity[rule.Code] = sev
+	}
+
+	var selected []Rule
+	for _, rule := range rules {
+		on, ok := overrides[rule.Code]
+		if !ok {
+			switch {
+			case cfg.Profile == ProfileStrict:
+				on = true
+			case rule.Extension != "":
+				on = extensions[rule.Extension]
+			case rule.Profile == "":
+				on = true
+			default:
+				on = profiles[rule.Profile]
+			}
+		}
+		if !on {
+			continue
+		}
+		if sev, ok := severity[rule.Code]; ok {
+			rule.Severity = sev
+		}
+		selected = append(selected, rule)
+	}
+	return selected, nil
+}
+
+func hasExtension(rules []Rule, ext string) bool {
+	for _, rule := range rules {
+		if rule.Extension == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// run applies rules to every subject in the document: the document itself,
+// then its plan, items in document order and edges.
+func run(doc *core.Document, rules []Rule) ValidationErrors {
+	var errs ValidationErrors
+	apply := func(s *Subject, scope Scope) {
+		for _, rule := range rules {
+			if rule.Scope&scope == 0 {
+				continue
+			}
+			for _, e := range rule.Check(s) {
+				if e.Code == "" {
+					e.Code = rule.Code
+				}
+				e.Severity = rule.Severity
+				errs = append(errs, e)
+			}
+		}
+	}
+
+	apply(&Subject{Doc: doc, Path: diag.RootPath, Plan: doc.Plan}, ScopeDocument)
+
+	if doc.TodoList != nil {
+		for i := range doc.TodoList.Items {
+			apply(&Subject{
+				Doc:      doc,
+				Path:     fmt.Sprintf("todoList.items[%d]", i),
+				TodoItem: &doc.TodoList.Items[i],
+			}, ScopeItem)
+		}
+	}
+
+	if plan := doc.Plan; plan != nil {
+		g := graph.New(plan)
+		apply(&Subject{Doc: doc, Path: "plan", Plan: plan, Graph: g}, ScopePlan)
+		for _, n := range g.Nodes() {
+			apply(&Subject{Doc: doc, Path: n.Path, Plan: plan, Graph: g, Item: n.Item, Node: n}, ScopeItem)
+		}
+		for i := range plan.Edges {
+			apply(&Subject{
+				Doc:   doc,
+				Path:  fmt.Sprintf("plan.edges[%d]", i),
+				Plan:  plan,
+				Graph: g,
+				Edge:  &plan.Edges[i],
+			}, ScopeEdge)
+		}
+	}
+	return errs
+}
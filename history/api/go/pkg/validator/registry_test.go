@@ -0,0 +1,250 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/diag"
+)
+
+// registryPlan breaks one rule of every built-in extension and profile.
+func registryPlan() *core.Document {
+	created := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	completed := created.Add(-time.Hour)
+	start, end := created, created.Add(-24*time.Hour)
+	over := 120.0
+	return &core.Document{
+		Info: core.Info{Version: "0.5"},
+		Plan: &core.Plan{
+			Title:      "Release",
+			Status:     core.PlanStatusApproved,
+			Narratives: map[string]string{"Proposal": "Ship it"},
+			Items: []core.PlanItem{
+				{ID: "build", UID: "u1", Title: "Build", Status: core.PlanItemStatusCompleted,
+					Created: &created, Completed: &completed, Tags: []string{"api", "api"}, Priority: "hihg"},
+				{ID: "test suite", UID: "u1", Title: "Test", Status: core.PlanItemStatusPending,
+					StartDate: &start, EndDate: &end, PercentComplete: &over},
+				{ID: "ship", Title: "Ship", Status: core.PlanItemStatusPending,
+					Participants: []core.Participant{{Role: "boss"}},
+					Recurrence:   &core.RecurrenceRule{Frequency: "hourly", Count: 2, Until: &created}},
+				{ID: "build", Title: "Rebuild", Status: core.PlanItemStatusPending},
+			},
+			Edges: []core.Edge{
+				{From: "build", To: "ship", Type: core.EdgeBlocks},
+				{From: "ship", To: "build", Type: "follows"},
+				{From: "ship", To: "ship", Type: core.EdgeBlocks},
+				{From: "build", To: "docs", Type: core.EdgeInforms},
+			},
+		},
+	}
+}
+
+func codes(errs ValidationErrors) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, e := range errs {
+		if !seen[e.Code] {
+			seen[e.Code] = true
+			out = append(out, e.Code)
+		}
+	}
+	return out
+}
+
+func TestRegistry_Select(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{"core", Config{}, nil},
+		{"extension profile", Config{Profile: ExtTimestamps}, []string{CodeTimestampOrder}},
+		{"extensions", Config{Extensions: []string{ExtScheduling, ExtRecurrence}},
+			[]string{CodeDateRange, CodePercentComplete, CodeRecurrence}},
+		{"strict", Config{Profile: ProfileStrict}, []string{
+			CodeTimestampOrder, CodeInvalidPriority, CodeDuplicateTag, CodeIDSyntax, CodeDateRange,
+			CodePercentComplete, CodeParticipant, CodeRecurrence, CodeDuplicateID, CodeDuplicateUID,
+			CodeEdgeCycle, CodeDanglingEdge, CodeSelfEdge, CodeCustomEdgeType,
+		}},
+		{"enable by name", Config{Enable: []string{"self-edge"}}, []string{CodeSelfEdge}},
+		{"disable wins", Config{Profile: ProfileStrict, Disable: []string{"edge-cycle", CodeSelfEdge, "timestamp-order",
+			"invalid-priority", "duplicate-tag", "id-syntax", "date-range", "percent-complete", "participant",
+			"recurrence", "duplicate-id", "duplicate-uid", "dangling-edge", "custom-edge-type"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewValidatorWithOptions(Options{Config: tt.cfg})
+			require.NoError(t, err)
+			got := codes(Check(v, registryPlan()))
+			if tt.want == nil {
+				assert.Empty(t, got)
+			} else {
+				assert.ElementsMatch(t, tt.want, got)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tt := range []struct {
+			cfg  Config
+			want error
+		}{
+			{Config{Profile: "lax"}, ErrUnknownProfile},
+			{Config{Extensions: []string{"teleportation"}}, ErrExtensionsNotSupported},
+			{Config{Enable: []string{"VB9999"}}, ErrUnknownRule},
+			{Config{Severity: map[string]diag.Severity{"no-such-rule": diag.SeverityInfo}}, ErrUnknownRule},
+			{Config{Severity: map[string]diag.Severity{CodeSelfEdge: "fatal"}}, ErrInvalidRule},
+		} {
+			_, err := NewValidatorWithOptions(Options{Config: tt.cfg})
+			assert.ErrorIs(t, err, tt.want)
+		}
+	})
+}
+
+func TestDanglingEdge_CrossDocument(t *testing.T) {
+	doc := &core.Document{
+		Info: core.Info{Version: "0.5"},
+		Plan: &core.Plan{
+			Title:      "P",
+			Status:     core.PlanStatusDraft,
+			Narratives: map[string]string{"Proposal": "Link plans"},
+			Items:      []core.PlanItem{{ID: "a", Title: "A", Status: core.PlanItemStatusPending}},
+			Edges: []core.Edge{
+				{From: "api.vbrief.json#setup", To: "a", Type: core.EdgeBlocks},
+				{From: "a", To: "file://ui.vbrief.json#home", Type: core.EdgeBlocks},
+				{From: "a", To: "b", Type: core.EdgeBlocks},
+			},
+		},
+	}
+	v, err := NewValidatorWithOptions(Options{Config: Config{Profile: ExtIdentifiers}})
+	require.NoError(t, err)
+	errs := Check(v, doc)
+	require.Len(t, errs, 1, "cross-document endpoints are checked by the workspace")
+	assert.Equal(t, "plan.edges[2].to", errs[0].Field)
+}
+
+func TestValidator_Severity(t *testing.T) {
+	doc := registryPlan()
+
+	v, err := NewValidatorWithOptions(Options{Config: Config{Extensions: []string{ExtMetadata}}})
+	require.NoError(t, err)
+	findings := Check(v, doc)
+	require.Len(t, findings, 2)
+	assert.Equal(t, diag.SeverityError, findings[0].Severity)
+	require.NotNil(t, findings[0].Fix)
+	assert.Equal(t, `"high"`, findings[0].Fix.Replacement)
+	assert.Equal(t, "plan.items[0].tags[1]", findings[1].Field)
+	assert.Equal(t, diag.SeverityWarning, findings[1].Severity)
+
+	var errs ValidationErrors
+	require.ErrorAs(t, v.Validate(doc), &errs)
+	assert.Len(t, errs, 1, "warnings do not fail validation")
+
+	v, err = NewValidatorWithOptions(Options{Config: Config{
+		Extensions: []string{ExtMetadata},
+		Severity:   map[string]diag.Severity{"invalid-priority": diag.SeverityWarning},
+	}})
+	require.NoError(t, err)
+	assert.NoError(t, v.Validate(doc))
+	assert.Len(t, Check(v, doc), 2)
+
+	t.Run("ValidateCore ignores extensions", func(t *testing.T) {
+		assert.NoError(t, v.ValidateCore(doc))
+	})
+}
+
+func TestRegistry_Register(t *testing.T) {
+	noEstimate := Rule{
+		Rule:  diag.Rule{Code: "ACME001", Name: "estimate-required", Description: "Items need an estimate."},
+		Scope: ScopeItem,
+		Check: func(s *Subject) ValidationErrors {
+			if s.Item == nil || s.Item.Metadata["estimate"] != nil {
+				return nil
+			}
+			return ValidationErrors{{Field: s.Path + ".metadata", Message: "estimate is required"}}
+		},
+		Profile: "acme",
+	}
+
+	reg := DefaultRegistry()
+	require.NoError(t, reg.Register(noEstimate))
+	assert.ErrorIs(t, reg.Register(noEstimate), ErrDuplicateRule)
+	assert.ErrorIs(t, reg.Register(Rule{Rule: diag.Rule{Code: "ACME002", Name: "x"}}), ErrInvalidRule)
+
+	rule, ok := reg.Lookup("estimate-required")
+	require.True(t, ok)
+	assert.Equal(t, diag.SeverityError, rule.Severity, "severity defaults to error")
+	_, ok = DefaultRegistry().Lookup("ACME001")
+	assert.False(t, ok, "registries are independent")
+
+	v, err := NewValidatorWithOptions(Options{Registry: reg})
+	require.NoError(t, err)
+	assert.NoError(t, v.Validate(registryPlan()), "custom profiles are opt-in")
+
+	v, err = NewValidatorWithOptions(Options{Registry: reg, Config: Config{Profile: "acme"}})
+	require.NoError(t, err)
+	err = v.Validate(registryPlan())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "plan.items[2].metadata: estimate is required")
+
+	assert.Equal(t, []string{ExtIdentifiers, ExtMetadata, ExtParticipants, ExtRecurrence, ExtScheduling, ExtTimestamps},
+		reg.Extensions())
+	assert.Equal(t, "plan|item", (ScopePlan | ScopeItem).String())
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+profile: strict
+extensions: [timestamps]
+disable: [custom-edge-type]
+severity:
+  duplicate-tag: error
+`))
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Profile:    ProfileStrict,
+		Extensions: []string{"timestamps"},
+		Disable:    []string{"custom-edge-type"},
+		Severity:   map[string]diag.Severity{"duplicate-tag": diag.SeverityError},
+	}, cfg)
+
+	cfg, err = ParseConfig([]byte(`{"profile": "identifiers"}`))
+	require.NoError(t, err)
+	assert.Equal(t, ExtIdentifiers, cfg.Profile)
+
+	cfg, err = ParseConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, Config{}, cfg)
+
+	_, err = ParseConfig([]byte("profiel: strict\n"))
+	assert.Error(t, err, "unknown keys are rejected")
+
+	path := filepath.Join(t.TempDir(), ConfigFile)
+	require.NoError(t, os.WriteFile(path, []byte("disable: [VB0015]\n"), 0o644))
+	cfg, err = LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"VB0015"}, cfg.Disable)
+}
+
+// errorsOnly implements Validator without Checker.
+type errorsOnly struct{ Validator }
+
+func TestCheck(t *testing.T) {
+	doc := &core.Document{Info: core.Info{Version: "0.5"}}
+
+	t.Run("uses Checker", func(t *testing.T) {
+		assert.NotEmpty(t, Check(NewValidator(), doc))
+	})
+
+	t.Run("falls back to Validate", func(t *testing.T) {
+		v := errorsOnly{NewValidator()}
+		_, isChecker := Validator(v).(Checker)
+		require.False(t, isChecker)
+		assert.Equal(t, Check(NewValidator(), doc), Check(v, doc))
+	})
+}
@@ -0,0 +1,542 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/diag"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+)
+
+// Rule codes reported by the validator. Codes are stable: a rule keeps its
+// code when its message changes, and retired codes are not reused.
+const (
+	CodeVersionRequired = "VB0010"
+	CodeMissingContent  = "VB0011"
+	CodeBothContents    = "VB0012"
+	CodeTitleRequired   = "VB0013"
+	CodeInvalidStatus   = "VB0014"
+	CodeProposal        = "VB0015"
+	CodeEmptyNarrative  = "VB0016"
+
+	CodeTimestampOrder  = "VB0201"
+	CodeIDSyntax        = "VB0210"
+	CodeDuplicateID     = "VB0211"
+	CodeDuplicateUID    = "VB0212"
+	CodeDanglingEdge    = "VB0213"
+	CodeSelfEdge        = "VB0214"
+	CodeInvalidPriority = "VB0220"
+	CodeDuplicateTag    = "VB0221"
+	CodeDateRange       = "VB0230"
+	CodePercentComplete = "VB0231"
+	CodeParticipant     = "VB0240"
+	CodeRecurrence      = "VB0250"
+	CodeCustomEdgeType  = "VB0260"
+	CodeEdgeCycle       = "VB0261"
+)
+
+// Extensions with built-in rules.
+const (
+	ExtTimestamps   = "timestamps"
+	ExtIdentifiers  = "identifiers"
+	ExtMetadata     = "metadata"
+	ExtScheduling   = "scheduling"
+	ExtParticipants = "participants"
+	ExtRecurrence   = "recurrence"
+)
+
+// Rules describes the built-in rules.
+var Rules = builtin.Descriptors()
+
+func builtinRules() []Rule {
+	return append(coreRules(), lintRules()...)
+}
+
+func coreRules() []Rule {
+	rule := func(code, name, description string, severity diag.Severity) diag.Rule {
+		return diag.Rule{Code: code, Name: name, Description: description, Severity: severity}
+	}
+	return []Rule{
+		// Core.
+		{Rule: rule(CodeVersionRequired, "version-required", "vBRIEFInfo.version must be set.", diag.SeverityError),
+			Scope: ScopeDocument, Check: checkVersion, Fix: fixVersion},
+		{Rule: rule(CodeMissingContent, "missing-content", "A document must contain a todoList or a plan.", diag.SeverityError),
+			Scope: ScopeDocument, Check: checkMissingContent},
+		{Rule: rule(CodeBothContents, "both-contents", "A document must not contain both a todoList and a plan.", diag.SeverityError),
+			Scope: ScopeDocument, Check: checkBothContents},
+		{Rule: rule(CodeTitleRequired, "title-required", "Plans and items must have a title.", diag.SeverityError),
+			Scope: ScopePlan | ScopeItem, Check: checkTitle},
+		{Rule: rule(CodeInvalidStatus, "invalid-status", "Statuses must be one of the values the schema allows.", diag.SeverityError),
+			Scope: ScopePlan | ScopeItem, Check: checkStatus},
+		{Rule: rule(CodeProposal, "proposal-required", "A plan must have a proposal narrative.", diag.SeverityError),
+			Scope: ScopePlan, Check: checkProposal},
+		{Rule: rule(CodeEmptyNarrative, "empty-narrative", "Narratives must not be empty.", diag.SeverityError),
+			Scope: ScopePlan, Check: checkNarratives},
+
+		// Extensions.
+		{Rule: rule(CodeTimestampOrder, "timestamp-order", "updated and completed must not be before created.", diag.SeverityError),
+			Scope: ScopeItem, Extension: ExtTimestamps, Check: checkTimestampOrder},
+		{Rule: rule(CodeIDSyntax, "id-syntax", "Item IDs must match the schema's ID pattern.", diag.SeverityError),
+			Scope: ScopeItem, Extension: ExtIdentifiers, Check: checkIDSyntax},
+		{Rule: rule(CodeDuplicateID, "duplicate-id", "Hierarchical item IDs must be unique.", diag.SeverityError),
+			Scope: ScopePlan, Extension: ExtIdentifiers, Check: checkDuplicateIDs, Fix: fixDuplicateID},
+		{Rule: rule(CodeDuplicateUID, "duplicate-uid", "Item UIDs must be unique.", diag.SeverityError),
+			Scope: ScopePlan, Extension: ExtIdentifiers, Check: checkDuplicateUIDs},
+		{Rule: rule(CodeDanglingEdge, "dangling-edge", "Edges must reference existing item IDs.", diag.SeverityError),
+			Scope: ScopeEdge, Extension: ExtIdentifiers, Check: checkDanglingEdge, Fix: fixDanglingEdge},
+		{Rule: rule(CodeSelfEdge, "self-edge", "An edge must not connect an item to itself.", diag.SeverityError),
+			Scope: ScopeEdge, Extension: ExtIdentifiers, Check: checkSelfEdge},
+		{Rule: rule(CodeInvalidPriority, "invalid-priority", "Priorities must be low, medium, high or critical.", diag.SeverityError),
+			Scope: ScopeItem, Extension: ExtMetadata, Check: checkPriority},
+		{Rule: rule(CodeDuplicateTag, "duplicate-tag", "An item should not repeat a tag.", diag.SeverityWarning),
+			Scope: ScopeItem, Extension: ExtMetadata, Check: checkDuplicateTags},
+		{Rule: rule(CodeDateRange, "date-range", "endDate must not be before startDate.", diag.SeverityError),
+			Scope: ScopeItem, Extension: ExtScheduling, Check: checkDateRange},
+		{Rule: rule(CodePercentComplete, "percent-complete", "percentComplete must be between 0 and 100.", diag.SeverityError),
+			Scope: ScopeItem, Extension: ExtScheduling, Check: checkPercentComplete},
+		{Rule: rule(CodeParticipant, "participant", "Participants need an ID and a known role.", diag.SeverityError),
+			Scope: ScopeItem, Extension: ExtParticipants, Check: checkParticipants},
+		{Rule: rule(CodeRecurrence, "recurrence", "Recurrence rules need a known frequency and at most one of count and until.", diag.SeverityError),
+			Scope: ScopeItem, Extension: ExtRecurrence, Check: checkRecurrence},
+
+		// Strict.
+		{Rule: rule(CodeCustomEdgeType, "custom-edge-type", "Edges use a type outside the core set.", diag.SeverityInfo),
+			Scope: ScopeEdge, Profile: ProfileStrict, Check: checkEdgeType},
+		{Rule: rule(CodeEdgeCycle, "edge-cycle", "Blocks edges must form a directed acyclic graph.", diag.SeverityError),
+			Scope: ScopePlan, Profile: ProfileStrict, Check: checkEdgeCycle},
+	}
+}
+
+func checkVersion(s *Subject) ValidationErrors {
+	if s.Doc.Info.Version != "" {
+		return nil
+	}
+	return ValidationErrors{{
+		Field:   "vBRIEFInfo.version",
+		Message: "version is required",
+		Fix: &diag.Fix{
+			Description: fmt.Sprintf("set version to %q", core.SpecVersion),
+			Replacement: strconv.Quote(core.SpecVersion),
+		},
+	}}
+}
+
+func fixVersion(doc *core.Document, _ ValidationError) error {
+	if doc.Info.Version == "" {
+		doc.Info.Version = core.SpecVersion
+	}
+	return nil
+}
+
+func checkMissingContent(s *Subject) ValidationErrors {
+	if s.Doc.TodoList != nil || s.Doc.Plan != nil {
+		return nil
+	}
+	return ValidationErrors{{Field: "document", Message: "must contain either todoList or plan"}}
+}
+
+func checkBothContents(s *Subject) ValidationErrors {
+	if s.Doc.TodoList == nil || s.Doc.Plan == nil {
+		return nil
+	}
+	return ValidationErrors{{Field: "document", Message: "cannot contain both todoList and plan"}}
+}
+
+func checkTitle(s *Subject) ValidationErrors {
+	var title string
+	switch {
+	case s.Item != nil:
+		title = s.Item.Title
+	case s.TodoItem != nil:
+		title = s.TodoItem.Title
+	default:
+		title = s.Plan.Title
+	}
+	if title != "" {
+		return nil
+	}
+	return ValidationErrors{{Field: s.Path + ".title", Message: "title is required"}}
+}
+
+func checkStatus(s *Subject) ValidationErrors {
+	switch {
+	case s.Item != nil:
+		if !s.Item.Status.IsValid() {
+			return ValidationErrors{statusError(s.Path+".status", string(s.Item.Status), itemStatuses)}
+		}
+	case s.TodoItem != nil:
+		if !s.TodoItem.Status.IsValid() {
+			return ValidationErrors{statusError(s.Path+".status", string(s.TodoItem.Status), itemStatuses)}
+		}
+	default:
+		if !s.Plan.Status.IsValid() {
+			return ValidationErrors{statusError(s.Path+".status", string(s.Plan.Status), planStatuses)}
+		}
+	}
+	return nil
+}
+
+func checkProposal(s *Subject) ValidationErrors {
+	// Accept the TitleCase key the spec recommends as well as the
+	// lowercase key older documents use.
+	for _, key := range []string{"proposal", "Proposal"} {
+		if _, ok := s.Plan.Narratives[key]; ok {
+			return nil
+		}
+	}
+	return ValidationErrors{{Field: "plan.narratives", Message: "proposal narrative is required"}}
+}
+
+func checkNarratives(s *Subject) ValidationErrors {
+	var errs ValidationErrors
+	// Report in key order so that errors are stable.
+	for _, key := range sortedKeys(s.Plan.Narratives) {
+		if s.Plan.Narratives[key] == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("plan.narratives.%s", key),
+				Message: "content is required",
+			})
+		}
+	}
+	return errs
+}
+
+func checkTimestampOrder(s *Subject) ValidationErrors {
+	item := s.Item
+	if item == nil || item.Created == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	if item.Updated != nil && item.Updated.Before(*item.Created) {
+		errs = append(errs, ValidationError{Field: s.Path + ".updated", Message: "updated is before created"})
+	}
+	if item.Completed != nil && item.Completed.Before(*item.Created) {
+		errs = append(errs, ValidationError{Field: s.Path + ".completed", Message: "completed is before created"})
+	}
+	return errs
+}
+
+// idPattern is the schema's pattern for item IDs.
+var idPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+(\.[a-zA-Z0-9_-]+)*$`)
+
+func checkIDSyntax(s *Subject) ValidationErrors {
+	if s.Item == nil || s.Item.ID == "" || idPattern.MatchString(s.Item.ID) {
+		return nil
+	}
+	return ValidationErrors{{
+		Field:   s.Path + ".id",
+		Message: fmt.Sprintf("id %q must contain only letters, digits, '-', '_' and '.'", s.Item.ID),
+	}}
+}
+
+func checkDuplicateIDs(s *Subject) ValidationErrors {
+	var errs ValidationErrors
+	first := make(map[string]string)
+	for _, n := range s.Graph.Nodes() {
+		if n.ID == "" {
+			continue
+		}
+		if path, ok := first[n.ID]; ok {
+			errs = append(errs, ValidationError{
+				Field:   n.Path + ".id",
+				Message: fmt.Sprintf("duplicate id %q (first used at %s)", n.ID, path),
+				Fix:     &diag.Fix{Description: "add a numeric suffix to the later id"},
+			})
+			continue
+		}
+		first[n.ID] = n.Path
+	}
+	return errs
+}
+
+// fixDuplicateID renames every later item that shares the finding's ID
+// to the first free "id-N", in document order. The first item keeps its
+// ID, so edges continue to point at it. The other findings for the same
+// ID are then no-ops.
+func fixDuplicateID(doc *core.Document, f ValidationError) error {
+	item, err := itemAt(doc, strings.TrimSuffix(f.Field, ".id"))
+	if err != nil {
+		return err
+	}
+	g := graph.New(doc.Plan)
+	var id string
+	taken := make(map[string]bool)
+	for _, n := range g.Nodes() {
+		taken[n.ID] = true
+		if n.Item == item {
+			id = n.ID
+		}
+	}
+	if id == "" {
+		return nil
+	}
+	first := true
+	for _, n := range g.Nodes() {
+		if n.ID != id {
+			continue
+		}
+		if first {
+			first = false
+			continue
+		}
+		parent, _ := graph.SplitID(n.ID)
+		for k := 2; ; k++ {
+			next := fmt.Sprintf("%s-%d", n.Item.ID, k)
+			if full := graph.JoinID(parent, next); !taken[full] {
+				taken[full] = true
+				n.Item.ID = next
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func checkDuplicateUIDs(s *Subject) ValidationErrors {
+	var errs ValidationErrors
+	first := make(map[string]string)
+	for _, n := range s.Graph.Nodes() {
+		uid := n.Item.UID
+		if uid == "" {
+			continue
+		}
+		if path, ok := first[uid]; ok {
+			errs = append(errs, ValidationError{
+				Field:   n.Path + ".uid",
+				Message: fmt.Sprintf("duplicate uid %q (first used at %s)", uid, path),
+			})
+			continue
+		}
+		first[uid] = n.Path
+	}
+	return errs
+}
+
+// checkDanglingEdge reports edge endpoints that name no item. Endpoints in
+// another document, such as "api.json#setup", are left to the workspace
+// package.
+func checkDanglingEdge(s *Subject) ValidationErrors {
+	var errs ValidationErrors
+	for _, end := range []struct{ field, id string }{{"from", s.Edge.From}, {"to", s.Edge.To}} {
+		if s.Graph.Node(end.id) == nil && !strings.Contains(end.id, "#") {
+			errs = append(errs, ValidationError{
+				Field:   s.Path + "." + end.field,
+				Message: fmt.Sprintf("unknown item %q", end.id),
+				Fix:     &diag.Fix{Description: "remove the edge"},
+			})
+		}
+	}
+	return errs
+}
+
+// fixDanglingEdge removes the edge a finding names if it is still
+// dangling. Removing an edge shifts the ones after it, so a stale finding
+// may name a different edge; that edge is removed only if it dangles too.
+func fixDanglingEdge(doc *core.Document, f ValidationError) error {
+	if doc.Plan == nil {
+		return fmt.Errorf("%w: %s", ErrFixTarget, f.Field)
+	}
+	var i int
+	if _, err := fmt.Sscanf(f.Field, "plan.edges[%d]", &i); err != nil {
+		return fmt.Errorf("%w: %s", ErrFixTarget, f.Field)
+	}
+	edges := doc.Plan.Edges
+	if i < 0 || i >= len(edges) {
+		return nil
+	}
+	g := graph.New(doc.Plan)
+	if g.Node(edges[i].From) != nil && g.Node(edges[i].To) != nil {
+		return nil
+	}
+	doc.Plan.Edges = append(edges[:i:i], edges[i+1:]...)
+	return nil
+}
+
+func checkSelfEdge(s *Subject) ValidationErrors {
+	if s.Edge.From != s.Edge.To {
+		return nil
+	}
+	return ValidationErrors{{Field: s.Path, Message: fmt.Sprintf("edge connects %q to itself", s.Edge.From)}}
+}
+
+func checkPriority(s *Subject) ValidationErrors {
+	if s.Item == nil || s.Item.Priority == "" || s.Item.Priority.IsValid() {
+		return nil
+	}
+	err := ValidationError{
+		Field:   s.Path + ".priority",
+		Message: fmt.Sprintf("invalid priority: %s", s.Item.Priority),
+	}
+	if p := closest(string(s.Item.Priority), priorities); p != "" {
+		err.Fix = &diag.Fix{
+			Description: fmt.Sprintf("change priority to %q", p),
+			Replacement: fmt.Sprintf("%q", p),
+		}
+	}
+	return ValidationErrors{err}
+}
+
+func checkDuplicateTags(s *Subject) ValidationErrors {
+	if s.Item == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	seen := make(map[string]bool)
+	for i, tag := range s.Item.Tags {
+		if seen[tag] {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.tags[%d]", s.Path, i),
+				Message: fmt.Sprintf("duplicate tag %q", tag),
+				Fix:     &diag.Fix{Description: "remove the duplicate tag"},
+			})
+		}
+		seen[tag] = true
+	}
+	return errs
+}
+
+func checkDateRange(s *Subject) ValidationErrors {
+	item := s.Item
+	if item == nil || item.StartDate == nil || item.EndDate == nil || !item.EndDate.Before(*item.StartDate) {
+		return nil
+	}
+	return ValidationErrors{{Field: s.Path + ".endDate", Message: "endDate is before startDate"}}
+}
+
+func checkPercentComplete(s *Subject) ValidationErrors {
+	if s.Item == nil || s.Item.PercentComplete == nil {
+		return nil
+	}
+	if p := *s.Item.PercentComplete; p < 0 || p > 100 {
+		return ValidationErrors{{
+			Field:   s.Path + ".percentComplete",
+			Message: fmt.Sprintf("percentComplete %g is outside 0-100", p),
+		}}
+	}
+	return nil
+}
+
+func checkParticipants(s *Subject) ValidationErrors {
+	if s.Item == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	for i, p := range s.Item.Participants {
+		prefix := fmt.Sprintf("%s.participants[%d]", s.Path, i)
+		if p.ID == "" {
+			errs = append(errs, ValidationError{Field: prefix + ".id", Message: "id is required"})
+		}
+		if !p.Role.IsValid() {
+			errs = append(errs, ValidationError{Field: prefix + ".role", Message: fmt.Sprintf("invalid role: %s", p.Role)})
+		}
+	}
+	return errs
+}
+
+func checkRecurrence(s *Subject) ValidationErrors {
+	if s.Item == nil || s.Item.Recurrence == nil {
+		return nil
+	}
+	r := s.Item.Recurrence
+	prefix := s.Path + ".recurrence"
+	var errs ValidationErrors
+	if !r.Frequency.IsValid() {
+		errs = append(errs, ValidationError{Field: prefix + ".frequency", Message: fmt.Sprintf("invalid frequency: %s", r.Frequency)})
+	}
+	if r.Interval < 0 {
+		errs = append(errs, ValidationError{Field: prefix + ".interval", Message: "interval must not be negative"})
+	}
+	if r.Count > 0 && r.Until != nil {
+		errs = append(errs, ValidationError{Field: prefix, Message: "count and until are mutually exclusive"})
+	}
+	return errs
+}
+
+func checkEdgeType(s *Subject) ValidationErrors {
+	if s.Edge.Type.IsCore() {
+		return nil
+	}
+	return ValidationErrors{{Field: s.Path + ".type", Message: fmt.Sprintf("edge type %q is not a core type", s.Edge.Type)}}
+}
+
+func checkEdgeCycle(s *Subject) ValidationErrors {
+	cycle := s.Graph.Cycle()
+	if cycle == nil {
+		return nil
+	}
+	return ValidationErrors{{Field: "plan.edges", Message: "blocks edges form a cycle: " + strings.Join(cycle, " -> ")}}
+}
+
+var (
+	itemStatuses = []string{"pending", "running", "inProgress", "completed", "blocked", "cancelled"}
+	planStatuses = []string{"draft", "proposed", "approved", "running", "inProgress", "completed", "cancelled"}
+	priorities   = []string{"low", "medium", "high", "critical"}
+)
+
+func statusError(field, status string, allowed []string) ValidationError {
+	err := ValidationError{
+		Field:   field,
+		Message: fmt.Sprintf("invalid status: %s", status),
+		Code:    CodeInvalidStatus,
+	}
+	if s := closest(status, allowed); s != "" {
+		err.Fix = &diag.Fix{
+			Description: fmt.Sprintf("change status to %q", s),
+			Replacement: fmt.Sprintf("%q", s),
+		}
+	}
+	return err
+}
+
+// closest returns the allowed value that differs from s only in case or by
+// at most two edits, or "" if there is none.
+func closest(s string, allowed []string) string {
+	if s == "" {
+		return ""
+	}
+	best, bestDist := "", 3
+	for _, a := range allowed {
+		if strings.EqualFold(s, a) {
+			return a
+		}
+		if d := distance(strings.ToLower(s), strings.ToLower(a)); d < bestDist {
+			best, bestDist = a, d
+		}
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance between two strings.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
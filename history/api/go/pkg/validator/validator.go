@@ -6,10 +6,12 @@ import (
 	"fmt"
 
 	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/diag"
 )
 
 var (
-	// ErrExtensionsNotSupported is returned when extensions are requested but not implemented.
+	// ErrExtensionsNotSupported is returned when an extension has no
+	// registered rules.
 	ErrExtensionsNotSupported = errors.New("extensions not supported")
 )
 
@@ -17,6 +19,30 @@ var (
 type ValidationError struct {
 	Field   string
 	Message string
+	// Code is the rule code, e.g. "VB0014".
+	Code string
+	// Severity defaults to diag.SeverityError when empty.
+	Severity diag.Severity
+	// Span locates the error in the source; see WithSourceMap.
+	Span *diag.Span
+	// Fix suggests how to resolve the error, if known.
+	Fix *diag.Fix
+}
+
+// Diagnostic returns the error as a diagnostic.
+func (e ValidationError) Diagnostic() diag.Diagnostic {
+	severity := e.Severity
+	if severity == "" {
+		severity = diag.SeverityError
+	}
+	return diag.Diagnostic{
+		Code:     e.Code,
+		Severity: severity,
+		Path:     e.Field,
+		Message:  e.Message,
+		Span:     e.Span,
+		Fix:      e.Fix,
+	}
 }
 
 // Error returns the error message.
@@ -39,192 +65,154 @@ func (e ValidationErrors) Error() string {
 	return result
 }
 
+// Diagnostics returns the errors as diagnostics.
+func (e ValidationErrors) Diagnostics() []diag.Diagnostic {
+	out := make([]diag.Diagnostic, len(e))
+	for i, err := range e {
+		out[i] = err.Diagnostic()
+	}
+	return out
+}
+
+// WithSourceMap returns a copy of the errors located in the source that sm
+// describes. An error about a value missing from the source is located at
+// the nearest enclosing value.
+func (e ValidationErrors) WithSourceMap(sm diag.SourceMap) ValidationErrors {
+	out := make(ValidationErrors, len(e))
+	for i, err := range e {
+		if span, ok := sm.Lookup(err.Field); ok {
+			err.Span = &span
+		}
+		out[i] = err
+	}
+	return out
+}
+
 // Validator validates vBRIEF documents.
 type Validator interface {
-	// Validate checks if a document is valid.
+	// Validate checks if a document is valid. It returns ValidationErrors
+	// holding the error-severity findings of the selected rules, or nil.
 	Validate(doc *core.Document) error
 
-	// ValidateCore checks only core requirements.
+	// ValidateCore checks only the core rules.
 	ValidateCore(doc *core.Document) error
 
-	// ValidateExtensions checks extension requirements.
-	//
-	// Extensions are not implemented yet, so this currently behaves as a safe no-op
-	// beyond core validation.
+	// ValidateExtensions checks the selected rules plus the rules of the
+	// named extensions. It returns ErrExtensionsNotSupported if an
+	// extension has no registered rules.
 	ValidateExtensions(doc *core.Document, extensions []string) error
 }
 
-type validator struct{}
-
-// NewValidator creates a new validator.
-func NewValidator() Validator {
-	return &validator{}
-}
-
-// New is an alias for NewValidator for convenience.
-func New() Validator {
-	return NewValidator()
+// Checker is implemented by validators that report every finding,
+// including warnings and info. The validators this package creates
+// implement it.
+type Checker interface {
+	// Check runs the selected rules and returns every finding.
+	Check(doc *core.Document) ValidationErrors
 }
 
-// Validate checks if a document is valid.
-func (v *validator) Validate(doc *core.Document) error {
-	var errors ValidationErrors
-
-	// Validate Info
-	if doc.Info.Version == "" {
-		errors = append(errors, ValidationError{
-			Field:   "vBRIEFInfo.version",
-			Message: "version is required",
-		})
+// Check returns every finding of v for doc. A validator that does not
+// implement Checker reports only the errors Validate returns.
+func Check(v Validator, doc *core.Document) ValidationErrors {
+	if c, ok := v.(Checker); ok {
+		return c.Check(doc)
 	}
-
-	// Must have either TodoList or Plan, but not both
-	if doc.TodoList == nil && doc.Plan == nil {
-		errors = append(errors, ValidationError{
-			Field:   "document",
-			Message: "must contain either todoList or plan",
-		})
+	err := v.Validate(doc)
+	if err == nil {
+		return nil
 	}
-
-	if doc.TodoList != nil && doc.Plan != nil {
-		errors = append(errors, ValidationError{
-			Field:   "document",
-			Message: "cannot contain both todoList and plan",
-		})
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		return errs
 	}
-
-	// Validate TodoList if present
-	if doc.TodoList != nil {
-		if errs := v.validateTodoList(doc.TodoList); len(errs) > 0 {
-			errors = append(errors, errs...)
-		}
-	}
-
-	// Validate Plan if present
-	if doc.Plan != nil {
-		if errs := v.validatePlan(doc.Plan); len(errs) > 0 {
-			errors = append(errors, errs...)
-		}
-	}
-
-	if len(errors) > 0 {
-		return errors
-	}
-
-	return nil
+	return ValidationErrors{{Field: diag.RootPath, Message: err.Error()}}
 }
 
-// ValidateCore checks only core requirements.
-func (v *validator) ValidateCore(doc *core.Document) error {
-	return v.Validate(doc)
+// Options configures NewValidatorWithOptions.
+type Options struct {
+	// Registry supplies the rules. Defaults to DefaultRegistry().
+	Registry *Registry
+	// Config selects and configures rules. The zero Config runs the core
+	// profile.
+	Config Config
 }
 
-// ValidateExtensions checks extension requirements.
-//
-// Extensions are not implemented yet. If any extensions are requested, this returns
-// ErrExtensionsNotSupported.
-func (v *validator) ValidateExtensions(doc *core.Document, extensions []string) error {
-	if len(extensions) > 0 {
-		return fmt.Errorf("%w: %v", ErrExtensionsNotSupported, extensions)
-	}
-	return v.Validate(doc)
+type validator struct {
+	registry *Registry
+	config   Config
+	rules    []Rule
 }
 
-func (v *validator) validateTodoList(list *core.TodoList) ValidationErrors {
-	var errors ValidationErrors
-
-	for i, item := range list.Items {
-		if errs := v.validateTodoItem(item, i); len(errs) > 0 {
-			errors = append(errors, errs...)
-		}
-	}
+// builtin holds the built-in rules used by NewValidator.
+var builtin = DefaultRegistry()
 
-	return errors
+// NewValidator creates a new validator that runs the core rules.
+func NewValidator() Validator {
+	rules, _ := builtin.Select(Config{})
+	return &validator{registry: builtin, rules: rules}
 }
 
-func (v *validator) validateTodoItem(item core.TodoItem, index int) ValidationErrors {
-	var errors ValidationErrors
-	prefix := fmt.Sprintf("todoList.items[%d]", index)
-
-	if item.Title == "" {
-		errors = append(errors, ValidationError{
-			Field:   prefix + ".title",
-			Message: "title is required",
-		})
-	}
-
-	if !item.Status.IsValid() {
-		errors = append(errors, ValidationError{
-			Field:   prefix + ".status",
-			Message: fmt.Sprintf("invalid status: %s", item.Status),
-		})
-	}
-
-	return errors
+// New is an alias for NewValidator for convenience.
+func New() Validator {
+	return NewValidator()
 }
 
-func (v *validator) validatePlan(plan *core.Plan) ValidationErrors {
-	var errors ValidationErrors
-
-	if plan.Title == "" {
-		errors = append(errors, ValidationError{
-			Field:   "plan.title",
-			Message: "title is required",
-		})
+// NewValidatorWithOptions creates a validator that runs the rules opts.Config
+// selects from opts.Registry. It returns an error if the config names an
+// unknown profile, extension or rule.
+func NewValidatorWithOptions(opts Options) (Validator, error) {
+	if opts.Registry == nil {
+		opts.Registry = DefaultRegistry()
 	}
-
-	if !plan.Status.IsValid() {
-		errors = append(errors, ValidationError{
-			Field:   "plan.status",
-			Message: fmt.Sprintf("invalid status: %s", plan.Status),
-		})
+	rules, err := opts.Registry.Select(opts.Config)
+	if err != nil {
+		return nil, err
 	}
+	return &validator{registry: opts.Registry, config: opts.Config, rules: rules}, nil
+}
 
-	// Proposal narrative is required
-	if _, ok := plan.Narratives["proposal"]; !ok {
-		errors = append(errors, ValidationError{
-			Field:   "plan.narratives",
-			Message: "proposal narrative is required",
-		})
-	}
+// Validate checks if a document is valid.
+func (v *validator) Validate(doc *core.Document) error {
+	return failures(run(doc, v.rules))
+}
 
-	// Validate all narratives
-	for key, content := range plan.Narratives {
-		if content == "" {
-			errors = append(errors, ValidationError{
-				Field:   fmt.Sprintf("plan.narratives.%s", key),
-				Message: "content is required",
-			})
-		}
+// ValidateCore checks only core requirements.
+func (v *validator) ValidateCore(doc *core.Document) error {
+	cfg := v.config
+	cfg.Profile, cfg.Extensions = ProfileCore, nil
+	rules, err := v.registry.Select(cfg)
+	if err != nil {
+		return err
 	}
+	return failures(run(doc, rules))
+}
 
-	// Validate plan items
-	for i, item := range plan.Items {
-		if errs := v.validatePlanItem(item, i); len(errs) > 0 {
-			errors = append(errors, errs...)
-		}
+// ValidateExtensions checks extension requirements.
+func (v *validator) ValidateExtensions(doc *core.Document, extensions []string) error {
+	cfg := v.config
+	cfg.Extensions = append(append([]string(nil), cfg.Extensions...), extensions...)
+	rules, err := v.registry.Select(cfg)
+	if err != nil {
+		return err
 	}
-
-	return errors
+	return failures(run(doc, rules))
 }
 
-func (v *validator) validatePlanItem(item core.PlanItem, index int) ValidationErrors {
-	var errors ValidationErrors
-	prefix := fmt.Sprintf("plan.items[%d]", index)
+// Check runs the selected rules and returns every finding.
+func (v *validator) Check(doc *core.Document) ValidationErrors {
+	return run(doc, v.rules)
+}
 
-	if item.Title == "" {
-		errors = append(errors, ValidationError{
-			Field:   prefix + ".title",
-			Message: "title is required",
-		})
+// failures returns the error-severity findings as an error, or nil.
+func failures(findings ValidationErrors) error {
+	var errs ValidationErrors
+	for _, f := range findings {
+		if f.Severity == diag.SeverityError {
+			errs = append(errs, f)
+		}
 	}
-
-	if !item.Status.IsValid() {
-		errors = append(errors, ValidationError{
-			Field:   prefix + ".status",
-			Message: fmt.Sprintf("invalid status: %s", item.Status),
-		})
+	if len(errs) > 0 {
+		return errs
 	}
-
-	return errors
+	return nil
 }
@@ -3,10 +3,12 @@ package validator
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/diag"
 )
 
 func TestValidator_ValidateTodoList(t *testing.T) {
@@ -191,11 +193,38 @@ func TestValidator_ValidateExtensions(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
-	t.Run("extensions requested returns not supported", func(t *testing.T) {
-		err := v.ValidateExtensions(doc, []string{"timestamps"})
+	t.Run("unknown extension returns not supported", func(t *testing.T) {
+		err := v.ValidateExtensions(doc, []string{"teleportation"})
 		assert.Error(t, err)
 		assert.True(t, errors.Is(err, ErrExtensionsNotSupported))
 	})
+
+	t.Run("runs the requested extensions' rules", func(t *testing.T) {
+		created := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+		updated := created.Add(-time.Hour)
+		doc := &core.Document{
+			Info: core.Info{Version: "0.5"},
+			Plan: &core.Plan{
+				Title:      "Plan",
+				Status:     core.PlanStatusDraft,
+				Narratives: map[string]string{"proposal": "Do it"},
+				Items: []core.PlanItem{
+					{ID: "a", Title: "A", Status: core.PlanItemStatusPending, Created: &created, Updated: &updated},
+					{ID: "a", Title: "B", Status: core.PlanItemStatusPending},
+				},
+			},
+		}
+		require.NoError(t, v.Validate(doc))
+
+		err := v.ValidateExtensions(doc, []string{ExtTimestamps})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "plan.items[0].updated: updated is before created")
+		assert.NotContains(t, err.Error(), "duplicate id")
+
+		err = v.ValidateExtensions(doc, []string{ExtTimestamps, ExtIdentifiers})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `plan.items[1].id: duplicate id "a"`)
+	})
 }
 
 func TestValidator_ValidatePhases(t *testing.T) {
@@ -261,6 +290,29 @@ func TestValidator_ValidatePhases(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "invalid status")
 	})
+
+	t.Run("nested subItems are validated", func(t *testing.T) {
+		doc := &core.Document{
+			Info: core.Info{Version: "0.2"},
+			Plan: &core.Plan{
+				Title:  "Plan",
+				Status: core.PlanStatusDraft,
+				Narratives: map[string]string{
+					"proposal": "Content",
+				},
+				Items: []core.PlanItem{
+					{Title: "Phase", Status: core.PlanItemStatusPending, SubItems: []core.PlanItem{
+						{Title: "Step", Status: core.PlanItemStatusPending},
+						{Title: "", Status: core.PlanItemStatusPending},
+					}},
+				},
+			},
+		}
+
+		err := v.Validate(doc)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "plan.items[0].subItems[1].title")
+	})
 }
 
 func TestValidationErrors(t *testing.T) {
@@ -328,3 +380,64 @@ func TestValidator_MultipleErrors(t *testing.T) {
 		assert.Contains(t, errStr, "invalid status")
 	})
 }
+
+func TestValidationError_Codes(t *testing.T) {
+	doc := &core.Document{
+		Plan: &core.Plan{
+			Status:     core.PlanStatus("Approved"),
+			Narratives: map[string]string{"Notes": ""},
+			Items: []core.PlanItem{
+				{Title: "Build", Status: core.PlanItemStatus("inProgres")},
+				{Title: "Ship", Status: core.PlanItemStatus("whenever")},
+			},
+		},
+	}
+	var errs ValidationErrors
+	require.ErrorAs(t, NewValidator().Validate(doc), &errs)
+
+	byField := make(map[string]ValidationError)
+	for _, e := range errs {
+		byField[e.Field] = e
+	}
+
+	tests := []struct {
+		field string
+		code  string
+		fix   string
+	}{
+		{"vBRIEFInfo.version", CodeVersionRequired, `"0.5"`},
+		{"plan.title", CodeTitleRequired, ""},
+		{"plan.status", CodeInvalidStatus, `"approved"`},
+		{"plan.items[0].status", CodeInvalidStatus, `"inProgress"`},
+		{"plan.items[1].status", CodeInvalidStatus, ""},
+		{"plan.narratives", CodeProposal, ""},
+		{"plan.narratives.Notes", CodeEmptyNarrative, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			e, ok := byField[tt.field]
+			require.True(t, ok)
+			assert.Equal(t, tt.code, e.Code)
+			d := e.Diagnostic()
+			assert.Equal(t, diag.SeverityError, d.Severity)
+			assert.Equal(t, tt.field, d.Path)
+			if tt.fix == "" {
+				assert.Nil(t, e.Fix)
+			} else {
+				require.NotNil(t, e.Fix)
+				assert.Equal(t, tt.fix, e.Fix.Replacement)
+			}
+		})
+	}
+
+	t.Run("every code is described", func(t *testing.T) {
+		codes := make(map[string]bool)
+		for _, r := range Rules {
+			codes[r.Code] = true
+		}
+		for _, e := range errs {
+			assert.True(t, codes[e.Code], e.Code)
+		}
+	})
+}
+
@@ -0,0 +1,283 @@
+package workspace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/diag"
+	"github.com/visionik/vBRIEF/api/go/pkg/validator"
+)
+
+// Workspace check codes.
+const (
+	CodeUnresolvedLink = "VB0280"
+	CodeCrossFileCycle = "VB0281"
+	CodeOrphanedPlan   = "VB0282"
+	CodeDuplicateUID   = "VB0283"
+	CodeUnreadablePlan = "VB0284"
+)
+
+// Rules describes the workspace checks, e.g. for diag.SARIFOptions.Rules.
+var Rules = []diag.Rule{
+	{Code: CodeUnresolvedLink, Name: "unresolved-link", Severity: diag.SeverityError,
+		Description: "planRefs and cross-file edge endpoints must name a plan or item in the workspace."},
+	{Code: CodeCrossFileCycle, Name: "cross-file-cycle", Severity: diag.SeverityError,
+		Description: "Edges, planRefs and nesting must not form a cycle that spans files."},
+	{Code: CodeOrphanedPlan, Name: "orphaned-plan", Severity: diag.SeverityWarning,
+		Description: "Every plan should be linked into the programme."},
+	{Code: CodeDuplicateUID, Name: "workspace-duplicate-uid", Severity: diag.SeverityError,
+		Description: "Item uids must be unique across files."},
+	{Code: CodeUnreadablePlan, Name: "unreadable-plan", Severity: diag.SeverityError,
+		Description: "Plan files must parse."},
+}
+
+// Finding is a problem found in one file of the workspace.
+type Finding struct {
+	// File is the path of the file the finding is about.
+	File string
+	validator.ValidationError
+}
+
+// String returns the finding on one line, prefixed with its file.
+func (f Finding) String() string {
+	return f.File + ": " + f.Diagnostic().String()
+}
+
+// Check runs the workspace-wide checks: unresolved links, cycles that span
+// files, orphaned plans, uids repeated across files and files that do not
+// parse. Problems within a single file are left to the validator.
+// Findings are sorted by file.
+func (w *Workspace) Check() []Finding {
+	var out []Finding
+	add := func(code, file, field, message string) {
+		sev := diag.SeverityError
+		for _, r := range Rules {
+			if r.Code == code {
+				sev = r.Severity
+			}
+		}
+		out = append(out, Finding{File: file, ValidationError: validator.ValidationError{
+			Field: field, Message: message, Code: code, Severity: sev,
+		}})
+	}
+
+	for _, f := range w.Files() {
+		if f.Err != nil {
+			add(CodeUnreadablePlan, f.Path, diag.RootPath, f.Err.Error())
+		}
+	}
+	for _, l := range w.unresolved {
+		missing := l.to
+		if w.nodes[l.to] != nil {
+			missing = l.from
+		}
+		add(CodeUnresolvedLink, l.file, l.field, fmt.Sprintf("%q is not in the workspace", missing))
+	}
+	for _, cycle := range w.cycles() {
+		first := w.nodes[cycle[0]]
+		add(CodeCrossFileCycle, first.File.Path, first.Path, "cycle across files: "+strings.Join(cycle, " -> "))
+	}
+	for _, f := range w.orphans() {
+		msg := "plan is not linked to or from any other plan"
+		if len(w.opts.Roots) > 0 {
+			msg = "plan is not reachable from any root"
+		}
+		add(CodeOrphanedPlan, f.Path, "plan", msg)
+	}
+	first := make(map[string]*Node)
+	for _, n := range w.order {
+		if n.Item == nil || n.Item.UID == "" {
+			continue
+		}
+		prev, ok := first[n.Item.UID]
+		if !ok {
+			first[n.Item.UID] = n
+			continue
+		}
+		if prev.File != n.File {
+			add(CodeDuplicateUID, n.File.Path, n.Path+".uid",
+				fmt.Sprintf("duplicate uid %q (first used at %s %s)", n.Item.UID, prev.File.Path, prev.Path))
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].File < out[j].File })
+	return out
+}
+
+// successors returns the keys a node leads to for cycle detection: its
+// children, the targets of its blocks edges and planRefs. Other edge
+// types carry no ordering.
+func (w *Workspace) successors(n *Node) []string {
+	var next []string
+	for _, c := range n.Children {
+		next = append(next, c.Key)
+	}
+	for _, e := range w.out[n.Key] {
+		if e.Type == EdgePlanRef || e.Type == core.EdgeBlocks {
+			next = append(next, e.To)
+		}
+	}
+	return next
+}
+
+// cycles returns one cycle, as node keys with the first repeated, for each
+// strongly connected component that spans more than one file.
+func (w *Workspace) cycles() [][]string {
+	// Tarjan's algorithm over nodes in workspace order.
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var sccs [][]string
+	var visit func(key string)
+	visit = func(key string) {
+		index[key] = len(index)
+		low[key] = index[key]
+		stack = append(stack, key)
+		onStack[key] = true
+		for _, next := range w.successors(w.nodes[key]) {
+			if _, ok := index[next]; !ok {
+				visit(next)
+				low[key] = min(low[key], low[next])
+			} else if onStack[next] {
+				low[key] = min(low[key], index[next])
+			}
+		}
+		if low[key] != index[key] {
+			return
+		}
+		var scc []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			scc = append(scc, top)
+			if top == key {
+				break
+			}
+		}
+		sccs = append(sccs, scc)
+	}
+	for _, n := range w.order {
+		if _, ok := index[n.Key]; !ok {
+			visit(n.Key)
+		}
+	}
+
+	var out [][]string
+	for _, scc := range sccs {
+		in := make(map[string]bool)
+		files := make(map[*File]bool)
+		for _, key := range scc {
+			in[key] = true
+			files[w.nodes[key].File] = true
+		}
+		if len(files) < 2 {
+			continue
+		}
+		start := scc[0]
+		for _, key := range scc {
+			if index[key] < index[start] {
+				start = key
+			}
+		}
+		out = append(out, w.cycleFrom(start, in))
+	}
+	sort.Slice(out, func(i, j int) bool { return index[out[i][0]] < index[out[j][0]] })
+	return out
+}
+
+// cycleFrom finds a shortest path from start back to itself within a
+// strongly connected component.
+func (w *Workspace) cycleFrom(start string, in map[string]bool) []string {
+	prev := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, next := range w.successors(w.nodes[key]) {
+			if !in[next] {
+				continue
+			}
+			if next == start {
+				path := []string{start}
+				for cur := key; cur != start; cur = prev[cur] {
+					path = append(path, cur)
+				}
+				path = append(path, start)
+				for i, j := 1, len(path)-2; i < j; i, j = i+1, j-1 {
+					path[i], path[j] = path[j], path[i]
+				}
+				return path
+			}
+			if _, ok := prev[next]; !ok {
+				prev[next] = key
+				queue = append(queue, next)
+			}
+		}
+	}
+	return []string{start, start}
+}
+
+// orphans returns the plan files that are not linked into the programme.
+func (w *Workspace) orphans() []*File {
+	links := make(map[string]map[string]bool)
+	linked := make(map[string]bool)
+	for _, e := range w.edges {
+		from, to := w.nodes[e.From].File.Path, w.nodes[e.To].File.Path
+		if from == to {
+			continue
+		}
+		decl, other := e.File, to
+		if decl == to {
+			other = from
+		}
+		if links[decl] == nil {
+			links[decl] = make(map[string]bool)
+		}
+		links[decl][other] = true
+		linked[from], linked[to] = true, true
+	}
+
+	var plans []*File
+	for _, f := range w.Files() {
+		if f.Document != nil && f.Document.Plan != nil {
+			plans = append(plans, f)
+		}
+	}
+
+	var out []*File
+	if len(w.opts.Roots) == 0 {
+		if len(plans) < 2 {
+			return nil
+		}
+		for _, f := range plans {
+			if !linked[f.Path] {
+				out = append(out, f)
+			}
+		}
+		return out
+	}
+
+	reached := make(map[string]bool)
+	queue := append([]string(nil), w.opts.Roots...)
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if reached[p] {
+			continue
+		}
+		reached[p] = true
+		for next := range links[p] {
+			queue = append(queue, next)
+		}
+	}
+	for _, f := range plans {
+		if !reached[f.Path] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,166 @@
+package workspace
+
+import (
+	"fmt"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+// Done reports whether a node's work is finished. An item is done when it
+// is completed or cancelled; otherwise an item with a planRef is done when
+// its target is, and an item with sub-items when they all are. A plan is
+// done when it is completed or cancelled, or all its items are done.
+func (w *Workspace) Done(n *Node) bool {
+	return w.done(n, make(map[*Node]bool))
+}
+
+func (w *Workspace) done(n *Node, visiting map[*Node]bool) bool {
+	if visiting[n] {
+		return false
+	}
+	visiting[n] = true
+	defer delete(visiting, n)
+
+	if n.IsPlan() {
+		switch n.File.Document.Plan.Status {
+		case core.PlanStatusCompleted, core.PlanStatusCancelled:
+			return true
+		}
+	} else {
+		switch n.Item.Status {
+		case core.PlanItemStatusCompleted, core.PlanItemStatusCancelled:
+			return true
+		}
+		if target := w.target(n); target != nil {
+			return w.done(target, visiting)
+		}
+	}
+	if len(n.Children) == 0 {
+		return false
+	}
+	for _, c := range n.Children {
+		if !w.done(c, visiting) {
+			return false
+		}
+	}
+	return true
+}
+
+// target returns the node an item's planRef resolves to, or nil.
+func (w *Workspace) target(n *Node) *Node {
+	for _, e := range w.out[n.Key] {
+		if e.Type == EdgePlanRef {
+			return w.nodes[e.To]
+		}
+	}
+	return nil
+}
+
+// isWork reports whether a node is an item whose work is its own, rather
+// than a container for sub-items or for a referenced plan.
+func (w *Workspace) isWork(n *Node) bool {
+	return !n.IsPlan() && len(n.Children) == 0 && w.target(n) == nil
+}
+
+// enclosing returns a node's ancestors, including the items that refer
+// to it, or to any of its ancestors, through planRefs.
+func (w *Workspace) enclosing(n *Node) []*Node {
+	var out []*Node
+	seen := map[*Node]bool{n: true}
+	queue := []*Node{n}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		next := make([]*Node, 0, 2)
+		if cur.Parent != nil {
+			next = append(next, cur.Parent)
+		}
+		for _, e := range w.in[cur.Key] {
+			if e.Type == EdgePlanRef {
+				next = append(next, w.nodes[e.From])
+			}
+		}
+		for _, m := range next {
+			if !seen[m] {
+				seen[m] = true
+				out = append(out, m)
+				queue = append(queue, m)
+			}
+		}
+	}
+	return out
+}
+
+// blockers returns the sources of blocks edges into n or anything that
+// encloses it.
+func (w *Workspace) blockers(n *Node) []*Node {
+	var out []*Node
+	for _, m := range append([]*Node{n}, w.enclosing(n)...) {
+		for _, e := range w.in[m.Key] {
+			if e.Type == core.EdgeBlocks {
+				out = append(out, w.nodes[e.From])
+			}
+		}
+	}
+	return out
+}
+
+// Ready returns the pending work items, across every file, whose blockers
+// are all done, in workspace order. A blocks edge into a plan, or into an
+// item that refers to one, holds back every item in that plan.
+func (w *Workspace) Ready() []*Node {
+	var ready []*Node
+	for _, n := range w.order {
+		if !w.isWork(n) || n.Item.Status != core.PlanItemStatusPending {
+			continue
+		}
+		unblocked := true
+		for _, b := range w.blockers(n) {
+			if !w.Done(b) {
+				unblocked = false
+				break
+			}
+		}
+		if unblocked {
+			ready = append(ready, n)
+		}
+	}
+	return ready
+}
+
+// Blockers returns the unfinished work items that must be done before the
+// node with the given key, such as a milestone, can be done: its blockers
+// and everything inside it, transitively, across files. They are in
+// workspace order.
+func (w *Workspace) Blockers(key string) ([]*Node, error) {
+	milestone := w.nodes[key]
+	if milestone == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownNode, key)
+	}
+	seen := map[*Node]bool{milestone: true}
+	queue := []*Node{milestone}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		deps := append(w.blockers(n), n.Children...)
+		if !n.IsPlan() {
+			if t := w.target(n); t != nil {
+				deps = append(deps, t)
+			}
+		}
+		for _, d := range deps {
+			if !seen[d] && !w.Done(d) {
+				seen[d] = true
+				queue = append(queue, d)
+			}
+		}
+	}
+
+	var out []*Node
+	for _, n := range w.order {
+		if n != milestone && seen[n] && w.isWork(n) {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,349 @@
+// Package workspace loads a directory tree of vBRIEF plans and joins them
+// into one graph, so a programme split across many files can be checked
+// and queried as a whole.
+//
+// Every plan and every plan item becomes a Node, keyed by the file's path
+// relative to the workspace root: "api.vbrief.json" for the plan itself
+// and "api.vbrief.json#setup.db" for an item. Edges join nodes:
+//
+//   - each plan's own edges, whose endpoints may name items in other files
+//     as "file.vbrief.json#item" (relative to the declaring file);
+//   - planRef links, as edges of type EdgePlanRef from the referring item
+//     to the plan or item it names.
+//
+// Refs to http(s):// documents are outside the workspace and are ignored.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	"github.com/visionik/vBRIEF/api/go/pkg/graph"
+	"github.com/visionik/vBRIEF/api/go/pkg/parser"
+	"github.com/visionik/vBRIEF/api/go/pkg/resolver"
+)
+
+// ErrUnknownNode is returned when a query names a node that is not in the
+// workspace.
+var ErrUnknownNode = errors.New("unknown node")
+
+// EdgePlanRef is the type of edges that stand for planRef links.
+const EdgePlanRef core.EdgeType = "planRef"
+
+// DefaultSuffix is the file name suffix of plans when Options.Match is nil.
+const DefaultSuffix = ".vbrief.json"
+
+// Options configures loading.
+type Options struct {
+	// Match reports whether a file, given its slash-separated path
+	// relative to the root, holds a plan. Nil matches names ending in
+	// DefaultSuffix.
+	Match func(path string) bool
+	// Roots lists the paths of the plans a programme starts from. Plans
+	// no root reaches are orphaned. Without roots, a plan is orphaned if
+	// it neither links to nor is linked from another plan.
+	Roots []string
+	// Parser parses plan files. Nil means parser.NewAutoParser().
+	Parser parser.Parser
+}
+
+// File is a plan file in the workspace.
+type File struct {
+	// Path is the file's slash-separated path relative to the root.
+	Path string
+	// Document is the parsed file, or nil if it could not be read.
+	Document *core.Document
+	// Err is the error reading or parsing the file, if any.
+	Err error
+
+	modTime time.Time
+	size    int64
+}
+
+// URI returns the file's URI, as planRefs name it.
+func (f *File) URI() string {
+	return "file://" + f.Path
+}
+
+// Node is a plan or plan item in the workspace graph.
+type Node struct {
+	// Key identifies the node: the file's path for a plan, and
+	// "path#id" for an item with hierarchical ID id. Items that cannot be
+	// referenced because they or an ancestor lack an ID are keyed by
+	// document path instead, e.g. "path#plan.items[2]".
+	Key  string
+	File *File
+	// ID is the item's hierarchical ID within its file; "" for plans.
+	ID string
+	// Item is nil for plans.
+	Item *core.PlanItem
+	// Path is the node's document path, e.g. "plan" or "plan.items[0]".
+	Path     string
+	Parent   *Node
+	Children []*Node
+}
+
+// IsPlan reports whether the node is a whole plan rather than an item.
+func (n *Node) IsPlan() bool {
+	return n.Item == nil
+}
+
+// Edge joins two nodes.
+type Edge struct {
+	// From and To are node keys.
+	From, To string
+	// Type is the plan edge's type, or EdgePlanRef.
+	Type core.EdgeType
+	// File and Field locate the declaring edge or planRef, e.g.
+	// "programme.vbrief.json" and "plan.edges[3]".
+	File, Field string
+}
+
+// link is a ref that names a node, or should, before it is resolved.
+type link struct {
+	file, field string
+	from        string
+	to          string // normalised URI, with fragment
+	typ         core.EdgeType
+}
+
+// Workspace is a set of plan files joined into one graph. It is not safe
+// for concurrent use while Reload runs.
+type Workspace struct {
+	fsys  fs.FS
+	opts  Options
+	files map[string]*File
+
+	order      []*Node
+	nodes      map[string]*Node
+	edges      []Edge
+	out, in    map[string][]Edge
+	unresolved []link
+}
+
+// Load loads the plans under dir.
+func Load(dir string) (*Workspace, error) {
+	return LoadFS(os.DirFS(dir), Options{})
+}
+
+// LoadFS loads the plans in fsys. Files that fail to parse do not stop
+// loading; they are kept with their error and reported by Check.
+func LoadFS(fsys fs.FS, opts Options) (*Workspace, error) {
+	if opts.Match == nil {
+		opts.Match = func(p string) bool { return strings.HasSuffix(p, DefaultSuffix) }
+	}
+	if opts.Parser == nil {
+		opts.Parser = parser.NewAutoParser()
+	}
+	w := &Workspace{fsys: fsys, opts: opts, files: make(map[string]*File)}
+	if _, err := w.Reload(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Reload rescans the tree, parses only files that are new or whose size
+// or modification time changed, drops files that are gone and rebuilds
+// the graph. It returns the paths of the files added, changed or
+// removed, sorted.
+func (w *Workspace) Reload() ([]string, error) {
+	seen := make(map[string]bool)
+	var changed []string
+	err := fs.WalkDir(w.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !w.opts.Match(p) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		seen[p] = true
+		if f, ok := w.files[p]; ok && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+			return nil
+		}
+		f := &File{Path: p, modTime: info.ModTime(), size: info.Size()}
+		if data, err := fs.ReadFile(w.fsys, p); err != nil {
+			f.Err = err
+		} else {
+			f.Document, f.Err = w.opts.Parser.ParseBytes(data)
+		}
+		w.files[p] = f
+		changed = append(changed, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for p := range w.files {
+		if !seen[p] {
+			delete(w.files, p)
+			changed = append(changed, p)
+		}
+	}
+	sort.Strings(changed)
+	w.build()
+	return changed, nil
+}
+
+// Files returns the workspace's files, sorted by path.
+func (w *Workspace) Files() []*File {
+	files := make([]*File, 0, len(w.files))
+	for _, f := range w.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// File returns the file at path, or nil.
+func (w *Workspace) File(path string) *File {
+	return w.files[path]
+}
+
+// Nodes returns every node: files in path order, each plan followed by
+// its items in document order.
+func (w *Workspace) Nodes() []*Node {
+	return w.order
+}
+
+// Node returns the node with the given key, or nil.
+func (w *Workspace) Node(key string) *Node {
+	return w.nodes[key]
+}
+
+// Edges returns the edges whose endpoints both resolve to nodes.
+func (w *Workspace) Edges() []Edge {
+	return w.edges
+}
+
+// Outgoing returns the edges from key.
+func (w *Workspace) Outgoing(key string) []Edge {
+	return w.out[key]
+}
+
+// Incoming returns the edges to key.
+func (w *Workspace) Incoming(key string) []Edge {
+	return w.in[key]
+}
+
+func (w *Workspace) build() {
+	w.order = nil
+	w.nodes = make(map[string]*Node)
+	w.edges = nil
+	w.out = make(map[string][]Edge)
+	w.in = make(map[string][]Edge)
+	w.unresolved = nil
+
+	var links []link
+	for _, f := range w.Files() {
+		if f.Document == nil || f.Document.Plan == nil {
+			continue
+		}
+		plan := &Node{Key: f.Path, File: f, Path: "plan"}
+		w.add(plan)
+		g := graph.New(f.Document.Plan)
+		byItem := map[*core.PlanItem]*Node{}
+		for _, gn := range g.Nodes() {
+			n := &Node{Key: itemKey(f.Path, gn), File: f, ID: gn.ID, Item: gn.Item, Path: gn.Path, Parent: plan}
+			if gn.Parent != nil {
+				n.Parent = byItem[gn.Parent.Item]
+			}
+			n.Parent.Children = append(n.Parent.Children, n)
+			byItem[gn.Item] = n
+			w.add(n)
+			if gn.Item.PlanRef != "" {
+				links = append(links, link{file: f.Path, field: gn.Path + ".planRef", from: n.Key, to: gn.Item.PlanRef, typ: EdgePlanRef})
+			}
+		}
+		for i, e := range f.Document.Plan.Edges {
+			from, to := e.From, e.To
+			if !strings.Contains(from, "#") && !strings.Contains(to, "#") {
+				if g.Node(from) != nil && g.Node(to) != nil {
+					w.connect(Edge{From: f.Path + "#" + from, To: f.Path + "#" + to, Type: e.Type, File: f.Path, Field: fmt.Sprintf("plan.edges[%d]", i)})
+				}
+				continue
+			}
+			links = append(links, link{file: f.Path, field: fmt.Sprintf("plan.edges[%d]", i), from: endpoint(from), to: endpoint(to), typ: e.Type})
+		}
+	}
+
+	for _, l := range links {
+		from, ok := w.resolve(l.file, l.from)
+		if !ok {
+			continue
+		}
+		to, ok := w.resolve(l.file, l.to)
+		if !ok {
+			continue
+		}
+		if w.nodes[from] == nil || w.nodes[to] == nil {
+			w.unresolved = append(w.unresolved, link{file: l.file, field: l.field, from: from, to: to, typ: l.typ})
+			continue
+		}
+		w.connect(Edge{From: from, To: to, Type: l.typ, File: l.file, Field: l.field})
+	}
+}
+
+func (w *Workspace) add(n *Node) {
+	w.order = append(w.order, n)
+	w.nodes[n.Key] = n
+}
+
+func (w *Workspace) connect(e Edge) {
+	w.edges = append(w.edges, e)
+	w.out[e.From] = append(w.out[e.From], e)
+	w.in[e.To] = append(w.in[e.To], e)
+}
+
+func itemKey(file string, n *graph.Node) string {
+	if n.ID == "" {
+		return file + "#" + n.Path
+	}
+	return file + "#" + n.ID
+}
+
+// endpoint turns an edge endpoint into a ref: "api.vbrief.json#setup"
+// becomes "file://api.vbrief.json#setup"; plain IDs become "#id".
+func endpoint(end string) string {
+	switch {
+	case strings.Contains(end, "://"), strings.HasPrefix(end, "#"):
+		return end
+	case strings.Contains(end, "#"):
+		return "file://" + end
+	default:
+		return "#" + end
+	}
+}
+
+// resolve turns a ref made in file into a node key. It reports false for
+// refs outside the workspace, such as http(s) URLs, and for refs that do
+// not parse; the lint profile reports those. Keys of missing nodes are
+// returned as they would be if the node existed.
+func (w *Workspace) resolve(file, ref string) (string, bool) {
+	if strings.HasPrefix(ref, file+"#") {
+		return ref, true
+	}
+	uri, fragment, err := resolver.Normalize("file://"+file, ref)
+	if err != nil {
+		return "", false
+	}
+	p, ok := strings.CutPrefix(uri, "file://")
+	if !ok {
+		return "", false
+	}
+	p = path.Clean(p)
+	if fragment == "" {
+		return p, true
+	}
+	return p + "#" + fragment, true
+}
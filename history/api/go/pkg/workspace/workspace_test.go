@@ -0,0 +1,253 @@
+package workspace
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// programmeFS is a programme split across files:
+//
+//	programme: api -> api plan, ui -> ui plan, launch (blocked by api and ui), docs -> missing
+//	api:       setup{db done, schema}, endpoints (blocked by setup)
+//	ui/ui:     screens, wire (blocked by ../api#endpoints)
+func programmeFS() fstest.MapFS {
+	file := func(s string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(s), ModTime: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	}
+	return fstest.MapFS{
+		"programme.vbrief.json": file(`{"vBRIEFInfo": {"version": "0.5"}, "plan": {"title": "Programme", "status": "running",
+			"items": [
+				{"id": "api", "title": "API", "status": "running", "planRef": "file://api.vbrief.json"},
+				{"id": "ui", "title": "UI", "status": "pending", "planRef": "file://ui/ui.vbrief.json"},
+				{"id": "launch", "title": "Launch", "status": "pending"},
+				{"id": "docs", "title": "Docs", "status": "blocked", "planRef": "file://docs.vbrief.json"}
+			],
+			"edges": [
+				{"from": "api", "to": "launch", "type": "blocks"},
+				{"from": "ui", "to": "launch", "type": "blocks"}
+			]}}`),
+		"api.vbrief.json": file(`{"vBRIEFInfo": {"version": "0.5"}, "plan": {"title": "API", "status": "running",
+			"items": [
+				{"id": "setup", "title": "Setup", "status": "running", "subItems": [
+					{"id": "db", "title": "DB", "status": "completed"},
+					{"id": "schema", "title": "Schema", "status": "pending"}
+				]},
+				{"id": "endpoints", "uid": "u-1", "title": "Endpoints", "status": "pending"}
+			],
+			"edges": [{"from": "setup", "to": "endpoints", "type": "blocks"}]}}`),
+		"ui/ui.vbrief.json": file(`{"vBRIEFInfo": {"version": "0.5"}, "plan": {"title": "UI", "status": "draft",
+			"items": [
+				{"id": "screens", "uid": "u-1", "title": "Screens", "status": "pending"},
+				{"id": "wire", "title": "Wire up", "status": "pending"}
+			],
+			"edges": [{"from": "../api.vbrief.json#endpoints", "to": "wire", "type": "blocks"}]}}`),
+		"orphan.vbrief.json": file(`{"vBRIEFInfo": {"version": "0.5"}, "plan": {"title": "Orphan", "status": "draft",
+			"items": [{"id": "o1", "title": "O1", "status": "pending"}]}}`),
+		"broken.vbrief.json": file(`{"plan": `),
+		"notes.json":         file(`not a plan`),
+	}
+}
+
+func keys(nodes []*Node) []string {
+	out := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, n.Key)
+	}
+	return out
+}
+
+func TestLoad(t *testing.T) {
+	w, err := LoadFS(programmeFS(), Options{})
+	require.NoError(t, err)
+
+	var paths []string
+	for _, f := range w.Files() {
+		paths = append(paths, f.Path)
+	}
+	assert.Equal(t, []string{"api.vbrief.json", "broken.vbrief.json", "orphan.vbrief.json", "programme.vbrief.json", "ui/ui.vbrief.json"}, paths)
+	assert.Error(t, w.File("broken.vbrief.json").Err)
+	assert.Equal(t, "file://ui/ui.vbrief.json", w.File("ui/ui.vbrief.json").URI())
+
+	n := w.Node("api.vbrief.json#setup.schema")
+	require.NotNil(t, n)
+	assert.Equal(t, "plan.items[0].subItems[0]", w.Node("api.vbrief.json#setup.db").Path)
+	assert.Equal(t, "api.vbrief.json#setup", n.Parent.Key)
+	assert.True(t, w.Node("api.vbrief.json").IsPlan())
+
+	edges := make(map[string]bool)
+	for _, e := range w.Edges() {
+		edges[string(e.Type)+" "+e.From+" -> "+e.To] = true
+	}
+	for _, want := range []string{
+		"planRef programme.vbrief.json#api -> api.vbrief.json",
+		"planRef programme.vbrief.json#ui -> ui/ui.vbrief.json",
+		"blocks programme.vbrief.json#api -> programme.vbrief.json#launch",
+		"blocks api.vbrief.json#setup -> api.vbrief.json#endpoints",
+		"blocks api.vbrief.json#endpoints -> ui/ui.vbrief.json#wire",
+	} {
+		assert.True(t, edges[want], want)
+	}
+	assert.Len(t, w.Edges(), 6)
+	assert.Len(t, w.Incoming("ui/ui.vbrief.json#wire"), 1)
+	assert.Len(t, w.Outgoing("programme.vbrief.json#ui"), 2)
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"no roots", Options{}, []string{
+			"broken.vbrief.json VB0284 document",
+			"orphan.vbrief.json VB0282 plan",
+			"programme.vbrief.json VB0280 plan.items[3].planRef",
+			"ui/ui.vbrief.json VB0283 plan.items[0].uid",
+		}},
+		{"roots", Options{Roots: []string{"programme.vbrief.json"}}, []string{
+			"broken.vbrief.json VB0284 document",
+			"orphan.vbrief.json VB0282 plan",
+			"programme.vbrief.json VB0280 plan.items[3].planRef",
+			"ui/ui.vbrief.json VB0283 plan.items[0].uid",
+		}},
+		{"links are followed from the declaring plan", Options{Roots: []string{"ui/ui.vbrief.json"}}, []string{
+			"broken.vbrief.json VB0284 document",
+			"orphan.vbrief.json VB0282 plan",
+			"programme.vbrief.json VB0280 plan.items[3].planRef",
+			"programme.vbrief.json VB0282 plan",
+			"ui/ui.vbrief.json VB0283 plan.items[0].uid",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := LoadFS(programmeFS(), tt.opts)
+			require.NoError(t, err)
+			var got []string
+			for _, f := range w.Check() {
+				got = append(got, f.File+" "+f.Code+" "+f.Field)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("messages", func(t *testing.T) {
+		w, err := LoadFS(programmeFS(), Options{})
+		require.NoError(t, err)
+		findings := w.Check()
+		assert.Equal(t, `programme.vbrief.json: error[VB0280]: "docs.vbrief.json" is not in the workspace (plan.items[3].planRef)`,
+			findings[2].String())
+		assert.Equal(t, `duplicate uid "u-1" (first used at api.vbrief.json plan.items[1])`, findings[3].Message)
+	})
+}
+
+func TestCheck_CrossFileCycle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.vbrief.json": {Data: []byte(`{"vBRIEFInfo": {"version": "0.5"}, "plan": {"title": "A", "status": "draft",
+			"items": [{"id": "x", "title": "X", "status": "pending"}, {"id": "z", "title": "Z", "status": "pending"}],
+			"edges": [{"from": "x", "to": "b.vbrief.json#y", "type": "blocks"}, {"from": "x", "to": "z", "type": "blocks"}]}}`)},
+		"b.vbrief.json": {Data: []byte(`{"vBRIEFInfo": {"version": "0.5"}, "plan": {"title": "B", "status": "draft",
+			"items": [{"id": "y", "title": "Y", "status": "pending", "planRef": "file://c.vbrief.json"}]}}`)},
+		"c.vbrief.json": {Data: []byte(`{"vBRIEFInfo": {"version": "0.5"}, "plan": {"title": "C", "status": "draft",
+			"items": [{"id": "w", "title": "W", "status": "pending"}],
+			"edges": [{"from": "w", "to": "a.vbrief.json#x", "type": "blocks"}, {"from": "w", "to": "a.vbrief.json#x", "type": "informs"}]}}`)},
+	}
+	w, err := LoadFS(fsys, Options{})
+	require.NoError(t, err)
+	findings := w.Check()
+	require.Len(t, findings, 1)
+	assert.Equal(t, CodeCrossFileCycle, findings[0].Code)
+	assert.Equal(t, "a.vbrief.json", findings[0].File)
+	assert.Equal(t, "cycle across files: a.vbrief.json#x -> b.vbrief.json#y -> c.vbrief.json -> c.vbrief.json#w -> a.vbrief.json#x",
+		findings[0].Message)
+}
+
+func TestQueries(t *testing.T) {
+	w, err := LoadFS(programmeFS(), Options{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"api.vbrief.json#setup.schema",
+		"orphan.vbrief.json#o1",
+		"ui/ui.vbrief.json#screens",
+	}, keys(w.Ready()))
+
+	blockers, err := w.Blockers("programme.vbrief.json#launch")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"api.vbrief.json#setup.schema",
+		"api.vbrief.json#endpoints",
+		"ui/ui.vbrief.json#screens",
+		"ui/ui.vbrief.json#wire",
+	}, keys(blockers))
+
+	blockers, err = w.Blockers("ui/ui.vbrief.json#wire")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api.vbrief.json#setup.schema", "api.vbrief.json#endpoints"}, keys(blockers))
+
+	_, err = w.Blockers("nope.vbrief.json#x")
+	assert.ErrorIs(t, err, ErrUnknownNode)
+
+	assert.False(t, w.Done(w.Node("programme.vbrief.json#api")))
+	assert.True(t, w.Done(w.Node("api.vbrief.json#setup.db")))
+}
+
+func TestReady_CancelledBlocker(t *testing.T) {
+	fsys := programmeFS()
+	// Cancelling the API endpoints, in another file, unblocks wire-up.
+	fsys["api.vbrief.json"].Data = []byte(`{"vBRIEFInfo": {"version": "0.5"}, "plan": {"title": "API", "status": "running",
+		"items": [
+			{"id": "setup", "title": "Setup", "status": "completed"},
+			{"id": "endpoints", "title": "Endpoints", "status": "cancelled"}
+		]}}`)
+	w, err := LoadFS(fsys, Options{})
+	require.NoError(t, err)
+
+	assert.True(t, w.Done(w.Node("api.vbrief.json#endpoints")))
+	assert.Equal(t, []string{
+		"orphan.vbrief.json#o1",
+		"ui/ui.vbrief.json#screens",
+		"ui/ui.vbrief.json#wire",
+	}, keys(w.Ready()))
+}
+
+func TestReload(t *testing.T) {
+	fsys := programmeFS()
+	w, err := LoadFS(fsys, Options{})
+	require.NoError(t, err)
+	api := w.File("api.vbrief.json").Document
+	ui := w.File("ui/ui.vbrief.json").Document
+
+	changed, err := w.Reload()
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+	assert.Same(t, api, w.File("api.vbrief.json").Document)
+
+	// Finish the schema, which unblocks the endpoints.
+	fsys["api.vbrief.json"] = &fstest.MapFile{
+		Data: []byte(`{"vBRIEFInfo": {"version": "0.5"}, "plan": {"title": "API", "status": "running",
+			"items": [
+				{"id": "setup", "title": "Setup", "status": "completed"},
+				{"id": "endpoints", "title": "Endpoints", "status": "pending"}
+			],
+			"edges": [{"from": "setup", "to": "endpoints", "type": "blocks"}]}}`),
+		ModTime: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+	delete(fsys, "orphan.vbrief.json")
+	delete(fsys, "broken.vbrief.json")
+	fsys["docs.vbrief.json"] = &fstest.MapFile{Data: []byte(`{"vBRIEFInfo": {"version": "0.5"},
+		"plan": {"title": "Docs", "status": "draft", "items": []}}`)}
+
+	changed, err = w.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api.vbrief.json", "broken.vbrief.json", "docs.vbrief.json", "orphan.vbrief.json"}, changed)
+	assert.NotSame(t, api, w.File("api.vbrief.json").Document)
+	assert.Same(t, ui, w.File("ui/ui.vbrief.json").Document)
+	assert.Nil(t, w.File("orphan.vbrief.json"))
+
+	assert.Equal(t, []string{"api.vbrief.json#endpoints", "ui/ui.vbrief.json#screens"}, keys(w.Ready()))
+	assert.Empty(t, w.Check())
+}
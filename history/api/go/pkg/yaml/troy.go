@@ -0,0 +1,236 @@
+package yaml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// classFor names the TROY class used for the elements of a sequence stored
+// under key, or "" for sequences written as plain YAML.
+func classFor(key string, inTodoList bool) string {
+	switch key {
+	case "items":
+		if inTodoList {
+			return "TodoItem"
+		}
+		return "PlanItem"
+	case "subItems":
+		return "PlanItem"
+	case "edges":
+		return "Edge"
+	case "reminders":
+		return "Reminder"
+	case "participants":
+		return "Participant"
+	}
+	return ""
+}
+
+// takeClasses removes the "classes" entry from the top-level mapping and
+// returns the field lists it defines.
+func takeClasses(top *yamlv3.Node) (map[string][]string, error) {
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		if top.Content[i].Value != classesKey {
+			continue
+		}
+		defs := top.Content[i+1]
+		top.Content = append(top.Content[:i:i], top.Content[i+2:]...)
+		if defs.Kind != yamlv3.MappingNode {
+			return nil, fmt.Errorf("%w: line %d: %s must be a mapping", ErrInvalidDocument, defs.Line, classesKey)
+		}
+		classes := make(map[string][]string, len(defs.Content)/2)
+		for j := 0; j+1 < len(defs.Content); j += 2 {
+			name, fields := defs.Content[j], defs.Content[j+1]
+			if fields.Kind != yamlv3.SequenceNode || len(fields.Content) == 0 {
+				return nil, fmt.Errorf("%w: line %d: class %s needs a list of fields", ErrInvalidDocument, fields.Line, name.Value)
+			}
+			for _, f := range fields.Content {
+				if f.Kind != yamlv3.ScalarNode || f.Value == "" {
+					return nil, fmt.Errorf("%w: line %d: class %s has an invalid field", ErrInvalidDocument, f.Line, name.Value)
+				}
+				classes[name.Value] = append(classes[name.Value], f.Value)
+			}
+		}
+		return classes, nil
+	}
+	return nil, nil
+}
+
+// expand rewrites TROY class instances into plain mappings.
+func expand(n *yamlv3.Node, classes map[string][]string) error {
+	for _, c := range n.Content {
+		if err := expand(c, classes); err != nil {
+			return err
+		}
+	}
+	if !strings.HasPrefix(n.Tag, "!") || strings.HasPrefix(n.Tag, "!!") {
+		return nil
+	}
+	name := n.Tag[1:]
+	fields, ok := classes[name]
+	if !ok {
+		return fmt.Errorf("%w: line %d: unknown class %s", ErrInvalidDocument, n.Line, name)
+	}
+	switch n.Kind {
+	case yamlv3.MappingNode:
+		n.Tag = ""
+	case yamlv3.SequenceNode:
+		values := n.Content
+		if len(fields) == 1 {
+			values = []*yamlv3.Node{{Kind: yamlv3.SequenceNode, Tag: "!!seq", Content: n.Content, Line: n.Line}}
+		}
+		if len(values) > len(fields) {
+			return fmt.Errorf("%w: line %d: %s takes %d values, got %d", ErrInvalidDocument, n.Line, name, len(fields), len(values))
+		}
+		var content []*yamlv3.Node
+		for i, v := range values {
+			if v.Kind == yamlv3.ScalarNode && v.ShortTag() == "!!null" {
+				continue
+			}
+			key := &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: fields[i], Line: v.Line}
+			content = append(content, key, v)
+		}
+		n.Kind, n.Tag, n.Style, n.Content = yamlv3.MappingNode, "!!map", 0, content
+	default:
+		return fmt.Errorf("%w: line %d: class %s needs a sequence or mapping", ErrInvalidDocument, n.Line, name)
+	}
+	return nil
+}
+
+// compact rewrites the class-eligible sequences of a plain document tree
+// into positional TROY instances and prepends the class definitions.
+func compact(top *yamlv3.Node) {
+	fields := make(map[string][]string)
+	known := make(map[string]map[string]bool)
+	eachInstance(top, false, true, func(class string, m *yamlv3.Node) {
+		if known[class] == nil {
+			known[class] = make(map[string]bool)
+		}
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			if k := m.Content[i].Value; !known[class][k] {
+				known[class][k] = true
+				fields[class] = append(fields[class], k)
+			}
+		}
+	})
+	if len(fields) == 0 {
+		return
+	}
+
+	eachInstance(top, false, false, func(class string, m *yamlv3.Node) {
+		byKey := make(map[string]int, len(m.Content)/2)
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			byKey[m.Content[i].Value] = i
+		}
+		flow := true
+		var values []*yamlv3.Node
+		for _, f := range fields[class] {
+			i, ok := byKey[f]
+			if !ok {
+				values = append(values, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!null", Value: "null"})
+				continue
+			}
+			k, v := m.Content[i], m.Content[i+1]
+			// Key comments have nowhere else to go.
+			if k.HeadComment != "" {
+				v.HeadComment = strings.TrimSpace(k.HeadComment + "\n" + v.HeadComment)
+			}
+			if v.LineComment == "" {
+				v.LineComment = k.LineComment
+			}
+			if v.HeadComment != "" || v.LineComment != "" || v.FootComment != "" || !flowable(v) {
+				flow = false
+			} else if v.Kind != yamlv3.ScalarNode {
+				v.Style = yamlv3.FlowStyle
+			}
+			values = append(values, v)
+		}
+		for len(values) > 0 && values[len(values)-1].ShortTag() == "!!null" {
+			values = values[:len(values)-1]
+		}
+		m.Kind, m.Tag, m.Content = yamlv3.SequenceNode, "!"+class, values
+		if flow {
+			m.Style = yamlv3.FlowStyle
+		}
+	})
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	defs := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+	for _, name := range names {
+		list := &yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq", Style: yamlv3.FlowStyle}
+		for _, f := range fields[name] {
+			list.Content = append(list.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: f})
+		}
+		defs.Content = append(defs.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: name}, list)
+	}
+	key := &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: classesKey}
+	top.Content = append([]*yamlv3.Node{key, defs}, top.Content...)
+}
+
+// eachInstance calls fn for every mapping that compact turns into a class
+// instance, outermost first when preorder is set and innermost first
+// otherwise.
+func eachInstance(n *yamlv3.Node, inTodoList, preorder bool, fn func(class string, m *yamlv3.Node)) {
+	if n.Kind != yamlv3.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, v := n.Content[i].Value, n.Content[i+1]
+		todo := inTodoList || key == "todoList"
+		if v.Kind == yamlv3.MappingNode {
+			eachInstance(v, todo, preorder, fn)
+			continue
+		}
+		if v.Kind != yamlv3.SequenceNode {
+			continue
+		}
+		class := classFor(key, todo)
+		if class == "" || !allMappings(v.Content) {
+			class = ""
+		}
+		for _, el := range v.Content {
+			if class != "" && preorder {
+				fn(class, el)
+			}
+			eachInstance(el, todo, preorder, fn)
+			if class != "" && !preorder {
+				fn(class, el)
+			}
+		}
+	}
+}
+
+func allMappings(nodes []*yamlv3.Node) bool {
+	for _, n := range nodes {
+		if n.Kind != yamlv3.MappingNode {
+			return false
+		}
+	}
+	return len(nodes) > 0
+}
+
+// flowable reports whether a node reads well inside a flow sequence.
+func flowable(n *yamlv3.Node) bool {
+	switch n.Kind {
+	case yamlv3.ScalarNode:
+		return !strings.Contains(n.Value, "\n")
+	case yamlv3.SequenceNode, yamlv3.MappingNode:
+		if n.Tag != "" && !strings.HasPrefix(n.Tag, "!!") {
+			return false
+		}
+		for _, c := range n.Content {
+			if c.Kind != yamlv3.ScalarNode || !flowable(c) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
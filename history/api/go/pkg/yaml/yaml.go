@@ -0,0 +1,305 @@
+// Package yaml reads and writes vBRIEF documents as YAML and as TROY, the
+// tagged-class YAML encoding explored in misc/vBRIEF-alternative-TROY.md.
+//
+// Plain YAML maps one-to-one onto the JSON model: the same keys, the same
+// nesting and the same values. TROY adds a top-level "classes" mapping that
+// lists each class's fields once, so instances can be written positionally:
+//
+//	classes:
+//	  PlanItem: [id, title, status, subItems]
+//	plan:
+//	  title: Release
+//	  status: draft
+//	  narratives: {}
+//	  items:
+//	    - !PlanItem [api, Build API, running]
+//	    - !PlanItem [docs, Write docs, pending]
+//
+// A positional null leaves its field unset. A tagged mapping (!PlanItem
+// {title: ...}) is read as the plain mapping, and a class with a single
+// field takes the whole tagged sequence as that field's value.
+//
+// YAML comments have no place in the document model, so DecodeWithComments
+// returns them separately, keyed by path, and Options.Comments puts them
+// back on encode.
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ErrInvalidDocument is returned when YAML cannot be read as a document.
+var ErrInvalidDocument = errors.New("yaml: invalid document")
+
+// classesKey is the top-level key holding TROY class definitions.
+const classesKey = "classes"
+
+// Comment holds the comments attached to one node, with their "#" markers.
+type Comment struct {
+	// Head is written on the lines above the node.
+	Head string
+	// Line is written after the node on the same line.
+	Line string
+	// Foot is written on the lines below the node.
+	Foot string
+}
+
+// Comments maps node paths to their comments. Paths use JSON field names,
+// dots and indexes, e.g. "plan.items[0].title"; the empty path is the
+// document itself.
+type Comments map[string]Comment
+
+// Options configures Encode.
+type Options struct {
+	// TROY writes tagged-class TROY instead of plain YAML.
+	TROY bool
+	// Comments are re-attached to the nodes at their paths. Comments whose
+	// path no longer exists are dropped.
+	Comments Comments
+}
+
+// Decode reads a document from plain YAML or TROY.
+func Decode(data []byte) (*core.Document, error) {
+	doc, _, err := DecodeWithComments(data)
+	return doc, err
+}
+
+// DecodeWithComments reads a document from plain YAML or TROY and returns
+// the comments found in it.
+func DecodeWithComments(data []byte) (*core.Document, Comments, error) {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &root); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	if root.Kind != yamlv3.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yamlv3.MappingNode {
+		return nil, nil, fmt.Errorf("%w: top level is not a mapping", ErrInvalidDocument)
+	}
+	top := root.Content[0]
+
+	classes, err := takeClasses(top)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := expand(top, classes); err != nil {
+		return nil, nil, err
+	}
+
+	comments := make(Comments)
+	if c := nodeComment(&root, nil); c != (Comment{}) {
+		comments[""] = c
+	}
+	collectComments(top, "", comments)
+
+	value, err := toValue(top)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err = json.Marshal(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	var doc core.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	return &doc, comments, nil
+}
+
+// Encode writes a document as plain YAML or, with opts.TROY, as TROY.
+func Encode(doc *core.Document, opts Options) ([]byte, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("%w: nil document", ErrInvalidDocument)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, so this yields the node tree with JSON's key order.
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	top := root.Content[0]
+	resetStyle(top)
+
+	if c, ok := opts.Comments[""]; ok {
+		root.HeadComment, root.LineComment, root.FootComment = c.Head, c.Line, c.Foot
+	}
+	applyComments(top, "", opts.Comments)
+
+	if opts.TROY {
+		compact(top)
+	}
+
+	var buf bytes.Buffer
+	enc := yamlv3.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toValue converts a node to a JSON-compatible value. Scalars keep their
+// YAML type, except timestamps, which stay strings for encoding/json.
+func toValue(n *yamlv3.Node) (interface{}, error) {
+	switch n.Kind {
+	case yamlv3.AliasNode:
+		return toValue(n.Alias)
+	case yamlv3.MappingNode:
+		m := make(map[string]interface{}, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Kind == yamlv3.AliasNode {
+				k = k.Alias
+			}
+			if k.Kind == yamlv3.ScalarNode && k.Tag == "!!merge" {
+				return nil, fmt.Errorf("%w: line %d: merge keys are not supported", ErrInvalidDocument, k.Line)
+			}
+			val, err := toValue(v)
+			if err != nil {
+				return nil, err
+			}
+			m[k.Value] = val
+		}
+		return m, nil
+	case yamlv3.SequenceNode:
+		s := make([]interface{}, len(n.Content))
+		for i, c := range n.Content {
+			val, err := toValue(c)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = val
+		}
+		return s, nil
+	case yamlv3.ScalarNode:
+		switch n.ShortTag() {
+		case "!!null":
+			return nil, nil
+		case "!!bool":
+			var b bool
+			if err := n.Decode(&b); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidDocument, n.Line, err)
+			}
+			return b, nil
+		case "!!int":
+			var i int64
+			if err := n.Decode(&i); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidDocument, n.Line, err)
+			}
+			return json.Number(strconv.FormatInt(i, 10)), nil
+		case "!!float":
+			var f float64
+			if err := n.Decode(&f); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidDocument, n.Line, err)
+			}
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+		case "!!str", "!!timestamp", "!!binary":
+			return n.Value, nil
+		default:
+			return nil, fmt.Errorf("%w: line %d: unknown tag %s", ErrInvalidDocument, n.Line, n.Tag)
+		}
+	}
+	return nil, fmt.Errorf("%w: line %d: unexpected node", ErrInvalidDocument, n.Line)
+}
+
+// resetStyle clears the flow and quoting styles inherited from JSON so the
+// encoder picks block style and only quotes where YAML needs it.
+func resetStyle(n *yamlv3.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetStyle(c)
+	}
+}
+
+func childPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func indexPath(parent string, i int) string {
+	return fmt.Sprintf("%s[%d]", parent, i)
+}
+
+// nodeComment merges the comments of a mapping key and its value, or of a
+// single node when key is nil.
+func nodeComment(n, key *yamlv3.Node) Comment {
+	c := Comment{Head: n.HeadComment, Line: n.LineComment, Foot: n.FootComment}
+	if key != nil {
+		c.Head = strings.TrimSpace(strings.Join([]string{key.HeadComment, c.Head}, "\n"))
+		if c.Line == "" {
+			c.Line = key.LineComment
+		}
+		if c.Foot == "" {
+			c.Foot = key.FootComment
+		}
+	}
+	return c
+}
+
+func collectComments(n *yamlv3.Node, path string, out Comments) {
+	switch n.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			p := childPath(path, k.Value)
+			if c := nodeComment(v, k); c != (Comment{}) {
+				out[p] = c
+			}
+			collectComments(v, p, out)
+		}
+	case yamlv3.SequenceNode:
+		for i, c := range n.Content {
+			p := indexPath(path, i)
+			if cm := nodeComment(c, nil); cm != (Comment{}) {
+				out[p] = cm
+			}
+			collectComments(c, p, out)
+		}
+	}
+}
+
+func applyComments(n *yamlv3.Node, path string, comments Comments) {
+	if len(comments) == 0 {
+		return
+	}
+	switch n.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			p := childPath(path, k.Value)
+			if c, ok := comments[p]; ok {
+				k.HeadComment = c.Head
+				if v.Kind == yamlv3.ScalarNode {
+					v.LineComment = c.Line
+				} else {
+					k.LineComment = c.Line
+				}
+				v.FootComment = c.Foot
+			}
+			applyComments(v, p, comments)
+		}
+	case yamlv3.SequenceNode:
+		for i, c := range n.Content {
+			p := indexPath(path, i)
+			if cm, ok := comments[p]; ok {
+				c.HeadComment, c.LineComment, c.FootComment = cm.Head, cm.Line, cm.Foot
+			}
+			applyComments(c, p, comments)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package yaml
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/visionik/vBRIEF/api/go/pkg/core"
+)
+
+func sampleDoc() *core.Document {
+	created := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	return &core.Document{
+		Info: core.Info{Version: core.SpecVersion, Author: "ana"},
+		Plan: &core.Plan{
+			Title:      "Release",
+			Status:     core.PlanStatusApproved,
+			Narratives: map[string]string{"Overview": "Ship it.\nThen rest."},
+			Items: []core.PlanItem{
+				{
+					ID:       "api",
+					Title:    "Build API",
+					Status:   core.PlanItemStatusInProgress,
+					Created:  &created,
+					Tags:     []string{"backend", "yes"},
+					Metadata: map[string]interface{}{"points": 3.5, "owner": "ana"},
+					SubItems: []core.PlanItem{
+						{ID: "schema", Title: "Schema", Status: core.PlanItemStatusCompleted},
+					},
+				},
+				{ID: "docs", Title: "true", Status: core.PlanItemStatusPending, Priority: core.PriorityLow},
+			},
+			Edges: []core.Edge{{From: "api", To: "docs", Type: core.EdgeBlocks}},
+		},
+	}
+}
+
+// jsonOf compares documents through the JSON model, which is what a
+// lossless YAML mapping must preserve.
+func jsonOf(t *testing.T, doc *core.Document) string {
+	t.Helper()
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, troy := range []bool{false, true} {
+		name := "yaml"
+		if troy {
+			name = "troy"
+		}
+		t.Run(name, func(t *testing.T) {
+			doc := sampleDoc()
+			data, err := Encode(doc, Options{TROY: troy})
+			require.NoError(t, err)
+
+			got, err := Decode(data)
+			require.NoError(t, err)
+			assert.Equal(t, jsonOf(t, doc), jsonOf(t, got))
+
+			again, err := Encode(got, Options{TROY: troy})
+			require.NoError(t, err)
+			assert.Equal(t, string(data), string(again))
+		})
+	}
+}
+
+func TestEncode_TROY(t *testing.T) {
+	data, err := Encode(sampleDoc(), Options{TROY: true})
+	require.NoError(t, err)
+	out := string(data)
+	assert.Contains(t, out, "classes:\n  Edge: [from, to, type]\n")
+	assert.Contains(t, out, "      - - !PlanItem [schema, Schema, completed]\n")
+	assert.Contains(t, out, "    - !PlanItem [docs, \"true\", pending, null, null, null, null, low]\n",
+		"missing fields become nulls and scalars that read as other types stay quoted")
+	assert.Contains(t, out, "    - !Edge [api, docs, blocks]\n")
+}
+
+func TestDecode_TROY(t *testing.T) {
+	src := `classes:
+  PlanItem: [id, title, status, subItems]
+  Labels: [labels]
+vBRIEFInfo:
+  version: "0.5"
+plan:
+  title: Release
+  status: draft
+  narratives: {}
+  items:
+    - !PlanItem [api, Build API, inProgress]
+    - !PlanItem
+      - docs
+      - Write docs
+      - pending
+      - - !PlanItem {id: guide, title: Guide, status: pending, metadata: !Labels [a, b]}
+    - !PlanItem [misc, Misc, null]
+`
+	doc, err := Decode([]byte(src))
+	require.NoError(t, err)
+	items := doc.Plan.Items
+	require.Len(t, items, 3)
+	assert.Equal(t, core.PlanItemStatusInProgress, items[0].Status)
+	require.Len(t, items[1].SubItems, 1)
+	assert.Equal(t, "guide", items[1].SubItems[0].ID)
+	assert.Equal(t, map[string]interface{}{"labels": []interface{}{"a", "b"}}, items[1].SubItems[0].Metadata,
+		"a single-field class takes the whole sequence")
+	assert.Equal(t, core.PlanItemStatus(""), items[2].Status, "a positional null leaves the field unset")
+}
+
+func TestComments(t *testing.T) {
+	src := `# Release plan
+
+vBRIEFInfo:
+  version: "0.5"
+plan:
+  title: Release # working title
+  status: draft
+  narratives: {}
+  items:
+    # first up
+    - id: api
+      title: Build API
+      status: pending
+`
+	doc, comments, err := DecodeWithComments([]byte(src))
+	require.NoError(t, err)
+	assert.Equal(t, "# Release plan", comments[""].Head)
+	assert.Equal(t, "# working title", comments["plan.title"].Line)
+	assert.Equal(t, "# first up", comments["plan.items[0]"].Head)
+
+	doc.Plan.Items[0].Status = core.PlanItemStatusInProgress
+	data, err := Encode(doc, Options{Comments: comments})
+	require.NoError(t, err)
+	out := string(data)
+	assert.Contains(t, out, "# Release plan\n\nvBRIEFInfo:\n")
+	assert.Contains(t, out, "  title: Release # working title\n")
+	assert.Contains(t, out, "    # first up\n    - id: api\n")
+	assert.Contains(t, out, "      status: inProgress\n")
+
+	data, err = Encode(doc, Options{TROY: true, Comments: comments})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "    # first up\n    - !PlanItem [api, Build API, inProgress]\n")
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		msg  string
+	}{
+		{"not yaml", "plan: [", "did not find expected"},
+		{"not a mapping", "- a\n- b\n", "top level is not a mapping"},
+		{"bad classes", "classes: [a]\n", "classes must be a mapping"},
+		{"empty class", "classes:\n  A: []\n", "class A needs a list of fields"},
+		{"unknown class", "plan:\n  items:\n    - !Item [a]\n", "unknown class Item"},
+		{"too many values", "classes:\n  Edge: [from, to, type]\nplan:\n  edges:\n    - !Edge [a, b, blocks, x]\n", "Edge takes 3 values, got 4"},
+		{"tagged scalar", "classes:\n  A: [x, y]\nplan: !A z\n", "class A needs a sequence or mapping"},
+		{"merge key", "a: &a {x: 1}\nplan:\n  <<: *a\n", "merge keys are not supported"},
+		{"wrong type", "plan:\n  items: 3\n", "cannot unmarshal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.src))
+			require.ErrorIs(t, err, ErrInvalidDocument)
+			assert.Contains(t, err.Error(), tt.msg)
+		})
+	}
+}
+
+func TestEncode_NilDocument(t *testing.T) {
+	_, err := Encode(nil, Options{})
+	assert.ErrorIs(t, err, ErrInvalidDocument)
+}